@@ -123,7 +123,7 @@ func main() {
 			},
 			{
 				Name:   "list-nodes",
-				Usage:  "list all nodes",
+				Usage:  "list all nodes, except those that are draining",
 				Action: listNodes,
 			},
 		},
@@ -201,6 +201,19 @@ func startServer(c *cli.Context) error {
 
 	go func() {
 		sig := <-sigChan
+		if sig == syscall.SIGTERM {
+			// rooms are moved to other nodes first, unless another signal arrives while draining
+			logger.Infow("exit requested, draining", "signal", sig)
+			drained := make(chan struct{})
+			go func() {
+				server.Drain()
+				close(drained)
+			}()
+			select {
+			case <-drained:
+			case sig = <-sigChan:
+			}
+		}
 		logger.Infow("exit requested, shutting down", "signal", sig)
 		server.Stop()
 	}()
@@ -17,6 +17,8 @@ require (
 	github.com/magefile/mage v1.11.0
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.3.0
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/nats-io/nats-server/v2 v2.6.4
+	github.com/nats-io/nats.go v1.13.1-0.20211018182449-f2416a8b1483
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/pion/ice/v2 v2.1.10
 	github.com/pion/interceptor v0.0.15
@@ -25,6 +27,7 @@ require (
 	github.com/pion/rtcp v1.2.6
 	github.com/pion/rtp v1.7.1
 	github.com/pion/sdp/v3 v3.0.4
+	github.com/pion/srtp/v2 v2.0.5
 	github.com/pion/stun v0.3.5
 	github.com/pion/transport v0.12.3
 	github.com/pion/turn/v2 v2.0.5
@@ -43,6 +46,7 @@ require (
 	golang.org/x/sys v0.0.0-20210816183151-1e6c022a8912 // indirect
 	golang.org/x/tools v0.1.5 // indirect
 	google.golang.org/protobuf v1.27.1
+	gopkg.in/square/go-jose.v2 v2.5.1
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
 
@@ -0,0 +1,759 @@
+// Package client is a participant that runs in Go, for bots that publish and subscribe to rooms on the server
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/thoas/go-funk"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RTCClient is a participant connected with a WebSocket, like the SDKs. callbacks are called from the client's
+// goroutines, and should be set before Run
+type RTCClient struct {
+	id         string
+	conn       *websocket.Conn
+	publisher  *transport
+	subscriber *transport
+	reliableDC *webrtc.DataChannel
+	lossyDC    *webrtc.DataChannel
+	// sid => track
+	localTracks  map[string]webrtc.TrackLocal
+	lock         sync.Mutex
+	wsLock       sync.Mutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	connected    utils.AtomicFlag
+	iceConnected utils.AtomicFlag
+	// closed once ICE first connects
+	iceConnectedChan   chan struct{}
+	subscribedTracks   map[string][]*webrtc.TrackRemote
+	localParticipant   *livekit.ParticipantInfo
+	remoteParticipants map[string]*livekit.ParticipantInfo
+
+	// tracks waiting to be acked, cid => trackInfo
+	pendingPublishedTracks map[string]*livekit.TrackInfo
+
+	OnConnected func()
+	// a track is subscribed to, its StreamID is the SID of the participant publishing it
+	OnTrackSubscribed func(track *webrtc.TrackRemote)
+	// the track was unsubscribed from, or its publisher left
+	OnTrackUnsubscribed func(track *webrtc.TrackRemote)
+	// each packet read from a subscribed track
+	OnRTPPacket func(track *webrtc.TrackRemote, packet *rtp.Packet)
+	// packets from other participants, or sent through the Room Service
+	OnDataPacket func(packet *livekit.UserPacket, kind livekit.DataPacket_Kind)
+	// participants that joined, left or changed
+	OnParticipantUpdate func(participants []*livekit.ParticipantInfo)
+	// server messages that aren't part of the protocol, like room metadata updates
+	OnControlMessage func(msg *types.ControlMessage)
+
+	// to resume the signal connection, set when dialed
+	host  string
+	token string
+	opts  *Options
+}
+
+var (
+	// minimal settings only with stun server
+	rtcConf = webrtc.Configuration{
+		ICEServers: []webrtc.ICEServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+	}
+	extMimeMapping = map[string]string{
+		".ivf":  webrtc.MimeTypeVP8,
+		".h264": webrtc.MimeTypeH264,
+		".ogg":  webrtc.MimeTypeOpus,
+	}
+
+	// signal connections are resumed this many times, waiting longer after each attempt
+	maxResumeAttempts = 5
+	resumeBackoff     = 500 * time.Millisecond
+
+	ErrTrackNotPublished = errors.New("could not publish track after timeout")
+	ErrDataUnavailable   = errors.New("data channel is not open")
+)
+
+const (
+	lossyDataChannel    = "_lossy"
+	reliableDataChannel = "_reliable"
+	controlDataChannel  = "_control"
+)
+
+type Options struct {
+	AutoSubscribe bool
+}
+
+func NewWebSocketConn(host, token string, opts *Options) (*websocket.Conn, error) {
+	return dialWebSocket(host, token, opts, false)
+}
+
+func dialWebSocket(host, token string, opts *Options, reconnect bool) (*websocket.Conn, error) {
+	u, err := url.Parse(host + "/rtc")
+	if err != nil {
+		return nil, err
+	}
+	requestHeader := make(http.Header)
+	SetAuthorizationToken(requestHeader, token)
+
+	query := url.Values{}
+	if opts != nil {
+		query.Set("auto_subscribe", strconv.FormatBool(opts.AutoSubscribe))
+	}
+	if reconnect {
+		query.Set("reconnect", "1")
+	}
+	u.RawQuery = query.Encode()
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), requestHeader)
+	return conn, err
+}
+
+// Dial connects to host, like ws://localhost:7880, joining the room of the token once Run is called.
+// unlike clients created from a connection, the signal connection is resumed when it drops
+func Dial(host, token string, opts *Options) (*RTCClient, error) {
+	conn, err := NewWebSocketConn(host, token, opts)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewRTCClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	c.host = host
+	c.token = token
+	c.opts = opts
+	return c, nil
+}
+
+func SetAuthorizationToken(header http.Header, token string) {
+	header.Set("Authorization", "Bearer "+token)
+}
+
+func NewRTCClient(conn *websocket.Conn) (*RTCClient, error) {
+	var err error
+
+	c := &RTCClient{
+		conn:                   conn,
+		localTracks:            make(map[string]webrtc.TrackLocal),
+		pendingPublishedTracks: make(map[string]*livekit.TrackInfo),
+		subscribedTracks:       make(map[string][]*webrtc.TrackRemote),
+		remoteParticipants:     make(map[string]*livekit.ParticipantInfo),
+		iceConnectedChan:       make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	if c.publisher, err = newTransport(rtcConf); err != nil {
+		return nil, err
+	}
+	if c.subscriber, err = newTransport(rtcConf); err != nil {
+		c.publisher.Close()
+		return nil, err
+	}
+
+	c.publisher.pc.OnICECandidate(func(ic *webrtc.ICECandidate) {
+		if ic == nil {
+			return
+		}
+		c.SendIceCandidate(ic, livekit.SignalTarget_PUBLISHER)
+	})
+	c.subscriber.pc.OnICECandidate(func(ic *webrtc.ICECandidate) {
+		if ic == nil {
+			return
+		}
+		c.SendIceCandidate(ic, livekit.SignalTarget_SUBSCRIBER)
+	})
+
+	c.subscriber.pc.OnTrack(func(track *webrtc.TrackRemote, rtpReceiver *webrtc.RTPReceiver) {
+		go c.processTrack(track)
+	})
+
+	c.publisher.OnOffer(c.onOffer)
+
+	c.publisher.pc.OnICEConnectionStateChange(func(connectionState webrtc.ICEConnectionState) {
+		logger.Debugw("ICE state has changed", "state", connectionState.String(),
+			"participant", c.ID())
+		if connectionState == webrtc.ICEConnectionStateConnected {
+			if initialConnect := c.iceConnected.TrySet(true); !initialConnect {
+				return
+			}
+			close(c.iceConnectedChan)
+
+			if c.OnConnected != nil {
+				go c.OnConnected()
+			}
+		}
+	})
+
+	return c, nil
+}
+
+func (c *RTCClient) ID() string {
+	return c.id
+}
+
+// Run joins the room, and handles the session until the client is stopped or the server ends it
+func (c *RTCClient) Run() error {
+	c.setCloseHandler(c.conn)
+
+	// create data channels, in order to work
+	if err := c.createDataChannels(); err != nil {
+		return err
+	}
+
+	// run the session
+	for {
+		res, err := c.ReadResponse()
+		if isClosed(err) || (err != nil && c.ctx.Err() != nil) {
+			// the server ended the session, or the client was stopped
+			return nil
+		} else if err != nil && c.host != "" {
+			// the signal connection dropped
+			err = c.resume()
+			if err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		switch msg := res.Message.(type) {
+		case *livekit.SignalResponse_Join:
+			c.localParticipant = msg.Join.Participant
+			c.id = msg.Join.Participant.Sid
+
+			c.lock.Lock()
+			for _, p := range msg.Join.OtherParticipants {
+				c.remoteParticipants[p.Sid] = p
+			}
+			c.lock.Unlock()
+
+			logger.Debugw("join accepted, sending offer..", "participant", msg.Join.Participant.Identity)
+			logger.Debugw("other participants", "count", len(msg.Join.OtherParticipants))
+
+			// the offer is sent through onOffer, and starts the gathering of ICE candidates
+			if err = c.publisher.Negotiate(); err != nil {
+				return err
+			}
+
+			defer c.Stop()
+		case *livekit.SignalResponse_Answer:
+			//logger.Debugw("received server answer",
+			//	"participant", c.localParticipant.Identity,
+			//	"answer", msg.Answer.Sdp)
+			if err := c.handleAnswer(fromProtoSessionDescription(msg.Answer)); err != nil {
+				return err
+			}
+		case *livekit.SignalResponse_Offer:
+			//logger.Debugw("received server offer",
+			//	"participant", c.localParticipant.Identity,
+			//	"sdp", msg.Offer.Sdp)
+			desc := fromProtoSessionDescription(msg.Offer)
+			if err := c.handleOffer(desc); err != nil {
+				return err
+			}
+		case *livekit.SignalResponse_Trickle:
+			candidateInit, err := fromProtoTrickle(msg.Trickle)
+			if err != nil {
+				return err
+			}
+			if msg.Trickle.Target == livekit.SignalTarget_PUBLISHER {
+				err = c.publisher.AddICECandidate(candidateInit)
+			} else {
+				err = c.subscriber.AddICECandidate(candidateInit)
+			}
+			if err != nil {
+				return err
+			}
+		case *livekit.SignalResponse_Update:
+			participants := make(map[string]*livekit.ParticipantInfo)
+			for _, p := range msg.Update.Participants {
+				if p.State != livekit.ParticipantInfo_DISCONNECTED {
+					participants[p.Sid] = p
+				}
+			}
+			c.lock.Lock()
+			c.remoteParticipants = participants
+			c.lock.Unlock()
+			if c.OnParticipantUpdate != nil {
+				c.OnParticipantUpdate(msg.Update.Participants)
+			}
+
+		case *livekit.SignalResponse_TrackPublished:
+			logger.Debugw("track published", "track", msg.TrackPublished.Track.Name, "participant", c.localParticipant.Sid,
+				"cid", msg.TrackPublished.Cid, "trackSid", msg.TrackPublished.Track.Sid)
+			c.lock.Lock()
+			c.pendingPublishedTracks[msg.TrackPublished.Cid] = msg.TrackPublished.Track
+			c.lock.Unlock()
+		case *livekit.SignalResponse_Leave:
+			logger.Infow("server ended the session", "participant", c.ID())
+			return nil
+		}
+	}
+}
+
+// setCloseHandler answers close frames. once the connection is closed, Run ends the session when it was closed
+// normally, or resumes it
+func (c *RTCClient) setCloseHandler(conn *websocket.Conn) {
+	conn.SetCloseHandler(func(code int, text string) error {
+		logger.Infow("connection closed", "participant", c.ID(), "code", code, "text", text)
+		c.wsLock.Lock()
+		defer c.wsLock.Unlock()
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+		return nil
+	})
+}
+
+// isClosed is true when the signal connection was closed on purpose, by either side
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || websocket.IsCloseError(err, websocket.CloseNormalClosure)
+}
+
+// resume reconnects the signal connection, keeping the participant's session and its peer connections
+func (c *RTCClient) resume() error {
+	var err error
+	for attempt := 1; attempt <= maxResumeAttempts; attempt++ {
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(time.Duration(attempt) * resumeBackoff):
+		}
+
+		logger.Infow("resuming signal connection", "participant", c.ID(), "attempt", attempt)
+		var conn *websocket.Conn
+		if conn, err = dialWebSocket(c.host, c.token, c.opts, true); err != nil {
+			continue
+		}
+		c.setCloseHandler(conn)
+		c.wsLock.Lock()
+		_ = c.conn.Close()
+		c.conn = conn
+		c.wsLock.Unlock()
+		return nil
+	}
+	return err
+}
+
+func (c *RTCClient) createDataChannels() error {
+	pc := c.publisher.pc
+	reliableDC, err := pc.CreateDataChannel(reliableDataChannel, nil)
+	if err != nil {
+		return err
+	}
+	maxRetransmits := uint16(0)
+	ordered := false
+	lossyDC, err := pc.CreateDataChannel(lossyDataChannel, &webrtc.DataChannelInit{
+		Ordered:        &ordered,
+		MaxRetransmits: &maxRetransmits,
+	})
+	if err != nil {
+		return err
+	}
+	controlDC, err := pc.CreateDataChannel(controlDataChannel, nil)
+	if err != nil {
+		return err
+	}
+
+	reliableDC.OnMessage(func(msg webrtc.DataChannelMessage) {
+		c.handleDataMessage(msg.Data)
+	})
+	lossyDC.OnMessage(func(msg webrtc.DataChannelMessage) {
+		c.handleDataMessage(msg.Data)
+	})
+	controlDC.OnMessage(func(msg webrtc.DataChannelMessage) {
+		controlMsg := &types.ControlMessage{}
+		if err := json.Unmarshal(msg.Data, controlMsg); err != nil {
+			logger.Debugw("could not parse control message", "err", err)
+			return
+		}
+		if c.OnControlMessage != nil {
+			c.OnControlMessage(controlMsg)
+		}
+	})
+
+	c.lock.Lock()
+	c.reliableDC = reliableDC
+	c.lossyDC = lossyDC
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *RTCClient) handleDataMessage(data []byte) {
+	dp := &livekit.DataPacket{}
+	if err := proto.Unmarshal(data, dp); err != nil {
+		logger.Debugw("could not parse data packet", "err", err)
+		return
+	}
+	if user := dp.GetUser(); user != nil && c.OnDataPacket != nil {
+		c.OnDataPacket(user, dp.Kind)
+	}
+}
+
+func (c *RTCClient) WaitUntilConnected() error {
+	select {
+	case <-c.iceConnectedChan:
+		return nil
+	case <-time.After(5 * time.Second):
+		id := c.ID()
+		if c.localParticipant != nil {
+			id = c.localParticipant.Identity
+		}
+		return fmt.Errorf("%s could not connect after timeout", id)
+	}
+}
+
+func (c *RTCClient) ReadResponse() (*livekit.SignalResponse, error) {
+	for {
+		// the connection is only replaced by Run, which reads
+		c.wsLock.Lock()
+		conn := c.conn
+		c.wsLock.Unlock()
+
+		// handle special messages and pass on the rest
+		messageType, payload, err := conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		if c.ctx.Err() != nil {
+			return nil, c.ctx.Err()
+		}
+
+		msg := &livekit.SignalResponse{}
+		switch messageType {
+		case websocket.PingMessage:
+			c.wsLock.Lock()
+			_ = conn.WriteMessage(websocket.PongMessage, nil)
+			c.wsLock.Unlock()
+			continue
+		case websocket.BinaryMessage:
+			// protobuf encoded
+			err := proto.Unmarshal(payload, msg)
+			return msg, err
+		case websocket.TextMessage:
+			// json encoded, also write back JSON
+			err := protojson.Unmarshal(payload, msg)
+			return msg, err
+		default:
+			return nil, nil
+		}
+	}
+}
+
+func (c *RTCClient) SubscribedTracks() map[string][]*webrtc.TrackRemote {
+	// create a copy of this
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tracks := make(map[string][]*webrtc.TrackRemote, len(c.subscribedTracks))
+	for key, val := range c.subscribedTracks {
+		tracks[key] = val
+	}
+	return tracks
+}
+
+func (c *RTCClient) RemoteParticipants() []*livekit.ParticipantInfo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return funk.Values(c.remoteParticipants).([]*livekit.ParticipantInfo)
+}
+
+func (c *RTCClient) Stop() {
+	_ = c.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Leave{
+			Leave: &livekit.LeaveRequest{},
+		},
+	})
+	c.connected.TrySet(false)
+	c.iceConnected.TrySet(false)
+	c.cancel()
+	c.wsLock.Lock()
+	_ = c.conn.Close()
+	c.wsLock.Unlock()
+	c.publisher.Close()
+	c.subscriber.Close()
+}
+
+func (c *RTCClient) SendRequest(msg *livekit.SignalRequest) error {
+	payload, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, payload)
+}
+
+func (c *RTCClient) SendIceCandidate(ic *webrtc.ICECandidate, target livekit.SignalTarget) error {
+	return c.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Trickle{
+			Trickle: toProtoTrickle(ic.ToJSON(), target),
+		},
+	})
+}
+
+// PublishData sends a data packet to everyone else in the room, or to participants with destinationSids
+func (c *RTCClient) PublishData(data []byte, kind livekit.DataPacket_Kind, destinationSids ...string) error {
+	payload, err := proto.Marshal(&livekit.DataPacket{
+		Kind: kind,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				ParticipantSid:  c.ID(),
+				Payload:         data,
+				DestinationSids: destinationSids,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	dc := c.lossyDC
+	if kind == livekit.DataPacket_RELIABLE {
+		dc = c.reliableDC
+	}
+	c.lock.Unlock()
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return ErrDataUnavailable
+	}
+	return dc.Send(payload)
+}
+
+// PublishTrack publishes a track that's written to by the caller, returning its SID. samples written before
+// the client is connected are dropped
+func (c *RTCClient) PublishTrack(track webrtc.TrackLocal) (string, error) {
+	ti, err := c.waitForTrackPublished(track)
+	if err != nil {
+		return "", err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err = c.addLocalTrack(ti, track); err != nil {
+		return "", err
+	}
+	return ti.Sid, nil
+}
+
+// waitForTrackPublished sends AddTrack, and waits until the server has acknowledged it
+func (c *RTCClient) waitForTrackPublished(track webrtc.TrackLocal) (*livekit.TrackInfo, error) {
+	trackType := livekit.TrackType_AUDIO
+	if track.Kind() == webrtc.RTPCodecTypeVideo {
+		trackType = livekit.TrackType_VIDEO
+	}
+
+	if err := c.SendAddTrack(track.ID(), track.StreamID(), trackType); err != nil {
+		return nil, err
+	}
+
+	// wait till track published message is received
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-timeout:
+			return nil, ErrTrackNotPublished
+		default:
+			c.lock.Lock()
+			ti := c.pendingPublishedTracks[track.ID()]
+			c.lock.Unlock()
+			if ti != nil {
+				return ti, nil
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// addLocalTrack adds a published track to the publisher connection, expects lock to be held
+func (c *RTCClient) addLocalTrack(ti *livekit.TrackInfo, track webrtc.TrackLocal) error {
+	c.localTracks[ti.Sid] = track
+
+	if _, err := c.publisher.pc.AddTrack(track); err != nil {
+		return err
+	}
+	return c.publisher.Negotiate()
+}
+
+// AddFileTrack publishes a track from a file, with a codec matching its extension. the file is written once the
+// client is connected, until it ends or the writer is stopped
+func (c *RTCClient) AddFileTrack(path string, id string, label string) (*TrackWriter, error) {
+	// determine file mime
+	mime, ok := extMimeMapping[filepath.Ext(path)]
+	if !ok {
+		return nil, fmt.Errorf("%s has an unsupported extension", filepath.Base(path))
+	}
+
+	logger.Debugw("adding file track",
+		"mime", mime,
+	)
+
+	track, err := webrtc.NewTrackLocalStaticSample(
+		webrtc.RTPCodecCapability{MimeType: mime},
+		id,
+		label,
+	)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewTrackWriter(c.ctx, track, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.PublishTrack(track); err != nil {
+		writer.Stop()
+		return nil, err
+	}
+
+	// samples written before ICE connectivity would be dropped
+	go func() {
+		select {
+		case <-c.iceConnectedChan:
+			writer.Start()
+		case <-writer.ctx.Done():
+		}
+	}()
+	return writer, nil
+}
+
+// send AddTrack command to server to initiate server-side negotiation
+func (c *RTCClient) SendAddTrack(cid string, name string, trackType livekit.TrackType) error {
+	return c.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_AddTrack{
+			AddTrack: &livekit.AddTrackRequest{
+				Cid:  cid,
+				Name: name,
+				Type: trackType,
+			},
+		},
+	})
+}
+
+// handles a server initiated offer, handle on subscriber PC
+func (c *RTCClient) handleOffer(desc webrtc.SessionDescription) error {
+	if err := c.subscriber.SetRemoteDescription(desc); err != nil {
+		return err
+	}
+
+	// if we received an offer, we'd have to answer
+	answer, err := c.subscriber.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.subscriber.pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+
+	// send remote an answer
+	logger.Debugw("sending subscriber answer",
+		"participant", c.localParticipant.Identity,
+		//"sdp", answer,
+	)
+	return c.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Answer{
+			Answer: toProtoSessionDescription(answer),
+		},
+	})
+}
+
+// the client handles answer on the publisher PC
+func (c *RTCClient) handleAnswer(desc webrtc.SessionDescription) error {
+	logger.Debugw("handling server answer", "participant", c.localParticipant.Identity)
+	// remote answered the offer, establish connection
+	err := c.publisher.SetRemoteDescription(desc)
+	if err != nil {
+		return err
+	}
+
+	if !c.connected.TrySet(true) {
+		// already connected
+		return nil
+	}
+	return nil
+}
+
+func (c *RTCClient) onOffer(offer webrtc.SessionDescription) {
+	if c.localParticipant != nil {
+		logger.Debugw("starting negotiation", "participant", c.localParticipant.Identity)
+	}
+	_ = c.SendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{
+			Offer: toProtoSessionDescription(offer),
+		},
+	})
+}
+
+func (c *RTCClient) processTrack(track *webrtc.TrackRemote) {
+	lastUpdate := time.Time{}
+	pId := track.StreamID()
+	trackId := track.ID()
+	c.lock.Lock()
+	c.subscribedTracks[pId] = append(c.subscribedTracks[pId], track)
+	c.lock.Unlock()
+
+	logger.Debugw("client added track", "participant", c.localParticipant.Identity,
+		"pID", pId,
+		"track", trackId,
+	)
+	if c.OnTrackSubscribed != nil {
+		c.OnTrackSubscribed(track)
+	}
+
+	defer func() {
+		c.lock.Lock()
+		c.subscribedTracks[pId] = funk.Without(c.subscribedTracks[pId], track).([]*webrtc.TrackRemote)
+		c.lock.Unlock()
+		if c.OnTrackUnsubscribed != nil {
+			c.OnTrackUnsubscribed(track)
+		}
+	}()
+
+	numBytes := 0
+	for {
+		pkt, _, err := track.ReadRTP()
+		if c.ctx.Err() != nil {
+			break
+		}
+		if err == io.EOF || err == io.ErrClosedPipe {
+			break
+		}
+		if err != nil {
+			logger.Debugw("error reading RTP", "err", err)
+			continue
+		}
+		if c.OnRTPPacket != nil {
+			c.OnRTPPacket(track, pkt)
+		}
+		numBytes += pkt.MarshalSize()
+		if time.Now().Sub(lastUpdate) > 30*time.Second {
+			logger.Debugw("consumed from participant",
+				"track", trackId, "pID", pId,
+				"size", numBytes)
+			lastUpdate = time.Now()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// signalServer accepts signal connections, handling each with the connection's number, starting at 1
+func signalServer(t *testing.T, handle func(conn *websocket.Conn, r *http.Request, n int32)) (string, *int32) {
+	var dials int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		handle(conn, r, atomic.AddInt32(&dials, 1))
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), &dials
+}
+
+func writeResponse(t *testing.T, conn *websocket.Conn, res *livekit.SignalResponse) {
+	payload, err := protojson.Marshal(res)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, payload))
+}
+
+// join answers a connection with a join response, and waits for the client's offer
+func join(t *testing.T, conn *websocket.Conn) {
+	writeResponse(t, conn, &livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Join{
+			Join: &livekit.JoinResponse{
+				Participant: &livekit.ParticipantInfo{Sid: "PA_1", Identity: "bot"},
+			},
+		},
+	})
+	for {
+		_, payload, err := conn.ReadMessage()
+		require.NoError(t, err)
+		req := &livekit.SignalRequest{}
+		require.NoError(t, protojson.Unmarshal(payload, req))
+		if req.GetOffer() != nil {
+			return
+		}
+	}
+}
+
+func runClient(t *testing.T, host string) <-chan error {
+	c, err := Dial(host, "token", nil)
+	require.NoError(t, err)
+	t.Cleanup(c.Stop)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run()
+	}()
+	return done
+}
+
+func waitForRun(t *testing.T, done <-chan error) {
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("client is still running")
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("ends the session when the server closes the connection", func(t *testing.T) {
+		host, dials := signalServer(t, func(conn *websocket.Conn, r *http.Request, n int32) {
+			join(t, conn)
+			require.NoError(t, conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+		})
+
+		waitForRun(t, runClient(t, host))
+		require.EqualValues(t, 1, atomic.LoadInt32(dials))
+	})
+
+	t.Run("resumes the session when the connection drops", func(t *testing.T) {
+		backoff := resumeBackoff
+		resumeBackoff = 10 * time.Millisecond
+		defer func() {
+			resumeBackoff = backoff
+		}()
+
+		host, dials := signalServer(t, func(conn *websocket.Conn, r *http.Request, n int32) {
+			if n == 1 {
+				join(t, conn)
+				_ = conn.UnderlyingConn().Close()
+				return
+			}
+			require.Equal(t, "1", r.URL.Query().Get("reconnect"))
+			writeResponse(t, conn, &livekit.SignalResponse{
+				Message: &livekit.SignalResponse_Leave{
+					Leave: &livekit.LeaveRequest{},
+				},
+			})
+		})
+
+		waitForRun(t, runClient(t, host))
+		require.EqualValues(t, 2, atomic.LoadInt32(dials))
+	})
+}
+
+func TestHandleDataMessage(t *testing.T) {
+	c := &RTCClient{}
+	var received *livekit.UserPacket
+	var receivedKind livekit.DataPacket_Kind
+	c.OnDataPacket = func(packet *livekit.UserPacket, kind livekit.DataPacket_Kind) {
+		received = packet
+		receivedKind = kind
+	}
+
+	data, err := proto.Marshal(&livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{ParticipantSid: "PA_2", Payload: []byte("hello")},
+		},
+	})
+	require.NoError(t, err)
+	c.handleDataMessage(data)
+	require.NotNil(t, received)
+	require.Equal(t, "PA_2", received.ParticipantSid)
+	require.Equal(t, []byte("hello"), received.Payload)
+	require.Equal(t, livekit.DataPacket_RELIABLE, receivedKind)
+
+	// packets that can't be parsed are dropped
+	received = nil
+	c.handleDataMessage([]byte{0xff})
+	require.Nil(t, received)
+}
+
+func TestTransportNegotiate(t *testing.T) {
+	local, err := newTransport(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer local.Close()
+	remote, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer remote.Close()
+
+	offers := make(chan webrtc.SessionDescription, 2)
+	local.OnOffer(func(offer webrtc.SessionDescription) {
+		offers <- offer
+	})
+	answer := func(offer webrtc.SessionDescription) webrtc.SessionDescription {
+		require.NoError(t, remote.SetRemoteDescription(offer))
+		answer, err := remote.CreateAnswer(nil)
+		require.NoError(t, err)
+		require.NoError(t, remote.SetLocalDescription(answer))
+		return answer
+	}
+
+	_, err = local.pc.CreateDataChannel("data", nil)
+	require.NoError(t, err)
+	// candidates are added once there's a remote description
+	mid := "0"
+	require.NoError(t, local.AddICECandidate(webrtc.ICECandidateInit{
+		Candidate: "candidate:1 1 udp 2130706431 127.0.0.1 9 typ host",
+		SDPMid:    &mid,
+	}))
+	require.NoError(t, local.Negotiate())
+	offer := <-offers
+
+	// the next offer waits for the answer
+	_, err = local.pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio)
+	require.NoError(t, err)
+	require.NoError(t, local.Negotiate())
+	require.Len(t, offers, 0)
+
+	require.NoError(t, local.SetRemoteDescription(answer(offer)))
+	require.Empty(t, local.pendingCandidates)
+	require.Len(t, offers, 1)
+	offer = <-offers
+	require.Contains(t, offer.SDP, "m=audio")
+
+	require.NoError(t, local.SetRemoteDescription(answer(offer)))
+	require.Len(t, offers, 0)
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"time"
@@ -18,11 +19,11 @@ import (
 // Writes a file to an RTP track.
 // makes it easier to debug and create RTP streams
 type TrackWriter struct {
-	ctx      context.Context
-	cancel   context.CancelFunc
-	track    *webrtc.TrackLocalStaticSample
-	filePath string
-	mime     string
+	ctx    context.Context
+	cancel context.CancelFunc
+	track  *webrtc.TrackLocalStaticSample
+	file   *os.File
+	mime   string
 
 	ogg       *oggreader.OggReader
 	ivfheader *ivfreader.IVFFileHeader
@@ -30,69 +31,56 @@ type TrackWriter struct {
 	h264      *h264reader.H264Reader
 }
 
-func NewTrackWriter(ctx context.Context, track *webrtc.TrackLocalStaticSample, filePath string) *TrackWriter {
-	ctx, cancel := context.WithCancel(ctx)
-	return &TrackWriter{
-		ctx:      ctx,
-		cancel:   cancel,
-		track:    track,
-		filePath: filePath,
-		mime:     track.Codec().MimeType,
+// NewTrackWriter opens a file to write to the track, with a reader for the track's codec
+func NewTrackWriter(ctx context.Context, track *webrtc.TrackLocalStaticSample, filePath string) (*TrackWriter, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (w *TrackWriter) Start() error {
-	if w.filePath == "" {
-		go w.writeNull()
-		return nil
+	ctx, cancel := context.WithCancel(ctx)
+	w := &TrackWriter{
+		ctx:    ctx,
+		cancel: cancel,
+		track:  track,
+		file:   file,
+		mime:   track.Codec().MimeType,
+	}
+	switch w.mime {
+	case webrtc.MimeTypeOpus:
+		w.ogg, _, err = oggreader.NewWith(file)
+	case webrtc.MimeTypeVP8:
+		w.ivf, w.ivfheader, err = ivfreader.NewWith(file)
+	case webrtc.MimeTypeH264:
+		w.h264, err = h264reader.NewReader(file)
+	default:
+		err = fmt.Errorf("%s can't be written from a file", w.mime)
 	}
-
-	file, err := os.Open(w.filePath)
 	if err != nil {
-		return err
+		w.Stop()
+		return nil, err
 	}
+	return w, nil
+}
 
+// Start writes the file in the background, paced like it's played back
+func (w *TrackWriter) Start() {
 	logger.Infow("starting track writer",
 		"track", w.track.ID(),
 		"mime", w.mime)
 	switch w.mime {
 	case webrtc.MimeTypeOpus:
-		w.ogg, _, err = oggreader.NewWith(file)
-		if err != nil {
-			return err
-		}
 		go w.writeOgg()
 	case webrtc.MimeTypeVP8:
-		w.ivf, w.ivfheader, err = ivfreader.NewWith(file)
-		if err != nil {
-			return err
-		}
 		go w.writeVP8()
 	case webrtc.MimeTypeH264:
-		w.h264, err = h264reader.NewReader(file)
-		if err != nil {
-			return err
-		}
 		go w.writeH264()
 	}
-	return nil
 }
 
 func (w *TrackWriter) Stop() {
 	w.cancel()
-}
-
-func (w *TrackWriter) writeNull() {
-	defer w.onWriteComplete()
-	sample := media.Sample{Data: []byte{0x0, 0xff, 0xff, 0xff, 0xff}, Duration: 30 * time.Millisecond}
-	for {
-		select {
-		case <-time.After(20 * time.Millisecond):
-			w.track.WriteSample(sample)
-		case <-w.ctx.Done():
-			break
-		}
-	}
+	_ = w.file.Close()
 }
 
 func (w *TrackWriter) writeOgg() {
@@ -161,5 +149,5 @@ func (w *TrackWriter) writeH264() {
 }
 
 func (w *TrackWriter) onWriteComplete() {
-
+	_ = w.file.Close()
 }
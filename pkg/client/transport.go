@@ -0,0 +1,146 @@
+package client
+
+import (
+	"encoding/json"
+	"sync"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3"
+)
+
+// transport is one of the client's peer connections, publishing to the server or subscribing from it
+type transport struct {
+	pc *webrtc.PeerConnection
+
+	lock sync.Mutex
+	// candidates trickled before the remote description is set
+	pendingCandidates []webrtc.ICECandidateInit
+	// an offer is waiting for its answer, and whether another is needed once it's answered
+	negotiating bool
+	renegotiate bool
+	onOffer     func(offer webrtc.SessionDescription)
+}
+
+func newTransport(conf webrtc.Configuration) (*transport, error) {
+	me := &webrtc.MediaEngine{}
+	if err := me.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+	ir := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(me, ir); err != nil {
+		return nil, err
+	}
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(me), webrtc.WithInterceptorRegistry(ir))
+	pc, err := api.NewPeerConnection(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &transport{pc: pc}, nil
+}
+
+func (t *transport) AddICECandidate(candidate webrtc.ICECandidateInit) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.pc.RemoteDescription() == nil {
+		t.pendingCandidates = append(t.pendingCandidates, candidate)
+		return nil
+	}
+	return t.pc.AddICECandidate(candidate)
+}
+
+// SetRemoteDescription adds candidates trickled before it, and sends the next offer when the answer is to an
+// offer that's been superseded
+func (t *transport) SetRemoteDescription(sd webrtc.SessionDescription) error {
+	t.lock.Lock()
+	if err := t.pc.SetRemoteDescription(sd); err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	for _, c := range t.pendingCandidates {
+		if err := t.pc.AddICECandidate(c); err != nil {
+			t.lock.Unlock()
+			return err
+		}
+	}
+	t.pendingCandidates = nil
+
+	renegotiate := false
+	if sd.Type == webrtc.SDPTypeAnswer {
+		renegotiate = t.renegotiate
+		t.negotiating = false
+		t.renegotiate = false
+	}
+	t.lock.Unlock()
+
+	if renegotiate {
+		return t.Negotiate()
+	}
+	return nil
+}
+
+// OnOffer is called with each offer the transport creates
+func (t *transport) OnOffer(f func(offer webrtc.SessionDescription)) {
+	t.lock.Lock()
+	t.onOffer = f
+	t.lock.Unlock()
+}
+
+// Negotiate sends an offer, or another one once the offer that's been sent is answered
+func (t *transport) Negotiate() error {
+	t.lock.Lock()
+	if t.negotiating {
+		t.renegotiate = true
+		t.lock.Unlock()
+		return nil
+	}
+	offer, err := t.pc.CreateOffer(nil)
+	if err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	if err = t.pc.SetLocalDescription(offer); err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	t.negotiating = true
+	onOffer := t.onOffer
+	t.lock.Unlock()
+
+	if onOffer != nil {
+		onOffer(offer)
+	}
+	return nil
+}
+
+func (t *transport) Close() {
+	_ = t.pc.Close()
+}
+
+func toProtoSessionDescription(sd webrtc.SessionDescription) *livekit.SessionDescription {
+	return &livekit.SessionDescription{
+		Type: sd.Type.String(),
+		Sdp:  sd.SDP,
+	}
+}
+
+func fromProtoSessionDescription(sd *livekit.SessionDescription) webrtc.SessionDescription {
+	return webrtc.SessionDescription{
+		Type: webrtc.NewSDPType(sd.Type),
+		SDP:  sd.Sdp,
+	}
+}
+
+func toProtoTrickle(candidateInit webrtc.ICECandidateInit, target livekit.SignalTarget) *livekit.TrickleRequest {
+	data, _ := json.Marshal(candidateInit)
+	return &livekit.TrickleRequest{
+		CandidateInit: string(data),
+		Target:        target,
+	}
+}
+
+func fromProtoTrickle(trickle *livekit.TrickleRequest) (webrtc.ICECandidateInit, error) {
+	ci := webrtc.ICECandidateInit{}
+	err := json.Unmarshal([]byte(trickle.CandidateInit), &ci)
+	return ci, err
+}
@@ -18,18 +18,25 @@ var DefaultStunServers = []string{
 }
 
 type Config struct {
-	Port           uint32             `yaml:"port"`
-	PrometheusPort uint32             `yaml:"prometheus_port"`
-	RTC            RTCConfig          `yaml:"rtc"`
-	Redis          RedisConfig        `yaml:"redis"`
-	Audio          AudioConfig        `yaml:"audio"`
-	Room           RoomConfig         `yaml:"room"`
-	TURN           TURNConfig         `yaml:"turn"`
-	WebHook        WebHookConfig      `yaml:"webhook"`
-	NodeSelector   NodeSelectorConfig `yaml:"node_selector"`
-	KeyFile        string             `yaml:"key_file"`
-	Keys           map[string]string  `yaml:"keys"`
-	LogLevel       string             `yaml:"log_level"`
+	Port           uint32               `yaml:"port"`
+	PrometheusPort uint32               `yaml:"prometheus_port"`
+	RTC            RTCConfig            `yaml:"rtc"`
+	Redis          RedisConfig          `yaml:"redis"`
+	NATS           NATSConfig           `yaml:"nats"`
+	Audio          AudioConfig          `yaml:"audio"`
+	Room           RoomConfig           `yaml:"room"`
+	TURN           TURNConfig           `yaml:"turn"`
+	WebHook        WebHookConfig        `yaml:"webhook"`
+	NodeSelector   NodeSelectorConfig   `yaml:"node_selector"`
+	TrackRecording TrackRecordingConfig `yaml:"track_recording"`
+	KeyFile        string               `yaml:"key_file"`
+	Keys           map[string]string    `yaml:"keys"`
+	LogLevel       string               `yaml:"log_level"`
+	// time rooms on a draining node have to empty, before their participants are moved to other nodes
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// region the node is in, for the regionaware node selector
+	Region string      `yaml:"region"`
+	Limit  LimitConfig `yaml:"limit"`
 
 	Development bool `yaml:"development"`
 }
@@ -81,11 +88,49 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// NATSConfig routes between nodes with NATS instead of Redis, the server needs JetStream enabled
+type NATSConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
 type RoomConfig struct {
 	EnabledCodecs      []CodecSpec `yaml:"enabled_codecs"`
 	MaxParticipants    uint32      `yaml:"max_participants"`
 	EmptyTimeout       uint32      `yaml:"empty_timeout"`
 	EnableRemoteUnmute bool        `yaml:"enable_remote_unmute"`
+	LastN              uint32      `yaml:"last_n"`
+	DataLimits         DataLimits  `yaml:"data_limits"`
+	DataHistorySize    uint32      `yaml:"data_history_size"`
+	DataHistorySeconds uint32      `yaml:"data_history_seconds"`
+	// rooms with this many participants on a node take new participants on other nodes, with tracks
+	// relayed between them. rooms are kept on one node when 0
+	MaxParticipantsPerNode uint32 `yaml:"max_participants_per_node"`
+}
+
+// DataLimits applies to data packets sent by participants, limits of 0 are unlimited
+type DataLimits struct {
+	// largest user payload accepted, in bytes
+	MaxPayloadSize int `yaml:"max_payload_size"`
+	// rates of each participant
+	ParticipantPacketsPerSecond float64 `yaml:"participant_packets_per_second"`
+	ParticipantBytesPerSecond   float64 `yaml:"participant_bytes_per_second"`
+	// rates across all participants of a room
+	RoomPacketsPerSecond float64 `yaml:"room_packets_per_second"`
+	RoomBytesPerSecond   float64 `yaml:"room_bytes_per_second"`
+	// bursts allowed above the rates, they're at least a second's worth, a packet, and the largest payload
+	ParticipantPacketBurst float64 `yaml:"participant_packet_burst"`
+	ParticipantByteBurst   float64 `yaml:"participant_byte_burst"`
+	RoomPacketBurst        float64 `yaml:"room_packet_burst"`
+	RoomByteBurst          float64 `yaml:"room_byte_burst"`
+	// participants are disconnected when they go over a limit, instead of their packets being dropped
+	Disconnect bool `yaml:"disconnect"`
+}
+
+type TrackRecordingConfig struct {
+	// directory on RTC nodes that track recordings are written to, recording is disabled when empty
+	Directory string `yaml:"directory"`
 }
 
 type CodecSpec struct {
@@ -111,12 +156,32 @@ type WebHookConfig struct {
 type NodeSelectorConfig struct {
 	Kind         string  `yaml:"kind"`
 	SysloadLimit float32 `yaml:"sysload_limit"`
+	// locations of the regions nodes are in, rooms are placed in the nearest region with capacity when
+	// their own region has none
+	Regions []RegionConfig `yaml:"regions"`
+}
+
+// LimitConfig caps the load of each node, nodes at a limit aren't selected for rooms. limits of 0 are unlimited
+type LimitConfig struct {
+	NumClients   uint32 `yaml:"num_clients"`
+	NumRooms     uint32 `yaml:"num_rooms"`
+	NumTracksIn  uint32 `yaml:"num_tracks_in"`
+	NumTracksOut uint32 `yaml:"num_tracks_out"`
+	// bytes received and sent per second
+	BytesPerSec float32 `yaml:"bytes_per_sec"`
+}
+
+type RegionConfig struct {
+	Name string  `yaml:"name"`
+	Lat  float64 `yaml:"lat"`
+	Lon  float64 `yaml:"lon"`
 }
 
 func NewConfig(confString string, c *cli.Context) (*Config, error) {
 	// start with defaults
 	conf := &Config{
-		Port: 7880,
+		Port:         7880,
+		DrainTimeout: 10 * time.Minute,
 		RTC: RTCConfig{
 			UseExternalIP:     false,
 			TCPPort:           7881,
@@ -201,6 +266,10 @@ func (conf *Config) HasRedis() bool {
 	return conf.Redis.Address != ""
 }
 
+func (conf *Config) HasNATS() bool {
+	return conf.NATS.URL != ""
+}
+
 func (conf *Config) updateFromCLI(c *cli.Context) error {
 	if c.IsSet("dev") {
 		conf.Development = c.Bool("dev")
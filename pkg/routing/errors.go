@@ -7,6 +7,7 @@ var (
 	ErrIPNotSet             = errors.New("ip address is required and not set")
 	ErrHandlerNotDefined    = errors.New("handler not defined")
 	ErrNoAvailableNodes     = errors.New("could not find any available nodes")
+	ErrClusterFull          = errors.New("all available nodes are at their limits")
 	ErrIncorrectRTCNode     = errors.New("current node isn't the RTC node for the room")
 	ErrNodeNotFound         = errors.New("could not locate the node")
 	ErrInvalidRouterMessage = errors.New("invalid router message")
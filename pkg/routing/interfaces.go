@@ -2,6 +2,7 @@ package routing
 
 import (
 	"context"
+	"time"
 
 	livekit "github.com/livekit/protocol/proto"
 	"google.golang.org/protobuf/proto"
@@ -32,6 +33,10 @@ type ParticipantInit struct {
 	ProtocolVersion int32
 	AutoSubscribe   bool
 	Hidden          bool
+	// node to start the RTC session on, the node hosting the room when empty
+	RTCNodeId string
+	// relays publish tracks of a participant on another node. they're only started on the RTC node
+	Relayed bool
 }
 
 type NewParticipantCallback func(ctx context.Context, roomName string, pi ParticipantInit, requestSource MessageSource, responseSink MessageSink)
@@ -43,11 +48,31 @@ type Router interface {
 	GetNodeForRoom(ctx context.Context, roomName string) (*livekit.Node, error)
 	SetNodeForRoom(ctx context.Context, roomName string, nodeId string) error
 	ClearRoomState(ctx context.Context, roomName string) error
+	// GetRoomNodes returns the nodes participants of a room are connected to, with how many are on each.
+	// rooms are only on more than one node when they're cascaded
+	GetRoomNodes(ctx context.Context, roomName string) (map[string]uint32, error)
+	// AddRoomNode cascades a room to the node, before its first participant there has joined
+	AddRoomNode(ctx context.Context, roomName string, nodeId string) error
+	SetRoomNodeParticipants(ctx context.Context, roomName string, nodeId string, count uint32) error
+	RemoveRoomNode(ctx context.Context, roomName string, nodeId string) error
 	RegisterNode() error
 	UnregisterNode() error
-	RemoveDeadNodes() error
+	// ListDeadNodes returns the nodes that have stopped updating their stats
+	ListDeadNodes() ([]*livekit.Node, error)
+	// RemoveNode removes a node that's gone, along with the rooms it's tracked to have
+	RemoveNode(nodeId string) error
+	// GetNodeRooms returns the rooms hosted on or cascaded to the node
+	GetNodeRooms(ctx context.Context, nodeId string) ([]string, error)
 	GetNode(nodeId string) (*livekit.Node, error)
+	// ListNodes returns the nodes that can be selected for rooms, which leaves out draining nodes
 	ListNodes() ([]*livekit.Node, error)
+	// DrainNode stops the current node from being selected for rooms, the rooms on it keep running
+	DrainNode() error
+	// GetNodeRegions returns the regions of nodes that have one, by node ID
+	GetNodeRegions() (map[string]string, error)
+	// AcquireLease makes the current node the holder of the named lease for ttl, or extends the lease when it
+	// holds it already. it's false while another node holds it
+	AcquireLease(name string, ttl time.Duration) (bool, error)
 
 	// StartParticipantSignal participant signal connection is ready to start
 	StartParticipantSignal(ctx context.Context, roomName string, pi ParticipantInit) (connectionId string, reqSink MessageSink, resSource MessageSource, err error)
@@ -55,6 +80,15 @@ type Router interface {
 	// WriteRTCMessage sends a message to the RTC node
 	WriteRTCMessage(ctx context.Context, roomName, identity string, msg *livekit.RTCNodeMessage) error
 
+	// SetParticipantRTCNode routes RTC messages for a participant that's moved to another room
+	SetParticipantRTCNode(roomName, identity, nodeId string) error
+
+	// GetParticipantRTCNode returns the node RTC messages for a participant are routed to
+	GetParticipantRTCNode(roomName, identity string) (string, error)
+
+	// ClearParticipantState removes the routing of a participant, once its node is gone
+	ClearParticipantState(ctx context.Context, roomName, identity string) error
+
 	// OnNewParticipantRTC is called to start a new participant's RTC connection
 	OnNewParticipantRTC(callback NewParticipantCallback)
 
@@ -14,11 +14,13 @@ import (
 // a router of messages on the same node, basic implementation for local testing
 type LocalRouter struct {
 	currentNode LocalNode
+	region      string
 	lock        sync.RWMutex
 	// channels for each participant
 	requestChannels  map[string]*MessageChannel
 	responseChannels map[string]*MessageChannel
 	isStarted        utils.AtomicFlag
+	isDraining       utils.AtomicFlag
 
 	rtcMessageChan *MessageChannel
 
@@ -26,9 +28,10 @@ type LocalRouter struct {
 	onRTCMessage     RTCMessageCallback
 }
 
-func NewLocalRouter(currentNode LocalNode) *LocalRouter {
+func NewLocalRouter(currentNode LocalNode, region string) *LocalRouter {
 	return &LocalRouter{
 		currentNode:      currentNode,
+		region:           region,
 		requestChannels:  make(map[string]*MessageChannel),
 		responseChannels: make(map[string]*MessageChannel),
 		rtcMessageChan:   NewMessageChannel(),
@@ -51,6 +54,23 @@ func (r *LocalRouter) ClearRoomState(ctx context.Context, roomName string) error
 	return nil
 }
 
+func (r *LocalRouter) GetRoomNodes(ctx context.Context, roomName string) (map[string]uint32, error) {
+	// rooms can't be cascaded with a single node
+	return map[string]uint32{}, nil
+}
+
+func (r *LocalRouter) AddRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	return nil
+}
+
+func (r *LocalRouter) SetRoomNodeParticipants(ctx context.Context, roomName string, nodeId string, count uint32) error {
+	return nil
+}
+
+func (r *LocalRouter) RemoveRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	return nil
+}
+
 func (r *LocalRouter) RegisterNode() error {
 	return nil
 }
@@ -59,10 +79,19 @@ func (r *LocalRouter) UnregisterNode() error {
 	return nil
 }
 
-func (r *LocalRouter) RemoveDeadNodes() error {
+func (r *LocalRouter) ListDeadNodes() ([]*livekit.Node, error) {
+	return []*livekit.Node{}, nil
+}
+
+func (r *LocalRouter) RemoveNode(nodeId string) error {
 	return nil
 }
 
+func (r *LocalRouter) GetNodeRooms(ctx context.Context, nodeId string) ([]string, error) {
+	// rooms aren't tracked by node with a single node
+	return []string{}, nil
+}
+
 func (r *LocalRouter) GetNode(nodeId string) (*livekit.Node, error) {
 	if nodeId == r.currentNode.Id {
 		return r.currentNode, nil
@@ -71,11 +100,32 @@ func (r *LocalRouter) GetNode(nodeId string) (*livekit.Node, error) {
 }
 
 func (r *LocalRouter) ListNodes() ([]*livekit.Node, error) {
+	if r.isDraining.Get() {
+		return []*livekit.Node{}, nil
+	}
 	return []*livekit.Node{
 		r.currentNode,
 	}, nil
 }
 
+func (r *LocalRouter) DrainNode() error {
+	r.isDraining.TrySet(true)
+	return nil
+}
+
+func (r *LocalRouter) GetNodeRegions() (map[string]string, error) {
+	regions := make(map[string]string)
+	if r.region != "" {
+		regions[r.currentNode.Id] = r.region
+	}
+	return regions, nil
+}
+
+func (r *LocalRouter) AcquireLease(name string, ttl time.Duration) (bool, error) {
+	// the only node holds every lease
+	return true, nil
+}
+
 func (r *LocalRouter) StartParticipantSignal(ctx context.Context, roomName string, pi ParticipantInit) (connectionId string, reqSink MessageSink, resSource MessageSource, err error) {
 	// treat it as a new participant connecting
 	if r.onNewParticipant == nil {
@@ -108,6 +158,19 @@ func (r *LocalRouter) WriteRTCMessage(ctx context.Context, roomName, identity st
 	return r.writeRTCMessage(roomName, identity, msg, r.rtcMessageChan)
 }
 
+func (r *LocalRouter) SetParticipantRTCNode(roomName, identity, nodeId string) error {
+	// messages are always handled locally
+	return nil
+}
+
+func (r *LocalRouter) GetParticipantRTCNode(roomName, identity string) (string, error) {
+	return r.currentNode.Id, nil
+}
+
+func (r *LocalRouter) ClearParticipantState(ctx context.Context, roomName, identity string) error {
+	return nil
+}
+
 func (r *LocalRouter) writeRTCMessage(roomName, identity string, msg *livekit.RTCNodeMessage, sink MessageSink) error {
 	defer sink.Close()
 	msg.ParticipantKey = participantKey(roomName, identity)
@@ -199,3 +262,33 @@ func (r *LocalRouter) getOrCreateMessageChannel(target map[string]*MessageChanne
 
 	return mc
 }
+
+// handleSignalMessage forwards a message from the RTC node to the participant's signal connection
+func (r *LocalRouter) handleSignalMessage(sm *livekit.SignalNodeMessage) error {
+	connectionId := sm.ConnectionId
+
+	r.lock.RLock()
+	resSink := r.responseChannels[connectionId]
+	r.lock.RUnlock()
+
+	// if a client closed the channel, then sent more messages after that,
+	if resSink == nil {
+		return nil
+	}
+
+	switch rmb := sm.Message.(type) {
+	case *livekit.SignalNodeMessage_Response:
+		// logger.Debugw("forwarding signal message",
+		//	"connID", connectionId,
+		//	"type", fmt.Sprintf("%T", rmb.Response.Message))
+		if err := resSink.WriteMessage(rmb.Response); err != nil {
+			return err
+		}
+
+	case *livekit.SignalNodeMessage_EndSession:
+		// logger.Debugw("received EndSession, closing signal connection",
+		//	"connID", connectionId)
+		resSink.Close()
+	}
+	return nil
+}
@@ -0,0 +1,763 @@
+package routing
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/utils/stats"
+)
+
+const (
+	// key of room_name.node_id => number of participants of the room on the node
+	RoomNodesBucket = "room_nodes"
+
+	// key of node_id.room_name for rooms hosted on or cascaded to the node, so rooms of a dead node are found
+	// without listing every room
+	NodeRoomsBucket = "node_rooms"
+
+	// key of rtc.participant_key => node_id, and signal.connection_id => node_id
+	ParticipantRoutesBucket = "participant_routes"
+
+	// key of lease name => node_id holding it
+	LeasesBucket = "leases"
+)
+
+// NATSRouter routes signaling messages across nodes with NATS, like RedisRouter does with Redis. state is kept
+// in JetStream key-value buckets named like the Redis keys, and nodes are sent messages on subjects named like
+// the Redis channels
+type NATSRouter struct {
+	LocalRouter
+	nc        *nats.Conn
+	ctx       context.Context
+	isStarted utils.AtomicFlag
+
+	nodes     nats.KeyValue
+	roomNode  nats.KeyValue
+	roomNodes nats.KeyValue
+	nodeRooms nats.KeyValue
+	draining  nats.KeyValue
+	regions   nats.KeyValue
+	routes    nats.KeyValue
+	leases    nats.KeyValue
+
+	msgChan chan *nats.Msg
+	subs    []*nats.Subscription
+	cancel  func()
+}
+
+func NewNATSRouter(currentNode LocalNode, nc *nats.Conn, region string) (*NATSRouter, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not use JetStream")
+	}
+	nr := &NATSRouter{
+		LocalRouter: *NewLocalRouter(currentNode, region),
+		nc:          nc,
+	}
+	buckets := []struct {
+		kv     *nats.KeyValue
+		config nats.KeyValueConfig
+	}{
+		{&nr.nodes, nats.KeyValueConfig{Bucket: NodesKey}},
+		{&nr.roomNode, nats.KeyValueConfig{Bucket: NodeRoomKey}},
+		{&nr.roomNodes, nats.KeyValueConfig{Bucket: RoomNodesBucket}},
+		{&nr.nodeRooms, nats.KeyValueConfig{Bucket: NodeRoomsBucket}},
+		{&nr.draining, nats.KeyValueConfig{Bucket: DrainingNodesKey}},
+		{&nr.regions, nats.KeyValueConfig{Bucket: NodeRegionsKey}},
+		{&nr.routes, nats.KeyValueConfig{Bucket: ParticipantRoutesBucket, TTL: participantMappingTTL}},
+		{&nr.leases, nats.KeyValueConfig{Bucket: LeasesBucket}},
+	}
+	for _, b := range buckets {
+		if *b.kv, err = NATSBucket(js, &b.config); err != nil {
+			return nil, err
+		}
+	}
+	nr.ctx, nr.cancel = context.WithCancel(context.Background())
+	return nr, nil
+}
+
+func (r *NATSRouter) RegisterNode() error {
+	data, err := proto.Marshal((*livekit.Node)(r.currentNode))
+	if err != nil {
+		return err
+	}
+	if _, err := r.nodes.Put(NATSKey(r.currentNode.Id), data); err != nil {
+		return errors.Wrap(err, "could not register node")
+	}
+	if r.region != "" {
+		if _, err := r.regions.PutString(NATSKey(r.currentNode.Id), r.region); err != nil {
+			return errors.Wrap(err, "could not register node")
+		}
+	}
+	return nil
+}
+
+func (r *NATSRouter) UnregisterNode() error {
+	return r.removeNode(r.currentNode.Id)
+}
+
+func (r *NATSRouter) ListDeadNodes() ([]*livekit.Node, error) {
+	nodes, err := r.getNodes()
+	if err != nil {
+		return nil, err
+	}
+	dead := make([]*livekit.Node, 0)
+	for _, n := range nodes {
+		if IsDead(n) {
+			dead = append(dead, n)
+		}
+	}
+	return dead, nil
+}
+
+func (r *NATSRouter) RemoveNode(nodeId string) error {
+	if err := r.removeNode(nodeId); err != nil {
+		return err
+	}
+	entries, err := NATSEntries(r.nodeRooms, nodeRoomsFilter(nodeId))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := r.nodeRooms.Delete(entry.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *NATSRouter) GetNodeRooms(ctx context.Context, nodeId string) ([]string, error) {
+	entries, err := NATSEntries(r.nodeRooms, nodeRoomsFilter(nodeId))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rooms for node")
+	}
+	rooms := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		key := entry.Key()
+		roomName, err := ParseNATSKey(key[strings.LastIndex(key, ".")+1:])
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, roomName)
+	}
+	return rooms, nil
+}
+
+func (r *NATSRouter) GetNodeForRoom(ctx context.Context, roomName string) (*livekit.Node, error) {
+	entry, err := r.roomNode.Get(NATSKey(roomName))
+	if err == nats.ErrKeyNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not get node for room")
+	}
+
+	return r.GetNode(string(entry.Value()))
+}
+
+func (r *NATSRouter) SetNodeForRoom(ctx context.Context, roomName string, nodeId string) error {
+	if _, err := r.roomNode.PutString(NATSKey(roomName), nodeId); err != nil {
+		return err
+	}
+	// the node the room moved off of keeps it in its rooms, which is cleared when the room or that node goes
+	_, err := r.nodeRooms.Put(nodeRoomKey(nodeId, roomName), nil)
+	return err
+}
+
+func (r *NATSRouter) ClearRoomState(ctx context.Context, roomName string) error {
+	nodes, err := r.GetRoomNodes(ctx, roomName)
+	if err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	nodeIds := make([]string, 0, len(nodes)+1)
+	for nodeId := range nodes {
+		nodeIds = append(nodeIds, nodeId)
+	}
+	if entry, err := r.roomNode.Get(NATSKey(roomName)); err == nil {
+		nodeIds = append(nodeIds, string(entry.Value()))
+	} else if err != nats.ErrKeyNotFound {
+		return errors.Wrap(err, "could not clear room state")
+	}
+
+	if err := r.roomNode.Delete(NATSKey(roomName)); err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	for _, nodeId := range nodeIds {
+		if err := r.roomNodes.Delete(roomNodeKey(roomName, nodeId)); err != nil {
+			return errors.Wrap(err, "could not clear room state")
+		}
+		if err := r.nodeRooms.Delete(nodeRoomKey(nodeId, roomName)); err != nil {
+			return errors.Wrap(err, "could not clear room state")
+		}
+	}
+	return nil
+}
+
+func (r *NATSRouter) GetRoomNodes(ctx context.Context, roomName string) (map[string]uint32, error) {
+	entries, err := NATSEntries(r.roomNodes, roomNodesFilter(roomName))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get nodes for room")
+	}
+	nodes := make(map[string]uint32, len(entries))
+	for _, entry := range entries {
+		count, err := strconv.ParseUint(string(entry.Value()), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		key := entry.Key()
+		nodeId, err := ParseNATSKey(key[strings.LastIndex(key, ".")+1:])
+		if err != nil {
+			return nil, err
+		}
+		nodes[nodeId] = uint32(count)
+	}
+	return nodes, nil
+}
+
+func (r *NATSRouter) AddRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	if _, err := r.nodeRooms.Put(nodeRoomKey(nodeId, roomName), nil); err != nil {
+		return err
+	}
+	key := roomNodeKey(roomName, nodeId)
+	// the node's own count is kept when it's already there
+	if _, err := r.roomNodes.Get(key); err == nil {
+		return nil
+	} else if err != nats.ErrKeyNotFound {
+		return err
+	}
+	_, err := r.roomNodes.Create(key, []byte("0"))
+	if err != nil {
+		// created by another node in the meantime
+		if _, getErr := r.roomNodes.Get(key); getErr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *NATSRouter) SetRoomNodeParticipants(ctx context.Context, roomName string, nodeId string, count uint32) error {
+	if _, err := r.nodeRooms.Put(nodeRoomKey(nodeId, roomName), nil); err != nil {
+		return err
+	}
+	_, err := r.roomNodes.PutString(roomNodeKey(roomName, nodeId), strconv.FormatUint(uint64(count), 10))
+	return err
+}
+
+func (r *NATSRouter) RemoveRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	if err := r.roomNodes.Delete(roomNodeKey(roomName, nodeId)); err != nil {
+		return err
+	}
+	// the room stays in the rooms of the node hosting it
+	entry, err := r.roomNode.Get(NATSKey(roomName))
+	if err == nil && string(entry.Value()) == nodeId {
+		return nil
+	} else if err != nil && err != nats.ErrKeyNotFound {
+		return err
+	}
+	return r.nodeRooms.Delete(nodeRoomKey(nodeId, roomName))
+}
+
+func (r *NATSRouter) GetNode(nodeId string) (*livekit.Node, error) {
+	entry, err := r.nodes.Get(NATSKey(nodeId))
+	if err == nats.ErrKeyNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	n := livekit.Node{}
+	if err = proto.Unmarshal(entry.Value(), &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *NATSRouter) ListNodes() ([]*livekit.Node, error) {
+	nodes, err := r.getNodes()
+	if err != nil {
+		return nil, err
+	}
+	draining, err := natsKeys(r.draining)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list nodes")
+	}
+	if len(draining) == 0 {
+		return nodes, nil
+	}
+
+	isDraining := make(map[string]bool, len(draining))
+	for _, key := range draining {
+		isDraining[key] = true
+	}
+	selectable := make([]*livekit.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if !isDraining[NATSKey(n.Id)] {
+			selectable = append(selectable, n)
+		}
+	}
+	return selectable, nil
+}
+
+func (r *NATSRouter) DrainNode() error {
+	if _, err := r.draining.PutString(NATSKey(r.currentNode.Id), "1"); err != nil {
+		return errors.Wrap(err, "could not drain node")
+	}
+	return nil
+}
+
+func (r *NATSRouter) GetNodeRegions() (map[string]string, error) {
+	entries, err := NATSEntries(r.regions, nats.AllKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get node regions")
+	}
+	regions := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		nodeId, err := ParseNATSKey(entry.Key())
+		if err != nil {
+			return nil, err
+		}
+		regions[nodeId] = string(entry.Value())
+	}
+	return regions, nil
+}
+
+// AcquireLease takes the lease when it's free or has expired, a lease expires ttl after it was last set. updates
+// are conditional on the revision read, so only one node takes it
+func (r *NATSRouter) AcquireLease(name string, ttl time.Duration) (bool, error) {
+	key := NATSKey(name)
+	entry, err := r.leases.Get(key)
+	if err == nats.ErrKeyNotFound {
+		// fails when another node created it first
+		_, err = r.leases.Create(key, []byte(r.currentNode.Id))
+		return err == nil, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "could not acquire lease")
+	}
+	if string(entry.Value()) != r.currentNode.Id && time.Since(entry.Created()) < ttl {
+		return false, nil
+	}
+	// fails when another node updated it first
+	_, err = r.leases.Update(key, []byte(r.currentNode.Id), entry.Revision())
+	return err == nil, nil
+}
+
+// getNodes returns every registered node, including those that are draining
+func (r *NATSRouter) getNodes() ([]*livekit.Node, error) {
+	entries, err := NATSEntries(r.nodes, nats.AllKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list nodes")
+	}
+	nodes := make([]*livekit.Node, 0, len(entries))
+	for _, entry := range entries {
+		n := livekit.Node{}
+		if err := proto.Unmarshal(entry.Value(), &n); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, &n)
+	}
+	return nodes, nil
+}
+
+func (r *NATSRouter) removeNode(nodeId string) error {
+	for _, kv := range []nats.KeyValue{r.nodes, r.regions, r.draining} {
+		if err := kv.Delete(NATSKey(nodeId)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartParticipantSignal signal connection sets up paths to the RTC node, and starts to route messages to that message queue
+func (r *NATSRouter) StartParticipantSignal(ctx context.Context, roomName string, pi ParticipantInit) (connectionId string, reqSink MessageSink, resSource MessageSource, err error) {
+	pKey := participantKey(roomName, pi.Identity)
+
+	// find the node where the room is hosted at, or the one the participant was placed on
+	var rtcNode *livekit.Node
+	rtcNodeId := pi.RTCNodeId
+	if pi.Reconnect {
+		// resumed sessions are on the node they started on, which may be another node of a cascaded room
+		if nodeId, err := r.getParticipantRTCNode(pKey); err == nil {
+			rtcNodeId = nodeId
+		}
+	}
+	if rtcNodeId != "" {
+		rtcNode, err = r.GetNode(rtcNodeId)
+	} else {
+		rtcNode, err = r.GetNodeForRoom(ctx, roomName)
+	}
+	if err != nil {
+		return
+	}
+
+	// create a new connection id
+	connectionId = utils.NewGuid("CO_")
+
+	// map signal & rtc nodes
+	if err = r.setParticipantSignalNode(connectionId, r.currentNode.Id); err != nil {
+		return
+	}
+
+	sink := newRTCNodeSink(r.publish, rtcNode.Id, pKey)
+
+	// sends a message to start session
+	err = sink.WriteMessage(&livekit.StartSession{
+		RoomName: roomName,
+		Identity: pi.Identity,
+		Metadata: pi.Metadata,
+		// connection id is to allow the RTC node to identify where to route the message back to
+		ConnectionId:    connectionId,
+		Reconnect:       pi.Reconnect,
+		Permission:      pi.Permission,
+		ProtocolVersion: pi.ProtocolVersion,
+		AutoSubscribe:   pi.AutoSubscribe,
+		Hidden:          pi.Hidden,
+	})
+	if err != nil {
+		return
+	}
+
+	// index by connectionId, since there may be multiple connections for the participant
+	resChan := r.getOrCreateMessageChannel(r.responseChannels, connectionId)
+	return connectionId, sink, resChan, nil
+}
+
+func (r *NATSRouter) WriteRTCMessage(ctx context.Context, roomName, identity string, msg *livekit.RTCNodeMessage) error {
+	pkey := participantKey(roomName, identity)
+	rtcNode, err := r.getParticipantRTCNode(pkey)
+	if err != nil {
+		return err
+	}
+
+	rtcSink := newRTCNodeSink(r.publish, rtcNode, pkey)
+	return r.writeRTCMessage(roomName, identity, msg, rtcSink)
+}
+
+func (r *NATSRouter) SetParticipantRTCNode(roomName, identity, nodeId string) error {
+	return r.setParticipantRTCNode(participantKey(roomName, identity), nodeId)
+}
+
+func (r *NATSRouter) GetParticipantRTCNode(roomName, identity string) (string, error) {
+	return r.getParticipantRTCNode(participantKey(roomName, identity))
+}
+
+func (r *NATSRouter) ClearParticipantState(ctx context.Context, roomName, identity string) error {
+	return r.routes.Delete("rtc." + NATSKey(participantKey(roomName, identity)))
+}
+
+func (r *NATSRouter) startParticipantRTC(ss *livekit.StartSession, participantKey string) error {
+	// find the node where the room is hosted at
+	rtcNode, err := r.GetNodeForRoom(r.ctx, ss.RoomName)
+	if err != nil {
+		return err
+	}
+
+	if rtcNode.Id != r.currentNode.Id {
+		// or the room is cascaded to this node
+		roomNodes, err := r.GetRoomNodes(r.ctx, ss.RoomName)
+		if err != nil {
+			return err
+		}
+		if _, ok := roomNodes[r.currentNode.Id]; !ok {
+			err = ErrIncorrectRTCNode
+			logger.Errorw("called participant on incorrect node", err,
+				"rtcNode", rtcNode, "nodeID", r.currentNode.Id)
+			return err
+		}
+	}
+
+	if err := r.setParticipantRTCNode(participantKey, r.currentNode.Id); err != nil {
+		return err
+	}
+
+	// find signal node to send responses back
+	signalNode, err := r.getParticipantSignalNode(ss.ConnectionId)
+	if err != nil {
+		return err
+	}
+
+	// treat it as a new participant connecting
+	if r.onNewParticipant == nil {
+		return ErrHandlerNotDefined
+	}
+
+	if !ss.Reconnect {
+		// the previous rtc worker thread is still consuming off the request channel, sever it
+		r.lock.RLock()
+		requestChan, ok := r.requestChannels[participantKey]
+		r.lock.RUnlock()
+		if ok {
+			requestChan.Close()
+		}
+	}
+
+	pi := ParticipantInit{
+		Identity:        ss.Identity,
+		Metadata:        ss.Metadata,
+		Reconnect:       ss.Reconnect,
+		Permission:      ss.Permission,
+		ProtocolVersion: ss.ProtocolVersion,
+		AutoSubscribe:   ss.AutoSubscribe,
+		Hidden:          ss.Hidden,
+	}
+
+	reqChan := r.getOrCreateMessageChannel(r.requestChannels, participantKey)
+	resSink := newSignalNodeSink(r.publish, signalNode, ss.ConnectionId)
+	r.onNewParticipant(
+		r.ctx,
+		ss.RoomName,
+		pi,
+		reqChan,
+		resSink,
+	)
+	return nil
+}
+
+func (r *NATSRouter) Start() error {
+	if !r.isStarted.TrySet(true) {
+		return nil
+	}
+
+	// subscribed before returning, so no message to this node is missed
+	r.msgChan = make(chan *nats.Msg, 1000)
+	for _, subject := range []string{signalNodeChannel(r.currentNode.Id), rtcNodeChannel(r.currentNode.Id)} {
+		sub, err := r.nc.ChanSubscribe(subject, r.msgChan)
+		if err != nil {
+			r.unsubscribe()
+			return errors.Wrap(err, "unable to start NATS router")
+		}
+		r.subs = append(r.subs, sub)
+	}
+
+	go r.statsWorker()
+	go r.natsWorker()
+	return nil
+}
+
+func (r *NATSRouter) Stop() {
+	if !r.isStarted.TrySet(false) {
+		return
+	}
+	logger.Debugw("stopping NATSRouter")
+	r.unsubscribe()
+	_ = r.UnregisterNode()
+	r.cancel()
+}
+
+func (r *NATSRouter) unsubscribe() {
+	for _, sub := range r.subs {
+		_ = sub.Unsubscribe()
+	}
+	r.subs = nil
+}
+
+func (r *NATSRouter) publish(channel string, msg *nodeMessage) error {
+	return r.nc.Publish(channel, msg.data)
+}
+
+func (r *NATSRouter) setParticipantRTCNode(participantKey, nodeId string) error {
+	_, err := r.routes.PutString("rtc."+NATSKey(participantKey), nodeId)
+	if err != nil {
+		err = errors.Wrap(err, "could not set rtc node")
+	}
+	return err
+}
+
+func (r *NATSRouter) setParticipantSignalNode(connectionId, nodeId string) error {
+	if _, err := r.routes.PutString("signal."+NATSKey(connectionId), nodeId); err != nil {
+		return errors.Wrap(err, "could not set signal node")
+	}
+	return nil
+}
+
+func (r *NATSRouter) getParticipantRTCNode(participantKey string) (string, error) {
+	entry, err := r.routes.Get("rtc." + NATSKey(participantKey))
+	if err == nats.ErrKeyNotFound {
+		return "", ErrNodeNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return string(entry.Value()), nil
+}
+
+func (r *NATSRouter) getParticipantSignalNode(connectionId string) (string, error) {
+	entry, err := r.routes.Get("signal." + NATSKey(connectionId))
+	if err == nats.ErrKeyNotFound {
+		return "", ErrNodeNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return string(entry.Value()), nil
+}
+
+// update node stats and cleanup
+func (r *NATSRouter) statsWorker() {
+	for r.ctx.Err() == nil {
+		// update periodically seconds
+		select {
+		case <-time.After(statsUpdateInterval):
+			if err := stats.UpdateCurrentNodeStats(r.currentNode.Stats); err != nil {
+				logger.Errorw("could not update node stats", err, "nodeID", r.currentNode.Id)
+			}
+			if err := r.RegisterNode(); err != nil {
+				logger.Errorw("could not update node", err, "nodeID", r.currentNode.Id)
+			}
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// worker that consumes NATS messages intended for this node
+func (r *NATSRouter) natsWorker() {
+	defer func() {
+		logger.Debugw("finishing natsWorker", "nodeID", r.currentNode.Id)
+	}()
+	logger.Debugw("starting natsWorker", "nodeID", r.currentNode.Id)
+
+	sigChannel := signalNodeChannel(r.currentNode.Id)
+	rtcChannel := rtcNodeChannel(r.currentNode.Id)
+	for {
+		var msg *nats.Msg
+		select {
+		case <-r.ctx.Done():
+			return
+		case msg = <-r.msgChan:
+		}
+
+		if msg.Subject == sigChannel {
+			sm := livekit.SignalNodeMessage{}
+			if err := proto.Unmarshal(msg.Data, &sm); err != nil {
+				logger.Errorw("could not unmarshal signal message on sigchan", err)
+				continue
+			}
+			if err := r.handleSignalMessage(&sm); err != nil {
+				logger.Errorw("error processing signal message", err)
+				continue
+			}
+		} else if msg.Subject == rtcChannel {
+			rm := livekit.RTCNodeMessage{}
+			if err := proto.Unmarshal(msg.Data, &rm); err != nil {
+				logger.Errorw("could not unmarshal RTC message on rtcchan", err)
+				continue
+			}
+			if err := r.handleRTCMessage(&rm); err != nil {
+				logger.Errorw("error processing RTC message", err)
+				continue
+			}
+		}
+	}
+}
+
+func (r *NATSRouter) handleRTCMessage(rm *livekit.RTCNodeMessage) error {
+	pKey := rm.ParticipantKey
+
+	switch rmb := rm.Message.(type) {
+	case *livekit.RTCNodeMessage_StartSession:
+		// RTC session should start on this node
+		if err := r.startParticipantRTC(rmb.StartSession, pKey); err != nil {
+			return errors.Wrap(err, "could not start participant")
+		}
+
+	case *livekit.RTCNodeMessage_Request:
+		r.lock.RLock()
+		requestChan := r.requestChannels[pKey]
+		r.lock.RUnlock()
+		if err := requestChan.WriteMessage(rmb.Request); err != nil {
+			return err
+		}
+
+	default:
+		// route it to handler
+		if r.onRTCMessage != nil {
+			roomName, identity, err := parseParticipantKey(pKey)
+			if err != nil {
+				return err
+			}
+			r.onRTCMessage(r.ctx, roomName, identity, rm)
+		}
+	}
+	return nil
+}
+
+// NATSBucket opens the key-value bucket, creating it when it doesn't exist yet
+func NATSBucket(js nats.JetStreamContext, config *nats.KeyValueConfig) (nats.KeyValue, error) {
+	kv, err := js.KeyValue(config.Bucket)
+	if err == nats.ErrBucketNotFound {
+		// every node creates the buckets it needs, creating one that exists is a no-op
+		kv, err = js.CreateKeyValue(config)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open bucket "+config.Bucket)
+	}
+	return kv, nil
+}
+
+// NATSKey encodes a name for use in a bucket key, which only allows a limited set of characters
+func NATSKey(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func ParseNATSKey(key string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+func roomNodeKey(roomName, nodeId string) string {
+	return NATSKey(roomName) + "." + NATSKey(nodeId)
+}
+
+func nodeRoomKey(nodeId, roomName string) string {
+	return NATSKey(nodeId) + "." + NATSKey(roomName)
+}
+
+// nodeRoomsFilter matches the keys of the node in NodeRoomsBucket
+func nodeRoomsFilter(nodeId string) string {
+	return NATSKey(nodeId) + ".*"
+}
+
+// roomNodesFilter matches the keys of the room in RoomNodesBucket
+func roomNodesFilter(roomName string) string {
+	return NATSKey(roomName) + ".*"
+}
+
+// NATSEntries returns the entries of keys matching filter, a subject that may have wildcards, so only those
+// are read from the bucket
+func NATSEntries(kv nats.KeyValue, filter string) ([]nats.KeyValueEntry, error) {
+	watcher, err := kv.Watch(filter, nats.IgnoreDeletes())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = watcher.Stop()
+	}()
+
+	var entries []nats.KeyValueEntry
+	// current entries are followed by nil
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// natsKeys lists the keys in the bucket, which is empty rather than an error when it has none
+func natsKeys(kv nats.KeyValue) ([]string, error) {
+	keys, err := kv.Keys()
+	if err == nats.ErrNoKeysFound {
+		return nil, nil
+	}
+	return keys, err
+}
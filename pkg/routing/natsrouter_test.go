@@ -0,0 +1,200 @@
+package routing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+func TestNATSRouter(t *testing.T) {
+	url := startNATSServer(t)
+	nodeA := newNATSNode("ND_a")
+	nodeB := newNATSNode("ND_b")
+	routerA := newNATSRouter(t, url, nodeA, "us-west")
+	routerB := newNATSRouter(t, url, nodeB, "")
+	require.NoError(t, routerA.RegisterNode())
+	require.NoError(t, routerB.RegisterNode())
+
+	t.Run("registers nodes", func(t *testing.T) {
+		node, err := routerB.GetNode(nodeA.Id)
+		require.NoError(t, err)
+		require.Equal(t, nodeA.Id, node.Id)
+
+		nodes, err := routerA.ListNodes()
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+
+		regions, err := routerB.GetNodeRegions()
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{nodeA.Id: "us-west"}, regions)
+
+		_, err = routerA.GetNode("ND_unknown")
+		require.Equal(t, routing.ErrNotFound, err)
+	})
+
+	t.Run("maps rooms to nodes", func(t *testing.T) {
+		ctx := context.Background()
+		roomName := "room with spaces/and:symbols"
+		_, err := routerA.GetNodeForRoom(ctx, roomName)
+		require.Equal(t, routing.ErrNotFound, err)
+
+		require.NoError(t, routerA.SetNodeForRoom(ctx, roomName, nodeB.Id))
+		node, err := routerB.GetNodeForRoom(ctx, roomName)
+		require.NoError(t, err)
+		require.Equal(t, nodeB.Id, node.Id)
+
+		require.NoError(t, routerA.SetRoomNodeParticipants(ctx, roomName, nodeB.Id, 3))
+		require.NoError(t, routerA.AddRoomNode(ctx, roomName, nodeA.Id))
+		require.NoError(t, routerA.AddRoomNode(ctx, roomName, nodeB.Id))
+		// nodes of other rooms aren't read
+		require.NoError(t, routerA.AddRoomNode(ctx, "other room", nodeA.Id))
+		roomNodes, err := routerB.GetRoomNodes(ctx, roomName)
+		require.NoError(t, err)
+		require.Equal(t, map[string]uint32{nodeA.Id: 0, nodeB.Id: 3}, roomNodes)
+
+		require.NoError(t, routerA.RemoveRoomNode(ctx, roomName, nodeA.Id))
+		roomNodes, err = routerB.GetRoomNodes(ctx, roomName)
+		require.NoError(t, err)
+		require.Equal(t, map[string]uint32{nodeB.Id: 3}, roomNodes)
+
+		require.NoError(t, routerB.ClearRoomState(ctx, roomName))
+		_, err = routerA.GetNodeForRoom(ctx, roomName)
+		require.Equal(t, routing.ErrNotFound, err)
+		roomNodes, err = routerA.GetRoomNodes(ctx, roomName)
+		require.NoError(t, err)
+		require.Empty(t, roomNodes)
+	})
+
+	t.Run("leaves out draining nodes", func(t *testing.T) {
+		require.NoError(t, routerB.DrainNode())
+		nodes, err := routerA.ListNodes()
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		require.Equal(t, nodeA.Id, nodes[0].Id)
+	})
+
+	t.Run("tracks rooms of nodes", func(t *testing.T) {
+		ctx := context.Background()
+		require.NoError(t, routerA.SetNodeForRoom(ctx, "hosted", nodeB.Id))
+		require.NoError(t, routerA.AddRoomNode(ctx, "cascaded", nodeB.Id))
+		require.NoError(t, routerA.SetRoomNodeParticipants(ctx, "left", nodeB.Id, 1))
+		require.NoError(t, routerA.RemoveRoomNode(ctx, "left", nodeB.Id))
+		// rooms of other nodes aren't read
+		require.NoError(t, routerA.SetNodeForRoom(ctx, "other", nodeA.Id))
+		rooms, err := routerA.GetNodeRooms(ctx, nodeB.Id)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"hosted", "cascaded"}, rooms)
+
+		// the room stays with the node hosting it
+		require.NoError(t, routerA.RemoveRoomNode(ctx, "hosted", nodeB.Id))
+		require.NoError(t, routerA.ClearRoomState(ctx, "cascaded"))
+		rooms, err = routerA.GetNodeRooms(ctx, nodeB.Id)
+		require.NoError(t, err)
+		require.Equal(t, []string{"hosted"}, rooms)
+	})
+
+	t.Run("removes dead nodes", func(t *testing.T) {
+		ctx := context.Background()
+		nodeB.Stats.UpdatedAt = time.Now().Add(-time.Minute).Unix()
+		require.NoError(t, routerB.RegisterNode())
+		dead, err := routerA.ListDeadNodes()
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		require.Equal(t, nodeB.Id, dead[0].Id)
+
+		require.NoError(t, routerA.RemoveNode(nodeB.Id))
+		_, err = routerA.GetNode(nodeB.Id)
+		require.Equal(t, routing.ErrNotFound, err)
+		rooms, err := routerA.GetNodeRooms(ctx, nodeB.Id)
+		require.NoError(t, err)
+		require.Empty(t, rooms)
+	})
+
+	t.Run("routes signal messages to the RTC node", func(t *testing.T) {
+		ctx := context.Background()
+		nodeB.Stats.UpdatedAt = time.Now().Unix()
+		require.NoError(t, routerB.RegisterNode())
+		require.NoError(t, routerA.Start())
+		require.NoError(t, routerB.Start())
+		defer routerA.Stop()
+		defer routerB.Stop()
+
+		started := make(chan routing.MessageSource, 1)
+		routerB.OnNewParticipantRTC(func(ctx context.Context, roomName string, pi routing.ParticipantInit, requestSource routing.MessageSource, responseSink routing.MessageSink) {
+			if roomName == "signaled" && pi.Identity == "alice" {
+				_ = responseSink.WriteMessage(&livekit.SignalResponse{
+					Message: &livekit.SignalResponse_Leave{Leave: &livekit.LeaveRequest{}},
+				})
+				started <- requestSource
+			}
+		})
+		require.NoError(t, routerA.SetNodeForRoom(ctx, "signaled", nodeB.Id))
+
+		_, reqSink, resSource, err := routerA.StartParticipantSignal(ctx, "signaled", routing.ParticipantInit{Identity: "alice"})
+		require.NoError(t, err)
+
+		var reqSource routing.MessageSource
+		select {
+		case reqSource = <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("session was not started on the RTC node")
+		}
+		select {
+		case msg := <-resSource.ReadChan():
+			require.NotNil(t, msg.(*livekit.SignalResponse).GetLeave())
+		case <-time.After(5 * time.Second):
+			t.Fatal("response was not routed to the signal node")
+		}
+
+		require.NoError(t, reqSink.WriteMessage(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}},
+		}))
+		select {
+		case msg := <-reqSource.ReadChan():
+			require.NotNil(t, msg.(*livekit.SignalRequest).GetLeave())
+		case <-time.After(5 * time.Second):
+			t.Fatal("request was not routed to the RTC node")
+		}
+	})
+}
+
+func startNATSServer(t *testing.T) string {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns.ClientURL()
+}
+
+func newNATSRouter(t *testing.T, url string, node routing.LocalNode, region string) *routing.NATSRouter {
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	router, err := routing.NewNATSRouter(node, nc, region)
+	require.NoError(t, err)
+	return router
+}
+
+func newNATSNode(id string) *livekit.Node {
+	return &livekit.Node{
+		Id: id,
+		Stats: &livekit.NodeStats{
+			UpdatedAt: time.Now().Unix(),
+		},
+	}
+}
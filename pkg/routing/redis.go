@@ -15,10 +15,42 @@ const (
 
 	// hash of room_name => node_id
 	NodeRoomKey = "room_node_map"
+
+	// set of node_ids that are draining, and aren't selected for new rooms
+	DrainingNodesKey = "draining_nodes"
+
+	// hash of node_id => region, for nodes with a region
+	NodeRegionsKey = "node_regions"
 )
 
+// node_id holding the named lease, expires with the lease
+func leaseKey(name string) string {
+	return "lease:" + name
+}
+
+// acquireLeaseScript sets the lease to the node when it's free, or extends it when the node holds it already
+var acquireLeaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+if redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
 var redisCtx = context.Background()
 
+// hash of node_id => number of participants of the room on the node
+func roomNodesKey(roomName string) string {
+	return "room_nodes:" + roomName
+}
+
+// set of rooms hosted on or cascaded to the node, so rooms of a dead node are found without listing every room
+func nodeRoomsKey(nodeId string) string {
+	return "node_rooms:" + nodeId
+}
+
 // location of the participant's RTC connection, hash
 func participantRTCKey(participantKey string) string {
 	return "participant_rtc:" + participantKey
@@ -37,7 +69,7 @@ func signalNodeChannel(nodeId string) string {
 	return "signal_channel:" + nodeId
 }
 
-func publishRTCMessage(rc *redis.Client, nodeId string, participantKey string, msg proto.Message) error {
+func publishRTCMessage(sender *messageSender, nodeId string, participantKey string, msg proto.Message) error {
 	rm := &livekit.RTCNodeMessage{
 		ParticipantKey: participantKey,
 	}
@@ -63,10 +95,10 @@ func publishRTCMessage(rc *redis.Client, nodeId string, participantKey string, m
 
 	//logger.Debugw("publishing to rtc", "rtcChannel", rtcNodeChannel(nodeId),
 	//	"message", rm.Message)
-	return rc.Publish(redisCtx, rtcNodeChannel(nodeId), data).Err()
+	return sender.send(rtcNodeChannel(nodeId), data)
 }
 
-func publishSignalMessage(rc *redis.Client, nodeId string, connectionId string, msg proto.Message) error {
+func publishSignalMessage(sender *messageSender, nodeId string, connectionId string, msg proto.Message) error {
 	rm := &livekit.SignalNodeMessage{
 		ConnectionId: connectionId,
 	}
@@ -89,11 +121,11 @@ func publishSignalMessage(rc *redis.Client, nodeId string, connectionId string,
 
 	//logger.Debugw("publishing to signal", "signalChannel", signalNodeChannel(nodeId),
 	//	"message", rm.Message)
-	return rc.Publish(redisCtx, signalNodeChannel(nodeId), data).Err()
+	return sender.send(signalNodeChannel(nodeId), data)
 }
 
 type RTCNodeSink struct {
-	rc             *redis.Client
+	sender         *messageSender
 	nodeId         string
 	participantKey string
 	isClosed       utils.AtomicFlag
@@ -101,8 +133,12 @@ type RTCNodeSink struct {
 }
 
 func NewRTCNodeSink(rc *redis.Client, nodeId, participantKey string) *RTCNodeSink {
+	return newRTCNodeSink(redisPublisher(rc), nodeId, participantKey)
+}
+
+func newRTCNodeSink(publish publishFunc, nodeId, participantKey string) *RTCNodeSink {
 	return &RTCNodeSink{
-		rc:             rc,
+		sender:         newMessageSender(publish),
 		nodeId:         nodeId,
 		participantKey: participantKey,
 	}
@@ -112,7 +148,7 @@ func (s *RTCNodeSink) WriteMessage(msg proto.Message) error {
 	if s.isClosed.Get() {
 		return ErrChannelClosed
 	}
-	return publishRTCMessage(s.rc, s.nodeId, s.participantKey, msg)
+	return publishRTCMessage(s.sender, s.nodeId, s.participantKey, msg)
 }
 
 func (s *RTCNodeSink) Close() {
@@ -129,7 +165,7 @@ func (s *RTCNodeSink) OnClose(f func()) {
 }
 
 type SignalNodeSink struct {
-	rc           *redis.Client
+	sender       *messageSender
 	nodeId       string
 	connectionId string
 	isClosed     utils.AtomicFlag
@@ -137,8 +173,12 @@ type SignalNodeSink struct {
 }
 
 func NewSignalNodeSink(rc *redis.Client, nodeId, connectionId string) *SignalNodeSink {
+	return newSignalNodeSink(redisPublisher(rc), nodeId, connectionId)
+}
+
+func newSignalNodeSink(publish publishFunc, nodeId, connectionId string) *SignalNodeSink {
 	return &SignalNodeSink{
-		rc:           rc,
+		sender:       newMessageSender(publish),
 		nodeId:       nodeId,
 		connectionId: connectionId,
 	}
@@ -148,14 +188,14 @@ func (s *SignalNodeSink) WriteMessage(msg proto.Message) error {
 	if s.isClosed.Get() {
 		return ErrChannelClosed
 	}
-	return publishSignalMessage(s.rc, s.nodeId, s.connectionId, msg)
+	return publishSignalMessage(s.sender, s.nodeId, s.connectionId, msg)
 }
 
 func (s *SignalNodeSink) Close() {
 	if !s.isClosed.TrySet(true) {
 		return
 	}
-	publishSignalMessage(s.rc, s.nodeId, s.connectionId, &livekit.EndSession{})
+	publishSignalMessage(s.sender, s.nodeId, s.connectionId, &livekit.EndSession{})
 	if s.onClose != nil {
 		s.onClose()
 	}
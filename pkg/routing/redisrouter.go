@@ -2,6 +2,7 @@ package routing
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,24 +19,25 @@ const (
 	// expire participant mappings after a day
 	participantMappingTTL = 24 * time.Hour
 	statsUpdateInterval   = 2 * time.Second
+	deadNodeTimeout       = 30 * time.Second
 )
 
-// RedisRouter uses Redis pub/sub to route signaling messages across different nodes
+// RedisRouter uses Redis streams to route signaling messages across different nodes
 // It relies on the RTC node to be the primary driver of the participant connection.
-// Because
+// each node reads its streams through a consumer group, so messages sent while its connection is interrupted
+// are read once it's back, and those it has read but not acknowledged are read again
 type RedisRouter struct {
 	LocalRouter
 	rc        *redis.Client
 	ctx       context.Context
 	isStarted utils.AtomicFlag
 
-	pubsub *redis.PubSub
 	cancel func()
 }
 
-func NewRedisRouter(currentNode LocalNode, rc *redis.Client) *RedisRouter {
+func NewRedisRouter(currentNode LocalNode, rc *redis.Client, region string) *RedisRouter {
 	rr := &RedisRouter{
-		LocalRouter: *NewLocalRouter(currentNode),
+		LocalRouter: *NewLocalRouter(currentNode, region),
 		rc:          rc,
 	}
 	rr.ctx, rr.cancel = context.WithCancel(context.Background())
@@ -50,29 +52,66 @@ func (r *RedisRouter) RegisterNode() error {
 	if err := r.rc.HSet(r.ctx, NodesKey, r.currentNode.Id, data).Err(); err != nil {
 		return errors.Wrap(err, "could not register node")
 	}
+	if r.region != "" {
+		if err := r.rc.HSet(r.ctx, NodeRegionsKey, r.currentNode.Id, r.region).Err(); err != nil {
+			return errors.Wrap(err, "could not register node")
+		}
+	}
 	return nil
 }
 
 func (r *RedisRouter) UnregisterNode() error {
 	// could be called after Stop(), so we'd want to use an unrelated context
-	return r.rc.HDel(context.Background(), NodesKey, r.currentNode.Id).Err()
+	if err := r.rc.HDel(context.Background(), NodesKey, r.currentNode.Id).Err(); err != nil {
+		return err
+	}
+	if err := r.rc.HDel(context.Background(), NodeRegionsKey, r.currentNode.Id).Err(); err != nil {
+		return err
+	}
+	return r.rc.SRem(context.Background(), DrainingNodesKey, r.currentNode.Id).Err()
+}
+
+// nodeStreams are the streams a node reads its messages from
+func nodeStreams(nodeId string) []string {
+	return []string{signalNodeChannel(nodeId), rtcNodeChannel(nodeId)}
 }
 
-func (r *RedisRouter) RemoveDeadNodes() error {
-	nodes, err := r.ListNodes()
+func (r *RedisRouter) ListDeadNodes() ([]*livekit.Node, error) {
+	nodes, err := r.getNodes()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	dead := make([]*livekit.Node, 0)
 	for _, n := range nodes {
-		if !IsAvailable(n) {
-			if err := r.rc.HDel(context.Background(), NodesKey, n.Id).Err(); err != nil {
-				return err
-			}
+		if IsDead(n) {
+			dead = append(dead, n)
 		}
 	}
+	return dead, nil
+}
+
+func (r *RedisRouter) RemoveNode(nodeId string) error {
+	pp := r.rc.Pipeline()
+	pp.HDel(r.ctx, NodesKey, nodeId)
+	pp.HDel(r.ctx, NodeRegionsKey, nodeId)
+	pp.SRem(r.ctx, DrainingNodesKey, nodeId)
+	pp.Del(r.ctx, nodeRoomsKey(nodeId))
+	// messages to the node won't be read anymore
+	pp.Del(r.ctx, nodeStreams(nodeId)...)
+	if _, err := pp.Exec(r.ctx); err != nil {
+		return errors.Wrap(err, "could not remove node")
+	}
 	return nil
 }
 
+func (r *RedisRouter) GetNodeRooms(ctx context.Context, nodeId string) ([]string, error) {
+	rooms, err := r.rc.SMembers(r.ctx, nodeRoomsKey(nodeId)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rooms for node")
+	}
+	return rooms, nil
+}
+
 func (r *RedisRouter) GetNodeForRoom(ctx context.Context, roomName string) (*livekit.Node, error) {
 	nodeId, err := r.rc.HGet(r.ctx, NodeRoomKey, roomName).Result()
 	if err == redis.Nil {
@@ -85,16 +124,84 @@ func (r *RedisRouter) GetNodeForRoom(ctx context.Context, roomName string) (*liv
 }
 
 func (r *RedisRouter) SetNodeForRoom(ctx context.Context, roomName string, nodeId string) error {
-	return r.rc.HSet(r.ctx, NodeRoomKey, roomName, nodeId).Err()
+	// the node the room moved off of keeps it in its rooms, which is cleared when the room or that node goes
+	pp := r.rc.Pipeline()
+	pp.HSet(r.ctx, NodeRoomKey, roomName, nodeId)
+	pp.SAdd(r.ctx, nodeRoomsKey(nodeId), roomName)
+	_, err := pp.Exec(r.ctx)
+	return err
 }
 
 func (r *RedisRouter) ClearRoomState(ctx context.Context, roomName string) error {
-	if err := r.rc.HDel(r.ctx, NodeRoomKey, roomName).Err(); err != nil {
+	nodeIds, err := r.rc.HKeys(r.ctx, roomNodesKey(roomName)).Result()
+	if err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	if nodeId, err := r.rc.HGet(r.ctx, NodeRoomKey, roomName).Result(); err == nil {
+		nodeIds = append(nodeIds, nodeId)
+	} else if err != redis.Nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+
+	pp := r.rc.Pipeline()
+	pp.HDel(r.ctx, NodeRoomKey, roomName)
+	pp.Del(r.ctx, roomNodesKey(roomName))
+	for _, nodeId := range nodeIds {
+		pp.SRem(r.ctx, nodeRoomsKey(nodeId), roomName)
+	}
+	if _, err := pp.Exec(r.ctx); err != nil {
 		return errors.Wrap(err, "could not clear room state")
 	}
 	return nil
 }
 
+func (r *RedisRouter) GetRoomNodes(ctx context.Context, roomName string) (map[string]uint32, error) {
+	items, err := r.rc.HGetAll(r.ctx, roomNodesKey(roomName)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get nodes for room")
+	}
+	nodes := make(map[string]uint32, len(items))
+	for nodeId, val := range items {
+		count, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		nodes[nodeId] = uint32(count)
+	}
+	return nodes, nil
+}
+
+func (r *RedisRouter) AddRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	pp := r.rc.Pipeline()
+	// the node's own count is kept when it's already there
+	pp.HSetNX(r.ctx, roomNodesKey(roomName), nodeId, 0)
+	pp.SAdd(r.ctx, nodeRoomsKey(nodeId), roomName)
+	_, err := pp.Exec(r.ctx)
+	return err
+}
+
+func (r *RedisRouter) SetRoomNodeParticipants(ctx context.Context, roomName string, nodeId string, count uint32) error {
+	pp := r.rc.Pipeline()
+	pp.HSet(r.ctx, roomNodesKey(roomName), nodeId, count)
+	pp.SAdd(r.ctx, nodeRoomsKey(nodeId), roomName)
+	_, err := pp.Exec(r.ctx)
+	return err
+}
+
+func (r *RedisRouter) RemoveRoomNode(ctx context.Context, roomName string, nodeId string) error {
+	if err := r.rc.HDel(r.ctx, roomNodesKey(roomName), nodeId).Err(); err != nil {
+		return err
+	}
+	// the room stays in the rooms of the node hosting it
+	hostId, err := r.rc.HGet(r.ctx, NodeRoomKey, roomName).Result()
+	if err == nil && hostId == nodeId {
+		return nil
+	} else if err != nil && err != redis.Nil {
+		return err
+	}
+	return r.rc.SRem(r.ctx, nodeRoomsKey(nodeId), roomName).Err()
+}
+
 func (r *RedisRouter) GetNode(nodeId string) (*livekit.Node, error) {
 	data, err := r.rc.HGet(r.ctx, NodesKey, nodeId).Result()
 	if err == redis.Nil {
@@ -110,6 +217,56 @@ func (r *RedisRouter) GetNode(nodeId string) (*livekit.Node, error) {
 }
 
 func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
+	nodes, err := r.getNodes()
+	if err != nil {
+		return nil, err
+	}
+	draining, err := r.rc.SMembers(r.ctx, DrainingNodesKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list nodes")
+	}
+	if len(draining) == 0 {
+		return nodes, nil
+	}
+
+	isDraining := make(map[string]bool, len(draining))
+	for _, nodeId := range draining {
+		isDraining[nodeId] = true
+	}
+	selectable := make([]*livekit.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if !isDraining[n.Id] {
+			selectable = append(selectable, n)
+		}
+	}
+	return selectable, nil
+}
+
+func (r *RedisRouter) DrainNode() error {
+	if err := r.rc.SAdd(r.ctx, DrainingNodesKey, r.currentNode.Id).Err(); err != nil {
+		return errors.Wrap(err, "could not drain node")
+	}
+	return nil
+}
+
+func (r *RedisRouter) GetNodeRegions() (map[string]string, error) {
+	regions, err := r.rc.HGetAll(r.ctx, NodeRegionsKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get node regions")
+	}
+	return regions, nil
+}
+
+func (r *RedisRouter) AcquireLease(name string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLeaseScript.Run(r.ctx, r.rc, []string{leaseKey(name)}, r.currentNode.Id, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "could not acquire lease")
+	}
+	return acquired == 1, nil
+}
+
+// getNodes returns every registered node, including those that are draining
+func (r *RedisRouter) getNodes() ([]*livekit.Node, error) {
 	items, err := r.rc.HVals(r.ctx, NodesKey).Result()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not list nodes")
@@ -127,15 +284,28 @@ func (r *RedisRouter) ListNodes() ([]*livekit.Node, error) {
 
 // StartParticipantSignal signal connection sets up paths to the RTC node, and starts to route messages to that message queue
 func (r *RedisRouter) StartParticipantSignal(ctx context.Context, roomName string, pi ParticipantInit) (connectionId string, reqSink MessageSink, resSource MessageSource, err error) {
-	// find the node where the room is hosted at
-	rtcNode, err := r.GetNodeForRoom(ctx, roomName)
+	pKey := participantKey(roomName, pi.Identity)
+
+	// find the node where the room is hosted at, or the one the participant was placed on
+	var rtcNode *livekit.Node
+	rtcNodeId := pi.RTCNodeId
+	if pi.Reconnect {
+		// resumed sessions are on the node they started on, which may be another node of a cascaded room
+		if nodeId, err := r.getParticipantRTCNode(pKey); err == nil {
+			rtcNodeId = nodeId
+		}
+	}
+	if rtcNodeId != "" {
+		rtcNode, err = r.GetNode(rtcNodeId)
+	} else {
+		rtcNode, err = r.GetNodeForRoom(ctx, roomName)
+	}
 	if err != nil {
 		return
 	}
 
 	// create a new connection id
 	connectionId = utils.NewGuid("CO_")
-	pKey := participantKey(roomName, pi.Identity)
 
 	// map signal & rtc nodes
 	if err = r.setParticipantSignalNode(connectionId, r.currentNode.Id); err != nil {
@@ -177,6 +347,18 @@ func (r *RedisRouter) WriteRTCMessage(ctx context.Context, roomName, identity st
 	return r.writeRTCMessage(roomName, identity, msg, rtcSink)
 }
 
+func (r *RedisRouter) SetParticipantRTCNode(roomName, identity, nodeId string) error {
+	return r.setParticipantRTCNode(participantKey(roomName, identity), nodeId)
+}
+
+func (r *RedisRouter) GetParticipantRTCNode(roomName, identity string) (string, error) {
+	return r.getParticipantRTCNode(participantKey(roomName, identity))
+}
+
+func (r *RedisRouter) ClearParticipantState(ctx context.Context, roomName, identity string) error {
+	return r.rc.Del(r.ctx, participantRTCKey(participantKey(roomName, identity))).Err()
+}
+
 func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantKey string) error {
 	// find the node where the room is hosted at
 	rtcNode, err := r.GetNodeForRoom(r.ctx, ss.RoomName)
@@ -185,13 +367,20 @@ func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantK
 	}
 
 	if rtcNode.Id != r.currentNode.Id {
-		err = ErrIncorrectRTCNode
-		logger.Errorw("called participant on incorrect node", err,
-			"rtcNode", rtcNode, "nodeID", r.currentNode.Id)
-		return err
+		// or the room is cascaded to this node
+		roomNodes, err := r.GetRoomNodes(r.ctx, ss.RoomName)
+		if err != nil {
+			return err
+		}
+		if _, ok := roomNodes[r.currentNode.Id]; !ok {
+			err = ErrIncorrectRTCNode
+			logger.Errorw("called participant on incorrect node", err,
+				"rtcNode", rtcNode, "nodeID", r.currentNode.Id)
+			return err
+		}
 	}
 
-	if err := r.setParticipantRTCNode(participantKey, rtcNode.Id); err != nil {
+	if err := r.setParticipantRTCNode(participantKey, r.currentNode.Id); err != nil {
 		return err
 	}
 
@@ -245,17 +434,19 @@ func (r *RedisRouter) Start() error {
 		return nil
 	}
 
-	workerStarted := make(chan struct{})
-	go r.statsWorker()
-	go r.redisWorker(workerStarted)
-
-	// wait until worker is running
-	select {
-	case <-workerStarted:
-		return nil
-	case <-time.After(3 * time.Second):
-		return errors.New("Unable to start redis router")
+	// node IDs are kept across restarts, so a group that exists already is kept along with the messages it has
+	// pending and those that haven't been read
+	for _, stream := range nodeStreams(r.currentNode.Id) {
+		err := r.rc.XGroupCreateMkStream(r.ctx, stream, r.currentNode.Id, "$").Err()
+		if err != nil && !isBusyGroupError(err) {
+			r.isStarted.TrySet(false)
+			return errors.Wrap(err, "Unable to start redis router")
+		}
 	}
+
+	go r.statsWorker()
+	go r.redisWorker()
+	return nil
 }
 
 func (r *RedisRouter) Stop() {
@@ -263,7 +454,6 @@ func (r *RedisRouter) Stop() {
 		return
 	}
 	logger.Debugw("stopping RedisRouter")
-	_ = r.pubsub.Close()
 	_ = r.UnregisterNode()
 	r.cancel()
 }
@@ -318,7 +508,7 @@ func (r *RedisRouter) statsWorker() {
 }
 
 // worker that consumes redis messages intended for this node
-func (r *RedisRouter) redisWorker(startedChan chan struct{}) {
+func (r *RedisRouter) redisWorker() {
 	defer func() {
 		logger.Debugw("finishing redisWorker", "nodeID", r.currentNode.Id)
 	}()
@@ -326,67 +516,110 @@ func (r *RedisRouter) redisWorker(startedChan chan struct{}) {
 
 	sigChannel := signalNodeChannel(r.currentNode.Id)
 	rtcChannel := rtcNodeChannel(r.currentNode.Id)
-	r.pubsub = r.rc.Subscribe(r.ctx, sigChannel, rtcChannel)
-
-	close(startedChan)
-	for msg := range r.pubsub.Channel() {
-		if msg == nil {
-			return
+	deduper := newMessageDeduper()
+	// messages that are delivered but not acknowledged are pending, when a read fails or they couldn't be handled,
+	// or they were read before the node restarted. they're read again, before new ones
+	readPending := true
+	// attempts at handling pending messages, by message ID
+	attempts := make(map[string]int)
+	for r.ctx.Err() == nil {
+		id := ">"
+		if readPending {
+			id = "0"
 		}
-
-		if msg.Channel == sigChannel {
-			sm := livekit.SignalNodeMessage{}
-			if err := proto.Unmarshal([]byte(msg.Payload), &sm); err != nil {
-				logger.Errorw("could not unmarshal signal message on sigchan", err)
-				continue
-			}
-			if err := r.handleSignalMessage(&sm); err != nil {
-				logger.Errorw("error processing signal message", err)
-				continue
-			}
-		} else if msg.Channel == rtcChannel {
-			rm := livekit.RTCNodeMessage{}
-			if err := proto.Unmarshal([]byte(msg.Payload), &rm); err != nil {
-				logger.Errorw("could not unmarshal RTC message on rtcchan", err)
-				continue
+		streams, err := r.rc.XReadGroup(r.ctx, &redis.XReadGroupArgs{
+			Group:    r.currentNode.Id,
+			Consumer: r.currentNode.Id,
+			Streams:  []string{sigChannel, rtcChannel, id, id},
+			Count:    nodeStreamReadCount,
+			Block:    nodeStreamReadBlock,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			if r.ctx.Err() != nil {
+				return
 			}
-			if err := r.handleRTCMessage(&rm); err != nil {
-				logger.Errorw("error processing RTC message", err)
-				continue
+			logger.Errorw("could not read node streams", err, "nodeID", r.currentNode.Id)
+			readPending = true
+			time.Sleep(nodeStreamReadBlock)
+			continue
+		}
+
+		received := 0
+		failed := false
+		for _, stream := range streams {
+			// messages of a sender are handled in order, once one of them fails the rest wait for it
+			blocked := make(map[string]bool)
+			for _, entry := range stream.Messages {
+				received++
+				msg, err := parseStreamMessage(entry)
+				if err != nil {
+					logger.Errorw("could not parse message on node stream", err, "stream", stream.Stream)
+				} else if blocked[msg.sender] {
+					failed = true
+					continue
+				} else if !deduper.isDuplicate(msg) {
+					if err := r.handleStreamMessage(stream.Stream, msg); err != nil {
+						attempts[entry.ID]++
+						if attempts[entry.ID] < handleAttempts && !isFinalHandleError(err) {
+							// left pending to be handled again
+							blocked[msg.sender] = true
+							failed = true
+							continue
+						}
+						logger.Errorw("dropping message on node stream", err, "stream", stream.Stream)
+					}
+					deduper.received(msg)
+				}
+				delete(attempts, entry.ID)
+				if err := r.rc.XAck(r.ctx, stream.Stream, r.currentNode.Id, entry.ID).Err(); err != nil {
+					// it's read again as pending, and dropped as a duplicate
+					logger.Warnw("could not acknowledge message", err, "stream", stream.Stream)
+					failed = true
+				}
 			}
 		}
+		if failed {
+			readPending = true
+			time.Sleep(handleRetryInterval)
+		} else if readPending && received == 0 {
+			readPending = false
+		}
 	}
 }
 
-func (r *RedisRouter) handleSignalMessage(sm *livekit.SignalNodeMessage) error {
-	connectionId := sm.ConnectionId
-
-	r.lock.RLock()
-	resSink := r.responseChannels[connectionId]
-	r.lock.RUnlock()
-
-	// if a client closed the channel, then sent more messages after that,
-	if resSink == nil {
-		return nil
-	}
-
-	switch rmb := sm.Message.(type) {
-	case *livekit.SignalNodeMessage_Response:
-		// logger.Debugw("forwarding signal message",
-		//	"connID", connectionId,
-		//	"type", fmt.Sprintf("%T", rmb.Response.Message))
-		if err := resSink.WriteMessage(rmb.Response); err != nil {
-			return err
+// handleStreamMessage handles a message to this node. the errors it returns may be temporary, messages that can't
+// ever be handled are only logged
+func (r *RedisRouter) handleStreamMessage(stream string, msg *nodeMessage) error {
+	if stream == signalNodeChannel(r.currentNode.Id) {
+		sm := livekit.SignalNodeMessage{}
+		if err := proto.Unmarshal(msg.data, &sm); err != nil {
+			logger.Errorw("could not unmarshal signal message on sigchan", err)
+			return nil
+		}
+		if err := r.handleSignalMessage(&sm); err != nil {
+			return errors.Wrap(err, "error processing signal message")
+		}
+	} else if stream == rtcNodeChannel(r.currentNode.Id) {
+		rm := livekit.RTCNodeMessage{}
+		if err := proto.Unmarshal(msg.data, &rm); err != nil {
+			logger.Errorw("could not unmarshal RTC message on rtcchan", err)
+			return nil
+		}
+		if err := r.handleRTCMessage(&rm); err != nil {
+			return errors.Wrap(err, "error processing RTC message")
 		}
-
-	case *livekit.SignalNodeMessage_EndSession:
-		// logger.Debugw("received EndSession, closing signal connection",
-		//	"connID", connectionId)
-		resSink.Close()
 	}
 	return nil
 }
 
+// isFinalHandleError is true for errors handling a message again wouldn't get past
+func isFinalHandleError(err error) bool {
+	err = errors.Cause(err)
+	return err == ErrChannelClosed || err == ErrIncorrectRTCNode || err == ErrHandlerNotDefined
+}
+
 func (r *RedisRouter) handleRTCMessage(rm *livekit.RTCNodeMessage) error {
 	pKey := rm.ParticipantKey
 
@@ -0,0 +1,169 @@
+package routing
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/livekit/protocol/utils"
+)
+
+const (
+	// streams of each node are trimmed to about this many messages
+	nodeStreamMaxLen = 10000
+	// messages are read from the streams this many at a time
+	nodeStreamReadCount = 100
+	// reads wait this long for new messages, before checking if the router has stopped
+	nodeStreamReadBlock = time.Second
+
+	// a failed publish is retried with a growing delay, until it's been attempted this many times
+	publishAttempts      = 3
+	publishRetryInterval = 100 * time.Millisecond
+
+	// a message that couldn't be handled is tried again after a delay, until it's been attempted this many times
+	handleAttempts      = 3
+	handleRetryInterval = 100 * time.Millisecond
+
+	// senders are remembered for this long after their last message, duplicates come well within it
+	dedupWindow = 2 * time.Minute
+
+	senderPrefix = "MS_"
+)
+
+// nodeMessage is a message to another node. messages are numbered by the sink sending them, so the node
+// receiving them can drop those it's already had
+type nodeMessage struct {
+	sender string
+	seq    uint64
+	data   []byte
+}
+
+// publishFunc publishes a message on a channel of another node, through the router's transport
+type publishFunc func(channel string, msg *nodeMessage) error
+
+// messageSender numbers the messages of a sink. they're published one at a time, so a node receives them in
+// the order they were sent
+type messageSender struct {
+	publish publishFunc
+	id      string
+	lock    sync.Mutex
+	seq     uint64
+}
+
+func newMessageSender(publish publishFunc) *messageSender {
+	return &messageSender{
+		publish: publish,
+		id:      utils.NewGuid(senderPrefix),
+	}
+}
+
+func (s *messageSender) send(channel string, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.seq++
+	return s.publish(channel, &nodeMessage{
+		sender: s.id,
+		seq:    s.seq,
+		data:   data,
+	})
+}
+
+// redisPublisher adds messages to the stream of a node, which keeps them until the node has read them. failed
+// adds are retried, a retry of an add that did go through is dropped as a duplicate
+func redisPublisher(rc *redis.Client) publishFunc {
+	return func(channel string, msg *nodeMessage) error {
+		args := &redis.XAddArgs{
+			Stream: channel,
+			MaxLen: nodeStreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				"sender": msg.sender,
+				"seq":    msg.seq,
+				"data":   msg.data,
+			},
+		}
+		var err error
+		for attempt := 1; attempt <= publishAttempts; attempt++ {
+			if err = rc.XAdd(redisCtx, args).Err(); err == nil {
+				return nil
+			}
+			if attempt < publishAttempts {
+				time.Sleep(time.Duration(attempt) * publishRetryInterval)
+			}
+		}
+		return err
+	}
+}
+
+// parseStreamMessage reads a nodeMessage from a stream entry
+func parseStreamMessage(msg redis.XMessage) (*nodeMessage, error) {
+	sender, _ := msg.Values["sender"].(string)
+	seqVal, _ := msg.Values["seq"].(string)
+	data, _ := msg.Values["data"].(string)
+	if sender == "" {
+		return nil, ErrInvalidRouterMessage
+	}
+	seq, err := strconv.ParseUint(seqVal, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidRouterMessage
+	}
+	return &nodeMessage{
+		sender: sender,
+		seq:    seq,
+		data:   []byte(data),
+	}, nil
+}
+
+func isBusyGroupError(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+// messageDeduper drops messages a node has already had, by the last number received from each sender. messages are
+// only received once they've been handled, so one that failed isn't dropped when it's read again. it's only used by
+// the worker reading the node's messages
+type messageDeduper struct {
+	senders    map[string]*senderState
+	lastPruned time.Time
+}
+
+type senderState struct {
+	seq    uint64
+	seenAt time.Time
+}
+
+func newMessageDeduper() *messageDeduper {
+	return &messageDeduper{
+		senders:    make(map[string]*senderState),
+		lastPruned: time.Now(),
+	}
+}
+
+func (d *messageDeduper) isDuplicate(msg *nodeMessage) bool {
+	now := time.Now()
+	if now.Sub(d.lastPruned) > dedupWindow {
+		for id, s := range d.senders {
+			if now.Sub(s.seenAt) > dedupWindow {
+				delete(d.senders, id)
+			}
+		}
+		d.lastPruned = now
+	}
+
+	s := d.senders[msg.sender]
+	return s != nil && msg.seq <= s.seq
+}
+
+// received remembers the message was had, later ones of its sender with the same or a lower number are duplicates
+func (d *messageDeduper) received(msg *nodeMessage) {
+	s := d.senders[msg.sender]
+	if s == nil {
+		s = &senderState{}
+		d.senders[msg.sender] = s
+	}
+	if msg.seq > s.seq {
+		s.seq = msg.seq
+	}
+	s.seenAt = time.Now()
+}
@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSender(t *testing.T) {
+	var sent []*nodeMessage
+	sender := newMessageSender(func(channel string, msg *nodeMessage) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	require.NoError(t, sender.send("channel", []byte("first")))
+	require.NoError(t, sender.send("channel", []byte("second")))
+
+	require.Len(t, sent, 2)
+	require.Equal(t, sender.id, sent[0].sender)
+	require.Equal(t, uint64(1), sent[0].seq)
+	require.Equal(t, uint64(2), sent[1].seq)
+	require.Equal(t, "second", string(sent[1].data))
+	require.NotEqual(t, sender.id, newMessageSender(nil).id)
+}
+
+func TestMessageDeduper(t *testing.T) {
+	receive := func(d *messageDeduper, msg *nodeMessage) bool {
+		if d.isDuplicate(msg) {
+			return false
+		}
+		d.received(msg)
+		return true
+	}
+
+	t.Run("drops messages already received", func(t *testing.T) {
+		d := newMessageDeduper()
+		require.True(t, receive(d, &nodeMessage{sender: "a", seq: 1}))
+		require.True(t, receive(d, &nodeMessage{sender: "a", seq: 2}))
+		require.False(t, receive(d, &nodeMessage{sender: "a", seq: 2}))
+		require.False(t, receive(d, &nodeMessage{sender: "a", seq: 1}))
+		// numbered by each sender
+		require.True(t, receive(d, &nodeMessage{sender: "b", seq: 1}))
+		require.True(t, receive(d, &nodeMessage{sender: "a", seq: 3}))
+	})
+
+	t.Run("keeps messages that weren't handled", func(t *testing.T) {
+		d := newMessageDeduper()
+		msg := &nodeMessage{sender: "a", seq: 1}
+		require.False(t, d.isDuplicate(msg))
+		// read again after it failed
+		require.False(t, d.isDuplicate(msg))
+		d.received(msg)
+		require.True(t, d.isDuplicate(msg))
+	})
+
+	t.Run("forgets senders after the window", func(t *testing.T) {
+		d := newMessageDeduper()
+		require.True(t, receive(d, &nodeMessage{sender: "a", seq: 1}))
+		d.senders["a"].seenAt = time.Now().Add(-2 * dedupWindow)
+		d.lastPruned = time.Now().Add(-2 * dedupWindow)
+
+		require.True(t, receive(d, &nodeMessage{sender: "b", seq: 1}))
+		require.NotContains(t, d.senders, "a")
+	})
+}
+
+func TestParseStreamMessage(t *testing.T) {
+	msg, err := parseStreamMessage(redis.XMessage{
+		ID: "1-0",
+		Values: map[string]interface{}{
+			"sender": "MS_a",
+			"seq":    "7",
+			"data":   "payload",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &nodeMessage{sender: "MS_a", seq: 7, data: []byte("payload")}, msg)
+
+	_, err = parseStreamMessage(redis.XMessage{ID: "2-0", Values: map[string]interface{}{"data": "payload"}})
+	require.Equal(t, ErrInvalidRouterMessage, err)
+}
+
+func TestRedisPublisher(t *testing.T) {
+	rc := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	stream := "test_stream:" + newMessageSender(nil).id
+	defer rc.Del(redisCtx, stream)
+
+	sender := newMessageSender(redisPublisher(rc))
+	require.NoError(t, sender.send(stream, []byte("first")))
+	require.NoError(t, sender.send(stream, []byte("second")))
+
+	entries, err := rc.XRange(redisCtx, stream, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	msg, err := parseStreamMessage(entries[1])
+	require.NoError(t, err)
+	require.Equal(t, &nodeMessage{sender: sender.id, seq: 2, data: []byte("second")}, msg)
+}
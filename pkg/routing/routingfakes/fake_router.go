@@ -4,12 +4,53 @@ package routingfakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/livekit/livekit-server/pkg/routing"
 	livekit "github.com/livekit/protocol/proto"
 )
 
 type FakeRouter struct {
+	AcquireLeaseStub        func(string, time.Duration) (bool, error)
+	acquireLeaseMutex       sync.RWMutex
+	acquireLeaseArgsForCall []struct {
+		arg1 string
+		arg2 time.Duration
+	}
+	acquireLeaseReturns struct {
+		result1 bool
+		result2 error
+	}
+	acquireLeaseReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	AddRoomNodeStub        func(context.Context, string, string) error
+	addRoomNodeMutex       sync.RWMutex
+	addRoomNodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	addRoomNodeReturns struct {
+		result1 error
+	}
+	addRoomNodeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ClearParticipantStateStub        func(context.Context, string, string) error
+	clearParticipantStateMutex       sync.RWMutex
+	clearParticipantStateArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	clearParticipantStateReturns struct {
+		result1 error
+	}
+	clearParticipantStateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ClearRoomStateStub        func(context.Context, string) error
 	clearRoomStateMutex       sync.RWMutex
 	clearRoomStateArgsForCall []struct {
@@ -22,6 +63,16 @@ type FakeRouter struct {
 	clearRoomStateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DrainNodeStub        func() error
+	drainNodeMutex       sync.RWMutex
+	drainNodeArgsForCall []struct {
+	}
+	drainNodeReturns struct {
+		result1 error
+	}
+	drainNodeReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetNodeStub        func(string) (*livekit.Node, error)
 	getNodeMutex       sync.RWMutex
 	getNodeArgsForCall []struct {
@@ -49,6 +100,72 @@ type FakeRouter struct {
 		result1 *livekit.Node
 		result2 error
 	}
+	GetNodeRegionsStub        func() (map[string]string, error)
+	getNodeRegionsMutex       sync.RWMutex
+	getNodeRegionsArgsForCall []struct {
+	}
+	getNodeRegionsReturns struct {
+		result1 map[string]string
+		result2 error
+	}
+	getNodeRegionsReturnsOnCall map[int]struct {
+		result1 map[string]string
+		result2 error
+	}
+	GetNodeRoomsStub        func(context.Context, string) ([]string, error)
+	getNodeRoomsMutex       sync.RWMutex
+	getNodeRoomsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getNodeRoomsReturns struct {
+		result1 []string
+		result2 error
+	}
+	getNodeRoomsReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	GetParticipantRTCNodeStub        func(string, string) (string, error)
+	getParticipantRTCNodeMutex       sync.RWMutex
+	getParticipantRTCNodeArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	getParticipantRTCNodeReturns struct {
+		result1 string
+		result2 error
+	}
+	getParticipantRTCNodeReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	GetRoomNodesStub        func(context.Context, string) (map[string]uint32, error)
+	getRoomNodesMutex       sync.RWMutex
+	getRoomNodesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getRoomNodesReturns struct {
+		result1 map[string]uint32
+		result2 error
+	}
+	getRoomNodesReturnsOnCall map[int]struct {
+		result1 map[string]uint32
+		result2 error
+	}
+	ListDeadNodesStub        func() ([]*livekit.Node, error)
+	listDeadNodesMutex       sync.RWMutex
+	listDeadNodesArgsForCall []struct {
+	}
+	listDeadNodesReturns struct {
+		result1 []*livekit.Node
+		result2 error
+	}
+	listDeadNodesReturnsOnCall map[int]struct {
+		result1 []*livekit.Node
+		result2 error
+	}
 	ListNodesStub        func() ([]*livekit.Node, error)
 	listNodesMutex       sync.RWMutex
 	listNodesArgsForCall []struct {
@@ -81,14 +198,28 @@ type FakeRouter struct {
 	registerNodeReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RemoveDeadNodesStub        func() error
-	removeDeadNodesMutex       sync.RWMutex
-	removeDeadNodesArgsForCall []struct {
+	RemoveNodeStub        func(string) error
+	removeNodeMutex       sync.RWMutex
+	removeNodeArgsForCall []struct {
+		arg1 string
+	}
+	removeNodeReturns struct {
+		result1 error
+	}
+	removeNodeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RemoveRoomNodeStub        func(context.Context, string, string) error
+	removeRoomNodeMutex       sync.RWMutex
+	removeRoomNodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
 	}
-	removeDeadNodesReturns struct {
+	removeRoomNodeReturns struct {
 		result1 error
 	}
-	removeDeadNodesReturnsOnCall map[int]struct {
+	removeRoomNodeReturnsOnCall map[int]struct {
 		result1 error
 	}
 	SetNodeForRoomStub        func(context.Context, string, string) error
@@ -104,6 +235,33 @@ type FakeRouter struct {
 	setNodeForRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetParticipantRTCNodeStub        func(string, string, string) error
+	setParticipantRTCNodeMutex       sync.RWMutex
+	setParticipantRTCNodeArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	setParticipantRTCNodeReturns struct {
+		result1 error
+	}
+	setParticipantRTCNodeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetRoomNodeParticipantsStub        func(context.Context, string, string, uint32) error
+	setRoomNodeParticipantsMutex       sync.RWMutex
+	setRoomNodeParticipantsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 uint32
+	}
+	setRoomNodeParticipantsReturns struct {
+		result1 error
+	}
+	setRoomNodeParticipantsReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StartStub        func() error
 	startMutex       sync.RWMutex
 	startArgsForCall []struct {
@@ -165,6 +323,197 @@ type FakeRouter struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeRouter) AcquireLease(arg1 string, arg2 time.Duration) (bool, error) {
+	fake.acquireLeaseMutex.Lock()
+	ret, specificReturn := fake.acquireLeaseReturnsOnCall[len(fake.acquireLeaseArgsForCall)]
+	fake.acquireLeaseArgsForCall = append(fake.acquireLeaseArgsForCall, struct {
+		arg1 string
+		arg2 time.Duration
+	}{arg1, arg2})
+	stub := fake.AcquireLeaseStub
+	fakeReturns := fake.acquireLeaseReturns
+	fake.recordInvocation("AcquireLease", []interface{}{arg1, arg2})
+	fake.acquireLeaseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) AcquireLeaseCallCount() int {
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	return len(fake.acquireLeaseArgsForCall)
+}
+
+func (fake *FakeRouter) AcquireLeaseCalls(stub func(string, time.Duration) (bool, error)) {
+	fake.acquireLeaseMutex.Lock()
+	defer fake.acquireLeaseMutex.Unlock()
+	fake.AcquireLeaseStub = stub
+}
+
+func (fake *FakeRouter) AcquireLeaseArgsForCall(i int) (string, time.Duration) {
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	argsForCall := fake.acquireLeaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRouter) AcquireLeaseReturns(result1 bool, result2 error) {
+	fake.acquireLeaseMutex.Lock()
+	defer fake.acquireLeaseMutex.Unlock()
+	fake.AcquireLeaseStub = nil
+	fake.acquireLeaseReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) AcquireLeaseReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.acquireLeaseMutex.Lock()
+	defer fake.acquireLeaseMutex.Unlock()
+	fake.AcquireLeaseStub = nil
+	if fake.acquireLeaseReturnsOnCall == nil {
+		fake.acquireLeaseReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.acquireLeaseReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) AddRoomNode(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.addRoomNodeMutex.Lock()
+	ret, specificReturn := fake.addRoomNodeReturnsOnCall[len(fake.addRoomNodeArgsForCall)]
+	fake.addRoomNodeArgsForCall = append(fake.addRoomNodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.AddRoomNodeStub
+	fakeReturns := fake.addRoomNodeReturns
+	fake.recordInvocation("AddRoomNode", []interface{}{arg1, arg2, arg3})
+	fake.addRoomNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) AddRoomNodeCallCount() int {
+	fake.addRoomNodeMutex.RLock()
+	defer fake.addRoomNodeMutex.RUnlock()
+	return len(fake.addRoomNodeArgsForCall)
+}
+
+func (fake *FakeRouter) AddRoomNodeCalls(stub func(context.Context, string, string) error) {
+	fake.addRoomNodeMutex.Lock()
+	defer fake.addRoomNodeMutex.Unlock()
+	fake.AddRoomNodeStub = stub
+}
+
+func (fake *FakeRouter) AddRoomNodeArgsForCall(i int) (context.Context, string, string) {
+	fake.addRoomNodeMutex.RLock()
+	defer fake.addRoomNodeMutex.RUnlock()
+	argsForCall := fake.addRoomNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) AddRoomNodeReturns(result1 error) {
+	fake.addRoomNodeMutex.Lock()
+	defer fake.addRoomNodeMutex.Unlock()
+	fake.AddRoomNodeStub = nil
+	fake.addRoomNodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) AddRoomNodeReturnsOnCall(i int, result1 error) {
+	fake.addRoomNodeMutex.Lock()
+	defer fake.addRoomNodeMutex.Unlock()
+	fake.AddRoomNodeStub = nil
+	if fake.addRoomNodeReturnsOnCall == nil {
+		fake.addRoomNodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.addRoomNodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) ClearParticipantState(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.clearParticipantStateMutex.Lock()
+	ret, specificReturn := fake.clearParticipantStateReturnsOnCall[len(fake.clearParticipantStateArgsForCall)]
+	fake.clearParticipantStateArgsForCall = append(fake.clearParticipantStateArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ClearParticipantStateStub
+	fakeReturns := fake.clearParticipantStateReturns
+	fake.recordInvocation("ClearParticipantState", []interface{}{arg1, arg2, arg3})
+	fake.clearParticipantStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) ClearParticipantStateCallCount() int {
+	fake.clearParticipantStateMutex.RLock()
+	defer fake.clearParticipantStateMutex.RUnlock()
+	return len(fake.clearParticipantStateArgsForCall)
+}
+
+func (fake *FakeRouter) ClearParticipantStateCalls(stub func(context.Context, string, string) error) {
+	fake.clearParticipantStateMutex.Lock()
+	defer fake.clearParticipantStateMutex.Unlock()
+	fake.ClearParticipantStateStub = stub
+}
+
+func (fake *FakeRouter) ClearParticipantStateArgsForCall(i int) (context.Context, string, string) {
+	fake.clearParticipantStateMutex.RLock()
+	defer fake.clearParticipantStateMutex.RUnlock()
+	argsForCall := fake.clearParticipantStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) ClearParticipantStateReturns(result1 error) {
+	fake.clearParticipantStateMutex.Lock()
+	defer fake.clearParticipantStateMutex.Unlock()
+	fake.ClearParticipantStateStub = nil
+	fake.clearParticipantStateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) ClearParticipantStateReturnsOnCall(i int, result1 error) {
+	fake.clearParticipantStateMutex.Lock()
+	defer fake.clearParticipantStateMutex.Unlock()
+	fake.ClearParticipantStateStub = nil
+	if fake.clearParticipantStateReturnsOnCall == nil {
+		fake.clearParticipantStateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.clearParticipantStateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRouter) ClearRoomState(arg1 context.Context, arg2 string) error {
 	fake.clearRoomStateMutex.Lock()
 	ret, specificReturn := fake.clearRoomStateReturnsOnCall[len(fake.clearRoomStateArgsForCall)]
@@ -227,6 +576,59 @@ func (fake *FakeRouter) ClearRoomStateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRouter) DrainNode() error {
+	fake.drainNodeMutex.Lock()
+	ret, specificReturn := fake.drainNodeReturnsOnCall[len(fake.drainNodeArgsForCall)]
+	fake.drainNodeArgsForCall = append(fake.drainNodeArgsForCall, struct {
+	}{})
+	stub := fake.DrainNodeStub
+	fakeReturns := fake.drainNodeReturns
+	fake.recordInvocation("DrainNode", []interface{}{})
+	fake.drainNodeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) DrainNodeCallCount() int {
+	fake.drainNodeMutex.RLock()
+	defer fake.drainNodeMutex.RUnlock()
+	return len(fake.drainNodeArgsForCall)
+}
+
+func (fake *FakeRouter) DrainNodeCalls(stub func() error) {
+	fake.drainNodeMutex.Lock()
+	defer fake.drainNodeMutex.Unlock()
+	fake.DrainNodeStub = stub
+}
+
+func (fake *FakeRouter) DrainNodeReturns(result1 error) {
+	fake.drainNodeMutex.Lock()
+	defer fake.drainNodeMutex.Unlock()
+	fake.DrainNodeStub = nil
+	fake.drainNodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) DrainNodeReturnsOnCall(i int, result1 error) {
+	fake.drainNodeMutex.Lock()
+	defer fake.drainNodeMutex.Unlock()
+	fake.DrainNodeStub = nil
+	if fake.drainNodeReturnsOnCall == nil {
+		fake.drainNodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.drainNodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRouter) GetNode(arg1 string) (*livekit.Node, error) {
 	fake.getNodeMutex.Lock()
 	ret, specificReturn := fake.getNodeReturnsOnCall[len(fake.getNodeArgsForCall)]
@@ -356,6 +758,313 @@ func (fake *FakeRouter) GetNodeForRoomReturnsOnCall(i int, result1 *livekit.Node
 	}{result1, result2}
 }
 
+func (fake *FakeRouter) GetNodeRegions() (map[string]string, error) {
+	fake.getNodeRegionsMutex.Lock()
+	ret, specificReturn := fake.getNodeRegionsReturnsOnCall[len(fake.getNodeRegionsArgsForCall)]
+	fake.getNodeRegionsArgsForCall = append(fake.getNodeRegionsArgsForCall, struct {
+	}{})
+	stub := fake.GetNodeRegionsStub
+	fakeReturns := fake.getNodeRegionsReturns
+	fake.recordInvocation("GetNodeRegions", []interface{}{})
+	fake.getNodeRegionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) GetNodeRegionsCallCount() int {
+	fake.getNodeRegionsMutex.RLock()
+	defer fake.getNodeRegionsMutex.RUnlock()
+	return len(fake.getNodeRegionsArgsForCall)
+}
+
+func (fake *FakeRouter) GetNodeRegionsCalls(stub func() (map[string]string, error)) {
+	fake.getNodeRegionsMutex.Lock()
+	defer fake.getNodeRegionsMutex.Unlock()
+	fake.GetNodeRegionsStub = stub
+}
+
+func (fake *FakeRouter) GetNodeRegionsReturns(result1 map[string]string, result2 error) {
+	fake.getNodeRegionsMutex.Lock()
+	defer fake.getNodeRegionsMutex.Unlock()
+	fake.GetNodeRegionsStub = nil
+	fake.getNodeRegionsReturns = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetNodeRegionsReturnsOnCall(i int, result1 map[string]string, result2 error) {
+	fake.getNodeRegionsMutex.Lock()
+	defer fake.getNodeRegionsMutex.Unlock()
+	fake.GetNodeRegionsStub = nil
+	if fake.getNodeRegionsReturnsOnCall == nil {
+		fake.getNodeRegionsReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+			result2 error
+		})
+	}
+	fake.getNodeRegionsReturnsOnCall[i] = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetNodeRooms(arg1 context.Context, arg2 string) ([]string, error) {
+	fake.getNodeRoomsMutex.Lock()
+	ret, specificReturn := fake.getNodeRoomsReturnsOnCall[len(fake.getNodeRoomsArgsForCall)]
+	fake.getNodeRoomsArgsForCall = append(fake.getNodeRoomsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetNodeRoomsStub
+	fakeReturns := fake.getNodeRoomsReturns
+	fake.recordInvocation("GetNodeRooms", []interface{}{arg1, arg2})
+	fake.getNodeRoomsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) GetNodeRoomsCallCount() int {
+	fake.getNodeRoomsMutex.RLock()
+	defer fake.getNodeRoomsMutex.RUnlock()
+	return len(fake.getNodeRoomsArgsForCall)
+}
+
+func (fake *FakeRouter) GetNodeRoomsCalls(stub func(context.Context, string) ([]string, error)) {
+	fake.getNodeRoomsMutex.Lock()
+	defer fake.getNodeRoomsMutex.Unlock()
+	fake.GetNodeRoomsStub = stub
+}
+
+func (fake *FakeRouter) GetNodeRoomsArgsForCall(i int) (context.Context, string) {
+	fake.getNodeRoomsMutex.RLock()
+	defer fake.getNodeRoomsMutex.RUnlock()
+	argsForCall := fake.getNodeRoomsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRouter) GetNodeRoomsReturns(result1 []string, result2 error) {
+	fake.getNodeRoomsMutex.Lock()
+	defer fake.getNodeRoomsMutex.Unlock()
+	fake.GetNodeRoomsStub = nil
+	fake.getNodeRoomsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetNodeRoomsReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.getNodeRoomsMutex.Lock()
+	defer fake.getNodeRoomsMutex.Unlock()
+	fake.GetNodeRoomsStub = nil
+	if fake.getNodeRoomsReturnsOnCall == nil {
+		fake.getNodeRoomsReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.getNodeRoomsReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetParticipantRTCNode(arg1 string, arg2 string) (string, error) {
+	fake.getParticipantRTCNodeMutex.Lock()
+	ret, specificReturn := fake.getParticipantRTCNodeReturnsOnCall[len(fake.getParticipantRTCNodeArgsForCall)]
+	fake.getParticipantRTCNodeArgsForCall = append(fake.getParticipantRTCNodeArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetParticipantRTCNodeStub
+	fakeReturns := fake.getParticipantRTCNodeReturns
+	fake.recordInvocation("GetParticipantRTCNode", []interface{}{arg1, arg2})
+	fake.getParticipantRTCNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) GetParticipantRTCNodeCallCount() int {
+	fake.getParticipantRTCNodeMutex.RLock()
+	defer fake.getParticipantRTCNodeMutex.RUnlock()
+	return len(fake.getParticipantRTCNodeArgsForCall)
+}
+
+func (fake *FakeRouter) GetParticipantRTCNodeCalls(stub func(string, string) (string, error)) {
+	fake.getParticipantRTCNodeMutex.Lock()
+	defer fake.getParticipantRTCNodeMutex.Unlock()
+	fake.GetParticipantRTCNodeStub = stub
+}
+
+func (fake *FakeRouter) GetParticipantRTCNodeArgsForCall(i int) (string, string) {
+	fake.getParticipantRTCNodeMutex.RLock()
+	defer fake.getParticipantRTCNodeMutex.RUnlock()
+	argsForCall := fake.getParticipantRTCNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRouter) GetParticipantRTCNodeReturns(result1 string, result2 error) {
+	fake.getParticipantRTCNodeMutex.Lock()
+	defer fake.getParticipantRTCNodeMutex.Unlock()
+	fake.GetParticipantRTCNodeStub = nil
+	fake.getParticipantRTCNodeReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetParticipantRTCNodeReturnsOnCall(i int, result1 string, result2 error) {
+	fake.getParticipantRTCNodeMutex.Lock()
+	defer fake.getParticipantRTCNodeMutex.Unlock()
+	fake.GetParticipantRTCNodeStub = nil
+	if fake.getParticipantRTCNodeReturnsOnCall == nil {
+		fake.getParticipantRTCNodeReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getParticipantRTCNodeReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetRoomNodes(arg1 context.Context, arg2 string) (map[string]uint32, error) {
+	fake.getRoomNodesMutex.Lock()
+	ret, specificReturn := fake.getRoomNodesReturnsOnCall[len(fake.getRoomNodesArgsForCall)]
+	fake.getRoomNodesArgsForCall = append(fake.getRoomNodesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetRoomNodesStub
+	fakeReturns := fake.getRoomNodesReturns
+	fake.recordInvocation("GetRoomNodes", []interface{}{arg1, arg2})
+	fake.getRoomNodesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) GetRoomNodesCallCount() int {
+	fake.getRoomNodesMutex.RLock()
+	defer fake.getRoomNodesMutex.RUnlock()
+	return len(fake.getRoomNodesArgsForCall)
+}
+
+func (fake *FakeRouter) GetRoomNodesCalls(stub func(context.Context, string) (map[string]uint32, error)) {
+	fake.getRoomNodesMutex.Lock()
+	defer fake.getRoomNodesMutex.Unlock()
+	fake.GetRoomNodesStub = stub
+}
+
+func (fake *FakeRouter) GetRoomNodesArgsForCall(i int) (context.Context, string) {
+	fake.getRoomNodesMutex.RLock()
+	defer fake.getRoomNodesMutex.RUnlock()
+	argsForCall := fake.getRoomNodesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRouter) GetRoomNodesReturns(result1 map[string]uint32, result2 error) {
+	fake.getRoomNodesMutex.Lock()
+	defer fake.getRoomNodesMutex.Unlock()
+	fake.GetRoomNodesStub = nil
+	fake.getRoomNodesReturns = struct {
+		result1 map[string]uint32
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) GetRoomNodesReturnsOnCall(i int, result1 map[string]uint32, result2 error) {
+	fake.getRoomNodesMutex.Lock()
+	defer fake.getRoomNodesMutex.Unlock()
+	fake.GetRoomNodesStub = nil
+	if fake.getRoomNodesReturnsOnCall == nil {
+		fake.getRoomNodesReturnsOnCall = make(map[int]struct {
+			result1 map[string]uint32
+			result2 error
+		})
+	}
+	fake.getRoomNodesReturnsOnCall[i] = struct {
+		result1 map[string]uint32
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) ListDeadNodes() ([]*livekit.Node, error) {
+	fake.listDeadNodesMutex.Lock()
+	ret, specificReturn := fake.listDeadNodesReturnsOnCall[len(fake.listDeadNodesArgsForCall)]
+	fake.listDeadNodesArgsForCall = append(fake.listDeadNodesArgsForCall, struct {
+	}{})
+	stub := fake.ListDeadNodesStub
+	fakeReturns := fake.listDeadNodesReturns
+	fake.recordInvocation("ListDeadNodes", []interface{}{})
+	fake.listDeadNodesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) ListDeadNodesCallCount() int {
+	fake.listDeadNodesMutex.RLock()
+	defer fake.listDeadNodesMutex.RUnlock()
+	return len(fake.listDeadNodesArgsForCall)
+}
+
+func (fake *FakeRouter) ListDeadNodesCalls(stub func() ([]*livekit.Node, error)) {
+	fake.listDeadNodesMutex.Lock()
+	defer fake.listDeadNodesMutex.Unlock()
+	fake.ListDeadNodesStub = stub
+}
+
+func (fake *FakeRouter) ListDeadNodesReturns(result1 []*livekit.Node, result2 error) {
+	fake.listDeadNodesMutex.Lock()
+	defer fake.listDeadNodesMutex.Unlock()
+	fake.ListDeadNodesStub = nil
+	fake.listDeadNodesReturns = struct {
+		result1 []*livekit.Node
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) ListDeadNodesReturnsOnCall(i int, result1 []*livekit.Node, result2 error) {
+	fake.listDeadNodesMutex.Lock()
+	defer fake.listDeadNodesMutex.Unlock()
+	fake.ListDeadNodesStub = nil
+	if fake.listDeadNodesReturnsOnCall == nil {
+		fake.listDeadNodesReturnsOnCall = make(map[int]struct {
+			result1 []*livekit.Node
+			result2 error
+		})
+	}
+	fake.listDeadNodesReturnsOnCall[i] = struct {
+		result1 []*livekit.Node
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRouter) ListNodes() ([]*livekit.Node, error) {
 	fake.listNodesMutex.Lock()
 	ret, specificReturn := fake.listNodesReturnsOnCall[len(fake.listNodesArgsForCall)]
@@ -529,17 +1238,81 @@ func (fake *FakeRouter) RegisterNodeReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeRouter) RemoveDeadNodes() error {
-	fake.removeDeadNodesMutex.Lock()
-	ret, specificReturn := fake.removeDeadNodesReturnsOnCall[len(fake.removeDeadNodesArgsForCall)]
-	fake.removeDeadNodesArgsForCall = append(fake.removeDeadNodesArgsForCall, struct {
-	}{})
-	stub := fake.RemoveDeadNodesStub
-	fakeReturns := fake.removeDeadNodesReturns
-	fake.recordInvocation("RemoveDeadNodes", []interface{}{})
-	fake.removeDeadNodesMutex.Unlock()
+func (fake *FakeRouter) RemoveNode(arg1 string) error {
+	fake.removeNodeMutex.Lock()
+	ret, specificReturn := fake.removeNodeReturnsOnCall[len(fake.removeNodeArgsForCall)]
+	fake.removeNodeArgsForCall = append(fake.removeNodeArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.RemoveNodeStub
+	fakeReturns := fake.removeNodeReturns
+	fake.recordInvocation("RemoveNode", []interface{}{arg1})
+	fake.removeNodeMutex.Unlock()
 	if stub != nil {
-		return stub()
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) RemoveNodeCallCount() int {
+	fake.removeNodeMutex.RLock()
+	defer fake.removeNodeMutex.RUnlock()
+	return len(fake.removeNodeArgsForCall)
+}
+
+func (fake *FakeRouter) RemoveNodeCalls(stub func(string) error) {
+	fake.removeNodeMutex.Lock()
+	defer fake.removeNodeMutex.Unlock()
+	fake.RemoveNodeStub = stub
+}
+
+func (fake *FakeRouter) RemoveNodeArgsForCall(i int) string {
+	fake.removeNodeMutex.RLock()
+	defer fake.removeNodeMutex.RUnlock()
+	argsForCall := fake.removeNodeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRouter) RemoveNodeReturns(result1 error) {
+	fake.removeNodeMutex.Lock()
+	defer fake.removeNodeMutex.Unlock()
+	fake.RemoveNodeStub = nil
+	fake.removeNodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) RemoveNodeReturnsOnCall(i int, result1 error) {
+	fake.removeNodeMutex.Lock()
+	defer fake.removeNodeMutex.Unlock()
+	fake.RemoveNodeStub = nil
+	if fake.removeNodeReturnsOnCall == nil {
+		fake.removeNodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeNodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) RemoveRoomNode(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.removeRoomNodeMutex.Lock()
+	ret, specificReturn := fake.removeRoomNodeReturnsOnCall[len(fake.removeRoomNodeArgsForCall)]
+	fake.removeRoomNodeArgsForCall = append(fake.removeRoomNodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RemoveRoomNodeStub
+	fakeReturns := fake.removeRoomNodeReturns
+	fake.recordInvocation("RemoveRoomNode", []interface{}{arg1, arg2, arg3})
+	fake.removeRoomNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -547,37 +1320,44 @@ func (fake *FakeRouter) RemoveDeadNodes() error {
 	return fakeReturns.result1
 }
 
-func (fake *FakeRouter) RemoveDeadNodesCallCount() int {
-	fake.removeDeadNodesMutex.RLock()
-	defer fake.removeDeadNodesMutex.RUnlock()
-	return len(fake.removeDeadNodesArgsForCall)
+func (fake *FakeRouter) RemoveRoomNodeCallCount() int {
+	fake.removeRoomNodeMutex.RLock()
+	defer fake.removeRoomNodeMutex.RUnlock()
+	return len(fake.removeRoomNodeArgsForCall)
+}
+
+func (fake *FakeRouter) RemoveRoomNodeCalls(stub func(context.Context, string, string) error) {
+	fake.removeRoomNodeMutex.Lock()
+	defer fake.removeRoomNodeMutex.Unlock()
+	fake.RemoveRoomNodeStub = stub
 }
 
-func (fake *FakeRouter) RemoveDeadNodesCalls(stub func() error) {
-	fake.removeDeadNodesMutex.Lock()
-	defer fake.removeDeadNodesMutex.Unlock()
-	fake.RemoveDeadNodesStub = stub
+func (fake *FakeRouter) RemoveRoomNodeArgsForCall(i int) (context.Context, string, string) {
+	fake.removeRoomNodeMutex.RLock()
+	defer fake.removeRoomNodeMutex.RUnlock()
+	argsForCall := fake.removeRoomNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeRouter) RemoveDeadNodesReturns(result1 error) {
-	fake.removeDeadNodesMutex.Lock()
-	defer fake.removeDeadNodesMutex.Unlock()
-	fake.RemoveDeadNodesStub = nil
-	fake.removeDeadNodesReturns = struct {
+func (fake *FakeRouter) RemoveRoomNodeReturns(result1 error) {
+	fake.removeRoomNodeMutex.Lock()
+	defer fake.removeRoomNodeMutex.Unlock()
+	fake.RemoveRoomNodeStub = nil
+	fake.removeRoomNodeReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeRouter) RemoveDeadNodesReturnsOnCall(i int, result1 error) {
-	fake.removeDeadNodesMutex.Lock()
-	defer fake.removeDeadNodesMutex.Unlock()
-	fake.RemoveDeadNodesStub = nil
-	if fake.removeDeadNodesReturnsOnCall == nil {
-		fake.removeDeadNodesReturnsOnCall = make(map[int]struct {
+func (fake *FakeRouter) RemoveRoomNodeReturnsOnCall(i int, result1 error) {
+	fake.removeRoomNodeMutex.Lock()
+	defer fake.removeRoomNodeMutex.Unlock()
+	fake.RemoveRoomNodeStub = nil
+	if fake.removeRoomNodeReturnsOnCall == nil {
+		fake.removeRoomNodeReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.removeDeadNodesReturnsOnCall[i] = struct {
+	fake.removeRoomNodeReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
@@ -645,6 +1425,133 @@ func (fake *FakeRouter) SetNodeForRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRouter) SetParticipantRTCNode(arg1 string, arg2 string, arg3 string) error {
+	fake.setParticipantRTCNodeMutex.Lock()
+	ret, specificReturn := fake.setParticipantRTCNodeReturnsOnCall[len(fake.setParticipantRTCNodeArgsForCall)]
+	fake.setParticipantRTCNodeArgsForCall = append(fake.setParticipantRTCNodeArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.SetParticipantRTCNodeStub
+	fakeReturns := fake.setParticipantRTCNodeReturns
+	fake.recordInvocation("SetParticipantRTCNode", []interface{}{arg1, arg2, arg3})
+	fake.setParticipantRTCNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) SetParticipantRTCNodeCallCount() int {
+	fake.setParticipantRTCNodeMutex.RLock()
+	defer fake.setParticipantRTCNodeMutex.RUnlock()
+	return len(fake.setParticipantRTCNodeArgsForCall)
+}
+
+func (fake *FakeRouter) SetParticipantRTCNodeCalls(stub func(string, string, string) error) {
+	fake.setParticipantRTCNodeMutex.Lock()
+	defer fake.setParticipantRTCNodeMutex.Unlock()
+	fake.SetParticipantRTCNodeStub = stub
+}
+
+func (fake *FakeRouter) SetParticipantRTCNodeArgsForCall(i int) (string, string, string) {
+	fake.setParticipantRTCNodeMutex.RLock()
+	defer fake.setParticipantRTCNodeMutex.RUnlock()
+	argsForCall := fake.setParticipantRTCNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) SetParticipantRTCNodeReturns(result1 error) {
+	fake.setParticipantRTCNodeMutex.Lock()
+	defer fake.setParticipantRTCNodeMutex.Unlock()
+	fake.SetParticipantRTCNodeStub = nil
+	fake.setParticipantRTCNodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) SetParticipantRTCNodeReturnsOnCall(i int, result1 error) {
+	fake.setParticipantRTCNodeMutex.Lock()
+	defer fake.setParticipantRTCNodeMutex.Unlock()
+	fake.SetParticipantRTCNodeStub = nil
+	if fake.setParticipantRTCNodeReturnsOnCall == nil {
+		fake.setParticipantRTCNodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setParticipantRTCNodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipants(arg1 context.Context, arg2 string, arg3 string, arg4 uint32) error {
+	fake.setRoomNodeParticipantsMutex.Lock()
+	ret, specificReturn := fake.setRoomNodeParticipantsReturnsOnCall[len(fake.setRoomNodeParticipantsArgsForCall)]
+	fake.setRoomNodeParticipantsArgsForCall = append(fake.setRoomNodeParticipantsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 uint32
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.SetRoomNodeParticipantsStub
+	fakeReturns := fake.setRoomNodeParticipantsReturns
+	fake.recordInvocation("SetRoomNodeParticipants", []interface{}{arg1, arg2, arg3, arg4})
+	fake.setRoomNodeParticipantsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipantsCallCount() int {
+	fake.setRoomNodeParticipantsMutex.RLock()
+	defer fake.setRoomNodeParticipantsMutex.RUnlock()
+	return len(fake.setRoomNodeParticipantsArgsForCall)
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipantsCalls(stub func(context.Context, string, string, uint32) error) {
+	fake.setRoomNodeParticipantsMutex.Lock()
+	defer fake.setRoomNodeParticipantsMutex.Unlock()
+	fake.SetRoomNodeParticipantsStub = stub
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipantsArgsForCall(i int) (context.Context, string, string, uint32) {
+	fake.setRoomNodeParticipantsMutex.RLock()
+	defer fake.setRoomNodeParticipantsMutex.RUnlock()
+	argsForCall := fake.setRoomNodeParticipantsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipantsReturns(result1 error) {
+	fake.setRoomNodeParticipantsMutex.Lock()
+	defer fake.setRoomNodeParticipantsMutex.Unlock()
+	fake.SetRoomNodeParticipantsStub = nil
+	fake.setRoomNodeParticipantsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) SetRoomNodeParticipantsReturnsOnCall(i int, result1 error) {
+	fake.setRoomNodeParticipantsMutex.Lock()
+	defer fake.setRoomNodeParticipantsMutex.Unlock()
+	fake.SetRoomNodeParticipantsStub = nil
+	if fake.setRoomNodeParticipantsReturnsOnCall == nil {
+		fake.setRoomNodeParticipantsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setRoomNodeParticipantsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRouter) Start() error {
 	fake.startMutex.Lock()
 	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
@@ -914,12 +1821,30 @@ func (fake *FakeRouter) WriteRTCMessageReturnsOnCall(i int, result1 error) {
 func (fake *FakeRouter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.acquireLeaseMutex.RLock()
+	defer fake.acquireLeaseMutex.RUnlock()
+	fake.addRoomNodeMutex.RLock()
+	defer fake.addRoomNodeMutex.RUnlock()
+	fake.clearParticipantStateMutex.RLock()
+	defer fake.clearParticipantStateMutex.RUnlock()
 	fake.clearRoomStateMutex.RLock()
 	defer fake.clearRoomStateMutex.RUnlock()
+	fake.drainNodeMutex.RLock()
+	defer fake.drainNodeMutex.RUnlock()
 	fake.getNodeMutex.RLock()
 	defer fake.getNodeMutex.RUnlock()
 	fake.getNodeForRoomMutex.RLock()
 	defer fake.getNodeForRoomMutex.RUnlock()
+	fake.getNodeRegionsMutex.RLock()
+	defer fake.getNodeRegionsMutex.RUnlock()
+	fake.getNodeRoomsMutex.RLock()
+	defer fake.getNodeRoomsMutex.RUnlock()
+	fake.getParticipantRTCNodeMutex.RLock()
+	defer fake.getParticipantRTCNodeMutex.RUnlock()
+	fake.getRoomNodesMutex.RLock()
+	defer fake.getRoomNodesMutex.RUnlock()
+	fake.listDeadNodesMutex.RLock()
+	defer fake.listDeadNodesMutex.RUnlock()
 	fake.listNodesMutex.RLock()
 	defer fake.listNodesMutex.RUnlock()
 	fake.onNewParticipantRTCMutex.RLock()
@@ -928,10 +1853,16 @@ func (fake *FakeRouter) Invocations() map[string][][]interface{} {
 	defer fake.onRTCMessageMutex.RUnlock()
 	fake.registerNodeMutex.RLock()
 	defer fake.registerNodeMutex.RUnlock()
-	fake.removeDeadNodesMutex.RLock()
-	defer fake.removeDeadNodesMutex.RUnlock()
+	fake.removeNodeMutex.RLock()
+	defer fake.removeNodeMutex.RUnlock()
+	fake.removeRoomNodeMutex.RLock()
+	defer fake.removeRoomNodeMutex.RUnlock()
 	fake.setNodeForRoomMutex.RLock()
 	defer fake.setNodeForRoomMutex.RUnlock()
+	fake.setParticipantRTCNodeMutex.RLock()
+	defer fake.setParticipantRTCNodeMutex.RUnlock()
+	fake.setRoomNodeParticipantsMutex.RLock()
+	defer fake.setRoomNodeParticipantsMutex.RUnlock()
 	fake.startMutex.RLock()
 	defer fake.startMutex.RUnlock()
 	fake.startParticipantSignalMutex.RLock()
@@ -0,0 +1,42 @@
+package routing
+
+import (
+	livekit "github.com/livekit/protocol/proto"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// CapacitySelector selects the node with the most headroom, that's the node furthest from its tightest
+// limit. the load per CPU counts as a limit when SysloadLimit is set
+type CapacitySelector struct {
+	Limits       config.LimitConfig
+	SysloadLimit float32
+}
+
+func (s *CapacitySelector) SelectNode(nodes []*livekit.Node, room *livekit.Room) (*livekit.Node, error) {
+	nodes, err := GetNodesWithinLimits(nodes, s.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected *livekit.Node
+	maxHeadroom := 0.0
+	for _, node := range nodes {
+		headroom := nodeHeadroom(node, s.Limits)
+		if s.SysloadLimit > 0 {
+			numCpus := node.Stats.NumCpus
+			if numCpus == 0 {
+				numCpus = 1
+			}
+			load := float64(node.Stats.LoadAvgLast1Min / float32(numCpus))
+			if h := 1 - load/float64(s.SysloadLimit); h < headroom {
+				headroom = h
+			}
+		}
+		if selected == nil || headroom > maxHeadroom {
+			selected = node
+			maxHeadroom = headroom
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,74 @@
+package routing_test
+
+import (
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+func TestCapacitySelector_SelectNode(t *testing.T) {
+	selector := routing.CapacitySelector{
+		Limits: config.LimitConfig{
+			NumClients: 100,
+			NumRooms:   10,
+		},
+	}
+
+	t.Run("selects the node with the most headroom", func(t *testing.T) {
+		busy := newCapacityNode("busy", 80, 2)
+		roomy := newCapacityNode("roomy", 20, 6)
+		idle := newCapacityNode("idle", 50, 5)
+		node, err := selector.SelectNode([]*livekit.Node{busy, roomy, idle}, nil)
+		require.NoError(t, err)
+		require.Equal(t, idle, node)
+	})
+
+	t.Run("skips nodes at a limit", func(t *testing.T) {
+		full := newCapacityNode("full", 100, 0)
+		loaded := newCapacityNode("loaded", 90, 9)
+		node, err := selector.SelectNode([]*livekit.Node{full, loaded}, nil)
+		require.NoError(t, err)
+		require.Equal(t, loaded, node)
+	})
+
+	t.Run("counts load per CPU against the sysload limit", func(t *testing.T) {
+		s := selector
+		s.SysloadLimit = 1.0
+		hot := newCapacityNode("hot", 0, 0)
+		hot.Stats.LoadAvgLast1Min = 0.9
+		warm := newCapacityNode("warm", 30, 3)
+		node, err := s.SelectNode([]*livekit.Node{hot, warm}, nil)
+		require.NoError(t, err)
+		require.Equal(t, warm, node)
+	})
+
+	t.Run("errors when the cluster is full", func(t *testing.T) {
+		nodes := []*livekit.Node{newCapacityNode("clients", 100, 0), newCapacityNode("rooms", 0, 12)}
+		_, err := selector.SelectNode(nodes, nil)
+		require.Equal(t, routing.ErrClusterFull, err)
+	})
+
+	t.Run("errors without available nodes", func(t *testing.T) {
+		stale := newCapacityNode("stale", 0, 0)
+		stale.Stats.UpdatedAt = time.Now().Add(-time.Minute).Unix()
+		_, err := selector.SelectNode([]*livekit.Node{stale}, nil)
+		require.Equal(t, routing.ErrNoAvailableNodes, err)
+	})
+}
+
+func newCapacityNode(id string, numClients, numRooms int32) *livekit.Node {
+	return &livekit.Node{
+		Id: id,
+		Stats: &livekit.NodeStats{
+			UpdatedAt:  time.Now().Unix(),
+			NumCpus:    1,
+			NumClients: numClients,
+			NumRooms:   numRooms,
+		},
+	}
+}
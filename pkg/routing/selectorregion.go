@@ -0,0 +1,117 @@
+package routing
+
+import (
+	"math"
+	"sort"
+
+	livekit "github.com/livekit/protocol/proto"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// RegionSelector is a NodeSelector that can place rooms in a region. since nodes don't carry their region,
+// the region of each node is passed in, by node ID
+type RegionSelector interface {
+	NodeSelector
+	SelectNodeInRegion(nodes []*livekit.Node, nodeRegions map[string]string, room *livekit.Room, region string) (*livekit.Node, error)
+}
+
+// RegionAwareSelector places rooms on nodes in the region they're requested in, or the region of the
+// current node when they aren't. when that region has no nodes with capacity, the nearest region that
+// does is used. nodes within a region are selected by system load
+type RegionAwareSelector struct {
+	SystemLoadSelector
+	CurrentRegion string
+	Regions       []config.RegionConfig
+}
+
+// SelectNode selects by system load, since the regions of nodes aren't known
+func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node, room *livekit.Room) (*livekit.Node, error) {
+	return s.SelectNodeInRegion(nodes, nil, room, s.CurrentRegion)
+}
+
+func (s *RegionAwareSelector) SelectNodeInRegion(nodes []*livekit.Node, nodeRegions map[string]string, room *livekit.Room, region string) (*livekit.Node, error) {
+	nodes = GetAvailableNodes(nodes)
+	if len(nodes) == 0 {
+		return nil, ErrNoAvailableNodes
+	}
+	if region == "" {
+		region = s.CurrentRegion
+	}
+	if region == "" || len(nodeRegions) == 0 {
+		return s.SystemLoadSelector.SelectNode(nodes, room)
+	}
+
+	byRegion := make(map[string][]*livekit.Node)
+	for _, node := range nodes {
+		r := nodeRegions[node.Id]
+		byRegion[r] = append(byRegion[r], node)
+	}
+
+	// the requested region first, then by distance from it. regions without a location come last
+	regions := make([]string, 0, len(byRegion))
+	for r := range byRegion {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+	sort.SliceStable(regions, func(i, j int) bool {
+		return s.distance(region, regions[i]) < s.distance(region, regions[j])
+	})
+
+	for _, r := range regions {
+		if s.hasCapacity(byRegion[r]) {
+			return s.SystemLoadSelector.SelectNode(byRegion[r], room)
+		}
+	}
+	// every node is loaded, the nearest region takes the room
+	return s.SystemLoadSelector.SelectNode(byRegion[regions[0]], room)
+}
+
+// hasCapacity is true when a node is below the sysload limit, any node has capacity without a limit
+func (s *RegionAwareSelector) hasCapacity(nodes []*livekit.Node) bool {
+	if s.SysloadLimit <= 0 {
+		return len(nodes) > 0
+	}
+	for _, node := range nodes {
+		numCpus := node.Stats.NumCpus
+		if numCpus == 0 {
+			numCpus = 1
+		}
+		if node.Stats.LoadAvgLast1Min/float32(numCpus) < s.SysloadLimit {
+			return true
+		}
+	}
+	return false
+}
+
+// distance between regions in km, 0 for the same region and infinite when either has no location
+func (s *RegionAwareSelector) distance(from, to string) float64 {
+	if from == to {
+		return 0
+	}
+	a := s.region(from)
+	b := s.region(to)
+	if a == nil || b == nil {
+		return math.Inf(1)
+	}
+	return haversineDistance(a.Lat, a.Lon, b.Lat, b.Lon)
+}
+
+func (s *RegionAwareSelector) region(name string) *config.RegionConfig {
+	for i := range s.Regions {
+		if s.Regions[i].Name == name {
+			return &s.Regions[i]
+		}
+	}
+	return nil
+}
+
+// great-circle distance between two points, in km
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,92 @@
+package routing_test
+
+import (
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+func TestRegionAwareSelector_SelectNodeInRegion(t *testing.T) {
+	selector := routing.RegionAwareSelector{
+		SystemLoadSelector: routing.SystemLoadSelector{SysloadLimit: 1.0},
+		CurrentRegion:      "us-west",
+		Regions: []config.RegionConfig{
+			{Name: "us-west", Lat: 37.64, Lon: -120.99},
+			{Name: "us-east", Lat: 38.13, Lon: -78.45},
+			{Name: "eu-central", Lat: 50.11, Lon: 8.68},
+		},
+	}
+	usWest := newRegionNode("us_west", 0.0)
+	usEast := newRegionNode("us_east", 0.0)
+	euCentral := newRegionNode("eu_central", 0.0)
+	euCentralLoaded := newRegionNode("eu_central_loaded", 2.0)
+	nodeRegions := map[string]string{
+		usWest.Id:    "us-west",
+		usEast.Id:    "us-east",
+		euCentral.Id: "eu-central",
+	}
+
+	t.Run("selects a node in the region asked for", func(t *testing.T) {
+		nodes := []*livekit.Node{usWest, usEast, euCentral}
+		for i := 0; i < 5; i++ {
+			node, err := selector.SelectNodeInRegion(nodes, nodeRegions, nil, "eu-central")
+			require.NoError(t, err)
+			require.Equal(t, euCentral, node)
+		}
+	})
+
+	t.Run("defaults to the current region", func(t *testing.T) {
+		nodes := []*livekit.Node{usWest, usEast, euCentral}
+		node, err := selector.SelectNodeInRegion(nodes, nodeRegions, nil, "")
+		require.NoError(t, err)
+		require.Equal(t, usWest, node)
+	})
+
+	t.Run("falls back to the nearest region with capacity", func(t *testing.T) {
+		nodes := []*livekit.Node{usWest, usEast}
+		node, err := selector.SelectNodeInRegion(nodes, nodeRegions, nil, "eu-central")
+		require.NoError(t, err)
+		require.Equal(t, usEast, node)
+
+		regions := map[string]string{
+			usWest.Id:          "us-west",
+			euCentralLoaded.Id: "eu-central",
+		}
+		node, err = selector.SelectNodeInRegion([]*livekit.Node{usWest, euCentralLoaded}, regions, nil, "eu-central")
+		require.NoError(t, err)
+		require.Equal(t, usWest, node)
+	})
+
+	t.Run("treats a zero sysload limit as unlimited", func(t *testing.T) {
+		unlimited := selector
+		unlimited.SysloadLimit = 0
+		regions := map[string]string{
+			usWest.Id:          "us-west",
+			euCentralLoaded.Id: "eu-central",
+		}
+		node, err := unlimited.SelectNodeInRegion([]*livekit.Node{usWest, euCentralLoaded}, regions, nil, "eu-central")
+		require.NoError(t, err)
+		require.Equal(t, euCentralLoaded, node)
+	})
+
+	t.Run("errors without available nodes", func(t *testing.T) {
+		_, err := selector.SelectNodeInRegion(nil, nodeRegions, nil, "eu-central")
+		require.Equal(t, routing.ErrNoAvailableNodes, err)
+	})
+}
+
+func newRegionNode(id string, load float32) *livekit.Node {
+	return &livekit.Node{
+		Id: id,
+		Stats: &livekit.NodeStats{
+			UpdatedAt:       time.Now().Unix(),
+			NumCpus:         1,
+			LoadAvgLast1Min: load,
+		},
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/thoas/go-funk"
+
+	"github.com/livekit/livekit-server/pkg/config"
 )
 
 // checks if a node has been updated recently to be considered for selection
@@ -16,12 +18,59 @@ func IsAvailable(node *livekit.Node) bool {
 	return float64(delta) < limit
 }
 
+// IsDead is true once a node hasn't updated its stats for deadNodeTimeout. nodes that aren't available are only
+// removed then, so the rooms on them can be failed over first
+func IsDead(node *livekit.Node) bool {
+	return time.Now().Unix()-node.Stats.UpdatedAt > int64(deadNodeTimeout.Seconds())
+}
+
 func GetAvailableNodes(nodes []*livekit.Node) []*livekit.Node {
 	return funk.Filter(nodes, func(node *livekit.Node) bool {
 		return IsAvailable(node)
 	}).([]*livekit.Node)
 }
 
+// checks if a node is below all of its limits, and can take on more
+func IsWithinLimits(node *livekit.Node, limits config.LimitConfig) bool {
+	return nodeHeadroom(node, limits) > 0
+}
+
+// GetNodesWithinLimits returns available nodes that are below their limits. ErrClusterFull is returned when
+// there are available nodes, but all of them are at a limit
+func GetNodesWithinLimits(nodes []*livekit.Node, limits config.LimitConfig) ([]*livekit.Node, error) {
+	nodes = GetAvailableNodes(nodes)
+	if len(nodes) == 0 {
+		return nil, ErrNoAvailableNodes
+	}
+	nodes = funk.Filter(nodes, func(node *livekit.Node) bool {
+		return IsWithinLimits(node, limits)
+	}).([]*livekit.Node)
+	if len(nodes) == 0 {
+		return nil, ErrClusterFull
+	}
+	return nodes, nil
+}
+
+// nodeHeadroom is the fraction of the node's tightest limit that's left, 1 when it has no limits
+func nodeHeadroom(node *livekit.Node, limits config.LimitConfig) float64 {
+	headroom := 1.0
+	check := func(used float64, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		if h := 1 - used/limit; h < headroom {
+			headroom = h
+		}
+	}
+	stats := node.Stats
+	check(float64(stats.NumClients), float64(limits.NumClients))
+	check(float64(stats.NumRooms), float64(limits.NumRooms))
+	check(float64(stats.NumTracksIn), float64(limits.NumTracksIn))
+	check(float64(stats.NumTracksOut), float64(limits.NumTracksOut))
+	check(float64(stats.BytesInPerSec+stats.BytesOutPerSec), float64(limits.BytesPerSec))
+	return headroom
+}
+
 func participantKey(roomName, identity string) string {
 	return roomName + "|" + identity
 }
@@ -0,0 +1,261 @@
+package rtc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// minimum time between allocations triggered by bandwidth estimates
+	allocationInterval = time.Second
+	// bandwidth held back from allocation to absorb bitrate fluctuations
+	allocationHeadroom = 0.1
+)
+
+const (
+	priorityScreenShare = iota
+	priorityActiveSpeaker
+	priorityDefault
+)
+
+// used for layers that haven't reported a bitrate yet, or are paused at the publisher
+var defaultLayerBitrates = [3]uint64{150000, 500000, 1500000}
+
+// BandwidthAllocator splits a subscriber's estimated downstream bandwidth across its subscribed tracks.
+// audio is always forwarded. video is prioritized by screen share, then active speakers, then everyone else.
+// lower priority tracks have their layers reduced, or are paused when there isn't enough bandwidth
+type BandwidthAllocator struct {
+	participantID string
+
+	lock           sync.Mutex
+	estimate       uint64
+	tracks         map[string]*SubscribedTrack
+	activeSpeakers map[string]bool
+	lastAllocation time.Time
+	// an allocation is scheduled for the end of allocationInterval, with the latest estimate
+	allocationPending bool
+	// how well the last allocation satisfied what the subscriber requested
+	quality types.ConnectionQuality
+}
+
+type allocationCandidate struct {
+	trackID  string
+	priority int
+	// highest layer the subscriber requested, -1 when not needed
+	maxLayer int32
+	bitrates [3]uint64
+}
+
+func NewBandwidthAllocator(participantID string) *BandwidthAllocator {
+	return &BandwidthAllocator{
+		participantID:  participantID,
+		tracks:         make(map[string]*SubscribedTrack),
+		activeSpeakers: make(map[string]bool),
+		quality:        types.ConnectionQualityExcellent,
+	}
+}
+
+func (a *BandwidthAllocator) AddTrack(subTrack *SubscribedTrack) {
+	a.lock.Lock()
+	a.tracks[subTrack.ID()] = subTrack
+	a.lock.Unlock()
+
+	a.allocate()
+}
+
+func (a *BandwidthAllocator) RemoveTrack(subTrack *SubscribedTrack) {
+	a.lock.Lock()
+	delete(a.tracks, subTrack.ID())
+	a.lock.Unlock()
+
+	a.allocate()
+}
+
+// SetActiveSpeakers updates the participants whose video is prioritized
+func (a *BandwidthAllocator) SetActiveSpeakers(speakers []*livekit.SpeakerInfo) {
+	active := make(map[string]bool, len(speakers))
+	for _, speaker := range speakers {
+		if speaker.Active {
+			active[speaker.Sid] = true
+		}
+	}
+
+	a.lock.Lock()
+	a.activeSpeakers = active
+	a.lock.Unlock()
+
+	a.allocate()
+}
+
+// Estimate returns the last downstream bandwidth estimate in bps, 0 when unknown
+func (a *BandwidthAllocator) Estimate() uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.estimate
+}
+
+// ConnectionQuality rates the subscriber's downlink, poor when tracks had to be paused
+// and good when they had to be downgraded
+func (a *BandwidthAllocator) ConnectionQuality() types.ConnectionQuality {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.quality
+}
+
+// SetEstimate updates the downstream bandwidth estimate in bps, reallocating at most once per allocationInterval.
+// estimates within the interval are allocated once it ends
+func (a *BandwidthAllocator) SetEstimate(estimate uint64) {
+	a.lock.Lock()
+	a.estimate = estimate
+	if a.allocationPending {
+		a.lock.Unlock()
+		return
+	}
+	wait := allocationInterval - time.Since(a.lastAllocation)
+	if wait > 0 {
+		a.allocationPending = true
+		time.AfterFunc(wait, a.allocate)
+	}
+	a.lock.Unlock()
+
+	if wait <= 0 {
+		a.allocate()
+	}
+}
+
+func (a *BandwidthAllocator) allocate() {
+	a.lock.Lock()
+	a.lastAllocation = time.Now()
+	a.allocationPending = false
+	estimate := a.estimate
+	tracks := make(map[string]*SubscribedTrack, len(a.tracks))
+	var audioBitrate uint64
+	candidates := make([]allocationCandidate, 0, len(a.tracks))
+	for id, st := range a.tracks {
+		tracks[id] = st
+		if st.Kind() == livekit.TrackType_AUDIO {
+			audioBitrate += st.LayerBitrates()[0]
+			continue
+		}
+		priority := priorityDefault
+		if st.Source() == types.TrackSourceScreenShare {
+			priority = priorityScreenShare
+		} else if a.activeSpeakers[st.PublisherID()] {
+			priority = priorityActiveSpeaker
+		}
+		maxLayer := st.SpatialLayer()
+		if !st.Simulcasted() && maxLayer > 0 {
+			maxLayer = 0
+		}
+		candidates = append(candidates, allocationCandidate{
+			trackID:  id,
+			priority: priority,
+			maxLayer: maxLayer,
+			bitrates: st.LayerBitrates(),
+		})
+	}
+	a.lock.Unlock()
+
+	var allocation map[string]int32
+	if estimate == 0 {
+		// no estimate yet, leave tracks unconstrained
+		allocation = make(map[string]int32, len(candidates))
+		for _, c := range candidates {
+			allocation[c.trackID] = maxAllocatedLayer
+		}
+	} else {
+		budget := uint64(float64(estimate) * (1 - allocationHeadroom))
+		if audioBitrate < budget {
+			budget -= audioBitrate
+		} else {
+			budget = 0
+		}
+		allocation = allocateLayers(budget, candidates)
+	}
+
+	quality := types.ConnectionQualityExcellent
+	for _, c := range candidates {
+		layer, ok := allocation[c.trackID]
+		if !ok {
+			continue
+		}
+		if layer < 0 {
+			quality = types.ConnectionQualityPoor
+			break
+		} else if layer < c.maxLayer {
+			quality = types.ConnectionQualityGood
+		}
+	}
+	a.lock.Lock()
+	a.quality = quality
+	a.lock.Unlock()
+
+	for id, layer := range allocation {
+		if st := tracks[id]; st != nil && st.SetAllocatedLayer(layer) {
+			logger.Debugw("allocated video layer",
+				"pID", a.participantID,
+				"track", id,
+				"layer", layer,
+				"estimate", estimate)
+		}
+	}
+}
+
+// allocateLayers assigns each video track the highest layer that fits within budget, in order of priority.
+// every track gets its lowest layer before any track is upgraded, tracks that don't fit are paused (-1).
+// tracks the subscriber doesn't need are left out
+func allocateLayers(budget uint64, candidates []allocationCandidate) map[string]int32 {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		return candidates[i].trackID < candidates[j].trackID
+	})
+
+	allocation := make(map[string]int32, len(candidates))
+	for _, c := range candidates {
+		if c.maxLayer < 0 {
+			// subscriber doesn't need this track, don't spend on it
+			continue
+		}
+		allocation[c.trackID] = -1
+		if bitrate := layerBitrate(c.bitrates, 0); bitrate <= budget {
+			budget -= bitrate
+			allocation[c.trackID] = 0
+		}
+	}
+
+	for _, c := range candidates {
+		current, ok := allocation[c.trackID]
+		if !ok || current < 0 {
+			continue
+		}
+		for layer := current + 1; layer <= c.maxLayer; layer++ {
+			// upgrading replaces the current layer's bitrate
+			var extra uint64
+			if next, cur := layerBitrate(c.bitrates, layer), layerBitrate(c.bitrates, current); next > cur {
+				extra = next - cur
+			}
+			if extra > budget {
+				break
+			}
+			budget -= extra
+			current = layer
+		}
+		allocation[c.trackID] = current
+	}
+	return allocation
+}
+
+func layerBitrate(bitrates [3]uint64, layer int32) uint64 {
+	if bitrates[layer] == 0 {
+		return defaultLayerBitrates[layer]
+	}
+	return bitrates[layer]
+}
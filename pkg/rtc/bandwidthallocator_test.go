@@ -0,0 +1,72 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocateLayers(t *testing.T) {
+	bitrates := [3]uint64{100000, 400000, 1000000}
+	newCandidates := func() []allocationCandidate {
+		return []allocationCandidate{
+			{trackID: "other", priority: priorityDefault, maxLayer: 2, bitrates: bitrates},
+			{trackID: "speaker", priority: priorityActiveSpeaker, maxLayer: 2, bitrates: bitrates},
+			{trackID: "screen", priority: priorityScreenShare, maxLayer: 2, bitrates: bitrates},
+		}
+	}
+
+	t.Run("all tracks get requested layers with enough bandwidth", func(t *testing.T) {
+		allocation := allocateLayers(5000000, newCandidates())
+		require.Equal(t, map[string]int32{"other": 2, "speaker": 2, "screen": 2}, allocation)
+	})
+
+	t.Run("upgrades in order of priority", func(t *testing.T) {
+		// 300k for low layers, then 900k to upgrade screen share, 300k to upgrade speaker to medium
+		allocation := allocateLayers(1500000, newCandidates())
+		require.Equal(t, map[string]int32{"other": 0, "speaker": 1, "screen": 2}, allocation)
+	})
+
+	t.Run("pauses lowest priority tracks first", func(t *testing.T) {
+		allocation := allocateLayers(250000, newCandidates())
+		require.Equal(t, map[string]int32{"other": -1, "speaker": 0, "screen": 0}, allocation)
+	})
+
+	t.Run("respects subscriber requested layer", func(t *testing.T) {
+		candidates := newCandidates()
+		candidates[2].maxLayer = 0
+		candidates[1].maxLayer = -1
+		allocation := allocateLayers(5000000, candidates)
+		require.Equal(t, map[string]int32{"other": 2, "screen": 0}, allocation)
+	})
+
+	t.Run("uses default bitrates for unknown layers", func(t *testing.T) {
+		allocation := allocateLayers(defaultLayerBitrates[1], []allocationCandidate{
+			{trackID: "video", priority: priorityDefault, maxLayer: 2},
+		})
+		require.Equal(t, map[string]int32{"video": 1}, allocation)
+	})
+}
+
+func TestSetEstimate(t *testing.T) {
+	a := NewBandwidthAllocator("PA_1")
+	lastAllocation := func() time.Time {
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		return a.lastAllocation
+	}
+
+	a.SetEstimate(1000000)
+	first := lastAllocation()
+	require.False(t, first.IsZero())
+
+	// estimates within the interval are allocated once it ends
+	a.SetEstimate(2000000)
+	a.SetEstimate(3000000)
+	require.Equal(t, first, lastAllocation())
+	require.Eventually(t, func() bool {
+		return lastAllocation().Sub(first) >= allocationInterval
+	}, 2*allocationInterval, 10*time.Millisecond)
+	require.EqualValues(t, 3000000, a.Estimate())
+}
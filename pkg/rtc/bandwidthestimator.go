@@ -0,0 +1,248 @@
+package rtc
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/transport/packetio"
+)
+
+const (
+	// sent packets remembered to match with transport-wide feedback
+	sentPacketHistory = 1 << 12
+	// receive rate is measured over at least this much of the subscriber's time
+	twccRateWindow = 500 * time.Millisecond
+	// loss fractions where the estimate is held, below it grows and above it backs off
+	twccLowLoss  = 0.02
+	twccHighLoss = 0.1
+	// growth of the estimate per second while there's no loss
+	twccGrowthRate = 0.08
+)
+
+type sentPacket struct {
+	seq  uint16
+	size int
+	set  bool
+}
+
+// BandwidthEstimator estimates a subscriber's downstream bandwidth from the feedback it sends.
+// REMB is used as reported. transport-wide congestion control feedback gives the rate packets are arriving at,
+// which backs off when packets are lost and grows slowly while they aren't. when both are known the lower wins
+type BandwidthEstimator struct {
+	interceptor.NoOp
+
+	lock sync.Mutex
+	sent [sentPacketHistory]sentPacket
+
+	rembEstimate uint64
+	twccEstimate uint64
+	lastFbCount  uint8
+	hasFeedback  bool
+
+	// receive window, in microseconds of the subscriber's clock
+	windowStarted  bool
+	windowStart    int64
+	windowEnd      int64
+	windowBytes    int
+	windowReceived int
+	windowLost     int
+	lastUpdate     time.Time
+
+	onEstimate func(bps uint64)
+}
+
+func NewBandwidthEstimator() *BandwidthEstimator {
+	return &BandwidthEstimator{}
+}
+
+// OnEstimate is called whenever the estimate is updated
+func (e *BandwidthEstimator) OnEstimate(f func(bps uint64)) {
+	e.lock.Lock()
+	e.onEstimate = f
+	e.lock.Unlock()
+}
+
+// Estimate returns the downstream bandwidth in bps, 0 when unknown
+func (e *BandwidthEstimator) Estimate() uint64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.estimate()
+}
+
+// BindLocalStream remembers the size of each packet sent with a transport-wide sequence number
+func (e *BandwidthEstimator) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	var extID uint8
+	for _, ext := range info.RTPHeaderExtensions {
+		if ext.URI == sdp.TransportCCURI {
+			extID = uint8(ext.ID)
+			break
+		}
+	}
+	if extID == 0 {
+		return writer
+	}
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		var tcc rtp.TransportCCExtension
+		if ext := header.GetExtension(extID); ext != nil && tcc.Unmarshal(ext) == nil {
+			e.lock.Lock()
+			e.sent[tcc.TransportSequence%sentPacketHistory] = sentPacket{
+				seq:  tcc.TransportSequence,
+				size: header.MarshalSize() + len(payload),
+				set:  true,
+			}
+			e.lock.Unlock()
+		}
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+// WrapBufferFactory returns a buffer factory that passes feedback written to its RTCP buffers to the estimator
+func (e *BandwidthEstimator) WrapBufferFactory(createBuffer func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser) func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+	return func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+		buffer := createBuffer(packetType, ssrc)
+		if packetType == packetio.RTPBufferPacket {
+			return buffer
+		}
+		return &feedbackWriter{
+			ReadWriteCloser: buffer,
+			estimator:       e,
+		}
+	}
+}
+
+// HandleRTCP updates the estimate from feedback. compound packets are delivered to every stream they reference,
+// repeated transport-wide feedback is ignored
+func (e *BandwidthEstimator) HandleRTCP(pkts []rtcp.Packet) {
+	e.lock.Lock()
+	before := e.estimate()
+	for _, pkt := range pkts {
+		switch pkt := pkt.(type) {
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			e.rembEstimate = uint64(pkt.Bitrate)
+		case *rtcp.TransportLayerCC:
+			if e.hasFeedback && pkt.FbPktCount == e.lastFbCount {
+				continue
+			}
+			e.hasFeedback = true
+			e.lastFbCount = pkt.FbPktCount
+			e.handleTransportCC(pkt)
+		}
+	}
+	after := e.estimate()
+	onEstimate := e.onEstimate
+	e.lock.Unlock()
+
+	if after != before && onEstimate != nil {
+		onEstimate(after)
+	}
+}
+
+// assumes lock is held
+func (e *BandwidthEstimator) estimate() uint64 {
+	if e.rembEstimate != 0 && (e.twccEstimate == 0 || e.rembEstimate < e.twccEstimate) {
+		return e.rembEstimate
+	}
+	return e.twccEstimate
+}
+
+// assumes lock is held
+func (e *BandwidthEstimator) handleTransportCC(fb *rtcp.TransportLayerCC) {
+	arrival := int64(fb.ReferenceTime) * 64000
+	deltas := fb.RecvDeltas
+	for i, status := range transportCCStatuses(fb) {
+		if status == rtcp.TypeTCCPacketNotReceived {
+			e.windowLost++
+			continue
+		}
+		if status != rtcp.TypeTCCPacketReceivedWithoutDelta && len(deltas) > 0 {
+			arrival += deltas[0].Delta
+			deltas = deltas[1:]
+		}
+		e.windowReceived++
+
+		if !e.windowStarted || arrival < e.windowStart {
+			// first packet, or the subscriber's clock went backwards. the rate is measured after it
+			e.windowStarted = true
+			e.windowStart = arrival
+			e.windowEnd = arrival
+			e.windowBytes = 0
+			continue
+		}
+		seq := fb.BaseSequenceNumber + uint16(i)
+		if sent := e.sent[seq%sentPacketHistory]; sent.set && sent.seq == seq {
+			e.windowBytes += sent.size
+		}
+		if arrival > e.windowEnd {
+			e.windowEnd = arrival
+		}
+	}
+
+	elapsed := time.Duration(e.windowEnd-e.windowStart) * time.Microsecond
+	if elapsed < twccRateWindow {
+		return
+	}
+	rate := uint64(float64(e.windowBytes) * 8 / elapsed.Seconds())
+	loss := float64(e.windowLost) / float64(e.windowLost+e.windowReceived)
+	now := time.Now()
+	switch {
+	case loss > twccHighLoss:
+		e.twccEstimate = uint64(float64(rate) * (1 - loss/2))
+	case loss < twccLowLoss:
+		// nothing is lost, the subscriber may be able to take more than what's being sent
+		estimate := e.twccEstimate
+		if rate > estimate {
+			estimate = rate
+		}
+		if !e.lastUpdate.IsZero() {
+			estimate = uint64(float64(estimate) * (1 + twccGrowthRate*now.Sub(e.lastUpdate).Seconds()))
+		}
+		e.twccEstimate = estimate
+	default:
+		if e.twccEstimate == 0 {
+			e.twccEstimate = rate
+		}
+	}
+	e.lastUpdate = now
+
+	e.windowStart = e.windowEnd
+	e.windowBytes = 0
+	e.windowReceived = 0
+	e.windowLost = 0
+}
+
+// transportCCStatuses expands the packet status chunks of feedback, one status per packet
+func transportCCStatuses(fb *rtcp.TransportLayerCC) []uint16 {
+	statuses := make([]uint16, 0, fb.PacketStatusCount)
+	for _, chunk := range fb.PacketChunks {
+		switch chunk := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			for i := uint16(0); i < chunk.RunLength; i++ {
+				statuses = append(statuses, chunk.PacketStatusSymbol)
+			}
+		case *rtcp.StatusVectorChunk:
+			statuses = append(statuses, chunk.SymbolList...)
+		}
+	}
+	if len(statuses) > int(fb.PacketStatusCount) {
+		// the last chunk may be padded
+		statuses = statuses[:fb.PacketStatusCount]
+	}
+	return statuses
+}
+
+type feedbackWriter struct {
+	io.ReadWriteCloser
+	estimator *BandwidthEstimator
+}
+
+func (w *feedbackWriter) Write(p []byte) (n int, err error) {
+	if pkts, err := rtcp.Unmarshal(p); err == nil {
+		w.estimator.HandleRTCP(pkts)
+	}
+	return w.ReadWriteCloser.Write(p)
+}
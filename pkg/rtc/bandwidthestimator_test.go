@@ -0,0 +1,105 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthEstimator(t *testing.T) {
+	const payloadSize = 1000
+	// sends packets with sequence numbers [from, to), returns the size of each
+	send := func(t *testing.T, e *BandwidthEstimator, from, to uint16) int {
+		writer := e.BindLocalStream(&interceptor.StreamInfo{
+			RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: sdp.TransportCCURI, ID: 3}},
+		}, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+			return len(payload), nil
+		}))
+		var size int
+		for seq := from; seq < to; seq++ {
+			ext, err := (&rtp.TransportCCExtension{TransportSequence: seq}).Marshal()
+			require.NoError(t, err)
+			header := &rtp.Header{Version: 2, SequenceNumber: seq}
+			require.NoError(t, header.SetExtension(3, ext))
+			_, err = writer.Write(header, make([]byte, payloadSize), nil)
+			require.NoError(t, err)
+			size = header.MarshalSize() + payloadSize
+		}
+		return size
+	}
+	// feedback for packets arriving every 8ms, every lostEvery-th packet is lost
+	feedback := func(fbCount uint8, base, count uint16, lostEvery uint16) *rtcp.TransportLayerCC {
+		fb := &rtcp.TransportLayerCC{
+			BaseSequenceNumber: base,
+			PacketStatusCount:  count,
+			ReferenceTime:      uint32(base) / 8,
+			FbPktCount:         fbCount,
+		}
+		var delta int64
+		for i := uint16(0); i < count; i++ {
+			delta += 8000
+			status := rtcp.TypeTCCPacketReceivedSmallDelta
+			if lostEvery != 0 && i%lostEvery == lostEvery-1 {
+				status = rtcp.TypeTCCPacketNotReceived
+			} else {
+				fb.RecvDeltas = append(fb.RecvDeltas, &rtcp.RecvDelta{Type: status, Delta: delta})
+				delta = 0
+			}
+			fb.PacketChunks = append(fb.PacketChunks, &rtcp.RunLengthChunk{PacketStatusSymbol: status, RunLength: 1})
+		}
+		return fb
+	}
+
+	t.Run("estimates receive rate from transport-wide feedback", func(t *testing.T) {
+		e := NewBandwidthEstimator()
+		var updated uint64
+		e.OnEstimate(func(bps uint64) {
+			updated = bps
+		})
+		size := send(t, e, 0, 128)
+		e.HandleRTCP([]rtcp.Packet{feedback(0, 0, 128, 0)})
+
+		// 125 packets a second
+		expected := uint64(125 * size * 8)
+		require.InDelta(t, expected, e.Estimate(), float64(expected)/100)
+		require.Equal(t, e.Estimate(), updated)
+	})
+
+	t.Run("ignores repeated feedback", func(t *testing.T) {
+		e := NewBandwidthEstimator()
+		send(t, e, 0, 128)
+		fb := feedback(0, 0, 128, 0)
+		e.HandleRTCP([]rtcp.Packet{fb})
+		estimate := e.Estimate()
+		e.HandleRTCP([]rtcp.Packet{fb})
+		require.Equal(t, estimate, e.Estimate())
+	})
+
+	t.Run("backs off when packets are lost", func(t *testing.T) {
+		e := NewBandwidthEstimator()
+		send(t, e, 0, 256)
+		e.HandleRTCP([]rtcp.Packet{feedback(0, 0, 128, 0)})
+		before := e.Estimate()
+
+		// a quarter of packets lost
+		e.HandleRTCP([]rtcp.Packet{feedback(1, 128, 128, 4)})
+		require.Less(t, e.Estimate(), before*8/10)
+	})
+
+	t.Run("uses the lower of REMB and transport-wide estimates", func(t *testing.T) {
+		e := NewBandwidthEstimator()
+		e.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 300000}})
+		require.Equal(t, uint64(300000), e.Estimate())
+
+		send(t, e, 0, 128)
+		e.HandleRTCP([]rtcp.Packet{feedback(0, 0, 128, 0)})
+		require.Equal(t, uint64(300000), e.Estimate())
+
+		e.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 5000000}})
+		require.Less(t, e.Estimate(), uint64(5000000))
+	})
+}
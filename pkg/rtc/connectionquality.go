@@ -0,0 +1,84 @@
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/utils/stats"
+)
+
+const (
+	connectionQualityUpdateInterval = 5 * time.Second
+)
+
+// connectionQualityFor rates a connection from its packet loss and jitter
+func connectionQualityFor(lossPercentage float64, jitterMs float64) types.ConnectionQuality {
+	switch {
+	case lossPercentage <= 2 && jitterMs <= 30:
+		return types.ConnectionQualityExcellent
+	case lossPercentage <= 5 && jitterMs <= 100:
+		return types.ConnectionQualityGood
+	default:
+		return types.ConnectionQualityPoor
+	}
+}
+
+type qualityStream struct {
+	ssrc      uint32
+	clockRate uint32
+}
+
+// connectionQualityTracker rates the streams of a transport by the worst of them.
+// NACKs are counted since the last rating, streams that haven't sent or received packets since are left out,
+// their last reports are stale
+type connectionQualityTracker struct {
+	lock sync.Mutex
+	// counters at the last rating, only for the streams that were rated
+	last map[uint32]stats.StreamSnapshot
+}
+
+func newConnectionQualityTracker() *connectionQualityTracker {
+	return &connectionQualityTracker{
+		last: make(map[uint32]stats.StreamSnapshot),
+	}
+}
+
+func (c *connectionQualityTracker) rate(streamStats *stats.StreamStats, streams []qualityStream) types.ConnectionQuality {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	last := c.last
+	c.last = make(map[uint32]stats.StreamSnapshot, len(streams))
+	quality := types.ConnectionQualityExcellent
+	for _, s := range streams {
+		snapshot, ok := streamStats.Get(s.ssrc)
+		if !ok {
+			continue
+		}
+		c.last[s.ssrc] = snapshot
+		prev := last[s.ssrc]
+		if snapshot.Packets == prev.Packets {
+			continue
+		}
+		if q := streamConnectionQuality(prev, snapshot, s.clockRate); q < quality {
+			quality = q
+		}
+	}
+	return quality
+}
+
+// streamConnectionQuality takes the worse of the reported loss and the share of packets NACKed since prev
+func streamConnectionQuality(prev, snapshot stats.StreamSnapshot, clockRate uint32) types.ConnectionQuality {
+	lossPercentage := float64(snapshot.FractionLost) * 100 / 256
+	if nacks := snapshot.NackCount - prev.NackCount; nacks > 0 {
+		if nackPercentage := float64(nacks) * 100 / float64(snapshot.Packets-prev.Packets); nackPercentage > lossPercentage {
+			lossPercentage = nackPercentage
+		}
+	}
+	var jitterMs float64
+	if clockRate != 0 {
+		jitterMs = float64(snapshot.Jitter) * 1000 / float64(clockRate)
+	}
+	return connectionQualityFor(lossPercentage, jitterMs)
+}
@@ -0,0 +1,77 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/utils/stats"
+)
+
+func TestConnectionQualityFor(t *testing.T) {
+	require.Equal(t, types.ConnectionQualityExcellent, connectionQualityFor(0, 5))
+	require.Equal(t, types.ConnectionQualityGood, connectionQualityFor(3, 5))
+	require.Equal(t, types.ConnectionQualityGood, connectionQualityFor(1, 60))
+	require.Equal(t, types.ConnectionQualityPoor, connectionQualityFor(10, 5))
+	require.Equal(t, types.ConnectionQualityPoor, connectionQualityFor(0, 200))
+}
+
+func TestConnectionQualityTracker(t *testing.T) {
+	streams := []qualityStream{{ssrc: 1, clockRate: 90000}, {ssrc: 2, clockRate: 90000}}
+
+	t.Run("worst stream determines quality", func(t *testing.T) {
+		streamStats := stats.NewStreamStats()
+		tracker := newConnectionQualityTracker()
+		require.Equal(t, types.ConnectionQualityExcellent, tracker.rate(streamStats, streams))
+
+		streamStats.AddPacket(1, 100)
+		streamStats.AddPacket(2, 100)
+		streamStats.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+			{SSRC: 1, FractionLost: 0, Jitter: 900},
+			{SSRC: 2, FractionLost: 10, Jitter: 900},
+		}}})
+		require.Equal(t, types.ConnectionQualityGood, tracker.rate(streamStats, streams))
+	})
+
+	t.Run("counts NACKs since the last rating", func(t *testing.T) {
+		streamStats := stats.NewStreamStats()
+		tracker := newConnectionQualityTracker()
+		for i := 0; i < 10; i++ {
+			streamStats.AddPacket(1, 100)
+		}
+		streamStats.HandleRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{MediaSSRC: 1}})
+		require.Equal(t, types.ConnectionQualityPoor, tracker.rate(streamStats, streams))
+
+		for i := 0; i < 100; i++ {
+			streamStats.AddPacket(1, 100)
+		}
+		require.Equal(t, types.ConnectionQualityExcellent, tracker.rate(streamStats, streams))
+	})
+
+	t.Run("leaves out streams without new packets", func(t *testing.T) {
+		streamStats := stats.NewStreamStats()
+		tracker := newConnectionQualityTracker()
+		streamStats.AddPacket(2, 100)
+		streamStats.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+			{SSRC: 2, FractionLost: 50},
+		}}})
+		require.Equal(t, types.ConnectionQualityPoor, tracker.rate(streamStats, streams))
+
+		// layer was paused, its report is stale
+		streamStats.AddPacket(1, 100)
+		require.Equal(t, types.ConnectionQualityExcellent, tracker.rate(streamStats, streams))
+	})
+
+	t.Run("forgets streams that are no longer rated", func(t *testing.T) {
+		streamStats := stats.NewStreamStats()
+		tracker := newConnectionQualityTracker()
+		streamStats.AddPacket(1, 100)
+		tracker.rate(streamStats, streams)
+		require.Len(t, tracker.last, 1)
+
+		tracker.rate(streamStats, streams[1:])
+		require.Empty(t, tracker.last)
+	})
+}
@@ -0,0 +1,56 @@
+package rtc
+
+import (
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+)
+
+// upper bound of the history kept when it's only limited by age
+const maxDataHistorySize = 1000
+
+// DataHistoryEntry is a reliable user packet, kept for participants that join later
+type DataHistoryEntry struct {
+	ParticipantSid string    `json:"participant_sid"`
+	Payload        []byte    `json:"payload"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+func (e *DataHistoryEntry) ToDataPacket() *livekit.DataPacket {
+	return &livekit.DataPacket{
+		Kind: livekit.DataPacket_RELIABLE,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				ParticipantSid: e.ParticipantSid,
+				Payload:        e.Payload,
+			},
+		},
+	}
+}
+
+func (o RoomOptions) KeepsDataHistory() bool {
+	return o.DataHistorySize > 0 || o.DataHistorySeconds > 0
+}
+
+// TrimDataHistory drops the oldest entries that are beyond the room's history settings
+func (o RoomOptions) TrimDataHistory(history []*DataHistoryEntry, now time.Time) []*DataHistoryEntry {
+	if !o.KeepsDataHistory() {
+		return nil
+	}
+
+	start := 0
+	if o.DataHistorySeconds > 0 {
+		cutoff := now.Add(-time.Duration(o.DataHistorySeconds) * time.Second)
+		for start < len(history) && history[start].SentAt.Before(cutoff) {
+			start++
+		}
+	}
+	size := int(o.DataHistorySize)
+	if size == 0 || size > maxDataHistorySize {
+		size = maxDataHistorySize
+	}
+	if len(history)-start > size {
+		start = len(history) - size
+	}
+	return history[start:]
+}
@@ -0,0 +1,22 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimDataHistory(t *testing.T) {
+	now := time.Now()
+	history := []*DataHistoryEntry{
+		{Payload: []byte("1"), SentAt: now.Add(-10 * time.Minute)},
+		{Payload: []byte("2"), SentAt: now.Add(-time.Minute)},
+		{Payload: []byte("3"), SentAt: now},
+	}
+
+	require.Nil(t, RoomOptions{}.TrimDataHistory(history, now))
+	require.Equal(t, history[1:], RoomOptions{DataHistorySize: 2}.TrimDataHistory(history, now))
+	require.Equal(t, history[1:], RoomOptions{DataHistorySeconds: 5 * 60}.TrimDataHistory(history, now))
+	require.Equal(t, history[2:], RoomOptions{DataHistorySize: 5, DataHistorySeconds: 30}.TrimDataHistory(history, now))
+}
@@ -0,0 +1,110 @@
+package rtc
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate per second on average, with bursts of up to burst
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket allows bursts of at least a second's worth, and at least min
+func newTokenBucket(rate, burst, min float64, now time.Time) *tokenBucket {
+	burst = math.Max(burst, math.Max(rate, min))
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// has is true when n can be taken. a full bucket takes more than its burst, the debt is paid off as it refills
+func (b *tokenBucket) has(n float64) bool {
+	return b.tokens >= math.Min(n, b.burst)
+}
+
+// dataLimiter limits the packets and bytes a sender forwards per second, rates of 0 are unlimited
+type dataLimiter struct {
+	lock    sync.Mutex
+	packets *tokenBucket
+	bytes   *tokenBucket
+}
+
+// newDataLimiter allows bursts above the rates, of at least a second's worth, a packet and the largest payload
+func newDataLimiter(packetsPerSecond, packetBurst, bytesPerSecond, byteBurst float64, maxPayloadSize int) *dataLimiter {
+	now := time.Now()
+	l := &dataLimiter{}
+	if packetsPerSecond > 0 {
+		l.packets = newTokenBucket(packetsPerSecond, packetBurst, 1, now)
+	}
+	if bytesPerSecond > 0 {
+		l.bytes = newTokenBucket(bytesPerSecond, byteBurst, float64(maxPayloadSize), now)
+	}
+	return l
+}
+
+func (l *dataLimiter) has(now time.Time, size int) bool {
+	if l.packets != nil {
+		l.packets.refill(now)
+		if !l.packets.has(1) {
+			return false
+		}
+	}
+	if l.bytes != nil {
+		l.bytes.refill(now)
+		if !l.bytes.has(float64(size)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *dataLimiter) take(size int) {
+	if l.packets != nil {
+		l.packets.tokens--
+	}
+	if l.bytes != nil {
+		l.bytes.tokens -= float64(size)
+	}
+}
+
+// allowData takes a packet of size bytes from every limiter when it's within all of them. otherwise nothing is
+// taken, and the first limiter it's over is returned. nil limiters are unlimited, limiters are locked in the
+// order they're passed in
+func allowData(now time.Time, size int, limiters ...*dataLimiter) *dataLimiter {
+	for _, l := range limiters {
+		if l != nil {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+		}
+	}
+
+	for _, l := range limiters {
+		if l != nil && !l.has(now, size) {
+			return l
+		}
+	}
+	for _, l := range limiters {
+		if l != nil {
+			l.take(size)
+		}
+	}
+	return nil
+}
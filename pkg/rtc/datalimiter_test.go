@@ -0,0 +1,71 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataLimiter(t *testing.T) {
+	t.Run("limits packets and bytes", func(t *testing.T) {
+		l := newDataLimiter(2, 0, 100, 0, 0)
+		now := time.Now()
+		require.Nil(t, allowData(now, 60, l))
+		// over the byte rate, and the packet isn't counted
+		require.Equal(t, l, allowData(now, 60, l))
+		require.Nil(t, allowData(now, 40, l))
+		require.Equal(t, l, allowData(now, 0, l))
+
+		// refills over time, up to a second's worth
+		require.Nil(t, allowData(now.Add(500*time.Millisecond), 50, l))
+		require.Equal(t, l, allowData(now.Add(500*time.Millisecond), 0, l))
+		require.Nil(t, allowData(now.Add(time.Hour), 100, l))
+		require.Equal(t, l, allowData(now.Add(time.Hour), 1, l))
+	})
+
+	t.Run("allows bursts above the rates", func(t *testing.T) {
+		l := newDataLimiter(1, 3, 0, 0, 0)
+		now := time.Now()
+		for i := 0; i < 3; i++ {
+			require.Nil(t, allowData(now, 0, l))
+		}
+		require.Equal(t, l, allowData(now, 0, l))
+	})
+
+	t.Run("allows a packet at rates below one per second", func(t *testing.T) {
+		l := newDataLimiter(0.5, 0, 0, 0, 0)
+		now := time.Now()
+		require.Nil(t, allowData(now, 0, l))
+		require.Equal(t, l, allowData(now.Add(time.Second), 0, l))
+		require.Nil(t, allowData(now.Add(2*time.Second), 0, l))
+	})
+
+	t.Run("allows payloads larger than the byte rate", func(t *testing.T) {
+		l := newDataLimiter(0, 0, 100, 0, 0)
+		now := time.Now()
+		require.Nil(t, allowData(now, 500, l))
+		// until the debt is paid off
+		require.Equal(t, l, allowData(now.Add(4*time.Second), 1, l))
+		require.Nil(t, allowData(now.Add(5*time.Second), 1, l))
+	})
+
+	t.Run("takes nothing when over any limiter", func(t *testing.T) {
+		participant := newDataLimiter(10, 0, 0, 0, 0)
+		room := newDataLimiter(1, 0, 0, 0, 0)
+		now := time.Now()
+		require.Nil(t, allowData(now, 0, participant, room))
+		require.Equal(t, room, allowData(now, 0, participant, room))
+		for i := 0; i < 9; i++ {
+			require.Nil(t, allowData(now, 0, participant))
+		}
+		require.Equal(t, participant, allowData(now, 0, participant))
+	})
+
+	t.Run("rates of 0 are unlimited", func(t *testing.T) {
+		l := newDataLimiter(0, 0, 0, 0, 0)
+		for i := 0; i < 100; i++ {
+			require.Nil(t, allowData(time.Now(), 1024, l, nil))
+		}
+	})
+}
@@ -7,7 +7,15 @@ var (
 	ErrPermissionDenied        = errors.New("no permissions to access the room")
 	ErrMaxParticipantsExceeded = errors.New("room has exceeded its max participants")
 	ErrAlreadyJoined           = errors.New("a participant with the same identity is already in the room")
+	ErrParticipantNotInRoom    = errors.New("participant is not in the room")
 	ErrUnexpectedOffer         = errors.New("expected answer SDP, received offer")
 	ErrDataChannelUnavailable  = errors.New("data channel is not available")
 	ErrCannotSubscribe         = errors.New("participant does not have permission to subscribe")
+	ErrTrackNotReady           = errors.New("track is not receiving media yet")
+	ErrAlreadyRecording        = errors.New("track is already being recorded")
+	ErrNotRecording            = errors.New("track is not being recorded")
+	ErrEgressNotFound          = errors.New("RTP egress does not exist")
+	ErrEgressExists            = errors.New("RTP egress already exists")
+	ErrNoTransceiver           = errors.New("no transceiver was offered for the track")
+	ErrOfferNotReceiveOnly     = errors.New("subscriber offer must only receive media")
 )
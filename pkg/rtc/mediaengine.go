@@ -12,82 +12,121 @@ const (
 	frameMarking = "urn:ietf:params:rtp-hdrext:framemarking"
 )
 
+var videoRTCPFeedback = []webrtc.RTCPFeedback{
+	{Type: webrtc.TypeRTCPFBGoogREMB, Parameter: ""},
+	{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"},
+	{Type: webrtc.TypeRTCPFBNACK, Parameter: ""},
+	{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
+
+// subscribers are also asked for transport-wide congestion control feedback, to estimate their bandwidth
+var subscriberVideoRTCPFeedback = append(append([]webrtc.RTCPFeedback{}, videoRTCPFeedback...),
+	webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
+
 func createPubMediaEngine(codecs []*livekit.Codec) (*webrtc.MediaEngine, error) {
 	me := &webrtc.MediaEngine{}
+	if err := registerCodecs(me, codecs, videoRTCPFeedback); err != nil {
+		return nil, err
+	}
+
+	for _, extension := range []string{
+		sdp.SDESMidURI,
+		sdp.SDESRTPStreamIDURI,
+		sdp.TransportCCURI,
+		frameMarking,
+	} {
+		if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: extension}, webrtc.RTPCodecTypeVideo); err != nil {
+			return nil, err
+		}
+	}
+	for _, extension := range []string{
+		sdp.SDESMidURI,
+		sdp.SDESRTPStreamIDURI,
+		sdp.AudioLevelURI,
+	} {
+		if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: extension}, webrtc.RTPCodecTypeAudio); err != nil {
+			return nil, err
+		}
+	}
+
+	return me, nil
+}
+
+// registerCodecs registers the supported codecs that are enabled
+func registerCodecs(me *webrtc.MediaEngine, codecs []*livekit.Codec, videoFeedback []webrtc.RTCPFeedback) error {
 	opusCodec := webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1", RTCPFeedback: nil}
 	if isCodecEnabled(codecs, opusCodec) {
 		if err := me.RegisterCodec(webrtc.RTPCodecParameters{
 			RTPCodecCapability: opusCodec,
 			PayloadType:        111,
 		}, webrtc.RTPCodecTypeAudio); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	videoRTCPFeedback := []webrtc.RTCPFeedback{
-		{Type: webrtc.TypeRTCPFBGoogREMB, Parameter: ""},
-		{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"},
-		{Type: webrtc.TypeRTCPFBNACK, Parameter: ""},
-		{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
 	for _, codec := range []webrtc.RTPCodecParameters{
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoFeedback},
 			PayloadType:        96,
 		},
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=0", RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=0", RTCPFeedback: videoFeedback},
 			PayloadType:        98,
 		},
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=1", RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=1", RTCPFeedback: videoFeedback},
 			PayloadType:        100,
 		},
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", RTCPFeedback: videoFeedback},
 			PayloadType:        125,
 		},
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42e01f", RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42e01f", RTCPFeedback: videoFeedback},
 			PayloadType:        108,
 		},
 		{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640032", RTCPFeedback: videoRTCPFeedback},
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640032", RTCPFeedback: videoFeedback},
 			PayloadType:        123,
 		},
 	} {
 		if isCodecEnabled(codecs, codec.RTPCodecCapability) {
 			if err := me.RegisterCodec(codec, webrtc.RTPCodecTypeVideo); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
+	return nil
+}
 
-	for _, extension := range []string{
-		sdp.SDESMidURI,
-		sdp.SDESRTPStreamIDURI,
-		sdp.TransportCCURI,
-		frameMarking,
-	} {
-		if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: extension}, webrtc.RTPCodecTypeVideo); err != nil {
-			return nil, err
-		}
+func createSubMediaEngine() (*webrtc.MediaEngine, error) {
+	me := &webrtc.MediaEngine{}
+	if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: sdp.TransportCCURI}, webrtc.RTPCodecTypeVideo); err != nil {
+		return nil, err
 	}
-	for _, extension := range []string{
-		sdp.SDESMidURI,
-		sdp.SDESRTPStreamIDURI,
-		sdp.AudioLevelURI,
-	} {
-		if err := me.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: extension}, webrtc.RTPCodecTypeAudio); err != nil {
-			return nil, err
+	return me, nil
+}
+
+// registerSubscriberCodec registers a codec tracks are sent to subscribers with
+func registerSubscriberCodec(me *webrtc.MediaEngine, codec webrtc.RTPCodecParameters, kind webrtc.RTPCodecType) error {
+	if kind == webrtc.RTPCodecTypeVideo {
+		feedback := append([]webrtc.RTCPFeedback{}, codec.RTCPFeedback...)
+		for _, fb := range subscriberVideoRTCPFeedback {
+			if !hasRTCPFeedback(feedback, fb) {
+				feedback = append(feedback, fb)
+			}
 		}
+		codec.RTCPFeedback = feedback
 	}
-
-	return me, nil
+	return me.RegisterCodec(codec, kind)
 }
 
-func createSubMediaEngine() (*webrtc.MediaEngine, error) {
-	me := &webrtc.MediaEngine{}
-	return me, nil
+func hasRTCPFeedback(feedback []webrtc.RTCPFeedback, fb webrtc.RTCPFeedback) bool {
+	for _, f := range feedback {
+		if f.Type == fb.Type && f.Parameter == fb.Parameter {
+			return true
+		}
+	}
+	return false
 }
 
 func isCodecEnabled(codecs []*livekit.Codec, cap webrtc.RTPCodecCapability) bool {
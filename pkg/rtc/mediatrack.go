@@ -2,10 +2,12 @@ package rtc
 
 import (
 	"errors"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/bep/debounce"
 	"github.com/livekit/protocol/logger"
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/livekit/protocol/utils"
@@ -13,6 +15,7 @@ import (
 	"github.com/pion/ion-sfu/pkg/sfu"
 	"github.com/pion/ion-sfu/pkg/twcc"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
 
@@ -21,6 +24,15 @@ import (
 	"github.com/livekit/livekit-server/pkg/utils/stats"
 )
 
+const (
+	// downgrades are delayed so that layers aren't toggled when subscribers briefly change settings
+	dynacastDowngradeInterval = 3 * time.Second
+
+	recorderObserverID = "recorder"
+	// keyframes are requested this often until the recorder starts writing
+	recorderKeyFrameInterval = 500 * time.Millisecond
+)
+
 var (
 	feedbackTypes = []webrtc.RTCPFeedback{
 		{Type: webrtc.TypeRTCPFBGoogREMB},
@@ -34,6 +46,7 @@ type MediaTrack struct {
 	params      MediaTrackParams
 	ssrc        webrtc.SSRC
 	name        string
+	source      types.TrackSource
 	streamID    string
 	kind        livekit.TrackType
 	codec       webrtc.RTPCodecParameters
@@ -50,7 +63,24 @@ type MediaTrack struct {
 	receiver         sfu.Receiver
 	lastPLI          time.Time
 
-	onClose func()
+	// SSRCs of each up track
+	ssrcs []uint32
+	// SSRC and spatial layer of the best up track, which is the one recorded
+	bestSSRC  uint32
+	bestLayer int32
+	recorder  *TrackRecorder
+	// plain RTP subscribers, by egress ID
+	rtpEgresses map[string]*RTPEgress
+
+	// dimensions of the simulcast layers, as advertised by the publisher
+	layers []types.VideoLayer
+
+	// highest spatial layer needed by subscribers, -1 when none are needed
+	maxSubscribedLayer int32
+	dynacastDebouncer  func(func())
+
+	onClose                   func()
+	onSubscribedQualityChange func(trackID string, qualities []types.SubscribedQuality)
 }
 
 type MediaTrackParams struct {
@@ -63,16 +93,26 @@ type MediaTrackParams struct {
 	Stats          *stats.RoomStatsReporter
 	Width          uint32
 	Height         uint32
+	RTPTap         *RTPTap
 }
 
 func NewMediaTrack(track *webrtc.TrackRemote, params MediaTrackParams) *MediaTrack {
+	t := newMediaTrack(ToProtoTrackKind(track.Kind()), params)
+	t.ssrc = track.SSRC()
+	t.streamID = track.StreamID()
+	t.codec = track.Codec()
+	return t
+}
+
+func newMediaTrack(kind livekit.TrackType, params MediaTrackParams) *MediaTrack {
 	t := &MediaTrack{
-		params:           params,
-		ssrc:             track.SSRC(),
-		streamID:         track.StreamID(),
-		kind:             ToProtoTrackKind(track.Kind()),
-		codec:            track.Codec(),
-		subscribedTracks: make(map[string]*SubscribedTrack),
+		params:             params,
+		kind:               kind,
+		subscribedTracks:   make(map[string]*SubscribedTrack),
+		rtpEgresses:        make(map[string]*RTPEgress),
+		maxSubscribedLayer: spatialLayerForQuality(livekit.VideoQuality_HIGH),
+		bestLayer:          -1,
+		dynacastDebouncer:  debounce.New(dynacastDowngradeInterval),
 	}
 
 	return t
@@ -93,6 +133,16 @@ func (t *MediaTrack) Name() string {
 	return t.name
 }
 
+// Source is what the track is captured from, unknown when the client didn't say
+func (t *MediaTrack) Source() types.TrackSource {
+	return t.source
+}
+
+// Codec is what the track is published with
+func (t *MediaTrack) Codec() webrtc.RTPCodecParameters {
+	return t.codec
+}
+
 func (t *MediaTrack) IsMuted() bool {
 	return t.muted.Get()
 }
@@ -115,6 +165,11 @@ func (t *MediaTrack) OnClose(f func()) {
 	t.onClose = f
 }
 
+// OnSubscribedQualityChange is called when the set of simulcast layers needed by subscribers changes
+func (t *MediaTrack) OnSubscribedQualityChange(f func(trackID string, qualities []types.SubscribedQuality)) {
+	t.onSubscribedQualityChange = f
+}
+
 func (t *MediaTrack) IsSubscriber(subId string) bool {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
@@ -142,7 +197,7 @@ func (t *MediaTrack) AddSubscriber(sub types.Participant) error {
 	}
 
 	codec := t.receiver.Codec()
-	if err := sub.SubscriberMediaEngine().RegisterCodec(codec, t.receiver.Kind()); err != nil {
+	if err := registerSubscriberCodec(sub.SubscriberMediaEngine(), codec, t.receiver.Kind()); err != nil {
 		return err
 	}
 
@@ -164,16 +219,18 @@ func (t *MediaTrack) AddSubscriber(sub types.Participant) error {
 	if err != nil {
 		return err
 	}
-	subTrack := NewSubscribedTrack(downTrack)
+	subTrack := NewSubscribedTrack(t, downTrack)
+	subTrack.OnSubscriptionChanged(t.updateSubscribedQuality)
 
-	transceiver, err := sub.SubscriberPC().AddTransceiverFromTrack(downTrack, webrtc.RTPTransceiverInit{
-		Direction: webrtc.RTPTransceiverDirectionSendonly,
-	})
+	transceiver, err := sub.AddSubscriberTransceiver(downTrack)
 	if err != nil {
 		return err
 	}
 
 	downTrack.SetTransceiver(transceiver)
+	if encodings := transceiver.Sender().GetParameters().Encodings; len(encodings) > 0 {
+		subTrack.ssrc = uint32(encodings[0].SSRC)
+	}
 	// when outtrack is bound, start loop to send reports
 	downTrack.OnBind(func() {
 		go t.sendDownTrackBindingReports(sub)
@@ -184,6 +241,7 @@ func (t *MediaTrack) AddSubscriber(sub types.Participant) error {
 			t.lock.Lock()
 			delete(t.subscribedTracks, sub.ID())
 			t.lock.Unlock()
+			t.updateSubscribedQuality()
 
 			t.params.Stats.SubSubscribedTrack(t.kind.String())
 
@@ -230,9 +288,40 @@ func (t *MediaTrack) AddSubscriber(sub types.Participant) error {
 	}()
 
 	t.params.Stats.AddSubscribedTrack(t.kind.String())
+	go t.updateSubscribedQuality()
 	return nil
 }
 
+func (t *MediaTrack) Simulcasted() bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.simulcasted
+}
+
+// SetLayers updates the dimensions of the track's simulcast layers
+func (t *MediaTrack) SetLayers(layers []types.VideoLayer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.layers = layers
+}
+
+// Layers returns the dimensions of the track's simulcast layers, empty until the publisher advertises them
+func (t *MediaTrack) Layers() []types.VideoLayer {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.layers
+}
+
+// LayerBitrates returns the bitrate of each layer received from the publisher, in bps
+func (t *MediaTrack) LayerBitrates() [3]uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.receiver == nil {
+		return [3]uint64{}
+	}
+	return t.receiver.GetBitrate()
+}
+
 func (t *MediaTrack) NumUpTracks() uint32 {
 	return atomic.LoadUint32(&t.numUpTracks)
 }
@@ -292,6 +381,9 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			t.receiver = nil
 			onclose := t.onClose
 			t.lock.Unlock()
+			if err := t.StopRecording(); err != nil && err != ErrNotRecording {
+				logger.Warnw("could not stop recording", err, "track", t.ID())
+			}
 			t.RemoveAllSubscribers()
 			t.params.Stats.SubPublishedTrack(t.kind.String())
 			if onclose != nil {
@@ -304,6 +396,16 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 	// when RID is set, track is simulcasted
 	t.simulcasted = track.RID() != ""
 	atomic.AddUint32(&t.numUpTracks, 1)
+	t.ssrcs = append(t.ssrcs, uint32(track.SSRC()))
+	layer := spatialLayerForRID(track.RID())
+	if layer > t.bestLayer {
+		t.bestLayer = layer
+		t.bestSSRC = uint32(track.SSRC())
+	}
+	if t.simulcasted {
+		// pause layers if no one subscribes
+		t.dynacastDebouncer(t.applySubscribedQuality)
+	}
 
 	buff.Bind(receiver.GetParameters(), buffer.Options{
 		MaxBitRate: t.params.ReceiverConfig.maxBitrate,
@@ -329,6 +431,9 @@ func (t *MediaTrack) RemoveAllSubscribers() {
 		go subTrack.DownTrack().Close()
 	}
 	t.subscribedTracks = make(map[string]*SubscribedTrack)
+	for _, egress := range t.rtpEgresses {
+		go egress.Close()
+	}
 }
 
 func (t *MediaTrack) ToProto() *livekit.TrackInfo {
@@ -343,6 +448,286 @@ func (t *MediaTrack) ToProto() *livekit.TrackInfo {
 	}
 }
 
+// SSRCs returns the SSRC of each up track
+func (t *MediaTrack) SSRCs() []uint32 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return append([]uint32(nil), t.ssrcs...)
+}
+
+// StartRecording writes the track to a file named basePath, with an extension for its codec.
+// when simulcasted, the best layer is recorded. returns the path of the file
+func (t *MediaTrack) StartRecording(basePath string) (string, error) {
+	t.lock.Lock()
+	if t.recorder != nil {
+		t.lock.Unlock()
+		return "", ErrAlreadyRecording
+	}
+	if t.receiver == nil || t.params.RTPTap == nil {
+		t.lock.Unlock()
+		return "", ErrTrackNotReady
+	}
+	recorder, err := NewTrackRecorder(basePath, t.bestSSRC, t.codec, t.params.Width, t.params.Height)
+	if err != nil {
+		t.lock.Unlock()
+		return "", err
+	}
+	t.recorder = recorder
+	t.params.RTPTap.Observe(recorder.SSRC(), recorderObserverID, recorder.WritePacket)
+	t.lock.Unlock()
+
+	logger.Infow("started recording track",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"file", recorder.FilePath())
+	if t.Kind() == livekit.TrackType_VIDEO {
+		// keep the recorded layer flowing, and start it on a keyframe
+		t.updateSubscribedQuality()
+		go t.requestRecorderKeyFrame(recorder)
+	}
+	return recorder.FilePath(), nil
+}
+
+// requestRecorderKeyFrame asks the publisher for a keyframe until the recorder has one, or stops
+func (t *MediaTrack) requestRecorderKeyFrame(recorder *TrackRecorder) {
+	ticker := time.NewTicker(recorderKeyFrameInterval)
+	defer ticker.Stop()
+	for {
+		t.lock.RLock()
+		recording := t.recorder == recorder
+		t.lock.RUnlock()
+		if !recording || recorder.HasKeyFrame() {
+			return
+		}
+		t.sendRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: recorder.SSRC()}})
+		<-ticker.C
+	}
+}
+
+// sendRTCP sends packets to the publisher without blocking, they are dropped when its RTCP channel is full or closed
+func (t *MediaTrack) sendRTCP(pkts []rtcp.Packet) {
+	defer RecoverSilent()
+	select {
+	case t.params.RTCPChan <- pkts:
+	default:
+	}
+}
+
+// StopRecording finishes writing the recording file
+func (t *MediaTrack) StopRecording() error {
+	t.lock.Lock()
+	recorder := t.recorder
+	t.recorder = nil
+	t.lock.Unlock()
+	if recorder == nil {
+		return ErrNotRecording
+	}
+
+	t.params.RTPTap.Observe(recorder.SSRC(), recorderObserverID, nil)
+	err := recorder.Stop()
+	logger.Infow("stopped recording track",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"file", recorder.FilePath())
+	t.updateSubscribedQuality()
+	return err
+}
+
+// StartRTPEgress is a subscriber that forwards the track as plain RTP to addr, returning an SDP describing
+// the stream. when simulcasted, the layer for quality is forwarded, or the middle one when it isn't published
+func (t *MediaTrack) StartRTPEgress(egressID string, addr *net.UDPAddr, quality livekit.VideoQuality) (string, error) {
+	var sdp string
+	err := t.addRTPEgress(egressID, quality, func(receiver sfu.Receiver) (*RTPEgress, error) {
+		egress, err := NewRTPEgress(egressID, receiver, t.codec, addr, t.params.ReceiverConfig.packetBufferSize)
+		if err == nil {
+			sdp = egress.SDP()
+		}
+		return egress, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	logger.Infow("started RTP egress",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"egressID", egressID,
+		"addr", addr.String())
+	return sdp, nil
+}
+
+// ForwardRTP is a subscriber that passes the track's packets to writeRTP, as RTP egress would send them.
+// it returns a function that asks the publisher for a keyframe, and is stopped with StopRTPEgress
+func (t *MediaTrack) ForwardRTP(egressID string, quality livekit.VideoQuality, writeRTP func(header *rtp.Header, payload []byte)) (func(), error) {
+	var egress *RTPEgress
+	err := t.addRTPEgress(egressID, quality, func(receiver sfu.Receiver) (*RTPEgress, error) {
+		var err error
+		egress, err = NewLocalRTPEgress(egressID, receiver, t.codec, t.params.ReceiverConfig.packetBufferSize, writeRTP)
+		return egress, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infow("started forwarding track",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"egressID", egressID)
+	return egress.RequestKeyFrame, nil
+}
+
+// addRTPEgress adds an egress created from the track's receiver, which is removed when it closes
+func (t *MediaTrack) addRTPEgress(egressID string, quality livekit.VideoQuality, newEgress func(receiver sfu.Receiver) (*RTPEgress, error)) error {
+	t.lock.Lock()
+	if t.receiver == nil {
+		t.lock.Unlock()
+		return ErrTrackNotReady
+	}
+	if t.rtpEgresses[egressID] != nil {
+		t.lock.Unlock()
+		return ErrEgressExists
+	}
+	egress, err := newEgress(NewWrappedReceiver(t.receiver, t.ID(), t.params.ParticipantID))
+	if err != nil {
+		t.lock.Unlock()
+		return err
+	}
+	egress.OnClose(func() {
+		t.lock.Lock()
+		if t.rtpEgresses[egressID] == egress {
+			delete(t.rtpEgresses, egressID)
+		}
+		t.lock.Unlock()
+		t.updateSubscribedQuality()
+	})
+	t.rtpEgresses[egressID] = egress
+	// the DownTrack asks the publisher for a keyframe to start with
+	t.receiver.AddDownTrack(egress.DownTrack(), true)
+	if t.simulcasted {
+		egress.SwitchSpatialLayer(spatialLayerForQuality(quality))
+	}
+	t.lock.Unlock()
+
+	// keep the forwarded layer flowing
+	t.updateSubscribedQuality()
+	return nil
+}
+
+func (t *MediaTrack) StopRTPEgress(egressID string) error {
+	t.lock.RLock()
+	egress := t.rtpEgresses[egressID]
+	t.lock.RUnlock()
+	if egress == nil {
+		return ErrEgressNotFound
+	}
+
+	// removed from the track as it closes
+	egress.Close()
+	logger.Infow("stopped RTP egress",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"egressID", egressID)
+	return nil
+}
+
+// Stats aggregates the publisher's streams for this track, using counters from the publisher transport
+func (t *MediaTrack) Stats(streamStats *stats.StreamStats) types.TrackStats {
+	t.lock.RLock()
+	ssrcs := append([]uint32(nil), t.ssrcs...)
+	t.lock.RUnlock()
+
+	ts := types.TrackStats{
+		TrackSid:       t.ID(),
+		ParticipantSid: t.params.ParticipantID,
+		Kind:           t.Kind().String(),
+		Muted:          t.IsMuted(),
+	}
+	if t.Simulcasted() {
+		// layers that are paused, or that the publisher stopped sending, have no bitrate
+		ts.SpatialLayer = -1
+		bitrates := t.LayerBitrates()
+		for layer := len(bitrates) - 1; layer >= 0; layer-- {
+			if bitrates[layer] > 0 {
+				ts.SpatialLayer = int32(layer)
+				break
+			}
+		}
+	}
+	for _, ssrc := range ssrcs {
+		if snapshot, ok := streamStats.Get(ssrc); ok {
+			addStreamSnapshot(&ts, snapshot, t.codec.ClockRate)
+		}
+	}
+	return ts
+}
+
+// updateSubscribedQuality lets the publisher know which simulcast layers are needed.
+// new layers are enabled right away, while pausing of unused layers is delayed
+func (t *MediaTrack) updateSubscribedQuality() {
+	t.lock.RLock()
+	if t.kind != livekit.TrackType_VIDEO || !t.simulcasted {
+		t.lock.RUnlock()
+		return
+	}
+	maxLayer := t.getMaxSubscribedLayer()
+	current := t.maxSubscribedLayer
+	t.lock.RUnlock()
+
+	if maxLayer > current {
+		t.applySubscribedQuality()
+	} else if maxLayer < current {
+		t.dynacastDebouncer(t.applySubscribedQuality)
+	}
+}
+
+func (t *MediaTrack) applySubscribedQuality() {
+	t.lock.Lock()
+	maxLayer := t.getMaxSubscribedLayer()
+	if maxLayer == t.maxSubscribedLayer {
+		t.lock.Unlock()
+		return
+	}
+	t.maxSubscribedLayer = maxLayer
+	onSubscribedQualityChange := t.onSubscribedQualityChange
+	t.lock.Unlock()
+
+	logger.Debugw("subscribed quality changed",
+		"track", t.ID(),
+		"pID", t.params.ParticipantID,
+		"maxLayer", maxLayer)
+
+	if onSubscribedQualityChange == nil {
+		return
+	}
+	qualities := make([]types.SubscribedQuality, 0, 3)
+	for layer := int32(0); layer < 3; layer++ {
+		qualities = append(qualities, types.SubscribedQuality{
+			Quality: qualityForSpatialLayer(layer),
+			Enabled: layer <= maxLayer,
+		})
+	}
+	onSubscribedQualityChange(t.ID(), qualities)
+}
+
+// this function assumes caller holds lock
+func (t *MediaTrack) getMaxSubscribedLayer() int32 {
+	maxLayer := int32(-1)
+	if t.recorder != nil {
+		maxLayer = t.bestLayer
+	}
+	for _, egress := range t.rtpEgresses {
+		if layer := egress.SpatialLayer(); layer > maxLayer {
+			maxLayer = layer
+		}
+	}
+	for _, st := range t.subscribedTracks {
+		if layer := st.SpatialLayer(); layer > maxLayer {
+			maxLayer = layer
+		}
+	}
+	return maxLayer
+}
+
 // this function assumes caller holds lock
 func (t *MediaTrack) shouldStartWithBestQuality() bool {
 	return len(t.subscribedTracks) < 10
@@ -397,6 +782,10 @@ func (t *MediaTrack) DebugInfo() map[string]interface{} {
 		"PubMuted": t.muted.Get(),
 	}
 
+	t.lock.RLock()
+	info["MaxSubscribedLayer"] = t.maxSubscribedLayer
+	t.lock.RUnlock()
+
 	subscribedTrackInfo := make([]map[string]interface{}, 0)
 	t.lock.RLock()
 	for _, track := range t.subscribedTracks {
@@ -417,3 +806,33 @@ func (t *MediaTrack) DebugInfo() map[string]interface{} {
 
 	return info
 }
+
+// addStreamSnapshot adds counters of a stream to the track's, loss and jitter are of the worst stream
+func addStreamSnapshot(ts *types.TrackStats, snapshot stats.StreamSnapshot, clockRate uint32) {
+	ts.Bitrate += snapshot.Bitrate
+	ts.Packets += snapshot.Packets
+	ts.PacketsLost += snapshot.TotalLost
+	ts.NackCount += snapshot.NackCount
+	ts.PLICount += snapshot.PLICount
+	ts.FIRCount += snapshot.FIRCount
+	if lossPercentage := float64(snapshot.FractionLost) * 100 / 256; lossPercentage > ts.LossPercentage {
+		ts.LossPercentage = lossPercentage
+	}
+	if clockRate != 0 {
+		if jitterMs := float64(snapshot.Jitter) * 1000 / float64(clockRate); jitterMs > ts.Jitter {
+			ts.Jitter = jitterMs
+		}
+	}
+}
+
+// simulcast layers are identified by RID, tracks without one only have a single layer
+func spatialLayerForRID(rid string) int32 {
+	switch rid {
+	case quarterResolution:
+		return 0
+	case halfResolution:
+		return 1
+	default:
+		return 2
+	}
+}
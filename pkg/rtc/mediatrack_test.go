@@ -0,0 +1,104 @@
+package rtc
+
+import (
+	"testing"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/ion-sfu/pkg/sfu"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/utils/stats"
+)
+
+func TestSubscribedQuality(t *testing.T) {
+	newTrack := func() *MediaTrack {
+		mt := newMediaTrack(livekit.TrackType_VIDEO, MediaTrackParams{TrackID: "video"})
+		mt.simulcasted = true
+		return mt
+	}
+
+	t.Run("pauses all layers without subscribers", func(t *testing.T) {
+		mt := newTrack()
+		var qualities []types.SubscribedQuality
+		mt.OnSubscribedQualityChange(func(trackID string, q []types.SubscribedQuality) {
+			require.Equal(t, "video", trackID)
+			qualities = q
+		})
+		mt.applySubscribedQuality()
+
+		require.Len(t, qualities, 3)
+		for _, q := range qualities {
+			require.False(t, q.Enabled)
+		}
+	})
+
+	t.Run("enables layers up to highest subscribed quality", func(t *testing.T) {
+		mt := newTrack()
+		low := NewSubscribedTrack(mt, nil)
+		low.quality.Store(livekit.VideoQuality_LOW)
+		medium := NewSubscribedTrack(mt, nil)
+		medium.quality.Store(livekit.VideoQuality_MEDIUM)
+		mt.subscribedTracks["low"] = low
+		mt.subscribedTracks["medium"] = medium
+
+		var qualities []types.SubscribedQuality
+		mt.OnSubscribedQualityChange(func(trackID string, q []types.SubscribedQuality) {
+			qualities = q
+		})
+		mt.applySubscribedQuality()
+
+		require.Equal(t, []types.SubscribedQuality{
+			{Quality: livekit.VideoQuality_LOW, Enabled: true},
+			{Quality: livekit.VideoQuality_MEDIUM, Enabled: true},
+			{Quality: livekit.VideoQuality_HIGH, Enabled: false},
+		}, qualities)
+
+		// disabled subscribers do not need any layers
+		medium.subMuted.TrySet(true)
+		mt.applySubscribedQuality()
+		require.True(t, qualities[0].Enabled)
+		require.False(t, qualities[1].Enabled)
+	})
+
+	t.Run("does not notify when unchanged", func(t *testing.T) {
+		mt := newTrack()
+		mt.subscribedTracks["high"] = NewSubscribedTrack(mt, nil)
+		called := false
+		mt.OnSubscribedQualityChange(func(trackID string, q []types.SubscribedQuality) {
+			called = true
+		})
+		mt.applySubscribedQuality()
+		require.False(t, called)
+	})
+}
+
+type fakeBitrateReceiver struct {
+	sfu.Receiver
+	bitrates [3]uint64
+}
+
+func (r *fakeBitrateReceiver) GetBitrate() [3]uint64 {
+	return r.bitrates
+}
+
+func TestMediaTrackStats(t *testing.T) {
+	t.Run("reports the highest layer received", func(t *testing.T) {
+		mt := newMediaTrack(livekit.TrackType_VIDEO, MediaTrackParams{TrackID: "video"})
+		mt.simulcasted = true
+		mt.ssrcs = []uint32{1, 2, 3}
+		receiver := &fakeBitrateReceiver{bitrates: [3]uint64{150000, 500000, 0}}
+		mt.receiver = receiver
+
+		require.EqualValues(t, 1, mt.Stats(stats.NewStreamStats()).SpatialLayer)
+
+		// paused
+		receiver.bitrates = [3]uint64{}
+		require.EqualValues(t, -1, mt.Stats(stats.NewStreamStats()).SpatialLayer)
+	})
+
+	t.Run("tracks that aren't simulcasted have one layer", func(t *testing.T) {
+		mt := newMediaTrack(livekit.TrackType_AUDIO, MediaTrackParams{TrackID: "audio"})
+		require.EqualValues(t, 0, mt.Stats(stats.NewStreamStats()).SpatialLayer)
+	})
+}
@@ -1,6 +1,7 @@
 package rtc
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sync"
@@ -27,6 +28,7 @@ import (
 const (
 	lossyDataChannel    = "_lossy"
 	reliableDataChannel = "_reliable"
+	controlDataChannel  = "_control"
 	sdBatchSize         = 20
 )
 
@@ -40,6 +42,8 @@ type ParticipantParams struct {
 	ThrottleConfig  config.PLIThrottleConfig
 	EnabledCodecs   []*livekit.Codec
 	Hidden          bool
+	// relays publish tracks of a participant connected to another node
+	Relayed bool
 }
 
 type ParticipantImpl struct {
@@ -59,6 +63,9 @@ type ParticipantImpl struct {
 	reliableDCSub *webrtc.DataChannel
 	lossyDC       *webrtc.DataChannel
 	lossyDCSub    *webrtc.DataChannel
+	// control channel for updates not covered by signaling
+	controlDC    *webrtc.DataChannel
+	controlDCSub *webrtc.DataChannel
 
 	// when first connected
 	connectedAt time.Time
@@ -69,12 +76,27 @@ type ParticipantImpl struct {
 	// hold reference for MediaTrack
 	twcc *twcc.Responder
 
+	// splits downstream bandwidth across subscribed tracks
+	bandwidthAllocator *BandwidthAllocator
+	uplinkQuality      *connectionQualityTracker
+	downlinkQuality    *connectionQualityTracker
+
+	// publishers whose video is forwarded when the room limits video to last-N speakers, nil when unrestricted
+	lastNPublishers map[string]bool
+	// tracks the subscriber wants regardless of last-N
+	pinnedTracks map[string]bool
+
+	// guards the use of transceivers offered by the client
+	transceiverLock sync.Mutex
+
 	// tracks the current participant is subscribed to, map of otherParticipantId => []DownTrack
 	subscribedTracks map[string][]types.SubscribedTrack
 	// publishedTracks that participant is publishing
 	publishedTracks map[string]types.PublishedTrack
 	// client intended to publish, yet to be reconciled
 	pendingTracks map[string]*livekit.TrackInfo
+	// source of each pending track, by track sid
+	pendingSources map[string]types.TrackSource
 
 	lock sync.RWMutex
 	once sync.Once
@@ -99,8 +121,13 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 		subscribedTracks: make(map[string][]types.SubscribedTrack),
 		publishedTracks:  make(map[string]types.PublishedTrack, 0),
 		pendingTracks:    make(map[string]*livekit.TrackInfo),
+		pendingSources:   make(map[string]types.TrackSource),
+		pinnedTracks:     make(map[string]bool),
 		connectedAt:      time.Now(),
+		uplinkQuality:    newConnectionQualityTracker(),
+		downlinkQuality:  newConnectionQualityTracker(),
 	}
+	p.bandwidthAllocator = NewBandwidthAllocator(p.id)
 	p.state.Store(livekit.ParticipantInfo_JOINING)
 	p.updateAfterActive.Store(false)
 
@@ -122,6 +149,7 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.subscriber.BandwidthEstimator().OnEstimate(p.bandwidthAllocator.SetEstimate)
 
 	p.publisher.pc.OnICECandidate(func(c *webrtc.ICECandidate) {
 		if c == nil || p.State() == livekit.ParticipantInfo_DISCONNECTED {
@@ -138,6 +166,14 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 
 	primaryPC := p.publisher.pc
 
+	if p.ProtocolVersion().OffersSubscriber() {
+		// codecs are usually registered as tracks are subscribed, but are needed to answer
+		if err = registerCodecs(p.subscriber.me, p.params.EnabledCodecs, subscriberVideoRTCPFeedback); err != nil {
+			return nil, err
+		}
+		primaryPC = p.subscriber.pc
+	}
+
 	if p.ProtocolVersion().SubscriberAsPrimary() {
 		primaryPC = p.subscriber.pc
 		ordered := true
@@ -156,6 +192,20 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 		if err != nil {
 			return nil, err
 		}
+		if p.ProtocolVersion().SupportsControlChannel() {
+			controlDCSub, err := primaryPC.CreateDataChannel(controlDataChannel, &webrtc.DataChannelInit{
+				Ordered: &ordered,
+			})
+			if err != nil {
+				return nil, err
+			}
+			controlDCSub.OnMessage(func(msg webrtc.DataChannelMessage) {
+				p.handleControlMessage(msg.Data)
+			})
+			p.lock.Lock()
+			p.controlDCSub = controlDCSub
+			p.lock.Unlock()
+		}
 	}
 	primaryPC.OnICEConnectionStateChange(p.handlePrimaryICEStateChange)
 	p.publisher.pc.OnTrack(p.onMediaTrack)
@@ -270,6 +320,10 @@ func (p *ParticipantImpl) OnClose(callback func(types.Participant)) {
 
 // HandleOffer an offer from remote participant, used when clients make the initial connection
 func (p *ParticipantImpl) HandleOffer(sdp webrtc.SessionDescription) (answer webrtc.SessionDescription, err error) {
+	if p.ProtocolVersion().OffersSubscriber() {
+		return p.handleSubscriberOffer(sdp)
+	}
+
 	logger.Debugw("answering pub offer", "state", p.State().String(),
 		"participant", p.Identity(), "pID", p.ID(),
 		//"sdp", sdp.SDP,
@@ -309,6 +363,66 @@ func (p *ParticipantImpl) HandleOffer(sdp webrtc.SessionDescription) (answer web
 	return
 }
 
+// handleSubscriberOffer answers clients that can't take offers from the server, such as WHEP players.
+// the subscriber connection becomes the primary one, and tracks are sent on the transceivers offered
+func (p *ParticipantImpl) handleSubscriberOffer(sdp webrtc.SessionDescription) (answer webrtc.SessionDescription, err error) {
+	logger.Debugw("answering sub offer", "state", p.State().String(),
+		"participant", p.Identity(), "pID", p.ID(),
+	)
+
+	if !isReceiveOnlyOffer(sdp) {
+		err = ErrOfferNotReceiveOnly
+		return
+	}
+
+	// transceivers of tracks subscribed before the offer are matched to offered ones of the same kind
+	if err = p.subscriber.SetRemoteDescription(sdp); err != nil {
+		return
+	}
+
+	// other offered transceivers have no tracks until subscribed, placeholders are answered in their place
+	p.transceiverLock.Lock()
+	for _, transceiver := range p.subscriber.pc.GetTransceivers() {
+		if transceiver.Sender() != nil || transceiver.Direction() != webrtc.RTPTransceiverDirectionSendonly {
+			continue
+		}
+		placeholder := newPlaceholderTrack(utils.NewGuid(utils.TrackPrefix), p.ID(), transceiver.Kind())
+		if _, err = p.subscriber.pc.AddTrack(placeholder); err != nil {
+			break
+		}
+	}
+	p.transceiverLock.Unlock()
+	if err != nil {
+		err = errors.Wrap(err, "could not add placeholder track")
+		return
+	}
+
+	answer, err = p.subscriber.pc.CreateAnswer(nil)
+	if err != nil {
+		err = errors.Wrap(err, "could not create answer")
+		return
+	}
+
+	if err = p.subscriber.pc.SetLocalDescription(answer); err != nil {
+		err = errors.Wrap(err, "could not set local description")
+		return
+	}
+
+	err = p.writeMessage(&livekit.SignalResponse{
+		Message: &livekit.SignalResponse_Answer{
+			Answer: ToProtoSessionDescription(answer),
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	if p.State() == livekit.ParticipantInfo_JOINING {
+		p.updateState(livekit.ParticipantInfo_JOINED)
+	}
+	return
+}
+
 // AddTrack is called when client intends to publish track.
 // records track details and lets client know it's ok to proceed
 func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
@@ -329,6 +443,7 @@ func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
 		Muted:  req.Muted,
 	}
 	p.pendingTracks[req.Cid] = ti
+	p.pendingSources[ti.Sid] = TrackSourceFromRequest(req)
 
 	_ = p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_TrackPublished{
@@ -387,6 +502,16 @@ func (p *ParticipantImpl) Start() {
 }
 
 func (p *ParticipantImpl) Close() error {
+	return p.close(false)
+}
+
+// CloseWithReconnect closes the participant, and lets its client know it can join again. that's used when
+// the node it's on is going away, so it reconnects to another
+func (p *ParticipantImpl) CloseWithReconnect() error {
+	return p.close(true)
+}
+
+func (p *ParticipantImpl) close(canReconnect bool) error {
 	if !p.isClosed.TrySet(true) {
 		// already closed
 		return nil
@@ -395,7 +520,9 @@ func (p *ParticipantImpl) Close() error {
 	// send leave message
 	_ = p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_Leave{
-			Leave: &livekit.LeaveRequest{},
+			Leave: &livekit.LeaveRequest{
+				CanReconnect: canReconnect,
+			},
 		},
 	})
 
@@ -436,12 +563,16 @@ func (p *ParticipantImpl) Close() error {
 }
 
 func (p *ParticipantImpl) Negotiate() {
+	// the client would not accept an offer
+	if p.ProtocolVersion().OffersSubscriber() {
+		return
+	}
 	p.subscriber.Negotiate()
 }
 
 // ICERestart restarts subscriber ICE connections
 func (p *ParticipantImpl) ICERestart() error {
-	if p.subscriber.pc.RemoteDescription() == nil {
+	if p.subscriber.pc.RemoteDescription() == nil || p.ProtocolVersion().OffersSubscriber() {
 		// not connected, skip
 		return nil
 	}
@@ -450,6 +581,35 @@ func (p *ParticipantImpl) ICERestart() error {
 	})
 }
 
+// AddSubscriberTransceiver adds a transceiver to send the track on. when the client offers the subscriber
+// connection, an unused transceiver from its offer is taken instead once it's been offered
+func (p *ParticipantImpl) AddSubscriberTransceiver(track webrtc.TrackLocal) (*webrtc.RTPTransceiver, error) {
+	p.transceiverLock.Lock()
+	defer p.transceiverLock.Unlock()
+
+	if !p.ProtocolVersion().OffersSubscriber() || p.subscriber.pc.RemoteDescription() == nil {
+		return p.subscriber.pc.AddTransceiverFromTrack(track, webrtc.RTPTransceiverInit{
+			Direction: webrtc.RTPTransceiverDirectionSendonly,
+		})
+	}
+
+	for _, transceiver := range p.subscriber.pc.GetTransceivers() {
+		sender := transceiver.Sender()
+		if transceiver.Kind() != track.Kind() || sender == nil {
+			continue
+		}
+		// transceivers sending other tracks are in use
+		if _, ok := sender.Track().(*placeholderTrack); !ok {
+			continue
+		}
+		if err := sender.ReplaceTrack(track); err != nil {
+			return nil, err
+		}
+		return transceiver, nil
+	}
+	return nil, ErrNoTransceiver
+}
+
 // AddSubscriber subscribes op to all publishedTracks
 func (p *ParticipantImpl) AddSubscriber(op types.Participant) (int, error) {
 	p.lock.RLock()
@@ -539,6 +699,11 @@ func (p *ParticipantImpl) SendActiveSpeakers(speakers []*livekit.SpeakerInfo) er
 	})
 }
 
+// UpdateActiveSpeakers lets the participant prioritize video from active speakers
+func (p *ParticipantImpl) UpdateActiveSpeakers(speakers []*livekit.SpeakerInfo) {
+	p.bandwidthAllocator.SetActiveSpeakers(speakers)
+}
+
 func (p *ParticipantImpl) SendDataPacket(dp *livekit.DataPacket) error {
 	if p.State() != livekit.ParticipantInfo_ACTIVE {
 		return ErrDataChannelUnavailable
@@ -570,6 +735,34 @@ func (p *ParticipantImpl) SendDataPacket(dp *livekit.DataPacket) error {
 	return dc.Send(data)
 }
 
+// SendControlMessage sends a message over the control data channel, skipping clients that haven't opened one
+func (p *ParticipantImpl) SendControlMessage(msg *types.ControlMessage) error {
+	if p.State() != livekit.ParticipantInfo_ACTIVE {
+		return nil
+	}
+
+	// sent on the channel the client opened, or else the one the server opened on the subscriber connection
+	p.lock.RLock()
+	channels := []*webrtc.DataChannel{p.controlDC, p.controlDCSub}
+	p.lock.RUnlock()
+	var dc *webrtc.DataChannel
+	for _, c := range channels {
+		if c != nil && c.ReadyState() == webrtc.DataChannelStateOpen {
+			dc = c
+			break
+		}
+	}
+	if dc == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return dc.Send(data)
+}
+
 func (p *ParticipantImpl) SetTrackMuted(trackId string, muted bool, fromAdmin bool) {
 	isPending := false
 	p.lock.RLock()
@@ -636,6 +829,67 @@ func (p *ParticipantImpl) GetAudioLevel() (level uint8, active bool) {
 	return
 }
 
+// GetConnectionQuality rates the participant's connection by the worst of its published and subscribed streams,
+// and how much of what it subscribed to its bandwidth allows
+func (p *ParticipantImpl) GetConnectionQuality() types.ConnectionQuality {
+	var published, subscribed []qualityStream
+	p.lock.RLock()
+	for _, pt := range p.publishedTracks {
+		if mt, ok := pt.(*MediaTrack); ok && !mt.IsMuted() {
+			for _, ssrc := range mt.SSRCs() {
+				published = append(published, qualityStream{ssrc: ssrc, clockRate: mt.Codec().ClockRate})
+			}
+		}
+	}
+	for _, tracks := range p.subscribedTracks {
+		for _, subTrack := range tracks {
+			if st, ok := subTrack.(*SubscribedTrack); ok && st.ForwardedLayer() >= 0 {
+				subscribed = append(subscribed, qualityStream{ssrc: st.SSRC(), clockRate: st.publishedTrack.Codec().ClockRate})
+			}
+		}
+	}
+	p.lock.RUnlock()
+
+	quality := p.bandwidthAllocator.ConnectionQuality()
+	if q := p.uplinkQuality.rate(p.publisher.StreamStats(), published); q < quality {
+		quality = q
+	}
+	if q := p.downlinkQuality.rate(p.subscriber.StreamStats(), subscribed); q < quality {
+		quality = q
+	}
+	return quality
+}
+
+// GetStats returns statistics for published and subscribed tracks
+func (p *ParticipantImpl) GetStats() *types.ParticipantStats {
+	ps := &types.ParticipantStats{
+		ParticipantSid: p.id,
+		Identity:       p.Identity(),
+		UpdatedAt:      time.Now().Unix(),
+		Published:      make([]types.TrackStats, 0),
+		Subscribed:     make([]types.TrackStats, 0),
+	}
+
+	pubRTT := p.publisher.RTT()
+	for _, pt := range p.GetPublishedTracks() {
+		if mt, ok := pt.(*MediaTrack); ok {
+			ts := mt.Stats(p.publisher.StreamStats())
+			ts.RTT = pubRTT
+			ps.Published = append(ps.Published, ts)
+		}
+	}
+
+	subRTT := p.subscriber.RTT()
+	for _, st := range p.GetSubscribedTracks() {
+		if subTrack, ok := st.(*SubscribedTrack); ok {
+			ts := subTrack.Stats(p.subscriber.StreamStats())
+			ts.RTT = subRTT
+			ps.Subscribed = append(ps.Subscribed, ts)
+		}
+	}
+	return ps
+}
+
 func (p *ParticipantImpl) CanPublish() bool {
 	return p.permission == nil || p.permission.CanPublish
 }
@@ -652,6 +906,10 @@ func (p *ParticipantImpl) Hidden() bool {
 	return p.params.Hidden
 }
 
+func (p *ParticipantImpl) Relayed() bool {
+	return p.params.Relayed
+}
+
 func (p *ParticipantImpl) SubscriberPC() *webrtc.PeerConnection {
 	return p.subscriber.pc
 }
@@ -675,6 +933,16 @@ func (p *ParticipantImpl) AddSubscribedTrack(pubId string, subTrack types.Subscr
 	p.lock.Lock()
 	p.subscribedTracks[pubId] = append(p.subscribedTracks[pubId], subTrack)
 	p.lock.Unlock()
+
+	if st, ok := subTrack.(*SubscribedTrack); ok {
+		if st.Kind() == livekit.TrackType_VIDEO {
+			p.lock.RLock()
+			paused := p.isLastNPaused(pubId, st.ID())
+			p.lock.RUnlock()
+			st.SetLastNPaused(paused)
+		}
+		p.bandwidthAllocator.AddTrack(st)
+	}
 }
 
 // RemoveSubscribedTrack removes a track to the participant's subscribed list
@@ -682,7 +950,6 @@ func (p *ParticipantImpl) RemoveSubscribedTrack(pubId string, subTrack types.Sub
 	logger.Debugw("removed subscribedTrack", "pIDs", []string{pubId, p.ID()},
 		"participant", p.Identity(), "track", subTrack.ID())
 	p.lock.Lock()
-	defer p.lock.Unlock()
 	tracks := make([]types.SubscribedTrack, 0, len(p.subscribedTracks[pubId]))
 	for _, tr := range p.subscribedTracks[pubId] {
 		if tr != subTrack {
@@ -690,6 +957,11 @@ func (p *ParticipantImpl) RemoveSubscribedTrack(pubId string, subTrack types.Sub
 		}
 	}
 	p.subscribedTracks[pubId] = tracks
+	p.lock.Unlock()
+
+	if st, ok := subTrack.(*SubscribedTrack); ok {
+		p.bandwidthAllocator.RemoveTrack(st)
+	}
 }
 
 func (p *ParticipantImpl) sendIceCandidate(c *webrtc.ICECandidate, target livekit.SignalTarget) {
@@ -805,9 +1077,13 @@ func (p *ParticipantImpl) onMediaTrack(track *webrtc.TrackRemote, rtpReceiver *w
 			Stats:          p.params.Stats,
 			Width:          ti.Width,
 			Height:         ti.Height,
+			RTPTap:         p.publisher.RTPTap(),
 		})
 		mt.name = ti.Name
+		mt.source = p.pendingSources[ti.Sid]
+		delete(p.pendingSources, ti.Sid)
 		mt.SetMuted(ti.Muted)
+		mt.OnSubscribedQualityChange(p.onSubscribedQualityChange)
 		newTrack = true
 	}
 
@@ -847,6 +1123,14 @@ func (p *ParticipantImpl) onDataChannel(dc *webrtc.DataChannel) {
 		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
 			p.handleDataMessage(livekit.DataPacket_LOSSY, msg.Data)
 		})
+	case controlDataChannel:
+		// read by SendControlMessage from other goroutines
+		p.lock.Lock()
+		p.controlDC = dc
+		p.lock.Unlock()
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			p.handleControlMessage(msg.Data)
+		})
 	default:
 		logger.Warnw("unsupported datachannel added", nil, "participant", p.Identity(), "pID", p.ID(), "label", dc.Label())
 	}
@@ -898,6 +1182,150 @@ func (p *ParticipantImpl) handleDataMessage(kind livekit.DataPacket_Kind, data [
 	}
 }
 
+func (p *ParticipantImpl) handleControlMessage(data []byte) {
+	msg := types.ControlMessage{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.Warnw("could not parse control message", err,
+			"participant", p.Identity(), "pID", p.ID())
+		return
+	}
+
+	switch {
+	case msg.TrackDimensionsUpdate != nil:
+		p.updateTrackDimensions(msg.TrackDimensionsUpdate)
+	case msg.TrackLayersUpdate != nil:
+		p.updateTrackLayers(msg.TrackLayersUpdate)
+	case msg.PinnedTracksUpdate != nil:
+		p.updatePinnedTracks(msg.PinnedTracksUpdate)
+	default:
+		logger.Debugw("received unsupported control message", "participant", p.Identity(), "pID", p.ID())
+	}
+}
+
+// adaptive stream, subscriber reporting the size tracks are rendered at
+func (p *ParticipantImpl) updateTrackDimensions(update *types.TrackDimensionsUpdate) {
+	for _, subTrack := range p.GetSubscribedTracks() {
+		for _, sid := range update.TrackSids {
+			if subTrack.ID() != sid {
+				continue
+			}
+			logger.Debugw("updating track dimensions",
+				"participant", p.Identity(),
+				"pID", p.ID(),
+				"track", sid,
+				"width", update.Width,
+				"height", update.Height)
+			subTrack.UpdateSubscriberDimensions(update.Width, update.Height)
+		}
+	}
+}
+
+// publisher advertising the dimensions of a track's simulcast layers
+func (p *ParticipantImpl) updateTrackLayers(update *types.TrackLayersUpdate) {
+	p.lock.RLock()
+	mt, ok := p.publishedTracks[update.TrackSid].(*MediaTrack)
+	p.lock.RUnlock()
+	if !ok {
+		return
+	}
+	logger.Debugw("updating track layers",
+		"participant", p.Identity(),
+		"pID", p.ID(),
+		"track", update.TrackSid,
+		"layers", update.Layers)
+	mt.SetLayers(update.Layers)
+}
+
+// subscriber pinning tracks that should be forwarded regardless of last-N
+func (p *ParticipantImpl) updatePinnedTracks(update *types.PinnedTracksUpdate) {
+	pinned := make(map[string]bool, len(update.TrackSids))
+	for _, sid := range update.TrackSids {
+		pinned[sid] = true
+	}
+
+	p.lock.Lock()
+	p.pinnedTracks = pinned
+	p.lock.Unlock()
+
+	p.applyLastN()
+}
+
+// SetLastNPublishers limits video forwarded to the subscriber to tracks from the given publishers,
+// along with tracks it has pinned. nil removes the limit
+func (p *ParticipantImpl) SetLastNPublishers(publisherIDs []string) {
+	var lastN map[string]bool
+	if publisherIDs != nil {
+		lastN = make(map[string]bool, len(publisherIDs))
+		for _, pID := range publisherIDs {
+			lastN[pID] = true
+		}
+	}
+
+	p.lock.Lock()
+	unchanged := sameLastNPublishers(p.lastNPublishers, lastN)
+	p.lastNPublishers = lastN
+	p.lock.Unlock()
+
+	if !unchanged {
+		p.applyLastN()
+	}
+}
+
+func sameLastNPublishers(a, b map[string]bool) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+	for pID := range a {
+		if !b[pID] {
+			return false
+		}
+	}
+	return true
+}
+
+// pauses subscribed video outside of last-N, and resumes video that's back in
+func (p *ParticipantImpl) applyLastN() {
+	paused := make(map[*SubscribedTrack]bool)
+	p.lock.RLock()
+	for pubID, tracks := range p.subscribedTracks {
+		for _, t := range tracks {
+			st, ok := t.(*SubscribedTrack)
+			if !ok || st.Kind() != livekit.TrackType_VIDEO {
+				continue
+			}
+			paused[st] = p.isLastNPaused(pubID, st.ID())
+		}
+	}
+	p.lock.RUnlock()
+
+	if len(paused) == 0 {
+		return
+	}
+	for st, pause := range paused {
+		st.SetLastNPaused(pause)
+	}
+	p.bandwidthAllocator.allocate()
+}
+
+// assumes lock is held
+func (p *ParticipantImpl) isLastNPaused(pubID, trackID string) bool {
+	return p.lastNPublishers != nil && !p.lastNPublishers[pubID] && !p.pinnedTracks[trackID]
+}
+
+// dynacast, let the publisher know which layers are in use
+func (p *ParticipantImpl) onSubscribedQualityChange(trackID string, qualities []types.SubscribedQuality) {
+	err := p.SendControlMessage(&types.ControlMessage{
+		SubscribedQualityUpdate: &types.SubscribedQualityUpdate{
+			TrackSid:            trackID,
+			SubscribedQualities: qualities,
+		},
+	})
+	if err != nil {
+		logger.Warnw("could not send subscribed quality update", err,
+			"participant", p.Identity(), "pID", p.ID(), "track", trackID)
+	}
+}
+
 func (p *ParticipantImpl) handleTrackPublished(track types.PublishedTrack) {
 	// fill in
 	p.lock.Lock()
@@ -1042,8 +1470,9 @@ func (p *ParticipantImpl) rtcpSendWorker() {
 
 func (p *ParticipantImpl) DebugInfo() map[string]interface{} {
 	info := map[string]interface{}{
-		"ID":    p.id,
-		"State": p.State().String(),
+		"ID":                p.id,
+		"State":             p.State().String(),
+		"BandwidthEstimate": p.bandwidthAllocator.Estimate(),
 	}
 
 	publishedTrackInfo := make(map[string]interface{})
@@ -0,0 +1,47 @@
+package rtc
+
+import (
+	"github.com/pion/webrtc/v3"
+)
+
+// placeholderTrack holds a transceiver offered by the client, so it's sending once answered. it's replaced by
+// a DownTrack when the participant subscribes to a track
+type placeholderTrack struct {
+	id       string
+	streamID string
+	kind     webrtc.RTPCodecType
+}
+
+func newPlaceholderTrack(id, streamID string, kind webrtc.RTPCodecType) *placeholderTrack {
+	return &placeholderTrack{
+		id:       id,
+		streamID: streamID,
+		kind:     kind,
+	}
+}
+
+// Bind accepts any of the negotiated codecs, nothing is written until the track is replaced. the DownTrack
+// replacing it binds the codec of its own track, as long as the client offered it
+func (t *placeholderTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	codecs := ctx.CodecParameters()
+	if len(codecs) == 0 {
+		return webrtc.RTPCodecParameters{}, webrtc.ErrUnsupportedCodec
+	}
+	return codecs[0], nil
+}
+
+func (t *placeholderTrack) Unbind(_ webrtc.TrackLocalContext) error {
+	return nil
+}
+
+func (t *placeholderTrack) ID() string {
+	return t.id
+}
+
+func (t *placeholderTrack) StreamID() string {
+	return t.streamID
+}
+
+func (t *placeholderTrack) Kind() webrtc.RTPCodecType {
+	return t.kind
+}
@@ -22,6 +22,11 @@ const (
 	DefaultEmptyTimeout       = 5 * 60 // 5m
 	DefaultRoomDepartureGrace = 20
 	AudioLevelQuantization    = 8 // ideally power of 2 to minimize float decimal
+
+	// reasons data packets are dropped
+	dataDropPayloadSize     = "payload_size"
+	dataDropParticipantRate = "participant_rate"
+	dataDropRoomRate        = "room_rate"
 )
 
 type Room struct {
@@ -45,24 +50,58 @@ type Room struct {
 
 	statsReporter *stats.RoomStatsReporter
 
-	onParticipantChanged func(p types.Participant)
-	onClose              func()
+	options RoomOptions
+	// video publishers ranked by how recently they've spoken, for last-N
+	lastNLock    sync.Mutex
+	lastNRanking []string
+	// top of the ranking subscribers were last limited to
+	lastNTop []string
+
+	dataLimits      config.DataLimits
+	roomDataLimiter *dataLimiter
+	// by participant identity
+	dataLimiters map[string]*dataLimiter
+	// participants being disconnected for going over data limits, by participant ID
+	dataLimitDisconnects map[string]bool
+
+	dataHistoryLock sync.Mutex
+	dataHistory     []*DataHistoryEntry
+
+	// set through the Room Service, it isn't part of the protocol yet
+	metadata string
+
+	onParticipantChanged       func(p types.Participant)
+	onConnectionQualityChanged func(p types.Participant, quality types.ConnectionQuality)
+	onDataHistoryUpdate        func()
+	onClose                    func()
 }
 
 type ParticipantOptions struct {
 	AutoSubscribe bool
 }
 
+// RoomOptions are room settings that aren't part of the protocol
+type RoomOptions struct {
+	// when set, subscribers receive video only from the N most recent active speakers, and tracks they've pinned
+	LastN uint32 `json:"last_n"`
+	// reliable user packets are replayed to participants that join later, up to DataHistorySize of them,
+	// sent within the last DataHistorySeconds. either is unlimited when 0, history is off when both are
+	DataHistorySize    uint32 `json:"data_history_size"`
+	DataHistorySeconds uint32 `json:"data_history_seconds"`
+}
+
 func NewRoom(room *livekit.Room, config WebRTCConfig, iceServers []*livekit.ICEServer, audioConfig *config.AudioConfig) *Room {
 	r := &Room{
-		Room:            proto.Clone(room).(*livekit.Room),
-		config:          config,
-		iceServers:      iceServers,
-		audioConfig:     audioConfig,
-		statsReporter:   stats.NewRoomStatsReporter(room.Name),
-		participants:    make(map[string]types.Participant),
-		participantOpts: make(map[string]*ParticipantOptions),
-		bufferFactory:   buffer.NewBufferFactory(config.Receiver.packetBufferSize, logger.GetLogger()),
+		Room:                 proto.Clone(room).(*livekit.Room),
+		config:               config,
+		iceServers:           iceServers,
+		audioConfig:          audioConfig,
+		statsReporter:        stats.NewRoomStatsReporter(room.Name),
+		participants:         make(map[string]types.Participant),
+		participantOpts:      make(map[string]*ParticipantOptions),
+		dataLimiters:         make(map[string]*dataLimiter),
+		dataLimitDisconnects: make(map[string]bool),
+		bufferFactory:        buffer.NewBufferFactory(config.Receiver.packetBufferSize, logger.GetLogger()),
 	}
 	if r.Room.EmptyTimeout == 0 {
 		r.Room.EmptyTimeout = DefaultEmptyTimeout
@@ -72,6 +111,7 @@ func NewRoom(room *livekit.Room, config WebRTCConfig, iceServers []*livekit.ICES
 	}
 	r.statsReporter.RoomStarted()
 	go r.audioUpdateWorker()
+	go r.connectionQualityWorker()
 	return r
 }
 
@@ -112,6 +152,72 @@ func (r *Room) GetActiveSpeakers() []*livekit.SpeakerInfo {
 	return speakers
 }
 
+func (r *Room) Options() RoomOptions {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.options
+}
+
+func (r *Room) Metadata() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.metadata
+}
+
+// SetMetadata updates the room's metadata, and sends it to everyone in the room
+func (r *Room) SetMetadata(metadata string) {
+	r.lock.Lock()
+	changed := r.metadata != metadata
+	r.metadata = metadata
+	r.lock.Unlock()
+	if !changed {
+		return
+	}
+
+	for _, p := range r.GetParticipants() {
+		if p.State() == livekit.ParticipantInfo_ACTIVE {
+			r.sendMetadata(p, metadata)
+		}
+	}
+}
+
+func (r *Room) sendMetadata(p types.Participant, metadata string) {
+	err := p.SendControlMessage(&types.ControlMessage{
+		RoomMetadataUpdate: &types.RoomMetadataUpdate{
+			Metadata: metadata,
+		},
+	})
+	if err != nil {
+		logger.Warnw("could not send room metadata", err,
+			"participant", p.Identity(), "pID", p.ID(), "room", r.Room.Name)
+	}
+}
+
+// SetDataLimits limits data packets sent by participants
+func (r *Room) SetDataLimits(limits config.DataLimits) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.dataLimits = limits
+	r.roomDataLimiter = newDataLimiter(limits.RoomPacketsPerSecond, limits.RoomPacketBurst,
+		limits.RoomBytesPerSecond, limits.RoomByteBurst, limits.MaxPayloadSize)
+	r.dataLimiters = make(map[string]*dataLimiter)
+	r.dataLimitDisconnects = make(map[string]bool)
+}
+
+func (r *Room) SetOptions(opts RoomOptions) {
+	r.lock.Lock()
+	lastN := r.options.LastN
+	r.options = opts
+	r.lock.Unlock()
+
+	if opts.LastN == 0 && lastN != 0 {
+		for _, p := range r.GetParticipants() {
+			p.SetLastNPublishers(nil)
+		}
+	}
+	r.updateLastN(nil)
+}
+
 func (r *Room) GetStatsReporter() *stats.RoomStatsReporter {
 	return r.statsReporter
 }
@@ -137,6 +243,31 @@ func (r *Room) LastLeftAt() int64 {
 }
 
 func (r *Room) Join(participant types.Participant, opts *ParticipantOptions) error {
+	if err := r.addParticipant(participant, opts); err != nil {
+		return err
+	}
+
+	time.AfterFunc(time.Minute, func() {
+		state := participant.State()
+		if state == livekit.ParticipantInfo_JOINING || state == livekit.ParticipantInfo_JOINED {
+			r.RemoveParticipant(participant.Identity())
+		}
+	})
+
+	if err := r.sendJoinResponse(participant); err != nil {
+		return err
+	}
+
+	if participant.ProtocolVersion().SubscriberAsPrimary() {
+		// initiates sub connection as primary
+		participant.Negotiate()
+	}
+
+	return nil
+}
+
+// addParticipant takes over the participant's callbacks, and adds it to the room
+func (r *Room) addParticipant(participant types.Participant, opts *ParticipantOptions) error {
 	if r.isClosed.Get() {
 		return ErrRoomClosed
 	}
@@ -148,7 +279,8 @@ func (r *Room) Join(participant types.Participant, opts *ParticipantOptions) err
 		return ErrAlreadyJoined
 	}
 
-	if r.Room.MaxParticipants > 0 && int(r.Room.MaxParticipants) == len(r.participants) {
+	// relays of participants on other nodes were let in there
+	if r.Room.MaxParticipants > 0 && !participant.Relayed() && int(r.Room.MaxParticipants) <= r.numLocalParticipants() {
 		return ErrMaxParticipantsExceeded
 	}
 
@@ -160,31 +292,7 @@ func (r *Room) Join(participant types.Participant, opts *ParticipantOptions) err
 
 	// it's important to set this before connection, we don't want to miss out on any publishedTracks
 	participant.OnTrackPublished(r.onTrackPublished)
-	participant.OnStateChange(func(p types.Participant, oldState livekit.ParticipantInfo_State) {
-		logger.Debugw("participant state changed", "state", p.State(), "participant", p.Identity(), "pID", p.ID(),
-			"oldState", oldState)
-		if r.onParticipantChanged != nil {
-			r.onParticipantChanged(participant)
-		}
-		r.broadcastParticipantState(p, true)
-
-		state := p.State()
-		if state == livekit.ParticipantInfo_ACTIVE {
-			if p.UpdateAfterActive() {
-				_ = p.SendParticipantUpdate(ToProtoParticipants(r.GetParticipants()))
-			}
-
-			// subscribe participant to existing publishedTracks
-			r.subscribeToExistingTracks(p)
-
-			// start the workers once connectivity is established
-			p.Start()
-
-		} else if state == livekit.ParticipantInfo_DISCONNECTED {
-			// remove participant from room
-			go r.RemoveParticipant(p.Identity())
-		}
-	})
+	participant.OnStateChange(r.onParticipantStateChange)
 	participant.OnTrackUpdated(r.onTrackUpdated)
 	participant.OnMetadataUpdate(r.onParticipantMetadataUpdate)
 	participant.OnDataPacket(r.onDataPacket)
@@ -197,43 +305,141 @@ func (r *Room) Join(participant types.Participant, opts *ParticipantOptions) err
 	r.participants[participant.Identity()] = participant
 	r.participantOpts[participant.Identity()] = opts
 
-	// gather other participants and send join response
-	otherParticipants := make([]types.Participant, 0, len(r.participants))
-	for _, p := range r.participants {
-		if p.ID() != participant.ID() && !p.Hidden() {
-			otherParticipants = append(otherParticipants, p)
-		}
+	if r.onParticipantChanged != nil {
+		r.onParticipantChanged(participant)
 	}
+	return nil
+}
 
+func (r *Room) onParticipantStateChange(p types.Participant, oldState livekit.ParticipantInfo_State) {
+	logger.Debugw("participant state changed", "state", p.State(), "participant", p.Identity(), "pID", p.ID(),
+		"oldState", oldState)
 	if r.onParticipantChanged != nil {
-		r.onParticipantChanged(participant)
+		r.onParticipantChanged(p)
 	}
+	r.broadcastParticipantState(p, true)
 
-	time.AfterFunc(time.Minute, func() {
-		state := participant.State()
-		if state == livekit.ParticipantInfo_JOINING || state == livekit.ParticipantInfo_JOINED {
-			r.RemoveParticipant(participant.Identity())
+	state := p.State()
+	if state == livekit.ParticipantInfo_ACTIVE {
+		if p.UpdateAfterActive() {
+			_ = p.SendParticipantUpdate(ToProtoParticipants(r.GetParticipants()))
 		}
-	})
 
-	if err := participant.SendJoinResponse(r.Room, otherParticipants, r.iceServers); err != nil {
+		// subscribe participant to existing publishedTracks
+		r.updateLastN(nil)
+		r.subscribeToExistingTracks(p)
+		r.sendDataHistory(p)
+		if metadata := r.Metadata(); metadata != "" {
+			r.sendMetadata(p, metadata)
+		}
+
+		// start the workers once connectivity is established
+		p.Start()
+
+	} else if state == livekit.ParticipantInfo_DISCONNECTED {
+		// remove participant from room
+		go r.RemoveParticipant(p.Identity())
+	}
+}
+
+// sendJoinResponse sends the room, and everyone else visible in it, to the participant
+func (r *Room) sendJoinResponse(participant types.Participant) error {
+	participants := r.GetParticipants()
+	otherParticipants := make([]types.Participant, 0, len(participants))
+	for _, p := range participants {
+		if p.ID() != participant.ID() && !p.Hidden() {
+			otherParticipants = append(otherParticipants, p)
+		}
+	}
+	return participant.SendJoinResponse(r.Room, otherParticipants, r.iceServers)
+}
+
+// MoveParticipant moves a participant to another room, keeping its connections. it's sent a join response
+// for the new room, and tracks are subscribed to like it had just become active there.
+// packet stats of tracks it had published are still counted by the room it first joined
+func (r *Room) MoveParticipant(identity string, to *Room) error {
+	r.lock.RLock()
+	p := r.participants[identity]
+	opts := r.participantOpts[identity]
+	r.lock.RUnlock()
+	if p == nil {
+		return ErrParticipantNotInRoom
+	}
+	if to == r {
+		return ErrAlreadyJoined
+	}
+
+	if err := to.addParticipant(p, opts); err != nil {
 		return err
 	}
+	r.detachParticipant(p)
 
-	if participant.ProtocolVersion().SubscriberAsPrimary() {
-		// initiates sub connection as primary
-		participant.Negotiate()
+	if err := to.sendJoinResponse(p); err != nil {
+		to.RemoveParticipant(identity)
+		return err
+	}
+	to.broadcastParticipantState(p, true)
+	if p.State() != livekit.ParticipantInfo_ACTIVE {
+		// subscriptions are made when it becomes active
+		return nil
 	}
 
+	to.updateLastN(nil)
+	to.subscribeToExistingTracks(p)
+	to.subscribeToParticipant(p)
+	to.sendDataHistory(p)
+	if metadata := to.Metadata(); metadata != "" {
+		to.sendMetadata(p, metadata)
+	}
 	return nil
 }
 
+// detachParticipant removes a participant that's joined another room, without closing it
+func (r *Room) detachParticipant(p types.Participant) {
+	r.lock.Lock()
+	delete(r.participants, p.Identity())
+	delete(r.participantOpts, p.Identity())
+	delete(r.dataLimiters, p.Identity())
+	delete(r.dataLimitDisconnects, p.ID())
+	remaining := len(r.participants)
+	r.lock.Unlock()
+	r.statsReporter.SubParticipant()
+	if remaining == 0 {
+		r.leftAt.Store(time.Now().Unix())
+	}
+
+	// tracks of the room are no longer sent to it, and its tracks no longer sent to the room
+	for _, op := range r.GetParticipants() {
+		op.RemoveSubscriber(p.ID())
+		p.RemoveSubscriber(op.ID())
+	}
+	r.updateLastN(nil)
+
+	if p.Hidden() {
+		return
+	}
+	// others see it leave
+	info := p.ToProto()
+	info.State = livekit.ParticipantInfo_DISCONNECTED
+	for _, op := range r.GetParticipants() {
+		if op.State() == livekit.ParticipantInfo_DISCONNECTED {
+			continue
+		}
+		if err := op.SendParticipantUpdate([]*livekit.ParticipantInfo{info}); err != nil {
+			logger.Errorw("could not send update to participant", err,
+				"participant", p.Identity(), "pID", p.ID())
+		}
+	}
+}
+
 func (r *Room) RemoveParticipant(identity string) {
 	r.lock.Lock()
 	p, ok := r.participants[identity]
 	if ok {
 		delete(r.participants, identity)
 		delete(r.participantOpts, identity)
+		delete(r.dataLimiters, identity)
+		delete(r.dataLimitDisconnects, p.ID())
 	}
 	r.lock.Unlock()
 	if !ok {
@@ -253,6 +459,9 @@ func (r *Room) RemoveParticipant(identity string) {
 	// close participant as well
 	_ = p.Close()
 
+	// free up its last-N slot
+	r.updateLastN(nil)
+
 	r.lock.RLock()
 	if len(r.participants) == 0 {
 		r.leftAt.Store(time.Now().Unix())
@@ -307,7 +516,8 @@ func (r *Room) CloseIfEmpty() {
 	r.lock.RLock()
 	visibleParticipants := 0
 	for _, p := range r.participants {
-		if !p.Hidden() {
+		// relays are kept until the participants on this node have left
+		if !p.Hidden() && !p.Relayed() {
 			visibleParticipants++
 		}
 	}
@@ -334,6 +544,24 @@ func (r *Room) CloseIfEmpty() {
 	}
 }
 
+// NumLocalParticipants counts participants connected to this node, which doesn't include relays
+func (r *Room) NumLocalParticipants() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.numLocalParticipants()
+}
+
+// expects lock to be held
+func (r *Room) numLocalParticipants() int {
+	count := 0
+	for _, p := range r.participants {
+		if !p.Relayed() && p.State() != livekit.ParticipantInfo_DISCONNECTED {
+			count++
+		}
+	}
+	return count
+}
+
 func (r *Room) Close() {
 	if !r.isClosed.TrySet(true) {
 		return
@@ -362,6 +590,27 @@ func (r *Room) OnParticipantChanged(f func(participant types.Participant)) {
 	r.onParticipantChanged = f
 }
 
+// OnConnectionQualityChanged is called when a participant's connection quality rating changes
+func (r *Room) OnConnectionQualityChanged(f func(participant types.Participant, quality types.ConnectionQuality)) {
+	r.onConnectionQualityChanged = f
+}
+
+// OnDataHistoryUpdate is called when a packet is added to the data history
+func (r *Room) OnDataHistoryUpdate(f func()) {
+	r.onDataHistoryUpdate = f
+}
+
+// DataHistory returns packets that are replayed to participants as they join, oldest first
+func (r *Room) DataHistory() []*DataHistoryEntry {
+	opts := r.Options()
+	r.dataHistoryLock.Lock()
+	defer r.dataHistoryLock.Unlock()
+	r.dataHistory = opts.TrimDataHistory(r.dataHistory, time.Now())
+	history := make([]*DataHistoryEntry, len(r.dataHistory))
+	copy(history, r.dataHistory)
+	return history
+}
+
 func (r *Room) SendDataPacket(up *livekit.UserPacket, kind livekit.DataPacket_Kind) {
 	dp := &livekit.DataPacket{
 		Kind: kind,
@@ -391,6 +640,10 @@ func (r *Room) onTrackPublished(participant types.Participant, track types.Publi
 	// publish participant update, since track state is changed
 	r.broadcastParticipantState(participant, true)
 
+	if track.Kind() == livekit.TrackType_VIDEO {
+		r.updateLastN(nil)
+	}
+
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 
@@ -446,7 +699,14 @@ func (r *Room) onDataPacket(source types.Participant, dp *livekit.DataPacket) {
 	if source != nil && !source.CanPublishData() {
 		return
 	}
+	if source != nil && !r.allowDataPacket(source, dp) {
+		return
+	}
 	dest := dp.GetUser().GetDestinationSids()
+	// only packets sent to everyone are kept
+	if len(dest) == 0 {
+		r.keepDataPacket(dp)
+	}
 
 	for _, op := range r.GetParticipants() {
 		if op.State() != livekit.ParticipantInfo_ACTIVE {
@@ -471,6 +731,87 @@ func (r *Room) onDataPacket(source types.Participant, dp *livekit.DataPacket) {
 	}
 }
 
+func (r *Room) keepDataPacket(dp *livekit.DataPacket) {
+	user := dp.GetUser()
+	opts := r.Options()
+	if user == nil || dp.Kind != livekit.DataPacket_RELIABLE || !opts.KeepsDataHistory() {
+		return
+	}
+
+	entry := &DataHistoryEntry{
+		ParticipantSid: user.ParticipantSid,
+		Payload:        user.Payload,
+		SentAt:         time.Now(),
+	}
+	r.dataHistoryLock.Lock()
+	r.dataHistory = opts.TrimDataHistory(append(r.dataHistory, entry), entry.SentAt)
+	r.dataHistoryLock.Unlock()
+
+	if r.onDataHistoryUpdate != nil {
+		r.onDataHistoryUpdate()
+	}
+}
+
+func (r *Room) sendDataHistory(p types.Participant) {
+	for _, entry := range r.DataHistory() {
+		if err := p.SendDataPacket(entry.ToDataPacket()); err != nil {
+			logger.Warnw("could not send data history", err,
+				"participant", p.Identity(), "pID", p.ID(), "room", r.Room.Name)
+			return
+		}
+	}
+}
+
+// allowDataPacket checks a packet against data limits, counting it when it's dropped. it's only counted
+// against the participant's and the room's rates when it's within both
+func (r *Room) allowDataPacket(source types.Participant, dp *livekit.DataPacket) bool {
+	r.lock.Lock()
+	limits := r.dataLimits
+	roomLimiter := r.roomDataLimiter
+	limiter := r.dataLimiters[source.Identity()]
+	if limiter == nil && (limits.ParticipantPacketsPerSecond > 0 || limits.ParticipantBytesPerSecond > 0) {
+		limiter = newDataLimiter(limits.ParticipantPacketsPerSecond, limits.ParticipantPacketBurst,
+			limits.ParticipantBytesPerSecond, limits.ParticipantByteBurst, limits.MaxPayloadSize)
+		r.dataLimiters[source.Identity()] = limiter
+	}
+	r.lock.Unlock()
+
+	size := len(dp.GetUser().GetPayload())
+	reason := ""
+	if limits.MaxPayloadSize > 0 && size > limits.MaxPayloadSize {
+		reason = dataDropPayloadSize
+	} else if over := allowData(time.Now(), size, limiter, roomLimiter); over == nil {
+		return true
+	} else if over == limiter {
+		reason = dataDropParticipantRate
+	} else {
+		reason = dataDropRoomRate
+	}
+
+	r.statsReporter.DataPacketDropped(reason)
+	if limits.Disconnect && r.startDataLimitDisconnect(source) {
+		logger.Infow("disconnecting participant over data limit",
+			"participant", source.Identity(), "pID", source.ID(),
+			"room", r.Room.Name, "reason", reason)
+		go r.RemoveParticipant(source.Identity())
+	}
+	return false
+}
+
+// startDataLimitDisconnect is true the first time a participant that's still in the room goes over a limit
+func (r *Room) startDataLimitDisconnect(source types.Participant) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if p := r.participants[source.Identity()]; p == nil || p.ID() != source.ID() {
+		return false
+	}
+	if r.dataLimitDisconnects[source.ID()] {
+		return false
+	}
+	r.dataLimitDisconnects[source.ID()] = true
+	return true
+}
+
 func (r *Room) subscribeToExistingTracks(p types.Participant) {
 	r.lock.RLock()
 	shouldSubscribe := r.autoSubscribe(p)
@@ -499,6 +840,26 @@ func (r *Room) subscribeToExistingTracks(p types.Participant) {
 	}
 }
 
+// subscribeToParticipant subscribes active participants in the room to tracks p has published
+func (r *Room) subscribeToParticipant(p types.Participant) {
+	for _, op := range r.GetParticipants() {
+		if op.ID() == p.ID() || op.State() != livekit.ParticipantInfo_ACTIVE {
+			continue
+		}
+		r.lock.RLock()
+		shouldSubscribe := r.autoSubscribe(op)
+		r.lock.RUnlock()
+		if !shouldSubscribe {
+			continue
+		}
+		if _, err := p.AddSubscriber(op); err != nil {
+			logger.Errorw("could not subscribe to participant", err,
+				"participants", []string{p.Identity(), op.Identity()},
+				"pIDs", []string{p.ID(), op.ID()})
+		}
+	}
+}
+
 // broadcast an update about participant p
 func (r *Room) broadcastParticipantState(p types.Participant, skipSource bool) {
 	if p.Hidden() {
@@ -540,7 +901,9 @@ func (r *Room) sendSpeakerUpdates(speakers []*livekit.SpeakerInfo) {
 		},
 	}
 
+	r.updateLastN(speakers)
 	for _, p := range r.GetParticipants() {
+		p.UpdateActiveSpeakers(speakers)
 		if p.ProtocolVersion().HandlesDataPackets() {
 			_ = p.SendDataPacket(dp)
 		} else {
@@ -549,6 +912,146 @@ func (r *Room) sendSpeakerUpdates(speakers []*livekit.SpeakerInfo) {
 	}
 }
 
+// updateLastN limits each subscriber to video from the top N publishers of the ranking. speaker updates move
+// active speakers to the front, and subscribers are only updated when that changes who's forwarded. without
+// speakers, publishers that joined or left are re-ranked, and every subscriber is updated
+func (r *Room) updateLastN(speakers []*livekit.SpeakerInfo) {
+	lastN := int(r.Options().LastN)
+	if lastN == 0 {
+		return
+	}
+
+	var participants []types.Participant
+	if speakers == nil {
+		participants = r.GetParticipants()
+	}
+
+	r.lastNLock.Lock()
+	if speakers == nil {
+		r.lastNRanking = rankLastN(r.lastNRanking, videoPublishers(participants))
+		r.lastNTop = nil
+	} else {
+		promoteSpeakers(r.lastNRanking, speakers)
+	}
+	top := r.lastNRanking
+	if len(top) > lastN+1 {
+		top = top[:lastN+1]
+	}
+	changed := r.lastNTop == nil || !sameLastN(r.lastNTop, top, lastN)
+	if changed {
+		r.lastNTop = append(make([]string, 0, len(top)), top...)
+		top = r.lastNTop
+	}
+	r.lastNLock.Unlock()
+	if !changed {
+		return
+	}
+	if participants == nil {
+		participants = r.GetParticipants()
+	}
+
+	forwarded := top
+	if len(forwarded) > lastN {
+		forwarded = forwarded[:lastN]
+	}
+	for _, p := range participants {
+		pForwarded := forwarded
+		for i, pID := range forwarded {
+			// subscribers don't take up a slot with their own video
+			if pID == p.ID() {
+				pForwarded = make([]string, 0, lastN)
+				pForwarded = append(pForwarded, top[:i]...)
+				pForwarded = append(pForwarded, top[i+1:]...)
+				break
+			}
+		}
+		p.SetLastNPublishers(pForwarded)
+	}
+}
+
+func videoPublishers(participants []types.Participant) []types.Participant {
+	publishers := make([]types.Participant, 0, len(participants))
+	for _, p := range participants {
+		for _, track := range p.GetPublishedTracks() {
+			if track.Kind() == livekit.TrackType_VIDEO {
+				publishers = append(publishers, p)
+				break
+			}
+		}
+	}
+	return publishers
+}
+
+// rankLastN keeps the ranking of publishers, adding those that haven't been ranked to the back in the order
+// they joined, and dropping those that have left
+func rankLastN(ranking []string, publishers []types.Participant) []string {
+	isPublisher := make(map[string]bool, len(publishers))
+	for _, p := range publishers {
+		isPublisher[p.ID()] = true
+	}
+
+	ranked := make(map[string]bool, len(publishers))
+	next := make([]string, 0, len(publishers))
+	add := func(pID string) {
+		if isPublisher[pID] && !ranked[pID] {
+			ranked[pID] = true
+			next = append(next, pID)
+		}
+	}
+	for _, pID := range ranking {
+		add(pID)
+	}
+
+	sort.SliceStable(publishers, func(i, j int) bool {
+		return publishers[i].ConnectedAt().Before(publishers[j].ConnectedAt())
+	})
+	for _, p := range publishers {
+		add(p.ID())
+	}
+	return next
+}
+
+// promoteSpeakers moves active speakers that are ranked to the front of the ranking, loudest first
+func promoteSpeakers(ranking []string, speakers []*livekit.SpeakerInfo) {
+	for i := len(speakers) - 1; i >= 0; i-- {
+		if !speakers[i].Active {
+			continue
+		}
+		for j, pID := range ranking {
+			if pID == speakers[i].Sid {
+				copy(ranking[1:j+1], ranking[:j])
+				ranking[0] = pID
+				break
+			}
+		}
+	}
+}
+
+// sameLastN is true when the same publishers are forwarded with either top of a ranking: the first n, along
+// with the next one for subscribers among them
+func sameLastN(a, b []string, n int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) > n && a[n] != b[n] {
+		return false
+	}
+	top := len(a)
+	if top > n {
+		top = n
+	}
+	inA := make(map[string]bool, top)
+	for _, pID := range a[:top] {
+		inA[pID] = true
+	}
+	for _, pID := range b[:top] {
+		if !inA[pID] {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *Room) audioUpdateWorker() {
 	var smoothValues map[string]float32
 	var smoothFactor float32
@@ -623,6 +1126,48 @@ func (r *Room) audioUpdateWorker() {
 	}
 }
 
+func (r *Room) connectionQualityWorker() {
+	// map of participant sid -> last rating
+	lastQualities := make(map[string]types.ConnectionQuality)
+	for {
+		time.Sleep(connectionQualityUpdateInterval)
+		if r.isClosed.Get() {
+			return
+		}
+
+		participants := r.GetParticipants()
+		update := &types.ConnectionQualityUpdate{}
+		qualities := make(map[string]types.ConnectionQuality, len(participants))
+		for _, p := range participants {
+			if p.State() != livekit.ParticipantInfo_ACTIVE {
+				continue
+			}
+			quality := p.GetConnectionQuality()
+			qualities[p.ID()] = quality
+			if last, ok := lastQualities[p.ID()]; (!ok || last != quality) && r.onConnectionQualityChanged != nil {
+				r.onConnectionQualityChanged(p, quality)
+			}
+			if p.Hidden() {
+				continue
+			}
+			update.Updates = append(update.Updates, types.ConnectionQualityInfo{
+				ParticipantSid: p.ID(),
+				Quality:        quality,
+			})
+		}
+		lastQualities = qualities
+
+		if len(update.Updates) == 0 {
+			continue
+		}
+		for _, p := range participants {
+			_ = p.SendControlMessage(&types.ControlMessage{
+				ConnectionQualityUpdate: update,
+			})
+		}
+	}
+}
+
 func (r *Room) DebugInfo() map[string]interface{} {
 	info := map[string]interface{}{
 		"Name":      r.Room.Name,
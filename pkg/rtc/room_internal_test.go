@@ -0,0 +1,28 @@
+package rtc
+
+import (
+	"testing"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoteSpeakers(t *testing.T) {
+	ranking := []string{"a", "b", "c", "d"}
+	promoteSpeakers(ranking, []*livekit.SpeakerInfo{
+		{Sid: "c", Active: true},
+		{Sid: "unranked", Active: true},
+		{Sid: "b", Active: false},
+		{Sid: "d", Active: true},
+	})
+	require.Equal(t, []string{"c", "d", "a", "b"}, ranking)
+}
+
+func TestSameLastN(t *testing.T) {
+	// order within the top doesn't change who's forwarded
+	require.True(t, sameLastN([]string{"a", "b", "c"}, []string{"b", "a", "c"}, 2))
+	// subscribers among the top are forwarded the next one
+	require.False(t, sameLastN([]string{"a", "b", "c"}, []string{"a", "b", "d"}, 2))
+	require.False(t, sameLastN([]string{"a", "b", "c"}, []string{"a", "c", "b"}, 2))
+	require.False(t, sameLastN([]string{"a", "b"}, []string{"a", "b", "c"}, 2))
+}
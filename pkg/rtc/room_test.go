@@ -392,6 +392,45 @@ func TestActiveSpeakers(t *testing.T) {
 	})
 }
 
+func TestLastN(t *testing.T) {
+	t.Run("video is forwarded from the most recent speakers", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 4, protocol: types.DefaultProtocol})
+		defer rm.Close()
+		participants := rm.GetParticipants()
+		joined := time.Now()
+		for i, p := range participants {
+			p := p.(*typesfakes.FakeParticipant)
+			p.ConnectedAtReturns(joined.Add(time.Duration(i) * time.Second))
+			p.GetPublishedTracksReturns([]types.PublishedTrack{newMockTrack(livekit.TrackType_VIDEO, "webcam")})
+		}
+		lastForwarded := func(p types.Participant) []string {
+			fp := p.(*typesfakes.FakeParticipant)
+			if fp.SetLastNPublishersCallCount() == 0 {
+				return nil
+			}
+			return fp.SetLastNPublishersArgsForCall(fp.SetLastNPublishersCallCount() - 1)
+		}
+
+		rm.SetOptions(rtc.RoomOptions{LastN: 2})
+
+		// before anyone speaks, those who joined first are forwarded, excluding the subscriber itself
+		require.Equal(t, []string{participants[1].ID(), participants[2].ID()}, lastForwarded(participants[0]))
+		require.Equal(t, []string{participants[0].ID(), participants[1].ID()}, lastForwarded(participants[3]))
+
+		participants[3].(*typesfakes.FakeParticipant).GetAudioLevelReturns(10, true)
+		testutils.WithTimeout(t, "speaker moves to the front", func() bool {
+			forwarded := lastForwarded(participants[0])
+			return len(forwarded) == 2 && forwarded[0] == participants[3].ID() && forwarded[1] == participants[1].ID()
+		})
+
+		// disabling removes the limit
+		rm.SetOptions(rtc.RoomOptions{})
+		for _, p := range participants {
+			require.Nil(t, lastForwarded(p))
+		}
+	})
+}
+
 func TestDataChannel(t *testing.T) {
 	t.Parallel()
 
@@ -477,6 +516,197 @@ func TestDataChannel(t *testing.T) {
 			require.Zero(t, fp.SendDataPacketCallCount())
 		}
 	})
+
+	t.Run("packets over limits are dropped", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close()
+		rm.SetDataLimits(config.DataLimits{
+			MaxPayloadSize:              10,
+			ParticipantPacketsPerSecond: 2,
+		})
+		participants := rm.GetParticipants()
+		p := participants[0].(*typesfakes.FakeParticipant)
+		p1 := participants[1].(*typesfakes.FakeParticipant)
+
+		send := func(payload string) {
+			p.OnDataPacketArgsForCall(0)(p, &livekit.DataPacket{
+				Kind: livekit.DataPacket_RELIABLE,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{
+						Payload: []byte(payload),
+					},
+				},
+			})
+		}
+		send("too large payload")
+		require.Zero(t, p1.SendDataPacketCallCount())
+
+		for i := 0; i < 5; i++ {
+			send("message")
+		}
+		require.Equal(t, 2, p1.SendDataPacketCallCount())
+		require.Zero(t, p1.CloseCallCount())
+	})
+
+	t.Run("participants over limits are disconnected once", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close()
+		rm.SetDataLimits(config.DataLimits{
+			ParticipantPacketsPerSecond: 1,
+			Disconnect:                  true,
+		})
+		p := rm.GetParticipants()[0].(*typesfakes.FakeParticipant)
+		onData := p.OnDataPacketArgsForCall(0)
+		for i := 0; i < 5; i++ {
+			onData(p, &livekit.DataPacket{
+				Kind: livekit.DataPacket_RELIABLE,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{
+						Payload: []byte("message"),
+					},
+				},
+			})
+		}
+		require.Eventually(t, func() bool {
+			return p.CloseCallCount() == 1
+		}, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 1, p.CloseCallCount())
+	})
+
+	t.Run("history is sent to participants that join later", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close()
+		rm.SetOptions(rtc.RoomOptions{DataHistorySize: 2})
+		p := rm.GetParticipants()[0].(*typesfakes.FakeParticipant)
+
+		for i, payload := range []string{"one", "two", "three"} {
+			packet := &livekit.DataPacket{
+				Kind: livekit.DataPacket_RELIABLE,
+				Value: &livekit.DataPacket_User{
+					User: &livekit.UserPacket{
+						ParticipantSid: p.ID(),
+						Payload:        []byte(payload),
+					},
+				},
+			}
+			if i == 1 {
+				// lossy packets aren't kept
+				packet.Kind = livekit.DataPacket_LOSSY
+			}
+			p.OnDataPacketArgsForCall(0)(p, packet)
+		}
+		history := rm.DataHistory()
+		require.Len(t, history, 2)
+		require.Equal(t, []byte("one"), history[0].Payload)
+		require.Equal(t, []byte("three"), history[1].Payload)
+
+		newP := newMockParticipant("new", types.DefaultProtocol, false)
+		require.NoError(t, rm.Join(newP, &rtc.ParticipantOptions{AutoSubscribe: true}))
+		require.Zero(t, newP.SendDataPacketCallCount())
+
+		newP.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		newP.OnStateChangeArgsForCall(0)(newP, livekit.ParticipantInfo_JOINED)
+		require.Equal(t, 2, newP.SendDataPacketCallCount())
+		require.Equal(t, []byte("one"), newP.SendDataPacketArgsForCall(0).GetUser().Payload)
+		require.Equal(t, p.ID(), newP.SendDataPacketArgsForCall(0).GetUser().ParticipantSid)
+		require.Equal(t, []byte("three"), newP.SendDataPacketArgsForCall(1).GetUser().Payload)
+	})
+}
+
+func TestRoomMetadata(t *testing.T) {
+	t.Run("metadata is sent to participants in the room", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close()
+
+		rm.SetMetadata("meta")
+		require.Equal(t, "meta", rm.Metadata())
+		for _, p := range rm.GetParticipants() {
+			fp := p.(*typesfakes.FakeParticipant)
+			require.Equal(t, 1, fp.SendControlMessageCallCount())
+			require.Equal(t, "meta", fp.SendControlMessageArgsForCall(0).RoomMetadataUpdate.Metadata)
+		}
+
+		// unchanged metadata isn't sent again
+		rm.SetMetadata("meta")
+		for _, p := range rm.GetParticipants() {
+			require.Equal(t, 1, p.(*typesfakes.FakeParticipant).SendControlMessageCallCount())
+		}
+	})
+
+	t.Run("metadata is sent to participants that join later", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer rm.Close()
+		rm.SetMetadata("meta")
+
+		p := newMockParticipant("new", types.DefaultProtocol, false)
+		require.NoError(t, rm.Join(p, &rtc.ParticipantOptions{AutoSubscribe: true}))
+		require.Zero(t, p.SendControlMessageCallCount())
+
+		p.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		p.OnStateChangeArgsForCall(0)(p, livekit.ParticipantInfo_JOINED)
+		require.Equal(t, 1, p.SendControlMessageCallCount())
+		require.Equal(t, "meta", p.SendControlMessageArgsForCall(0).RoomMetadataUpdate.Metadata)
+	})
+}
+
+func TestMoveParticipant(t *testing.T) {
+	t.Run("participant is moved with its connections", func(t *testing.T) {
+		from := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer from.Close()
+		to := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer to.Close()
+
+		p := newMockParticipant("mover", types.DefaultProtocol, false)
+		p.ToProtoReturns(&livekit.ParticipantInfo{Sid: p.ID(), Identity: p.Identity()})
+		require.NoError(t, from.Join(p, &rtc.ParticipantOptions{AutoSubscribe: true}))
+		p.StateReturns(livekit.ParticipantInfo_ACTIVE)
+
+		require.NoError(t, from.MoveParticipant(p.Identity(), to))
+		require.Nil(t, from.GetParticipant(p.Identity()))
+		require.Equal(t, p, to.GetParticipant(p.Identity()))
+		require.Zero(t, p.CloseCallCount())
+
+		// a join response for each room
+		require.Equal(t, 2, p.SendJoinResponseCallCount())
+		info, others, _ := p.SendJoinResponseArgsForCall(1)
+		require.Equal(t, to.Room, info)
+		require.Len(t, others, 2)
+
+		// no longer sent to each other, and it's seen leaving
+		for _, op := range from.GetParticipants() {
+			fp := op.(*typesfakes.FakeParticipant)
+			require.Equal(t, p.ID(), fp.RemoveSubscriberArgsForCall(0))
+			updates := fp.SendParticipantUpdateArgsForCall(fp.SendParticipantUpdateCallCount() - 1)
+			require.Equal(t, livekit.ParticipantInfo_DISCONNECTED, updates[0].State)
+		}
+		require.Equal(t, 2, p.RemoveSubscriberCallCount())
+
+		// subscribed to each other
+		for _, op := range to.GetParticipants() {
+			if op == p {
+				continue
+			}
+			fp := op.(*typesfakes.FakeParticipant)
+			require.Equal(t, p, fp.AddSubscriberArgsForCall(fp.AddSubscriberCallCount()-1))
+		}
+		require.Equal(t, 2, p.AddSubscriberCallCount())
+	})
+
+	t.Run("participant is kept when the room is full", func(t *testing.T) {
+		from := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer from.Close()
+		to := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer to.Close()
+		to.Room.MaxParticipants = 1
+
+		p := newMockParticipant("mover", types.DefaultProtocol, false)
+		require.NoError(t, from.Join(p, nil))
+
+		require.Equal(t, rtc.ErrMaxParticipantsExceeded, from.MoveParticipant(p.Identity(), to))
+		require.Equal(t, p, from.GetParticipant(p.Identity()))
+		require.Nil(t, to.GetParticipant(p.Identity()))
+	})
 }
 
 func TestHiddenParticipants(t *testing.T) {
@@ -0,0 +1,307 @@
+package rtc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/interceptor"
+	"github.com/pion/ion-sfu/pkg/buffer"
+	"github.com/pion/ion-sfu/pkg/sfu"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// sender reports are sent this often, as they are to WebRTC subscribers
+	rtpEgressReportInterval = 5 * time.Second
+)
+
+// RTPEgress is a subscriber that forwards a published track as plain RTP to a UDP destination.
+// like a WebRTC subscriber it is a DownTrack of the track's receiver, so it has its own SSRC and sequence numbers,
+// starts on a keyframe, follows simulcast layer switches and answers NACKs.
+// RTCP is sent to the next port up, as is the convention for RTP/AVP. PLI, FIR and NACK from the receiver
+// are accepted on either port.
+// local egress passes packets to a function instead, for forwarding within the node
+type RTPEgress struct {
+	id         string
+	ssrc       uint32
+	addr       *net.UDPAddr
+	codec      webrtc.RTPCodecParameters
+	downTrack  *sfu.DownTrack
+	sender     *webrtc.RTPSender
+	rtcpReader *buffer.RTCPReader
+	rtpConn    *net.UDPConn
+	rtcpConn   *net.UDPConn
+	isClosed   utils.AtomicFlag
+	// spatial layer requested, -1 for tracks that aren't simulcasted
+	spatialLayer int32
+
+	lock    sync.Mutex
+	onClose func()
+}
+
+func NewRTPEgress(id string, receiver sfu.Receiver, codec webrtc.RTPCodecParameters, addr *net.UDPAddr, packetBufferSize int) (*RTPEgress, error) {
+	rtpConn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	rtcpConn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: addr.IP, Port: addr.Port + 1, Zone: addr.Zone})
+	if err != nil {
+		_ = rtpConn.Close()
+		return nil, err
+	}
+	e := &RTPEgress{
+		id:           id,
+		addr:         addr,
+		codec:        codec,
+		rtpConn:      rtpConn,
+		rtcpConn:     rtcpConn,
+		spatialLayer: -1,
+	}
+	err = e.bind(receiver, packetBufferSize, func(header *rtp.Header, payload []byte) {
+		pkt := rtp.Packet{Header: *header, Payload: payload}
+		if data, err := pkt.Marshal(); err == nil {
+			// errors are ignored as the destination may not be listening yet
+			_, _ = rtpConn.Write(data)
+		}
+	})
+	if err != nil {
+		_ = rtpConn.Close()
+		_ = rtcpConn.Close()
+		return nil, err
+	}
+
+	go e.rtcpWorker(rtpConn)
+	go e.rtcpWorker(rtcpConn)
+	go e.reportWorker()
+	return e, nil
+}
+
+// NewLocalRTPEgress passes packets to writeRTP, with the same rewriting as RTP egress. keyframes are
+// asked for with RequestKeyFrame
+func NewLocalRTPEgress(id string, receiver sfu.Receiver, codec webrtc.RTPCodecParameters, packetBufferSize int,
+	writeRTP func(header *rtp.Header, payload []byte)) (*RTPEgress, error) {
+	e := &RTPEgress{
+		id:           id,
+		codec:        codec,
+		spatialLayer: -1,
+	}
+	if err := e.bind(receiver, packetBufferSize, writeRTP); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// bind creates the DownTrack and binds it to an RTP sender that passes packets to writeRTP.
+// the sender is not part of a PeerConnection, its codec is the publisher's, with the same payload type
+func (e *RTPEgress) bind(receiver sfu.Receiver, packetBufferSize int, writeRTP func(header *rtp.Header, payload []byte)) error {
+	// RTCP from the destination is delivered through the factory, to the DownTrack's reader
+	bufferFactory := buffer.NewBufferFactory(packetBufferSize, logger.GetLogger())
+	downTrack, err := sfu.NewDownTrack(webrtc.RTPCodecCapability{
+		MimeType:     e.codec.MimeType,
+		ClockRate:    e.codec.ClockRate,
+		Channels:     e.codec.Channels,
+		SDPFmtpLine:  e.codec.SDPFmtpLine,
+		RTCPFeedback: feedbackTypes,
+	}, receiver, bufferFactory, e.id, packetBufferSize)
+	if err != nil {
+		return err
+	}
+
+	me := &webrtc.MediaEngine{}
+	if err := me.RegisterCodec(e.codec, downTrack.Kind()); err != nil {
+		return err
+	}
+	ir := &interceptor.Registry{}
+	ir.Add(&rtpWriter{writeRTP: writeRTP})
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(me), webrtc.WithInterceptorRegistry(ir))
+
+	// the transport is never started, it's only needed to create the sender
+	dtls, err := api.NewDTLSTransport(nil, nil)
+	if err != nil {
+		return err
+	}
+	sender, err := api.NewRTPSender(downTrack, dtls)
+	if err != nil {
+		return err
+	}
+	ssrc := sender.GetParameters().Encodings[0].SSRC
+	if err := sender.Send(webrtc.RTPSendParameters{
+		Encodings: []webrtc.RTPEncodingParameters{{
+			RTPCodingParameters: webrtc.RTPCodingParameters{SSRC: ssrc, PayloadType: e.codec.PayloadType},
+		}},
+	}); err != nil {
+		return err
+	}
+
+	e.ssrc = uint32(ssrc)
+	e.downTrack = downTrack
+	e.sender = sender
+	e.rtcpReader = bufferFactory.GetOrNew(packetio.RTCPBufferPacket, e.ssrc).(*buffer.RTCPReader)
+	// the receiver closes its DownTracks with its lock held, which unbinding needs
+	downTrack.OnCloseHandler(func() {
+		go e.Close()
+	})
+	return nil
+}
+
+func (e *RTPEgress) ID() string {
+	return e.id
+}
+
+// SSRC is the egress's own, packets are rewritten to it
+func (e *RTPEgress) SSRC() uint32 {
+	return e.ssrc
+}
+
+func (e *RTPEgress) DownTrack() *sfu.DownTrack {
+	return e.downTrack
+}
+
+// SwitchSpatialLayer forwards a simulcast layer, or the middle one when it isn't published.
+// call after the DownTrack is added to the receiver
+func (e *RTPEgress) SwitchSpatialLayer(layer int32) {
+	atomic.StoreInt32(&e.spatialLayer, layer)
+	err := e.downTrack.SwitchSpatialLayer(layer, true)
+	if err == sfu.ErrSpatialLayerNotFound && layer != spatialLayerForQuality(livekit.VideoQuality_MEDIUM) {
+		_ = e.downTrack.SwitchSpatialLayer(spatialLayerForQuality(livekit.VideoQuality_MEDIUM), true)
+	}
+}
+
+// SpatialLayer returns the simulcast layer requested, -1 when not simulcasted
+func (e *RTPEgress) SpatialLayer() int32 {
+	return atomic.LoadInt32(&e.spatialLayer)
+}
+
+// RequestKeyFrame asks the publisher for a keyframe, as a PLI from the destination would
+func (e *RTPEgress) RequestKeyFrame() {
+	data, err := (&rtcp.PictureLossIndication{MediaSSRC: e.ssrc}).Marshal()
+	if err != nil {
+		return
+	}
+	_, _ = e.rtcpReader.Write(data)
+}
+
+// OnClose is called once the egress is closed, by Close or when the track's receiver goes away
+func (e *RTPEgress) OnClose(f func()) {
+	e.lock.Lock()
+	e.onClose = f
+	e.lock.Unlock()
+}
+
+func (e *RTPEgress) Close() {
+	if !e.isClosed.TrySet(true) {
+		return
+	}
+	// removes the DownTrack from the receiver
+	_ = e.sender.Stop()
+	e.downTrack.Close()
+	if e.rtpConn != nil {
+		_ = e.rtpConn.Close()
+		_ = e.rtcpConn.Close()
+	}
+
+	e.lock.Lock()
+	onClose := e.onClose
+	e.lock.Unlock()
+	if onClose != nil {
+		onClose()
+	}
+}
+
+// rtcpWorker passes RTCP from the destination to the DownTrack, which forwards key frame requests to the
+// publisher and retransmits NACKed packets
+func (e *RTPEgress) rtcpWorker(conn *net.UDPConn) {
+	buf := make([]byte, rtpIngressMaxPacketSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if e.isClosed.Get() {
+				return
+			}
+			// the receiver isn't listening yet
+			continue
+		}
+		if _, err := rtcp.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		_, _ = e.rtcpReader.Write(buf[:n])
+	}
+}
+
+func (e *RTPEgress) reportWorker() {
+	ticker := time.NewTicker(rtpEgressReportInterval)
+	defer ticker.Stop()
+	// sender reports need reports from the publisher, the first may only describe the source
+	for ; ; <-ticker.C {
+		if e.isClosed.Get() {
+			return
+		}
+		var pkts []rtcp.Packet
+		if sr := e.downTrack.CreateSenderReport(); sr != nil {
+			pkts = append(pkts, sr)
+		}
+		// the DownTrack's own chunks need a transceiver
+		pkts = append(pkts, &rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: e.ssrc,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: e.downTrack.ID()}},
+		}}})
+		if data, err := rtcp.Marshal(pkts); err == nil {
+			_, _ = e.rtcpConn.Write(data)
+		}
+	}
+}
+
+// SDP describes the stream, so that tools like ffmpeg and GStreamer can receive it
+func (e *RTPEgress) SDP() string {
+	codec, trackID := e.codec, e.downTrack.ID()
+	mediaType, encoding := "audio", codec.MimeType
+	if parts := strings.SplitN(codec.MimeType, "/", 2); len(parts) == 2 {
+		mediaType, encoding = strings.ToLower(parts[0]), parts[1]
+	}
+	addrType := "IP4"
+	if e.addr.IP.To4() == nil {
+		addrType = "IP6"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("v=0\r\n")
+	fmt.Fprintf(&sb, "o=- 0 0 IN %s %s\r\n", addrType, e.addr.IP)
+	fmt.Fprintf(&sb, "s=%s\r\n", trackID)
+	fmt.Fprintf(&sb, "c=IN %s %s\r\n", addrType, e.addr.IP)
+	sb.WriteString("t=0 0\r\n")
+	fmt.Fprintf(&sb, "m=%s %d RTP/AVP %d\r\n", mediaType, e.addr.Port, codec.PayloadType)
+	if codec.Channels > 1 {
+		fmt.Fprintf(&sb, "a=rtpmap:%d %s/%d/%d\r\n", codec.PayloadType, encoding, codec.ClockRate, codec.Channels)
+	} else {
+		fmt.Fprintf(&sb, "a=rtpmap:%d %s/%d\r\n", codec.PayloadType, encoding, codec.ClockRate)
+	}
+	if codec.SDPFmtpLine != "" {
+		fmt.Fprintf(&sb, "a=fmtp:%d %s\r\n", codec.PayloadType, codec.SDPFmtpLine)
+	}
+	fmt.Fprintf(&sb, "a=ssrc:%d cname:%s\r\n", e.ssrc, trackID)
+	sb.WriteString("a=recvonly\r\n")
+	return sb.String()
+}
+
+// rtpWriter ends the sender's interceptor chain, passing packets to the destination instead of SRTP
+type rtpWriter struct {
+	interceptor.NoOp
+	writeRTP func(header *rtp.Header, payload []byte)
+}
+
+func (w *rtpWriter) BindLocalStream(_ *interceptor.StreamInfo, _ interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		w.writeRTP(header, payload)
+		return header.MarshalSize() + len(payload), nil
+	})
+}
@@ -0,0 +1,131 @@
+package rtc
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/ion-sfu/pkg/sfu"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// receives nothing from the publisher, only what an egress needs to bind and close
+type fakeEgressReceiver struct {
+	sfu.Receiver
+	deleted chan string
+}
+
+func (r *fakeEgressReceiver) TrackID() string {
+	return "TR_test"
+}
+
+func (r *fakeEgressReceiver) StreamID() string {
+	return "PA_publisher"
+}
+
+func (r *fakeEgressReceiver) GetSenderReportTime(layer int32) (uint32, uint64) {
+	return 0, 0
+}
+
+func (r *fakeEgressReceiver) DeleteDownTrack(peerID string) {
+	r.deleted <- peerID
+}
+
+func TestRTPEgress(t *testing.T) {
+	vp8 := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+		PayloadType:        96,
+	}
+
+	t.Run("describes its source on the RTCP port", func(t *testing.T) {
+		rtpConn, rtcpConn := listenRTPPair(t)
+		defer rtpConn.Close()
+		defer rtcpConn.Close()
+
+		receiver := &fakeEgressReceiver{deleted: make(chan string, 1)}
+		egress, err := NewRTPEgress("EG_test", receiver, vp8, rtpConn.LocalAddr().(*net.UDPAddr), 500)
+		require.NoError(t, err)
+		defer egress.Close()
+		require.NotZero(t, egress.SSRC())
+
+		buf := make([]byte, 1500)
+		_ = rtcpConn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := rtcpConn.Read(buf)
+		require.NoError(t, err)
+		pkts, err := rtcp.Unmarshal(buf[:n])
+		require.NoError(t, err)
+		require.Len(t, pkts, 1)
+		sdes, ok := pkts[0].(*rtcp.SourceDescription)
+		require.True(t, ok)
+		require.Equal(t, egress.SSRC(), sdes.Chunks[0].Source)
+		require.Equal(t, "TR_test", sdes.Chunks[0].Items[0].Text)
+	})
+
+	t.Run("removes itself from the receiver when closed", func(t *testing.T) {
+		rtpConn, rtcpConn := listenRTPPair(t)
+		defer rtpConn.Close()
+		defer rtcpConn.Close()
+
+		receiver := &fakeEgressReceiver{deleted: make(chan string, 1)}
+		egress, err := NewRTPEgress("EG_test", receiver, vp8, rtpConn.LocalAddr().(*net.UDPAddr), 500)
+		require.NoError(t, err)
+		closed := make(chan struct{})
+		egress.OnClose(func() {
+			close(closed)
+		})
+
+		// as the receiver does when the publisher goes away
+		egress.DownTrack().Close()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("egress was not closed")
+		}
+		require.Equal(t, "EG_test", <-receiver.deleted)
+		// closing again does nothing
+		egress.Close()
+	})
+
+	t.Run("describes the stream", func(t *testing.T) {
+		receiver := &fakeEgressReceiver{deleted: make(chan string, 1)}
+		egress, err := NewRTPEgress("EG_test", receiver, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:    webrtc.MimeTypeOpus,
+				ClockRate:   48000,
+				Channels:    2,
+				SDPFmtpLine: "minptime=10;useinbandfec=1",
+			},
+			PayloadType: 111,
+		}, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5004}, 500)
+		require.NoError(t, err)
+		defer egress.Close()
+
+		sdp := egress.SDP()
+		lines := strings.Split(strings.TrimSpace(sdp), "\r\n")
+		require.Contains(t, lines, "c=IN IP4 127.0.0.1")
+		require.Contains(t, lines, "m=audio 5004 RTP/AVP 111")
+		require.Contains(t, lines, "a=rtpmap:111 opus/48000/2")
+		require.Contains(t, lines, "a=fmtp:111 minptime=10;useinbandfec=1")
+		require.Contains(t, lines, "a=ssrc:"+strconv.FormatUint(uint64(egress.SSRC()), 10)+" cname:TR_test")
+	})
+}
+
+// listens on a port and the one after it
+func listenRTPPair(t *testing.T) (*net.UDPConn, *net.UDPConn) {
+	for i := 0; i < 10; i++ {
+		rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+		require.NoError(t, err)
+		port := rtpConn.LocalAddr().(*net.UDPAddr).Port
+		rtcpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port + 1})
+		if err == nil {
+			return rtpConn, rtcpConn
+		}
+		_ = rtpConn.Close()
+	}
+	t.Fatal("could not find consecutive ports")
+	return nil, nil
+}
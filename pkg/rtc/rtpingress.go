@@ -0,0 +1,634 @@
+package rtc
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// payload types that sources send with, unless others are given
+	RTPIngressVideoPayloadType = 96
+	RTPIngressAudioPayloadType = 111
+
+	rtpIngressMaxPacketSize = 1500
+	// a port takes packets from one source at a time, another is accepted once it's been quiet this long
+	rtpIngressSourceTimeout = 3 * time.Second
+	// candidates of the publisher connection are local, gathering shouldn't take long
+	rtpIngressGatherTimeout = 5 * time.Second
+)
+
+var (
+	ErrUnsupportedIngressCodec = errors.New("ingress video codec must be VP8 or H264")
+	ErrIngressGatherTimeout    = errors.New("timed out gathering ingress candidates")
+	ErrNoIngressPort           = errors.New("no UDP port available for ingress")
+	ErrIngressTrackNotFound    = errors.New("ingress track does not exist")
+	ErrIngressTracksFixed      = errors.New("tracks can only be changed on ingresses with tracks on ports of their own")
+)
+
+// RTPIngress receives plain RTP on UDP ports and publishes it into a room. It's a client of the room like
+// any other, so streams reach the room through a publisher connection, with receivers and buffers from the
+// room's buffer factory. the connection is needed as MediaTrack receivers are created from the pion receiver
+// and remote track of a publisher, which only a PeerConnection creates.
+// audio and video are received on the same port, told apart by payload type, unless tracks are given, which
+// each have a port of their own. a port takes packets from a single source, limited to SourceIP when it's set.
+// keyframe requests from the room are sent back to the source as PLI, other RTCP is ignored, so video sources
+// that can't take them should send keyframes regularly.
+// with an SRTP key, packets that aren't authentic or are replayed are dropped, and keyframe requests are SRTCP.
+// tracks on ports of their own can be added and removed, they're published and unpublished when the connection
+// is renegotiated.
+// local ingresses don't receive on UDP, packets of their tracks are written by the node
+type RTPIngress struct {
+	id     string
+	params RTPIngressParams
+	pc     *webrtc.PeerConnection
+	// tracks of local ingresses, by ID. they don't change
+	localTracks map[string]*webrtc.TrackLocalStaticRTP
+
+	lock  sync.Mutex
+	conns []*rtpIngressConn
+	// conns of tracks on ports of their own, senders of tracks, ports and names of tracks, by ID
+	trackConns        map[string]*rtpIngressConn
+	senders           map[string]*webrtc.RTPSender
+	ports             map[string]int
+	names             map[string]string
+	closed            bool
+	onClose           func()
+	onKeyFrameRequest func(trackID string)
+}
+
+type RTPIngressParams struct {
+	ID string
+	// VP8 or H264, video isn't received when empty
+	VideoCodec string
+	Audio      bool
+	// address to receive RTP on, a port is allocated when it's 0
+	Addr *net.UDPAddr
+	// ports are allocated from this range when it's set, like those of the node's WebRTC connections
+	PortRangeStart uint16
+	PortRangeEnd   uint16
+	// packets from other addresses are dropped when it's set
+	SourceIP net.IP
+	// payload types the source sends with, RTPIngressVideoPayloadType and RTPIngressAudioPayloadType when 0
+	VideoPayloadType uint8
+	AudioPayloadType uint8
+	// name of the tracks published, they're named after their kind when empty
+	TrackName string
+	// tracks received on ports of their own, instead of VideoCodec and Audio. only the IP of Addr is used
+	Tracks []RTPIngressTrack
+	// tracks are written with WriteRTP instead of being received, nothing is received on UDP
+	Local bool
+	// master key and salt RTP is protected with, it's received as SRTP when set
+	SRTPKey []byte
+	// codecs of the node, tracks are negotiated with them when they're set, so that tracks added later can
+	// use any of them. otherwise each track is negotiated with its own codec
+	EnabledCodecs []*livekit.Codec
+}
+
+// RTPIngressTrack is a stream with a codec of its own
+type RTPIngressTrack struct {
+	ID   string
+	Name string
+	Kind webrtc.RTPCodecType
+	// codec the source sends with, including its payload type
+	Codec webrtc.RTPCodecParameters
+}
+
+// streams received on a port, by the payload type the source sends them with
+type rtpIngressConn struct {
+	conn   *net.UDPConn
+	tracks map[uint8]*webrtc.TrackLocalStaticRTP
+
+	sourceIP net.IP
+	srtp     *srtpSession
+	// SSRC keyframe requests are sent from, each conn protects its RTCP with its own
+	rtcpSSRC uint32
+	// set when its track is removed, the ingress goes on without it
+	removed utils.AtomicFlag
+	// read once the connection is negotiated, only changed with the ingress's lock held
+	reading bool
+
+	lock sync.Mutex
+	// where packets come from, and the last SSRC of each stream, to send keyframe requests to
+	source       *net.UDPAddr
+	lastReceived time.Time
+	ssrcs        map[uint8]uint32
+}
+
+// ingressTrack is a track as it's negotiated, with the payload type the source sends it with
+type ingressTrack struct {
+	RTPIngressTrack
+	sourcePayloadType uint8
+	ownPort           bool
+}
+
+func NewRTPIngress(params RTPIngressParams) (*RTPIngress, error) {
+	tracks, err := ingressTracks(params)
+	if err != nil {
+		return nil, err
+	}
+
+	me := &webrtc.MediaEngine{}
+	if len(params.EnabledCodecs) > 0 {
+		if err := registerCodecs(me, params.EnabledCodecs, ingressVideoFeedback()); err != nil {
+			return nil, err
+		}
+	} else {
+		registered := make(map[webrtc.PayloadType]bool)
+		for _, t := range tracks {
+			if registered[t.Codec.PayloadType] {
+				continue
+			}
+			if err := me.RegisterCodec(t.Codec, t.Kind); err != nil {
+				return nil, err
+			}
+			registered[t.Codec.PayloadType] = true
+		}
+	}
+
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(me)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Addr == nil {
+		params.Addr = &net.UDPAddr{}
+	}
+	i := &RTPIngress{
+		id:          params.ID,
+		params:      params,
+		pc:          pc,
+		localTracks: make(map[string]*webrtc.TrackLocalStaticRTP),
+		trackConns:  make(map[string]*rtpIngressConn),
+		senders:     make(map[string]*webrtc.RTPSender),
+		ports:       make(map[string]int),
+		names:       make(map[string]string),
+	}
+	for _, t := range tracks {
+		if err := i.addIngressTrack(t); err != nil {
+			i.Close()
+			return nil, err
+		}
+	}
+
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			i.Close()
+		}
+	})
+	return i, nil
+}
+
+// addIngressTrack adds a track to the connection, and the port it's received on. assumes the lock is held, or
+// that the ingress is being created
+func (i *RTPIngress) addIngressTrack(t *ingressTrack) error {
+	if t.Name != "" {
+		i.names[t.ID] = t.Name
+	}
+	if i.params.Local {
+		trackID := t.ID
+		track, sender, err := i.addTrack(t, func() {
+			i.lock.Lock()
+			onKeyFrameRequest := i.onKeyFrameRequest
+			i.lock.Unlock()
+			if onKeyFrameRequest != nil {
+				onKeyFrameRequest(trackID)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		i.localTracks[t.ID] = track
+		i.senders[t.ID] = sender
+		return nil
+	}
+
+	var c *rtpIngressConn
+	if !t.ownPort && len(i.conns) > 0 {
+		c = i.conns[0]
+	} else {
+		listenAddr := i.params.Addr
+		if t.ownPort {
+			listenAddr = &net.UDPAddr{IP: listenAddr.IP, Zone: listenAddr.Zone}
+		}
+		var err error
+		if c, err = i.newConn(listenAddr); err != nil {
+			return err
+		}
+		i.conns = append(i.conns, c)
+	}
+
+	sourcePayloadType := t.sourcePayloadType
+	track, sender, err := i.addTrack(t, func() {
+		c.requestKeyFrame(sourcePayloadType)
+	})
+	if err != nil {
+		if t.ownPort {
+			_ = c.conn.Close()
+			i.conns = i.conns[:len(i.conns)-1]
+		}
+		return err
+	}
+	c.tracks[t.sourcePayloadType] = track
+	i.senders[t.ID] = sender
+	i.ports[t.ID] = c.conn.LocalAddr().(*net.UDPAddr).Port
+	if t.ownPort {
+		i.trackConns[t.ID] = c
+	}
+	return nil
+}
+
+func (i *RTPIngress) newConn(addr *net.UDPAddr) (*rtpIngressConn, error) {
+	conn, err := listenIngressUDP(addr, i.params.PortRangeStart, i.params.PortRangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	c := &rtpIngressConn{
+		conn:     conn,
+		tracks:   make(map[uint8]*webrtc.TrackLocalStaticRTP),
+		sourceIP: i.params.SourceIP,
+		ssrcs:    make(map[uint8]uint32),
+	}
+	if len(i.params.SRTPKey) > 0 {
+		if c.srtp, err = newSRTPSession(i.params.SRTPKey); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		c.rtcpSSRC = rand.Uint32()
+	}
+	return c, nil
+}
+
+// listenIngressUDP listens on addr, on a port of the range when addr has none and the range is set
+func listenIngressUDP(addr *net.UDPAddr, portStart, portEnd uint16) (*net.UDPConn, error) {
+	if addr.Port != 0 || portStart == 0 || portEnd < portStart {
+		return net.ListenUDP("udp", addr)
+	}
+	// start at a random port, so that ingresses don't all try the ones that were taken first
+	count := int(portEnd-portStart) + 1
+	first := rand.Intn(count)
+	for n := 0; n < count; n++ {
+		port := int(portStart) + (first+n)%count
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: addr.IP, Port: port, Zone: addr.Zone})
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, ErrNoIngressPort
+}
+
+// ingressTracks describes the tracks of params, as they're negotiated
+func ingressTracks(params RTPIngressParams) ([]*ingressTrack, error) {
+	var tracks []*ingressTrack
+	if len(params.Tracks) > 0 {
+		for _, t := range params.Tracks {
+			tracks = append(tracks, ownPortTrack(t))
+		}
+		return tracks, nil
+	}
+
+	if params.VideoCodec == "" && !params.Audio {
+		return nil, errors.New("ingress must receive audio or video")
+	}
+	if params.VideoCodec != "" {
+		var videoCodec webrtc.RTPCodecCapability
+		switch {
+		case strings.EqualFold(params.VideoCodec, "vp8"), strings.EqualFold(params.VideoCodec, webrtc.MimeTypeVP8):
+			videoCodec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}
+		case strings.EqualFold(params.VideoCodec, "h264"), strings.EqualFold(params.VideoCodec, webrtc.MimeTypeH264):
+			videoCodec = webrtc.RTPCodecCapability{
+				MimeType:    webrtc.MimeTypeH264,
+				ClockRate:   90000,
+				SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+			}
+		default:
+			return nil, ErrUnsupportedIngressCodec
+		}
+		videoCodec.RTCPFeedback = ingressVideoFeedback()
+		sourcePayloadType := params.VideoPayloadType
+		if sourcePayloadType == 0 {
+			sourcePayloadType = RTPIngressVideoPayloadType
+		}
+		tracks = append(tracks, &ingressTrack{
+			RTPIngressTrack: RTPIngressTrack{
+				ID:   params.ID + "_video",
+				Name: params.TrackName,
+				Kind: webrtc.RTPCodecTypeVideo,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: videoCodec,
+					PayloadType:        RTPIngressVideoPayloadType,
+				},
+			},
+			sourcePayloadType: sourcePayloadType,
+		})
+	}
+	if params.Audio {
+		sourcePayloadType := params.AudioPayloadType
+		if sourcePayloadType == 0 {
+			sourcePayloadType = RTPIngressAudioPayloadType
+		}
+		tracks = append(tracks, &ingressTrack{
+			RTPIngressTrack: RTPIngressTrack{
+				ID:   params.ID + "_audio",
+				Name: params.TrackName,
+				Kind: webrtc.RTPCodecTypeAudio,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+					PayloadType:        RTPIngressAudioPayloadType,
+				},
+			},
+			sourcePayloadType: sourcePayloadType,
+		})
+	}
+	return tracks, nil
+}
+
+// ownPortTrack is a track received on a port of its own, with the payload type of its codec
+func ownPortTrack(t RTPIngressTrack) *ingressTrack {
+	track := &ingressTrack{
+		RTPIngressTrack:   t,
+		sourcePayloadType: uint8(t.Codec.PayloadType),
+		ownPort:           true,
+	}
+	if t.Kind == webrtc.RTPCodecTypeVideo {
+		track.Codec.RTCPFeedback = ingressVideoFeedback()
+	}
+	return track
+}
+
+func ingressVideoFeedback() []webrtc.RTCPFeedback {
+	return []webrtc.RTCPFeedback{
+		{Type: webrtc.TypeRTCPFBNACK},
+		{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+	}
+}
+
+func (i *RTPIngress) addTrack(t *ingressTrack, onKeyFrameRequest func()) (*webrtc.TrackLocalStaticRTP, *webrtc.RTPSender, error) {
+	track, err := webrtc.NewTrackLocalStaticRTP(t.Codec.RTPCodecCapability, t.ID, i.id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender, err := i.pc.AddTrack(track)
+	if err != nil {
+		return nil, nil, err
+	}
+	// RTCP has to be read for interceptors to work, keyframe requests are passed on
+	go func() {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, packet := range packets {
+				switch packet.(type) {
+				case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+					onKeyFrameRequest()
+				}
+			}
+		}
+	}()
+	return track, sender, nil
+}
+
+func (i *RTPIngress) ID() string {
+	return i.id
+}
+
+// Port is the UDP port RTP is received on, the first track's when they each have one. 0 for local ingresses
+func (i *RTPIngress) Port() int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if len(i.conns) == 0 {
+		return 0
+	}
+	return i.conns[0].conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// WriteRTP publishes a packet of a track of a local ingress, its payload type and SSRC are rewritten to
+// what's negotiated
+func (i *RTPIngress) WriteRTP(trackID string, header *rtp.Header, payload []byte) error {
+	track := i.localTracks[trackID]
+	if track == nil {
+		return ErrIngressTrackNotFound
+	}
+	return track.WriteRTP(&rtp.Packet{Header: *header, Payload: payload})
+}
+
+// OnKeyFrameRequest is called when the room asks for a keyframe of a track of a local ingress
+func (i *RTPIngress) OnKeyFrameRequest(f func(trackID string)) {
+	i.lock.Lock()
+	i.onKeyFrameRequest = f
+	i.lock.Unlock()
+}
+
+// TrackPort is the UDP port RTP of a track is received on
+func (i *RTPIngress) TrackPort(trackID string) int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.ports[trackID]
+}
+
+// TrackName is the name a track is published with, empty when it's named after its kind
+func (i *RTPIngress) TrackName(trackID string) string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.names[trackID]
+}
+
+// AddTrack adds a track received on a port of its own, to ingresses created with Tracks, and returns the port.
+// it's published once the connection is renegotiated, with CreateOffer and SetAnswer
+func (i *RTPIngress) AddTrack(t RTPIngressTrack) (int, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if len(i.params.Tracks) == 0 || i.params.Local {
+		return 0, ErrIngressTracksFixed
+	}
+	if i.senders[t.ID] != nil {
+		return i.ports[t.ID], nil
+	}
+	if err := i.addIngressTrack(ownPortTrack(t)); err != nil {
+		return 0, err
+	}
+	return i.ports[t.ID], nil
+}
+
+// RemoveTrack stops receiving a track on a port of its own, it's unpublished once the connection is renegotiated
+func (i *RTPIngress) RemoveTrack(trackID string) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	c := i.trackConns[trackID]
+	if c == nil {
+		return ErrIngressTrackNotFound
+	}
+	if err := i.pc.RemoveTrack(i.senders[trackID]); err != nil {
+		return err
+	}
+	c.removed.TrySet(true)
+	_ = c.conn.Close()
+	conns := make([]*rtpIngressConn, 0, len(i.conns))
+	for _, conn := range i.conns {
+		if conn != c {
+			conns = append(conns, conn)
+		}
+	}
+	i.conns = conns
+	delete(i.trackConns, trackID)
+	delete(i.senders, trackID)
+	delete(i.ports, trackID)
+	delete(i.names, trackID)
+	return nil
+}
+
+// CreateOffer returns an offer with all of its candidates, for the publisher connection
+func (i *RTPIngress) CreateOffer() (webrtc.SessionDescription, error) {
+	offer, err := i.pc.CreateOffer(nil)
+	if err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	gatherComplete := webrtc.GatheringCompletePromise(i.pc)
+	if err = i.pc.SetLocalDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	select {
+	case <-gatherComplete:
+	case <-time.After(rtpIngressGatherTimeout):
+		return webrtc.SessionDescription{}, ErrIngressGatherTimeout
+	}
+	return *i.pc.LocalDescription(), nil
+}
+
+// SetAnswer completes the connection, or its renegotiation, and starts forwarding what's received on new ports
+func (i *RTPIngress) SetAnswer(answer webrtc.SessionDescription) error {
+	if err := i.pc.SetRemoteDescription(answer); err != nil {
+		return err
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for _, c := range i.conns {
+		if !c.reading {
+			c.reading = true
+			go i.readWorker(c)
+		}
+	}
+	return nil
+}
+
+func (i *RTPIngress) OnClose(f func()) {
+	i.lock.Lock()
+	i.onClose = f
+	i.lock.Unlock()
+}
+
+func (i *RTPIngress) Close() {
+	i.lock.Lock()
+	if i.closed {
+		i.lock.Unlock()
+		return
+	}
+	i.closed = true
+	onClose := i.onClose
+	conns := i.conns
+	i.lock.Unlock()
+
+	for _, c := range conns {
+		_ = c.conn.Close()
+	}
+	_ = i.pc.Close()
+	if onClose != nil {
+		onClose()
+	}
+}
+
+func (i *RTPIngress) readWorker(c *rtpIngressConn) {
+	defer Recover()
+
+	buf := make([]byte, rtpIngressMaxPacketSize)
+	pkt := &rtp.Packet{}
+	for {
+		n, source, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if c.removed.Get() {
+				return
+			}
+			if !IsEOF(err) {
+				logger.Debugw("ingress stopped reading", "ingress", i.id, "error", err)
+			}
+			i.Close()
+			return
+		}
+		data := buf[:n]
+		if c.srtp != nil {
+			if data, err = c.srtp.decryptRTP(data); err != nil {
+				// not authentic, replayed, or RTCP
+				continue
+			}
+		}
+		if err = pkt.Unmarshal(data); err != nil {
+			continue
+		}
+
+		track := c.tracks[pkt.PayloadType]
+		if track == nil {
+			// RTCP multiplexed on the port, or streams that weren't asked for
+			continue
+		}
+		if !c.accept(source, pkt) {
+			continue
+		}
+
+		// payload type and SSRC are rewritten to what's negotiated
+		if err = track.WriteRTP(pkt); err != nil && !IsEOF(err) {
+			logger.Debugw("could not forward ingress packet", "ingress", i.id, "error", err)
+		}
+	}
+}
+
+// accept takes packets from the allowed source, and from one address at a time
+func (c *rtpIngressConn) accept(source *net.UDPAddr, pkt *rtp.Packet) bool {
+	if c.sourceIP != nil && !c.sourceIP.Equal(source.IP) {
+		return false
+	}
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.source != nil && !(c.source.IP.Equal(source.IP) && c.source.Port == source.Port) &&
+		now.Sub(c.lastReceived) < rtpIngressSourceTimeout {
+		return false
+	}
+	c.source = source
+	c.lastReceived = now
+	c.ssrcs[pkt.PayloadType] = pkt.SSRC
+	return true
+}
+
+// requestKeyFrame sends a PLI for the stream back to where it's received from
+func (c *rtpIngressConn) requestKeyFrame(payloadType uint8) {
+	c.lock.Lock()
+	source := c.source
+	ssrc, ok := c.ssrcs[payloadType]
+	c.lock.Unlock()
+	if source == nil || !ok {
+		return
+	}
+
+	data, err := (&rtcp.PictureLossIndication{SenderSSRC: c.rtcpSSRC, MediaSSRC: ssrc}).Marshal()
+	if err != nil {
+		return
+	}
+	if c.srtp != nil {
+		if data, err = c.srtp.encryptRTCP(data); err != nil {
+			return
+		}
+	}
+	_, _ = c.conn.WriteToUDP(data, source)
+}
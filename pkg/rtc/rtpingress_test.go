@@ -0,0 +1,207 @@
+package rtc
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPIngress(t *testing.T) {
+	t.Run("requires a supported stream", func(t *testing.T) {
+		_, err := NewRTPIngress(RTPIngressParams{ID: "IN_test", VideoCodec: "VP9"})
+		require.Equal(t, ErrUnsupportedIngressCodec, err)
+
+		_, err = NewRTPIngress(RTPIngressParams{ID: "IN_test"})
+		require.Error(t, err)
+	})
+
+	t.Run("offers the streams received", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{ID: "IN_test", VideoCodec: "h264", Audio: true})
+		require.NoError(t, err)
+		defer ingress.Close()
+		require.NotZero(t, ingress.Port())
+
+		offer, err := ingress.CreateOffer()
+		require.NoError(t, err)
+		require.Equal(t, webrtc.SDPTypeOffer, offer.Type)
+		require.Contains(t, offer.SDP, "a=rtpmap:96 H264/90000")
+		require.Contains(t, offer.SDP, "a=rtpmap:111 opus/48000/2")
+		require.Equal(t, 2, strings.Count(offer.SDP, "a=sendrecv")+strings.Count(offer.SDP, "a=sendonly"))
+	})
+
+	t.Run("receives each track on its own port", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{
+			ID: "RL_test",
+			Tracks: []RTPIngressTrack{
+				{
+					ID:   "TR_video",
+					Name: "camera",
+					Kind: webrtc.RTPCodecTypeVideo,
+					Codec: webrtc.RTPCodecParameters{
+						RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+						PayloadType:        96,
+					},
+				},
+				{
+					ID:   "TR_audio",
+					Kind: webrtc.RTPCodecTypeAudio,
+					Codec: webrtc.RTPCodecParameters{
+						RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+						PayloadType:        111,
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		defer ingress.Close()
+
+		require.NotZero(t, ingress.TrackPort("TR_video"))
+		require.NotZero(t, ingress.TrackPort("TR_audio"))
+		require.NotEqual(t, ingress.TrackPort("TR_video"), ingress.TrackPort("TR_audio"))
+		require.Equal(t, "camera", ingress.TrackName("TR_video"))
+		require.Empty(t, ingress.TrackName("TR_audio"))
+
+		offer, err := ingress.CreateOffer()
+		require.NoError(t, err)
+		require.Contains(t, offer.SDP, "a=rtpmap:96 VP8/90000")
+		require.Contains(t, offer.SDP, "a=rtpmap:111 opus/48000/2")
+	})
+
+	t.Run("adds and removes tracks on ports of their own", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{
+			ID: "RL_test",
+			Tracks: []RTPIngressTrack{{
+				ID:   "TR_audio",
+				Kind: webrtc.RTPCodecTypeAudio,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+					PayloadType:        111,
+				},
+			}},
+			EnabledCodecs: []*livekit.Codec{{Mime: webrtc.MimeTypeOpus}, {Mime: webrtc.MimeTypeVP8}},
+		})
+		require.NoError(t, err)
+		defer ingress.Close()
+
+		port, err := ingress.AddTrack(RTPIngressTrack{
+			ID:   "TR_video",
+			Kind: webrtc.RTPCodecTypeVideo,
+			Codec: webrtc.RTPCodecParameters{
+				RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+				PayloadType:        120,
+			},
+		})
+		require.NoError(t, err)
+		require.NotZero(t, port)
+		require.Equal(t, port, ingress.TrackPort("TR_video"))
+
+		offer, err := ingress.CreateOffer()
+		require.NoError(t, err)
+		// negotiated with the node's codecs
+		require.Contains(t, offer.SDP, "a=rtpmap:96 VP8/90000")
+		require.Equal(t, 2, strings.Count(offer.SDP, "a=sendonly")+strings.Count(offer.SDP, "a=sendrecv"))
+
+		require.NoError(t, ingress.RemoveTrack("TR_audio"))
+		require.Zero(t, ingress.TrackPort("TR_audio"))
+		require.Equal(t, ErrIngressTrackNotFound, ingress.RemoveTrack("TR_audio"))
+	})
+
+	t.Run("only changes tracks on ports of their own", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{ID: "IN_test", Audio: true})
+		require.NoError(t, err)
+		defer ingress.Close()
+
+		_, err = ingress.AddTrack(RTPIngressTrack{ID: "TR_video", Kind: webrtc.RTPCodecTypeVideo})
+		require.Equal(t, ErrIngressTracksFixed, err)
+		require.Equal(t, ErrIngressTrackNotFound, ingress.RemoveTrack("IN_test_audio"))
+	})
+
+	t.Run("publishes what's written to local tracks", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{
+			ID:    "TB_test",
+			Local: true,
+			Tracks: []RTPIngressTrack{{
+				ID:   "TB_test",
+				Name: "keynote",
+				Kind: webrtc.RTPCodecTypeVideo,
+				Codec: webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+					PayloadType:        100,
+				},
+			}},
+		})
+		require.NoError(t, err)
+		defer ingress.Close()
+		require.Zero(t, ingress.Port())
+		require.Equal(t, "keynote", ingress.TrackName("TB_test"))
+
+		header := &rtp.Header{Version: 2, PayloadType: 100, SSRC: 1}
+		require.NoError(t, ingress.WriteRTP("TB_test", header, []byte{1}))
+		require.Equal(t, ErrIngressTrackNotFound, ingress.WriteRTP("TB_other", header, []byte{1}))
+
+		offer, err := ingress.CreateOffer()
+		require.NoError(t, err)
+		require.Contains(t, offer.SDP, "a=rtpmap:100 VP8/90000")
+	})
+
+	t.Run("closes once", func(t *testing.T) {
+		ingress, err := NewRTPIngress(RTPIngressParams{ID: "IN_test", Audio: true})
+		require.NoError(t, err)
+
+		closed := 0
+		ingress.OnClose(func() {
+			closed++
+		})
+		ingress.Close()
+		ingress.Close()
+		require.Equal(t, 1, closed)
+	})
+
+	t.Run("allocates ports from the range", func(t *testing.T) {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+		require.NoError(t, err)
+		port := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+		require.NoError(t, conn.Close())
+
+		params := RTPIngressParams{
+			ID:             "IN_test",
+			Audio:          true,
+			Addr:           &net.UDPAddr{IP: net.ParseIP("127.0.0.1")},
+			PortRangeStart: port,
+			PortRangeEnd:   port,
+		}
+		ingress, err := NewRTPIngress(params)
+		require.NoError(t, err)
+		defer ingress.Close()
+		require.EqualValues(t, port, ingress.Port())
+
+		_, err = NewRTPIngress(params)
+		require.Equal(t, ErrNoIngressPort, err)
+	})
+
+	t.Run("takes packets from one source at a time", func(t *testing.T) {
+		c := &rtpIngressConn{
+			sourceIP: net.ParseIP("10.0.0.1"),
+			ssrcs:    make(map[uint8]uint32),
+		}
+		pkt := &rtp.Packet{Header: rtp.Header{PayloadType: 96, SSRC: 1}}
+		first := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}
+		second := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5002}
+
+		require.False(t, c.accept(&net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 5000}, pkt))
+		require.True(t, c.accept(first, pkt))
+		require.False(t, c.accept(second, pkt))
+		require.True(t, c.accept(first, pkt))
+
+		// the first source went quiet
+		c.lastReceived = time.Now().Add(-rtpIngressSourceTimeout)
+		require.True(t, c.accept(second, pkt))
+		require.Equal(t, second, c.source)
+	})
+}
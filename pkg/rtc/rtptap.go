@@ -0,0 +1,103 @@
+package rtc
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/transport/packetio"
+)
+
+// RTPTap passes incoming RTP packets to observers of their SSRC, before they are buffered.
+// packets are passed as they arrive, observers need to handle reordering and must not block.
+// observers are set on the buffers of their stream as they change, so buffers without observers only
+// check that there are none
+type RTPTap struct {
+	lock      sync.Mutex
+	observers map[uint32]map[string]func(packet []byte)
+	// buffer of each stream
+	writers map[uint32]*rtpTapWriter
+}
+
+func NewRTPTap() *RTPTap {
+	return &RTPTap{
+		observers: make(map[uint32]map[string]func(packet []byte)),
+		writers:   make(map[uint32]*rtpTapWriter),
+	}
+}
+
+// Observe sets an observer of RTP for a stream, identified by observerID. nil removes it
+func (t *RTPTap) Observe(ssrc uint32, observerID string, f func(packet []byte)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if f == nil {
+		delete(t.observers[ssrc], observerID)
+		if len(t.observers[ssrc]) == 0 {
+			delete(t.observers, ssrc)
+		}
+	} else {
+		if t.observers[ssrc] == nil {
+			t.observers[ssrc] = make(map[string]func(packet []byte))
+		}
+		t.observers[ssrc][observerID] = f
+	}
+	if w := t.writers[ssrc]; w != nil {
+		w.setObservers(t.observers[ssrc])
+	}
+}
+
+// WrapBufferFactory returns a buffer factory that passes packets written to its RTP buffers through the tap
+func (t *RTPTap) WrapBufferFactory(createBuffer func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser) func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+	return func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+		buffer := createBuffer(packetType, ssrc)
+		if packetType != packetio.RTPBufferPacket {
+			return buffer
+		}
+		w := &rtpTapWriter{
+			ReadWriteCloser: buffer,
+			ssrc:            ssrc,
+			tap:             t,
+		}
+
+		t.lock.Lock()
+		w.setObservers(t.observers[ssrc])
+		t.writers[ssrc] = w
+		t.lock.Unlock()
+		return w
+	}
+}
+
+type rtpTapWriter struct {
+	io.ReadWriteCloser
+	ssrc uint32
+	tap  *RTPTap
+	// []func(packet []byte), nil without observers
+	observers atomic.Value
+}
+
+// setObservers replaces the observers packets are passed to. assumes the tap's lock is held
+func (w *rtpTapWriter) setObservers(observers map[string]func(packet []byte)) {
+	var fs []func(packet []byte)
+	for _, f := range observers {
+		fs = append(fs, f)
+	}
+	w.observers.Store(fs)
+}
+
+func (w *rtpTapWriter) Write(p []byte) (n int, err error) {
+	if fs, _ := w.observers.Load().([]func(packet []byte)); fs != nil {
+		for _, f := range fs {
+			f(p)
+		}
+	}
+	return w.ReadWriteCloser.Write(p)
+}
+
+func (w *rtpTapWriter) Close() error {
+	w.tap.lock.Lock()
+	if w.tap.writers[w.ssrc] == w {
+		delete(w.tap.writers, w.ssrc)
+	}
+	w.tap.lock.Unlock()
+	return w.ReadWriteCloser.Close()
+}
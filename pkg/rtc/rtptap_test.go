@@ -0,0 +1,67 @@
+package rtc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/transport/packetio"
+	"github.com/stretchr/testify/require"
+)
+
+type nopBuffer struct {
+	written int
+}
+
+func (b *nopBuffer) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (b *nopBuffer) Write(p []byte) (int, error) {
+	b.written++
+	return len(p), nil
+}
+
+func (b *nopBuffer) Close() error {
+	return nil
+}
+
+func TestRTPTap(t *testing.T) {
+	newBuffer := func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+		return &nopBuffer{}
+	}
+
+	t.Run("observers set before and after the buffer is created", func(t *testing.T) {
+		tap := NewRTPTap()
+		var first, second int
+		tap.Observe(1, "first", func(packet []byte) { first++ })
+		w := tap.WrapBufferFactory(newBuffer)(packetio.RTPBufferPacket, 1)
+		_, _ = w.Write([]byte{1})
+		tap.Observe(1, "second", func(packet []byte) { second++ })
+		_, _ = w.Write([]byte{2})
+		require.Equal(t, 2, first)
+		require.Equal(t, 1, second)
+
+		tap.Observe(1, "first", nil)
+		_, _ = w.Write([]byte{3})
+		require.Equal(t, 2, first)
+		require.Equal(t, 2, second)
+		require.Equal(t, 3, w.(*rtpTapWriter).ReadWriteCloser.(*nopBuffer).written)
+	})
+
+	t.Run("only RTP of the observed stream is passed", func(t *testing.T) {
+		tap := NewRTPTap()
+		var observed int
+		tap.Observe(1, "observer", func(packet []byte) { observed++ })
+		factory := tap.WrapBufferFactory(newBuffer)
+		_, _ = factory(packetio.RTPBufferPacket, 2).Write([]byte{1})
+		_, _ = factory(packetio.RTCPBufferPacket, 1).Write([]byte{1})
+		require.Equal(t, 0, observed)
+	})
+
+	t.Run("closed buffers are forgotten", func(t *testing.T) {
+		tap := NewRTPTap()
+		w := tap.WrapBufferFactory(newBuffer)(packetio.RTPBufferPacket, 1)
+		require.NoError(t, w.Close())
+		require.Empty(t, tap.writers)
+	})
+}
@@ -0,0 +1,172 @@
+package rtc
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v2"
+)
+
+const (
+	srtpProfile = srtp.ProtectionProfileAes128CmHmacSha1_80
+	// master key and salt of the profile
+	srtpKeyLength  = 16
+	srtpSaltLength = 14
+	// packets this far behind the newest are dropped as replays
+	srtpReplayWindow = 64
+)
+
+var ErrInvalidSRTPKey = errors.New("SRTP key must be a master key followed by its salt")
+
+// NewSRTPKey generates a master key and salt to protect streams between nodes with
+func NewSRTPKey() ([]byte, error) {
+	key := make([]byte, srtpKeyLength+srtpSaltLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// srtpSession protects the streams of one side of a connection. streams of both sides are protected with the
+// same key, each side's have SSRCs of their own
+type srtpSession struct {
+	lock    sync.Mutex
+	encrypt *srtp.Context
+	decrypt *srtp.Context
+}
+
+func newSRTPSession(key []byte) (*srtpSession, error) {
+	if len(key) != srtpKeyLength+srtpSaltLength {
+		return nil, ErrInvalidSRTPKey
+	}
+	masterKey, masterSalt := key[:srtpKeyLength], key[srtpKeyLength:]
+	encrypt, err := srtp.CreateContext(masterKey, masterSalt, srtpProfile)
+	if err != nil {
+		return nil, err
+	}
+	decrypt, err := srtp.CreateContext(masterKey, masterSalt, srtpProfile,
+		srtp.SRTPReplayProtection(srtpReplayWindow), srtp.SRTCPReplayProtection(srtpReplayWindow))
+	if err != nil {
+		return nil, err
+	}
+	return &srtpSession{encrypt: encrypt, decrypt: decrypt}, nil
+}
+
+func (s *srtpSession) encryptRTP(header *rtp.Header, payload []byte) ([]byte, error) {
+	pkt := rtp.Packet{Header: *header, Payload: payload}
+	data, err := pkt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.encrypt.EncryptRTP(nil, data, &pkt.Header)
+}
+
+// decryptRTP returns the packet when it's authentic, and hasn't been received before
+func (s *srtpSession) decryptRTP(data []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.decrypt.DecryptRTP(nil, data, nil)
+}
+
+func (s *srtpSession) encryptRTCP(data []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.encrypt.EncryptRTCP(nil, data, nil)
+}
+
+func (s *srtpSession) decryptRTCP(data []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.decrypt.DecryptRTCP(nil, data, nil)
+}
+
+// SRTPSender sends RTP to an SRTP ingress on another node, from a local address. keyframe requests the ingress
+// sends back are passed on, other RTCP is ignored
+type SRTPSender struct {
+	conn     *net.UDPConn
+	srtp     *srtpSession
+	isClosed utils.AtomicFlag
+
+	lock              sync.Mutex
+	onKeyFrameRequest func()
+}
+
+func NewSRTPSender(localAddr, addr *net.UDPAddr, key []byte) (*SRTPSender, error) {
+	session, err := newSRTPSession(key)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", localAddr, addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SRTPSender{
+		conn: conn,
+		srtp: session,
+	}
+	go s.rtcpWorker()
+	return s, nil
+}
+
+// WriteRTP sends a packet, errors are ignored as the ingress may not be listening yet
+func (s *SRTPSender) WriteRTP(header *rtp.Header, payload []byte) {
+	data, err := s.srtp.encryptRTP(header, payload)
+	if err != nil {
+		return
+	}
+	_, _ = s.conn.Write(data)
+}
+
+// OnKeyFrameRequest is called when the ingress asks for a keyframe
+func (s *SRTPSender) OnKeyFrameRequest(f func()) {
+	s.lock.Lock()
+	s.onKeyFrameRequest = f
+	s.lock.Unlock()
+}
+
+func (s *SRTPSender) Close() {
+	if !s.isClosed.TrySet(true) {
+		return
+	}
+	_ = s.conn.Close()
+}
+
+func (s *SRTPSender) rtcpWorker() {
+	buf := make([]byte, rtpIngressMaxPacketSize)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			if s.isClosed.Get() {
+				return
+			}
+			// the ingress isn't listening yet
+			continue
+		}
+		data, err := s.srtp.decryptRTCP(buf[:n])
+		if err != nil {
+			continue
+		}
+		pkts, err := rtcp.Unmarshal(data)
+		if err != nil {
+			continue
+		}
+		for _, pkt := range pkts {
+			switch pkt.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				s.lock.Lock()
+				onKeyFrameRequest := s.onKeyFrameRequest
+				s.lock.Unlock()
+				if onKeyFrameRequest != nil {
+					onKeyFrameRequest()
+				}
+			}
+		}
+	}
+}
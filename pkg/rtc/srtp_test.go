@@ -0,0 +1,73 @@
+package rtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRTPSender(t *testing.T) {
+	key, err := NewSRTPKey()
+	require.NoError(t, err)
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sender, err := NewSRTPSender(&net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, listener.LocalAddr().(*net.UDPAddr), key)
+	require.NoError(t, err)
+	defer sender.Close()
+	keyFrameRequests := make(chan struct{}, 1)
+	sender.OnKeyFrameRequest(func() {
+		keyFrameRequests <- struct{}{}
+	})
+
+	c := &rtpIngressConn{conn: listener, ssrcs: make(map[uint8]uint32), rtcpSSRC: 1234}
+	c.srtp, err = newSRTPSession(key)
+	require.NoError(t, err)
+
+	t.Run("sends packets only the key opens", func(t *testing.T) {
+		sender.WriteRTP(&rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 1, SSRC: 5678}, []byte{1, 2, 3})
+
+		buf := make([]byte, rtpIngressMaxPacketSize)
+		require.NoError(t, listener.SetReadDeadline(time.Now().Add(time.Second)))
+		n, source, err := listener.ReadFromUDP(buf)
+		require.NoError(t, err)
+		require.NotContains(t, string(buf[:n]), string([]byte{1, 2, 3}))
+
+		otherKey, err := NewSRTPKey()
+		require.NoError(t, err)
+		other, err := newSRTPSession(otherKey)
+		require.NoError(t, err)
+		_, err = other.decryptRTP(append([]byte{}, buf[:n]...))
+		require.Error(t, err)
+
+		data, err := c.srtp.decryptRTP(append([]byte{}, buf[:n]...))
+		require.NoError(t, err)
+		pkt := &rtp.Packet{}
+		require.NoError(t, pkt.Unmarshal(data))
+		require.Equal(t, []byte{1, 2, 3}, pkt.Payload)
+
+		// replayed
+		_, err = c.srtp.decryptRTP(append([]byte{}, buf[:n]...))
+		require.Error(t, err)
+
+		require.True(t, c.accept(source, pkt))
+	})
+
+	t.Run("passes on keyframe requests", func(t *testing.T) {
+		c.requestKeyFrame(96)
+		select {
+		case <-keyFrameRequests:
+		case <-time.After(time.Second):
+			require.Fail(t, "keyframe request not received")
+		}
+	})
+
+	t.Run("requires a key and salt", func(t *testing.T) {
+		_, err := NewSRTPSender(nil, listener.LocalAddr().(*net.UDPAddr), key[:16])
+		require.Equal(t, ErrInvalidSRTPKey, err)
+	})
+}
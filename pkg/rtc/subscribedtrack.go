@@ -1,6 +1,7 @@
 package rtc
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/bep/debounce"
@@ -8,24 +9,46 @@ import (
 	"github.com/livekit/protocol/utils"
 	"github.com/pion/ion-sfu/pkg/sfu"
 	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/utils/stats"
 )
 
 const (
 	subscriptionDebounceInterval = 100 * time.Millisecond
+	// allocated layer when bandwidth isn't constrained
+	maxAllocatedLayer = 2
 )
 
 type SubscribedTrack struct {
-	dt        *sfu.DownTrack
-	subMuted  utils.AtomicFlag
-	pubMuted  utils.AtomicFlag
+	publishedTrack *MediaTrack
+	dt             *sfu.DownTrack
+	// SSRC the subscriber receives the track with
+	ssrc     uint32
+	subMuted utils.AtomicFlag
+	pubMuted utils.AtomicFlag
+	// paused while the subscriber isn't rendering the track, without changing its subscription settings
+	invisible utils.AtomicFlag
 	debouncer func(func())
+	// quality requested by the subscriber
+	quality atomic.Value // livekit.VideoQuality
+	// highest layer allowed by the bandwidth allocator, -1 when paused
+	allocatedLayer int32
+	// paused when the publisher isn't among the subscriber's last-N speakers
+	lastNPaused utils.AtomicFlag
+
+	onSubscriptionChanged func()
 }
 
-func NewSubscribedTrack(dt *sfu.DownTrack) *SubscribedTrack {
-	return &SubscribedTrack{
-		dt:        dt,
-		debouncer: debounce.New(subscriptionDebounceInterval),
+func NewSubscribedTrack(publishedTrack *MediaTrack, dt *sfu.DownTrack) *SubscribedTrack {
+	t := &SubscribedTrack{
+		publishedTrack: publishedTrack,
+		dt:             dt,
+		debouncer:      debounce.New(subscriptionDebounceInterval),
+		allocatedLayer: maxAllocatedLayer,
 	}
+	t.quality.Store(livekit.VideoQuality_HIGH)
+	return t
 }
 
 func (t *SubscribedTrack) ID() string {
@@ -36,35 +59,203 @@ func (t *SubscribedTrack) DownTrack() *sfu.DownTrack {
 	return t.dt
 }
 
+func (t *SubscribedTrack) SSRC() uint32 {
+	return t.ssrc
+}
+
+func (t *SubscribedTrack) PublisherID() string {
+	return t.publishedTrack.params.ParticipantID
+}
+
+func (t *SubscribedTrack) Kind() livekit.TrackType {
+	return t.publishedTrack.Kind()
+}
+
+func (t *SubscribedTrack) Source() types.TrackSource {
+	return t.publishedTrack.Source()
+}
+
+func (t *SubscribedTrack) Simulcasted() bool {
+	return t.publishedTrack.Simulcasted()
+}
+
+// LayerBitrates returns the current bitrate of each layer received from the publisher
+func (t *SubscribedTrack) LayerBitrates() [3]uint64 {
+	return t.publishedTrack.LayerBitrates()
+}
+
 // has subscriber indicated it wants to mute this track
 func (t *SubscribedTrack) IsMuted() bool {
 	return t.subMuted.Get()
 }
 
+// SpatialLayer returns the highest spatial layer the subscriber needs, -1 when it doesn't need the track
+func (t *SubscribedTrack) SpatialLayer() int32 {
+	if t.subMuted.Get() || t.invisible.Get() || t.lastNPaused.Get() {
+		return -1
+	}
+	return spatialLayerForQuality(t.quality.Load().(livekit.VideoQuality))
+}
+
+// ForwardedLayer returns the spatial layer being forwarded to the subscriber, -1 when paused
+func (t *SubscribedTrack) ForwardedLayer() int32 {
+	allocated := atomic.LoadInt32(&t.allocatedLayer)
+	if t.subMuted.Get() || t.invisible.Get() || t.pubMuted.Get() || t.lastNPaused.Get() || allocated < 0 {
+		return -1
+	}
+	if t.Kind() != livekit.TrackType_VIDEO {
+		return 0
+	}
+	layer := spatialLayerForQuality(t.quality.Load().(livekit.VideoQuality))
+	if allocated < layer {
+		layer = allocated
+	}
+	if !t.Simulcasted() {
+		layer = 0
+	}
+	return layer
+}
+
+// Stats reports the stream sent to the subscriber, using counters from the subscriber transport
+func (t *SubscribedTrack) Stats(streamStats *stats.StreamStats) types.TrackStats {
+	ts := types.TrackStats{
+		TrackSid:       t.ID(),
+		ParticipantSid: t.PublisherID(),
+		Kind:           t.Kind().String(),
+		Muted:          t.subMuted.Get() || t.invisible.Get() || t.pubMuted.Get(),
+		SpatialLayer:   t.ForwardedLayer(),
+	}
+	if snapshot, ok := streamStats.Get(t.ssrc); ok {
+		addStreamSnapshot(&ts, snapshot, t.publishedTrack.codec.ClockRate)
+	}
+	return ts
+}
+
+// OnSubscriptionChanged is called after subscriber settings have been applied
+func (t *SubscribedTrack) OnSubscriptionChanged(f func()) {
+	t.onSubscriptionChanged = f
+}
+
 func (t *SubscribedTrack) SetPublisherMuted(muted bool) {
 	t.pubMuted.TrySet(muted)
 	t.updateDownTrackMute()
 }
 
+// SetLastNPaused pauses the track when its publisher falls out of the subscriber's last-N speakers
+func (t *SubscribedTrack) SetLastNPaused(paused bool) {
+	if !t.lastNPaused.TrySet(paused) {
+		return
+	}
+	t.updateDownTrackMute()
+	if t.onSubscriptionChanged != nil {
+		t.onSubscriptionChanged()
+	}
+}
+
+// SetAllocatedLayer limits the layer forwarded to the subscriber, -1 pauses the track.
+// returns true when the allocation has changed
+func (t *SubscribedTrack) SetAllocatedLayer(layer int32) bool {
+	if atomic.SwapInt32(&t.allocatedLayer, layer) == layer {
+		return false
+	}
+	t.updateDownTrackMute()
+	t.updateDownTrackLayer()
+	return true
+}
+
 func (t *SubscribedTrack) UpdateSubscriberSettings(enabled bool, quality livekit.VideoQuality) {
 	t.debouncer(func() {
 		t.subMuted.TrySet(!enabled)
+		t.quality.Store(quality)
 		t.updateDownTrackMute()
-		if enabled && t.dt.Kind() == webrtc.RTPCodecTypeVideo {
-			err := t.dt.SwitchSpatialLayer(spatialLayerForQuality(quality), true)
-			if err == sfu.ErrSpatialLayerNotFound && quality != livekit.VideoQuality_MEDIUM {
-				// try to switch to middle layer
-				_ = t.dt.SwitchSpatialLayer(spatialLayerForQuality(livekit.VideoQuality_MEDIUM), true)
-			}
+		t.updateDownTrackLayer()
+		if t.onSubscriptionChanged != nil {
+			t.onSubscriptionChanged()
+		}
+	})
+}
+
+// UpdateSubscriberDimensions selects the smallest layer that covers the size the track is rendered at.
+// tracks that are not visible (zero width or height) are paused, keeping the subscriber's settings
+func (t *SubscribedTrack) UpdateSubscriberDimensions(width, height uint32) {
+	t.debouncer(func() {
+		invisible := width == 0 || height == 0
+		t.invisible.TrySet(invisible)
+		if !invisible {
+			params := t.publishedTrack.params
+			t.quality.Store(qualityForDimensions(t.publishedTrack.Layers(), params.Width, params.Height, width, height))
+		}
+		t.updateDownTrackMute()
+		t.updateDownTrackLayer()
+		if t.onSubscriptionChanged != nil {
+			t.onSubscriptionChanged()
 		}
 	})
 }
 
 func (t *SubscribedTrack) updateDownTrackMute() {
-	muted := t.subMuted.Get() || t.pubMuted.Get()
+	muted := t.subMuted.Get() || t.invisible.Get() || t.pubMuted.Get() || t.lastNPaused.Get() ||
+		atomic.LoadInt32(&t.allocatedLayer) < 0
 	t.dt.Mute(muted)
 }
 
+// switches to the layer requested by the subscriber, within what's been allocated
+func (t *SubscribedTrack) updateDownTrackLayer() {
+	if t.subMuted.Get() || t.invisible.Get() || t.dt.Kind() != webrtc.RTPCodecTypeVideo {
+		return
+	}
+	layer := spatialLayerForQuality(t.quality.Load().(livekit.VideoQuality))
+	if allocated := atomic.LoadInt32(&t.allocatedLayer); allocated >= 0 && allocated < layer {
+		layer = allocated
+	}
+	err := t.dt.SwitchSpatialLayer(layer, true)
+	if err == sfu.ErrSpatialLayerNotFound && layer != spatialLayerForQuality(livekit.VideoQuality_MEDIUM) {
+		// try to switch to middle layer
+		_ = t.dt.SwitchSpatialLayer(spatialLayerForQuality(livekit.VideoQuality_MEDIUM), true)
+	}
+}
+
+// qualityForDimensions uses the dimensions of the layers advertised by the publisher. without them, each
+// simulcast layer is assumed to halve the resolution of the one above it
+func qualityForDimensions(layers []types.VideoLayer, pubWidth, pubHeight, width, height uint32) livekit.VideoQuality {
+	if len(layers) > 0 {
+		quality := livekit.VideoQuality_HIGH
+		found := false
+		for _, layer := range layers {
+			if layer.Width < width || layer.Height < height {
+				continue
+			}
+			if !found || spatialLayerForQuality(layer.Quality) < spatialLayerForQuality(quality) {
+				quality = layer.Quality
+				found = true
+			}
+		}
+		return quality
+	}
+	if pubWidth == 0 || pubHeight == 0 {
+		// publisher dimensions unknown
+		return livekit.VideoQuality_HIGH
+	}
+	for layer := int32(0); layer < 2; layer++ {
+		scale := uint32(1) << uint(2-layer)
+		if pubWidth/scale >= width && pubHeight/scale >= height {
+			return qualityForSpatialLayer(layer)
+		}
+	}
+	return livekit.VideoQuality_HIGH
+}
+
+func qualityForSpatialLayer(layer int32) livekit.VideoQuality {
+	switch layer {
+	case 0:
+		return livekit.VideoQuality_LOW
+	case 1:
+		return livekit.VideoQuality_MEDIUM
+	default:
+		return livekit.VideoQuality_HIGH
+	}
+}
+
 func spatialLayerForQuality(quality livekit.VideoQuality) int32 {
 	switch quality {
 	case livekit.VideoQuality_LOW:
@@ -0,0 +1,46 @@
+package rtc
+
+import (
+	"testing"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestQualityForDimensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		width    uint32
+		height   uint32
+		expected livekit.VideoQuality
+	}{
+		{name: "thumbnail", width: 160, height: 90, expected: livekit.VideoQuality_LOW},
+		{name: "exactly quarter", width: 320, height: 180, expected: livekit.VideoQuality_LOW},
+		{name: "grid tile", width: 480, height: 270, expected: livekit.VideoQuality_MEDIUM},
+		{name: "tall tile", width: 300, height: 400, expected: livekit.VideoQuality_HIGH},
+		{name: "fullscreen", width: 1920, height: 1080, expected: livekit.VideoQuality_HIGH},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, qualityForDimensions(nil, 1280, 720, test.width, test.height))
+		})
+	}
+
+	t.Run("unknown publisher dimensions", func(t *testing.T) {
+		require.Equal(t, livekit.VideoQuality_HIGH, qualityForDimensions(nil, 0, 0, 160, 90))
+	})
+	t.Run("advertised layer dimensions", func(t *testing.T) {
+		layers := []types.VideoLayer{
+			{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720},
+			{Quality: livekit.VideoQuality_MEDIUM, Width: 960, Height: 540},
+			{Quality: livekit.VideoQuality_LOW, Width: 480, Height: 270},
+		}
+		// a grid tile is covered by the low layer, which isn't a quarter of the publisher's resolution
+		require.Equal(t, livekit.VideoQuality_LOW, qualityForDimensions(layers, 1280, 720, 480, 270))
+		require.Equal(t, livekit.VideoQuality_MEDIUM, qualityForDimensions(layers, 1280, 720, 640, 360))
+		require.Equal(t, livekit.VideoQuality_HIGH, qualityForDimensions(layers, 1280, 720, 1920, 1080))
+	})
+}
@@ -0,0 +1,335 @@
+package rtc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/ion-sfu/pkg/buffer"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+const (
+	// packets queued for writing before new packets are dropped
+	recorderQueueSize = 500
+	// how far out of order packets can arrive before the sample they belong to is given up on
+	recorderMaxLate = 200
+)
+
+var ErrUnsupportedRecordingCodec = errors.New("codec is not supported for recording")
+
+// TrackRecorder writes RTP of a track to a file as it arrives: Opus to Ogg, VP8 and VP9 to IVF, and H264 to Annex-B.
+// video is written starting with the first keyframe received, earlier packets can't be decoded
+type TrackRecorder struct {
+	ssrc     uint32
+	mimeType string
+	filePath string
+	file     *os.File
+	builder  *samplebuilder.SampleBuilder
+	writer   sampleWriter
+	done     chan struct{}
+	err      error
+
+	hasKeyFrame utils.AtomicFlag
+
+	lock    sync.RWMutex
+	packets chan *rtp.Packet
+	closed  bool
+}
+
+type sampleWriter interface {
+	WriteSample(sample *media.Sample) error
+	Close() error
+}
+
+// NewTrackRecorder creates the recording file at basePath, with an extension for the codec
+func NewTrackRecorder(basePath string, ssrc uint32, codec webrtc.RTPCodecParameters, width, height uint32) (*TrackRecorder, error) {
+	var ext string
+	var depacketizer rtp.Depacketizer
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		ext, depacketizer = ".ogg", &codecs.OpusPacket{}
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		ext, depacketizer = ".ivf", &codecs.VP8Packet{}
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		ext, depacketizer = ".ivf", &codecs.VP9Packet{}
+	case strings.ToLower(webrtc.MimeTypeH264):
+		ext, depacketizer = ".h264", &codecs.H264Packet{}
+	default:
+		return nil, ErrUnsupportedRecordingCodec
+	}
+
+	filePath := basePath + ext
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var writer sampleWriter
+	switch ext {
+	case ".ogg":
+		writer, err = newOggSampleWriter(file, codec.ClockRate, codec.Channels)
+	case ".ivf":
+		writer, err = newIVFSampleWriter(file, codec.MimeType, codec.ClockRate, width, height)
+	default:
+		writer = &annexBSampleWriter{w: file}
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	r := &TrackRecorder{
+		ssrc:     ssrc,
+		mimeType: strings.ToLower(codec.MimeType),
+		filePath: filePath,
+		file:     file,
+		builder:  samplebuilder.New(recorderMaxLate, depacketizer, codec.ClockRate),
+		writer:   writer,
+		packets:  make(chan *rtp.Packet, recorderQueueSize),
+		done:     make(chan struct{}),
+	}
+	if ext == ".ogg" {
+		r.hasKeyFrame.TrySet(true)
+	}
+	go r.writeWorker()
+	return r, nil
+}
+
+func (r *TrackRecorder) SSRC() uint32 {
+	return r.ssrc
+}
+
+func (r *TrackRecorder) FilePath() string {
+	return r.filePath
+}
+
+// HasKeyFrame returns true once writing has started, on the first keyframe of video. audio is written right away
+func (r *TrackRecorder) HasKeyFrame() bool {
+	return r.hasKeyFrame.Get()
+}
+
+// WritePacket queues a raw RTP packet for writing, it does not block
+func (r *TrackRecorder) WritePacket(data []byte) {
+	// buffer is reused by the caller
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(append([]byte{}, data...)); err != nil {
+		return
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.packets <- pkt:
+	default:
+		logger.Debugw("recorder queue full, dropping packet", "file", r.filePath)
+	}
+}
+
+// Stop writes out what's been received and closes the file
+func (r *TrackRecorder) Stop() error {
+	r.lock.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.packets)
+	}
+	r.lock.Unlock()
+
+	<-r.done
+	return r.err
+}
+
+func (r *TrackRecorder) writeWorker() {
+	defer close(r.done)
+
+	for pkt := range r.packets {
+		if r.err != nil {
+			continue
+		}
+		if !r.hasKeyFrame.Get() {
+			if !isKeyFrame(r.mimeType, pkt.Payload) {
+				continue
+			}
+			r.hasKeyFrame.TrySet(true)
+		}
+		r.builder.Push(pkt)
+		for sample := r.builder.Pop(); sample != nil; sample = r.builder.Pop() {
+			if err := r.writer.WriteSample(sample); err != nil {
+				logger.Errorw("could not write recording", err, "file", r.filePath)
+				r.err = err
+				break
+			}
+		}
+	}
+
+	if err := r.writer.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// isKeyFrame returns true when an RTP payload starts a keyframe
+func isKeyFrame(mimeType string, payload []byte) bool {
+	switch mimeType {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		vp8 := buffer.VP8{}
+		return vp8.Unmarshal(payload) == nil && vp8.IsKeyFrame
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		vp9 := codecs.VP9Packet{}
+		_, err := vp9.Unmarshal(payload)
+		return err == nil && vp9.B && !vp9.P
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return isH264KeyFrame(payload)
+	}
+	return false
+}
+
+// isH264KeyFrame looks for an IDR slice or sequence parameter set, alone, aggregated or at the start of a fragment
+func isH264KeyFrame(payload []byte) bool {
+	const (
+		naluIDR   = 5
+		naluSPS   = 7
+		naluSTAPA = 24
+		naluFUA   = 28
+	)
+	if len(payload) < 1 {
+		return false
+	}
+	switch nalu := payload[0] & 0x1f; nalu {
+	case naluIDR, naluSPS:
+		return true
+	case naluSTAPA:
+		for i := 1; i+2 < len(payload); {
+			size := int(binary.BigEndian.Uint16(payload[i:]))
+			if t := payload[i+2] & 0x1f; t == naluIDR || t == naluSPS {
+				return true
+			}
+			i += 2 + size
+		}
+	case naluFUA:
+		if len(payload) < 2 || payload[1]&0x80 == 0 {
+			return false
+		}
+		t := payload[1] & 0x1f
+		return t == naluIDR || t == naluSPS
+	}
+	return false
+}
+
+// Ogg container, using packets rebuilt from samples
+type oggSampleWriter struct {
+	ogg            *oggwriter.OggWriter
+	sequenceNumber uint16
+}
+
+func newOggSampleWriter(w io.Writer, clockRate uint32, channels uint16) (*oggSampleWriter, error) {
+	// hide the file's Close, it's closed by the recorder
+	ogg, err := oggwriter.NewWith(struct{ io.Writer }{w}, clockRate, channels)
+	if err != nil {
+		return nil, err
+	}
+	return &oggSampleWriter{ogg: ogg}, nil
+}
+
+func (w *oggSampleWriter) WriteSample(sample *media.Sample) error {
+	w.sequenceNumber++
+	return w.ogg.WriteRTP(&rtp.Packet{
+		Header: rtp.Header{
+			SequenceNumber: w.sequenceNumber,
+			Timestamp:      sample.PacketTimestamp,
+		},
+		Payload: sample.Data,
+	})
+}
+
+func (w *oggSampleWriter) Close() error {
+	return w.ogg.Close()
+}
+
+// IVF container for VP8 and VP9, frame timestamps are in RTP clock units
+type ivfSampleWriter struct {
+	w              io.WriteSeeker
+	frames         uint32
+	firstTimestamp uint32
+}
+
+func newIVFSampleWriter(w io.WriteSeeker, mimeType string, clockRate uint32, width, height uint32) (*ivfSampleWriter, error) {
+	fourCC := "VP80"
+	if strings.EqualFold(mimeType, webrtc.MimeTypeVP9) {
+		fourCC = "VP90"
+	}
+	header := make([]byte, 32)
+	copy(header[0:], "DKIF")
+	binary.LittleEndian.PutUint16(header[4:], 0)  // version
+	binary.LittleEndian.PutUint16(header[6:], 32) // header size
+	copy(header[8:], fourCC)
+	binary.LittleEndian.PutUint16(header[12:], uint16(width))
+	binary.LittleEndian.PutUint16(header[14:], uint16(height))
+	binary.LittleEndian.PutUint32(header[16:], clockRate) // timebase denominator
+	binary.LittleEndian.PutUint32(header[20:], 1)         // timebase numerator
+	// frame count at 24 is filled in on close
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &ivfSampleWriter{w: w}, nil
+}
+
+func (w *ivfSampleWriter) WriteSample(sample *media.Sample) error {
+	if w.frames == 0 {
+		w.firstTimestamp = sample.PacketTimestamp
+	}
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(sample.Data)))
+	binary.LittleEndian.PutUint64(header[4:], uint64(sample.PacketTimestamp-w.firstTimestamp))
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(sample.Data); err != nil {
+		return err
+	}
+	w.frames++
+	return nil
+}
+
+func (w *ivfSampleWriter) Close() error {
+	if _, err := w.w.Seek(24, io.SeekStart); err != nil {
+		return err
+	}
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, w.frames)
+	_, err := w.w.Write(count)
+	return err
+}
+
+// H264 samples are depacketized with start codes, written as is
+type annexBSampleWriter struct {
+	w io.Writer
+}
+
+func (w *annexBSampleWriter) WriteSample(sample *media.Sample) error {
+	_, err := w.w.Write(sample.Data)
+	return err
+}
+
+func (w *annexBSampleWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package rtc
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackRecorder(t *testing.T) {
+	t.Run("rejects unsupported codecs", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "recorder")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		_, err = NewTrackRecorder(filepath.Join(dir, "track"), 1, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "video/AV1", ClockRate: 90000},
+		}, 0, 0)
+		require.Equal(t, ErrUnsupportedRecordingCodec, err)
+	})
+
+	t.Run("ivf header and frames", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "recording*.ivf")
+		require.NoError(t, err)
+		defer os.Remove(f.Name())
+
+		w, err := newIVFSampleWriter(f, webrtc.MimeTypeVP8, 90000, 640, 360)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteSample(&media.Sample{Data: []byte{1, 2, 3}, PacketTimestamp: 1000}))
+		require.NoError(t, w.WriteSample(&media.Sample{Data: []byte{4, 5}, PacketTimestamp: 4000}))
+		require.NoError(t, w.Close())
+		require.NoError(t, f.Close())
+
+		data, err := ioutil.ReadFile(f.Name())
+		require.NoError(t, err)
+		require.Len(t, data, 32+12+3+12+2)
+		require.Equal(t, "DKIF", string(data[0:4]))
+		require.Equal(t, "VP80", string(data[8:12]))
+		require.EqualValues(t, 640, binary.LittleEndian.Uint16(data[12:]))
+		require.EqualValues(t, 360, binary.LittleEndian.Uint16(data[14:]))
+		require.EqualValues(t, 2, binary.LittleEndian.Uint32(data[24:]))
+
+		// timestamps are relative to the first frame
+		second := data[32+12+3:]
+		require.EqualValues(t, 2, binary.LittleEndian.Uint32(second[0:]))
+		require.EqualValues(t, 3000, binary.LittleEndian.Uint64(second[4:]))
+	})
+
+	t.Run("starts video on a keyframe", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "recorder")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		r, err := NewTrackRecorder(filepath.Join(dir, "track"), 1, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+		}, 640, 360)
+		require.NoError(t, err)
+		// single packet frames, starting a partition. the low bit of the VP8 header is clear for keyframes
+		write := func(seq uint16, ts uint32, key bool) {
+			header := byte(1)
+			if key {
+				header = 0
+			}
+			pkt := &rtp.Packet{
+				Header:  rtp.Header{Version: 2, SequenceNumber: seq, Timestamp: ts, Marker: true, SSRC: 1},
+				Payload: []byte{0x10, header, 0, 0},
+			}
+			data, err := pkt.Marshal()
+			require.NoError(t, err)
+			r.WritePacket(data)
+		}
+
+		write(1, 0, false)
+		write(2, 3000, false)
+		write(3, 6000, true)
+		require.Eventually(t, r.HasKeyFrame, time.Second, 10*time.Millisecond)
+		write(4, 9000, false)
+		write(5, 12000, false)
+		require.NoError(t, r.Stop())
+
+		data, err := ioutil.ReadFile(r.FilePath())
+		require.NoError(t, err)
+		// frames from the keyframe on, the last one isn't known to be complete
+		require.EqualValues(t, 2, binary.LittleEndian.Uint32(data[24:]))
+	})
+
+	t.Run("audio doesn't wait for a keyframe", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "recorder")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		r, err := NewTrackRecorder(filepath.Join(dir, "track"), 1, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+		}, 0, 0)
+		require.NoError(t, err)
+		require.True(t, r.HasKeyFrame())
+		require.NoError(t, r.Stop())
+	})
+}
+
+func TestIsH264KeyFrame(t *testing.T) {
+	// IDR slice
+	require.True(t, isH264KeyFrame([]byte{0x65, 0x88}))
+	// non-IDR slice
+	require.False(t, isH264KeyFrame([]byte{0x41, 0x9a}))
+	// STAP-A with SPS and PPS
+	require.True(t, isH264KeyFrame([]byte{0x78, 0, 2, 0x67, 0x42, 0, 2, 0x68, 0xce}))
+	// first and later fragments of an IDR slice
+	require.True(t, isH264KeyFrame([]byte{0x7c, 0x85, 0x88}))
+	require.False(t, isH264KeyFrame([]byte{0x7c, 0x05, 0x88}))
+	require.False(t, isH264KeyFrame(nil))
+}
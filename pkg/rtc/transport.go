@@ -8,6 +8,7 @@ import (
 	"github.com/livekit/protocol/logger"
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/twcc"
 	"github.com/pion/webrtc/v3"
 
 	"github.com/livekit/livekit-server/pkg/utils/stats"
@@ -27,8 +28,12 @@ const (
 
 // PCTransport is a wrapper around PeerConnection, with some helper methods
 type PCTransport struct {
-	pc *webrtc.PeerConnection
-	me *webrtc.MediaEngine
+	pc          *webrtc.PeerConnection
+	me          *webrtc.MediaEngine
+	streamStats *stats.StreamStats
+	rtpTap      *RTPTap
+	// only set on subscriber transports
+	bwe *BandwidthEstimator
 
 	lock                  sync.Mutex
 	pendingCandidates     []webrtc.ICECandidateInit
@@ -45,7 +50,7 @@ type TransportParams struct {
 	EnabledCodecs []*livekit.Codec
 }
 
-func newPeerConnection(params TransportParams) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
+func newPeerConnection(params TransportParams, streamStats *stats.StreamStats, rtpTap *RTPTap, bwe *BandwidthEstimator) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
 	var me *webrtc.MediaEngine
 	var err error
 	if params.Target == livekit.SignalTarget_PUBLISHER {
@@ -65,8 +70,29 @@ func newPeerConnection(params TransportParams) (*webrtc.PeerConnection, *webrtc.
 		}
 		se.BufferFactory = wrapper.CreateBuffer
 	}
+	if se.BufferFactory != nil {
+		wrapper := &stats.StreamStatsBufferWrapper{
+			CreateBufferFunc: se.BufferFactory,
+			Stats:            streamStats,
+		}
+		se.BufferFactory = wrapper.CreateBuffer
+		se.BufferFactory = rtpTap.WrapBufferFactory(se.BufferFactory)
+		if bwe != nil {
+			se.BufferFactory = bwe.WrapBufferFactory(se.BufferFactory)
+		}
+	}
 
 	ir := &interceptor.Registry{}
+	ir.Add(stats.NewStreamStatsInterceptor(streamStats))
+	if bwe != nil {
+		// sequence numbers are added before packets reach the estimator
+		ir.Add(bwe)
+		twccExtension, err := twcc.NewHeaderExtensionInterceptor()
+		if err != nil {
+			return nil, nil, err
+		}
+		ir.Add(twccExtension)
+	}
 	if params.Stats != nil && params.Target == livekit.SignalTarget_SUBSCRIBER {
 		// only capture subscriber for outbound streams
 		ir.Add(stats.NewStatsInterceptor(params.Stats))
@@ -81,7 +107,13 @@ func newPeerConnection(params TransportParams) (*webrtc.PeerConnection, *webrtc.
 }
 
 func NewPCTransport(params TransportParams) (*PCTransport, error) {
-	pc, me, err := newPeerConnection(params)
+	streamStats := stats.NewStreamStats()
+	rtpTap := NewRTPTap()
+	var bwe *BandwidthEstimator
+	if params.Target == livekit.SignalTarget_SUBSCRIBER {
+		bwe = NewBandwidthEstimator()
+	}
+	pc, me, err := newPeerConnection(params, streamStats, rtpTap, bwe)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +121,9 @@ func NewPCTransport(params TransportParams) (*PCTransport, error) {
 	t := &PCTransport{
 		pc:                 pc,
 		me:                 me,
+		streamStats:        streamStats,
+		rtpTap:             rtpTap,
+		bwe:                bwe,
 		debouncedNegotiate: debounce.New(negotiationFrequency),
 		negotiationState:   negotiationStateNone,
 	}
@@ -125,6 +160,31 @@ func (t *PCTransport) PeerConnection() *webrtc.PeerConnection {
 	return t.pc
 }
 
+// StreamStats returns counters for the RTP streams sent and received on this transport
+func (t *PCTransport) StreamStats() *stats.StreamStats {
+	return t.streamStats
+}
+
+// RTPTap gives access to RTP received on this transport
+func (t *PCTransport) RTPTap() *RTPTap {
+	return t.rtpTap
+}
+
+// BandwidthEstimator estimates the downstream bandwidth of subscriber transports, nil for publishers
+func (t *PCTransport) BandwidthEstimator() *BandwidthEstimator {
+	return t.bwe
+}
+
+// RTT returns the round trip time in milliseconds of the selected ICE candidate pair, 0 when unknown
+func (t *PCTransport) RTT() float64 {
+	for _, s := range t.pc.GetStats() {
+		if pair, ok := s.(webrtc.ICECandidatePairStats); ok && pair.Nominated {
+			return pair.CurrentRoundTripTime * 1000
+		}
+	}
+	return 0
+}
+
 func (t *PCTransport) Close() {
 	_ = t.pc.Close()
 }
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"fmt"
+
+	livekit "github.com/livekit/protocol/proto"
+)
+
+// ControlMessage is exchanged with clients over the control data channel. It carries
+// updates that are not yet part of the signaling protocol. Exactly one field is set.
+type ControlMessage struct {
+	SubscribedQualityUpdate *SubscribedQualityUpdate `json:"subscribed_quality_update,omitempty"`
+	TrackDimensionsUpdate   *TrackDimensionsUpdate   `json:"track_dimensions_update,omitempty"`
+	TrackLayersUpdate       *TrackLayersUpdate       `json:"track_layers_update,omitempty"`
+	ConnectionQualityUpdate *ConnectionQualityUpdate `json:"connection_quality_update,omitempty"`
+	PinnedTracksUpdate      *PinnedTracksUpdate      `json:"pinned_tracks_update,omitempty"`
+	RoomMetadataUpdate      *RoomMetadataUpdate      `json:"room_metadata_update,omitempty"`
+}
+
+// SubscribedQualityUpdate lets a publisher know which simulcast layers of a track are
+// needed by subscribers. Layers that are not enabled can be paused by the publisher.
+type SubscribedQualityUpdate struct {
+	TrackSid            string              `json:"track_sid"`
+	SubscribedQualities []SubscribedQuality `json:"subscribed_qualities"`
+}
+
+type SubscribedQuality struct {
+	Quality livekit.VideoQuality `json:"quality"`
+	Enabled bool                 `json:"enabled"`
+}
+
+// TrackDimensionsUpdate is sent by subscribers with the size, in physical pixels, of the element
+// rendering the tracks. A zero width or height indicates the tracks are not visible.
+type TrackDimensionsUpdate struct {
+	TrackSids []string `json:"track_sids"`
+	Width     uint32   `json:"width"`
+	Height    uint32   `json:"height"`
+}
+
+// TrackLayersUpdate is sent by publishers with the dimensions of each simulcast layer of a track, so
+// subscribers are sent the smallest layer that covers the size they render it at
+type TrackLayersUpdate struct {
+	TrackSid string       `json:"track_sid"`
+	Layers   []VideoLayer `json:"layers"`
+}
+
+type VideoLayer struct {
+	Quality livekit.VideoQuality `json:"quality"`
+	Width   uint32               `json:"width"`
+	Height  uint32               `json:"height"`
+}
+
+// PinnedTracksUpdate is sent by subscribers with the video tracks they want to receive even when
+// the publisher isn't one of the room's last-N speakers. It replaces previously pinned tracks.
+type PinnedTracksUpdate struct {
+	TrackSids []string `json:"track_sids"`
+}
+
+// RoomMetadataUpdate is sent to everyone in the room when its metadata changes, and to participants as
+// they join a room that has metadata
+type RoomMetadataUpdate struct {
+	Metadata string `json:"metadata"`
+}
+
+// ConnectionQualityUpdate is sent periodically to everyone in the room
+type ConnectionQualityUpdate struct {
+	Updates []ConnectionQualityInfo `json:"updates"`
+}
+
+type ConnectionQualityInfo struct {
+	ParticipantSid string            `json:"participant_sid"`
+	Quality        ConnectionQuality `json:"quality"`
+}
+
+// ConnectionQuality is a rating of a participant's network, ordered from worst to best
+type ConnectionQuality int
+
+const (
+	ConnectionQualityPoor ConnectionQuality = iota
+	ConnectionQualityGood
+	ConnectionQualityExcellent
+)
+
+func (q ConnectionQuality) String() string {
+	switch q {
+	case ConnectionQualityPoor:
+		return "poor"
+	case ConnectionQualityGood:
+		return "good"
+	case ConnectionQualityExcellent:
+		return "excellent"
+	default:
+		return fmt.Sprintf("%d", int(q))
+	}
+}
+
+func (q ConnectionQuality) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+func (q *ConnectionQuality) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "poor":
+		*q = ConnectionQualityPoor
+	case "good":
+		*q = ConnectionQualityGood
+	case "excellent":
+		*q = ConnectionQualityExcellent
+	default:
+		return fmt.Errorf("unknown connection quality: %s", text)
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package types
 
 import (
+	"net"
 	"time"
 
 	"github.com/pion/ion-sfu/pkg/sfu"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 
 	"github.com/livekit/livekit-server/pkg/routing"
@@ -49,9 +51,14 @@ type Participant interface {
 	SendJoinResponse(info *livekit.Room, otherParticipants []Participant, iceServers []*livekit.ICEServer) error
 	SendParticipantUpdate(participants []*livekit.ParticipantInfo) error
 	SendActiveSpeakers(speakers []*livekit.SpeakerInfo) error
+	UpdateActiveSpeakers(speakers []*livekit.SpeakerInfo)
+	SetLastNPublishers(publisherIDs []string)
 	SendDataPacket(packet *livekit.DataPacket) error
+	SendControlMessage(msg *ControlMessage) error
 	SetTrackMuted(trackId string, muted bool, fromAdmin bool)
 	GetAudioLevel() (level uint8, active bool)
+	GetConnectionQuality() ConnectionQuality
+	GetStats() *ParticipantStats
 
 	// permissions
 
@@ -59,9 +66,12 @@ type Participant interface {
 	CanSubscribe() bool
 	CanPublishData() bool
 	Hidden() bool
+	// a relay of a participant connected to another node
+	Relayed() bool
 
 	Start()
 	Close() error
+	CloseWithReconnect() error
 
 	// callbacks
 
@@ -79,6 +89,7 @@ type Participant interface {
 	AddSubscribedTrack(participantId string, st SubscribedTrack)
 	RemoveSubscribedTrack(participantId string, st SubscribedTrack)
 	SubscriberPC() *webrtc.PeerConnection
+	AddSubscriberTransceiver(track webrtc.TrackLocal) (*webrtc.RTPTransceiver, error)
 	UpdateAfterActive() bool
 
 	DebugInfo() map[string]interface{}
@@ -92,6 +103,7 @@ type PublishedTrack interface {
 	ID() string
 	Kind() livekit.TrackType
 	Name() string
+	Codec() webrtc.RTPCodecParameters
 	IsMuted() bool
 	SetMuted(muted bool)
 	AddSubscriber(participant Participant) error
@@ -99,6 +111,11 @@ type PublishedTrack interface {
 	IsSubscriber(subId string) bool
 	RemoveAllSubscribers()
 	ToProto() *livekit.TrackInfo
+	StartRecording(basePath string) (string, error)
+	StopRecording() error
+	StartRTPEgress(egressID string, addr *net.UDPAddr, quality livekit.VideoQuality) (string, error)
+	StopRTPEgress(egressID string) error
+	ForwardRTP(egressID string, quality livekit.VideoQuality, writeRTP func(header *rtp.Header, payload []byte)) (func(), error)
 
 	// callbacks
 	OnClose(func())
@@ -111,6 +128,7 @@ type SubscribedTrack interface {
 	IsMuted() bool
 	SetPublisherMuted(muted bool)
 	UpdateSubscriberSettings(enabled bool, quality livekit.VideoQuality)
+	UpdateSubscriberDimensions(width, height uint32)
 }
 
 // interface for properties of webrtc.TrackRemote
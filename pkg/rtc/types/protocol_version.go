@@ -4,6 +4,10 @@ type ProtocolVersion int
 
 const DefaultProtocol = 2
 
+// HTTPSubscriberProtocol is used by WHEP players, which offer the subscriber connection and can't take offers.
+// SDKs never send it, so it supports none of their features
+const HTTPSubscriberProtocol ProtocolVersion = -1
+
 func (v ProtocolVersion) SupportsPackedStreamId() bool {
 	return v > 0
 }
@@ -20,3 +24,13 @@ func (v ProtocolVersion) HandlesDataPackets() bool {
 func (v ProtocolVersion) SubscriberAsPrimary() bool {
 	return v > 2
 }
+
+// SupportsControlChannel indicates clients accept ControlMessages on the control data channel
+func (v ProtocolVersion) SupportsControlChannel() bool {
+	return v > 3
+}
+
+// OffersSubscriber indicates clients offer the subscriber connection, and are sent answers instead of offers
+func (v ProtocolVersion) OffersSubscriber() bool {
+	return v == HTTPSubscriberProtocol
+}
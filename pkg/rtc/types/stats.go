@@ -0,0 +1,36 @@
+package types
+
+// ParticipantStats is a snapshot of statistics for the tracks a participant publishes and subscribes to
+type ParticipantStats struct {
+	ParticipantSid string `json:"participant_sid"`
+	Identity       string `json:"identity"`
+	// unix timestamp of when the snapshot was taken
+	UpdatedAt  int64        `json:"updated_at"`
+	Published  []TrackStats `json:"published"`
+	Subscribed []TrackStats `json:"subscribed"`
+}
+
+// TrackStats describes a single track. For published tracks, loss and feedback counts are what the server
+// reported to the publisher. For subscribed tracks, they are what the subscriber reported to the server.
+type TrackStats struct {
+	TrackSid string `json:"track_sid"`
+	// participant that published the track
+	ParticipantSid string `json:"participant_sid"`
+	Kind           string `json:"kind"`
+	Muted          bool   `json:"muted"`
+	// bits per second
+	Bitrate     uint64 `json:"bitrate"`
+	Packets     uint64 `json:"packets"`
+	PacketsLost uint32 `json:"packets_lost"`
+	// packets lost in the last report interval
+	LossPercentage float64 `json:"loss_percentage"`
+	// milliseconds
+	Jitter float64 `json:"jitter"`
+	RTT    float64 `json:"rtt"`
+
+	NackCount uint64 `json:"nack_count"`
+	PLICount  uint64 `json:"pli_count"`
+	FIRCount  uint64 `json:"fir_count"`
+	// highest simulcast layer being received (published) or forwarded (subscribed), -1 when paused
+	SpatialLayer int32 `json:"spatial_layer"`
+}
@@ -0,0 +1,12 @@
+package types
+
+// TrackSource is what a published track is captured from.
+// values match the source newer clients send in AddTrackRequest, which this protocol version doesn't define
+type TrackSource int32
+
+const (
+	TrackSourceUnknown TrackSource = iota
+	TrackSourceCamera
+	TrackSourceMicrophone
+	TrackSourceScreenShare
+)
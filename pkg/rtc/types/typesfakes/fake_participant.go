@@ -44,6 +44,19 @@ type FakeParticipant struct {
 		result1 int
 		result2 error
 	}
+	AddSubscriberTransceiverStub        func(webrtc.TrackLocal) (*webrtc.RTPTransceiver, error)
+	addSubscriberTransceiverMutex       sync.RWMutex
+	addSubscriberTransceiverArgsForCall []struct {
+		arg1 webrtc.TrackLocal
+	}
+	addSubscriberTransceiverReturns struct {
+		result1 *webrtc.RTPTransceiver
+		result2 error
+	}
+	addSubscriberTransceiverReturnsOnCall map[int]struct {
+		result1 *webrtc.RTPTransceiver
+		result2 error
+	}
 	AddTrackStub        func(*livekit.AddTrackRequest)
 	addTrackMutex       sync.RWMutex
 	addTrackArgsForCall []struct {
@@ -89,6 +102,16 @@ type FakeParticipant struct {
 	closeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CloseWithReconnectStub        func() error
+	closeWithReconnectMutex       sync.RWMutex
+	closeWithReconnectArgsForCall []struct {
+	}
+	closeWithReconnectReturns struct {
+		result1 error
+	}
+	closeWithReconnectReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ConnectedAtStub        func() time.Time
 	connectedAtMutex       sync.RWMutex
 	connectedAtArgsForCall []struct {
@@ -121,6 +144,16 @@ type FakeParticipant struct {
 		result1 uint8
 		result2 bool
 	}
+	GetConnectionQualityStub        func() types.ConnectionQuality
+	getConnectionQualityMutex       sync.RWMutex
+	getConnectionQualityArgsForCall []struct {
+	}
+	getConnectionQualityReturns struct {
+		result1 types.ConnectionQuality
+	}
+	getConnectionQualityReturnsOnCall map[int]struct {
+		result1 types.ConnectionQuality
+	}
 	GetPublishedTracksStub        func() []types.PublishedTrack
 	getPublishedTracksMutex       sync.RWMutex
 	getPublishedTracksArgsForCall []struct {
@@ -141,6 +174,16 @@ type FakeParticipant struct {
 	getResponseSinkReturnsOnCall map[int]struct {
 		result1 routing.MessageSink
 	}
+	GetStatsStub        func() *types.ParticipantStats
+	getStatsMutex       sync.RWMutex
+	getStatsArgsForCall []struct {
+	}
+	getStatsReturns struct {
+		result1 *types.ParticipantStats
+	}
+	getStatsReturnsOnCall map[int]struct {
+		result1 *types.ParticipantStats
+	}
 	GetSubscribedTracksStub        func() []types.SubscribedTrack
 	getSubscribedTracksMutex       sync.RWMutex
 	getSubscribedTracksArgsForCall []struct {
@@ -279,6 +322,16 @@ type FakeParticipant struct {
 	rTCPChanReturnsOnCall map[int]struct {
 		result1 chan []rtcp.Packet
 	}
+	RelayedStub        func() bool
+	relayedMutex       sync.RWMutex
+	relayedArgsForCall []struct {
+	}
+	relayedReturns struct {
+		result1 bool
+	}
+	relayedReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	RemoveSubscribedTrackStub        func(string, types.SubscribedTrack)
 	removeSubscribedTrackMutex       sync.RWMutex
 	removeSubscribedTrackArgsForCall []struct {
@@ -301,6 +354,17 @@ type FakeParticipant struct {
 	sendActiveSpeakersReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SendControlMessageStub        func(*types.ControlMessage) error
+	sendControlMessageMutex       sync.RWMutex
+	sendControlMessageArgsForCall []struct {
+		arg1 *types.ControlMessage
+	}
+	sendControlMessageReturns struct {
+		result1 error
+	}
+	sendControlMessageReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SendDataPacketStub        func(*livekit.DataPacket) error
 	sendDataPacketMutex       sync.RWMutex
 	sendDataPacketArgsForCall []struct {
@@ -336,6 +400,11 @@ type FakeParticipant struct {
 	sendParticipantUpdateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetLastNPublishersStub        func([]string)
+	setLastNPublishersMutex       sync.RWMutex
+	setLastNPublishersArgsForCall []struct {
+		arg1 []string
+	}
 	SetMetadataStub        func(string)
 	setMetadataMutex       sync.RWMutex
 	setMetadataArgsForCall []struct {
@@ -402,6 +471,11 @@ type FakeParticipant struct {
 	toProtoReturnsOnCall map[int]struct {
 		result1 *livekit.ParticipantInfo
 	}
+	UpdateActiveSpeakersStub        func([]*livekit.SpeakerInfo)
+	updateActiveSpeakersMutex       sync.RWMutex
+	updateActiveSpeakersArgsForCall []struct {
+		arg1 []*livekit.SpeakerInfo
+	}
 	UpdateAfterActiveStub        func() bool
 	updateAfterActiveMutex       sync.RWMutex
 	updateAfterActiveArgsForCall []struct {
@@ -575,6 +649,70 @@ func (fake *FakeParticipant) AddSubscriberReturnsOnCall(i int, result1 int, resu
 	}{result1, result2}
 }
 
+func (fake *FakeParticipant) AddSubscriberTransceiver(arg1 webrtc.TrackLocal) (*webrtc.RTPTransceiver, error) {
+	fake.addSubscriberTransceiverMutex.Lock()
+	ret, specificReturn := fake.addSubscriberTransceiverReturnsOnCall[len(fake.addSubscriberTransceiverArgsForCall)]
+	fake.addSubscriberTransceiverArgsForCall = append(fake.addSubscriberTransceiverArgsForCall, struct {
+		arg1 webrtc.TrackLocal
+	}{arg1})
+	stub := fake.AddSubscriberTransceiverStub
+	fakeReturns := fake.addSubscriberTransceiverReturns
+	fake.recordInvocation("AddSubscriberTransceiver", []interface{}{arg1})
+	fake.addSubscriberTransceiverMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeParticipant) AddSubscriberTransceiverCallCount() int {
+	fake.addSubscriberTransceiverMutex.RLock()
+	defer fake.addSubscriberTransceiverMutex.RUnlock()
+	return len(fake.addSubscriberTransceiverArgsForCall)
+}
+
+func (fake *FakeParticipant) AddSubscriberTransceiverCalls(stub func(webrtc.TrackLocal) (*webrtc.RTPTransceiver, error)) {
+	fake.addSubscriberTransceiverMutex.Lock()
+	defer fake.addSubscriberTransceiverMutex.Unlock()
+	fake.AddSubscriberTransceiverStub = stub
+}
+
+func (fake *FakeParticipant) AddSubscriberTransceiverArgsForCall(i int) webrtc.TrackLocal {
+	fake.addSubscriberTransceiverMutex.RLock()
+	defer fake.addSubscriberTransceiverMutex.RUnlock()
+	argsForCall := fake.addSubscriberTransceiverArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeParticipant) AddSubscriberTransceiverReturns(result1 *webrtc.RTPTransceiver, result2 error) {
+	fake.addSubscriberTransceiverMutex.Lock()
+	defer fake.addSubscriberTransceiverMutex.Unlock()
+	fake.AddSubscriberTransceiverStub = nil
+	fake.addSubscriberTransceiverReturns = struct {
+		result1 *webrtc.RTPTransceiver
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeParticipant) AddSubscriberTransceiverReturnsOnCall(i int, result1 *webrtc.RTPTransceiver, result2 error) {
+	fake.addSubscriberTransceiverMutex.Lock()
+	defer fake.addSubscriberTransceiverMutex.Unlock()
+	fake.AddSubscriberTransceiverStub = nil
+	if fake.addSubscriberTransceiverReturnsOnCall == nil {
+		fake.addSubscriberTransceiverReturnsOnCall = make(map[int]struct {
+			result1 *webrtc.RTPTransceiver
+			result2 error
+		})
+	}
+	fake.addSubscriberTransceiverReturnsOnCall[i] = struct {
+		result1 *webrtc.RTPTransceiver
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeParticipant) AddTrack(arg1 *livekit.AddTrackRequest) {
 	fake.addTrackMutex.Lock()
 	fake.addTrackArgsForCall = append(fake.addTrackArgsForCall, struct {
@@ -819,6 +957,59 @@ func (fake *FakeParticipant) CloseReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeParticipant) CloseWithReconnect() error {
+	fake.closeWithReconnectMutex.Lock()
+	ret, specificReturn := fake.closeWithReconnectReturnsOnCall[len(fake.closeWithReconnectArgsForCall)]
+	fake.closeWithReconnectArgsForCall = append(fake.closeWithReconnectArgsForCall, struct {
+	}{})
+	stub := fake.CloseWithReconnectStub
+	fakeReturns := fake.closeWithReconnectReturns
+	fake.recordInvocation("CloseWithReconnect", []interface{}{})
+	fake.closeWithReconnectMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) CloseWithReconnectCallCount() int {
+	fake.closeWithReconnectMutex.RLock()
+	defer fake.closeWithReconnectMutex.RUnlock()
+	return len(fake.closeWithReconnectArgsForCall)
+}
+
+func (fake *FakeParticipant) CloseWithReconnectCalls(stub func() error) {
+	fake.closeWithReconnectMutex.Lock()
+	defer fake.closeWithReconnectMutex.Unlock()
+	fake.CloseWithReconnectStub = stub
+}
+
+func (fake *FakeParticipant) CloseWithReconnectReturns(result1 error) {
+	fake.closeWithReconnectMutex.Lock()
+	defer fake.closeWithReconnectMutex.Unlock()
+	fake.CloseWithReconnectStub = nil
+	fake.closeWithReconnectReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeParticipant) CloseWithReconnectReturnsOnCall(i int, result1 error) {
+	fake.closeWithReconnectMutex.Lock()
+	defer fake.closeWithReconnectMutex.Unlock()
+	fake.CloseWithReconnectStub = nil
+	if fake.closeWithReconnectReturnsOnCall == nil {
+		fake.closeWithReconnectReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeWithReconnectReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeParticipant) ConnectedAt() time.Time {
 	fake.connectedAtMutex.Lock()
 	ret, specificReturn := fake.connectedAtReturnsOnCall[len(fake.connectedAtArgsForCall)]
@@ -981,6 +1172,59 @@ func (fake *FakeParticipant) GetAudioLevelReturnsOnCall(i int, result1 uint8, re
 	}{result1, result2}
 }
 
+func (fake *FakeParticipant) GetConnectionQuality() types.ConnectionQuality {
+	fake.getConnectionQualityMutex.Lock()
+	ret, specificReturn := fake.getConnectionQualityReturnsOnCall[len(fake.getConnectionQualityArgsForCall)]
+	fake.getConnectionQualityArgsForCall = append(fake.getConnectionQualityArgsForCall, struct {
+	}{})
+	stub := fake.GetConnectionQualityStub
+	fakeReturns := fake.getConnectionQualityReturns
+	fake.recordInvocation("GetConnectionQuality", []interface{}{})
+	fake.getConnectionQualityMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) GetConnectionQualityCallCount() int {
+	fake.getConnectionQualityMutex.RLock()
+	defer fake.getConnectionQualityMutex.RUnlock()
+	return len(fake.getConnectionQualityArgsForCall)
+}
+
+func (fake *FakeParticipant) GetConnectionQualityCalls(stub func() types.ConnectionQuality) {
+	fake.getConnectionQualityMutex.Lock()
+	defer fake.getConnectionQualityMutex.Unlock()
+	fake.GetConnectionQualityStub = stub
+}
+
+func (fake *FakeParticipant) GetConnectionQualityReturns(result1 types.ConnectionQuality) {
+	fake.getConnectionQualityMutex.Lock()
+	defer fake.getConnectionQualityMutex.Unlock()
+	fake.GetConnectionQualityStub = nil
+	fake.getConnectionQualityReturns = struct {
+		result1 types.ConnectionQuality
+	}{result1}
+}
+
+func (fake *FakeParticipant) GetConnectionQualityReturnsOnCall(i int, result1 types.ConnectionQuality) {
+	fake.getConnectionQualityMutex.Lock()
+	defer fake.getConnectionQualityMutex.Unlock()
+	fake.GetConnectionQualityStub = nil
+	if fake.getConnectionQualityReturnsOnCall == nil {
+		fake.getConnectionQualityReturnsOnCall = make(map[int]struct {
+			result1 types.ConnectionQuality
+		})
+	}
+	fake.getConnectionQualityReturnsOnCall[i] = struct {
+		result1 types.ConnectionQuality
+	}{result1}
+}
+
 func (fake *FakeParticipant) GetPublishedTracks() []types.PublishedTrack {
 	fake.getPublishedTracksMutex.Lock()
 	ret, specificReturn := fake.getPublishedTracksReturnsOnCall[len(fake.getPublishedTracksArgsForCall)]
@@ -1087,6 +1331,59 @@ func (fake *FakeParticipant) GetResponseSinkReturnsOnCall(i int, result1 routing
 	}{result1}
 }
 
+func (fake *FakeParticipant) GetStats() *types.ParticipantStats {
+	fake.getStatsMutex.Lock()
+	ret, specificReturn := fake.getStatsReturnsOnCall[len(fake.getStatsArgsForCall)]
+	fake.getStatsArgsForCall = append(fake.getStatsArgsForCall, struct {
+	}{})
+	stub := fake.GetStatsStub
+	fakeReturns := fake.getStatsReturns
+	fake.recordInvocation("GetStats", []interface{}{})
+	fake.getStatsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) GetStatsCallCount() int {
+	fake.getStatsMutex.RLock()
+	defer fake.getStatsMutex.RUnlock()
+	return len(fake.getStatsArgsForCall)
+}
+
+func (fake *FakeParticipant) GetStatsCalls(stub func() *types.ParticipantStats) {
+	fake.getStatsMutex.Lock()
+	defer fake.getStatsMutex.Unlock()
+	fake.GetStatsStub = stub
+}
+
+func (fake *FakeParticipant) GetStatsReturns(result1 *types.ParticipantStats) {
+	fake.getStatsMutex.Lock()
+	defer fake.getStatsMutex.Unlock()
+	fake.GetStatsStub = nil
+	fake.getStatsReturns = struct {
+		result1 *types.ParticipantStats
+	}{result1}
+}
+
+func (fake *FakeParticipant) GetStatsReturnsOnCall(i int, result1 *types.ParticipantStats) {
+	fake.getStatsMutex.Lock()
+	defer fake.getStatsMutex.Unlock()
+	fake.GetStatsStub = nil
+	if fake.getStatsReturnsOnCall == nil {
+		fake.getStatsReturnsOnCall = make(map[int]struct {
+			result1 *types.ParticipantStats
+		})
+	}
+	fake.getStatsReturnsOnCall[i] = struct {
+		result1 *types.ParticipantStats
+	}{result1}
+}
+
 func (fake *FakeParticipant) GetSubscribedTracks() []types.SubscribedTrack {
 	fake.getSubscribedTracksMutex.Lock()
 	ret, specificReturn := fake.getSubscribedTracksReturnsOnCall[len(fake.getSubscribedTracksArgsForCall)]
@@ -1852,6 +2149,59 @@ func (fake *FakeParticipant) RTCPChanReturnsOnCall(i int, result1 chan []rtcp.Pa
 	}{result1}
 }
 
+func (fake *FakeParticipant) Relayed() bool {
+	fake.relayedMutex.Lock()
+	ret, specificReturn := fake.relayedReturnsOnCall[len(fake.relayedArgsForCall)]
+	fake.relayedArgsForCall = append(fake.relayedArgsForCall, struct {
+	}{})
+	stub := fake.RelayedStub
+	fakeReturns := fake.relayedReturns
+	fake.recordInvocation("Relayed", []interface{}{})
+	fake.relayedMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) RelayedCallCount() int {
+	fake.relayedMutex.RLock()
+	defer fake.relayedMutex.RUnlock()
+	return len(fake.relayedArgsForCall)
+}
+
+func (fake *FakeParticipant) RelayedCalls(stub func() bool) {
+	fake.relayedMutex.Lock()
+	defer fake.relayedMutex.Unlock()
+	fake.RelayedStub = stub
+}
+
+func (fake *FakeParticipant) RelayedReturns(result1 bool) {
+	fake.relayedMutex.Lock()
+	defer fake.relayedMutex.Unlock()
+	fake.RelayedStub = nil
+	fake.relayedReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeParticipant) RelayedReturnsOnCall(i int, result1 bool) {
+	fake.relayedMutex.Lock()
+	defer fake.relayedMutex.Unlock()
+	fake.RelayedStub = nil
+	if fake.relayedReturnsOnCall == nil {
+		fake.relayedReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.relayedReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeParticipant) RemoveSubscribedTrack(arg1 string, arg2 types.SubscribedTrack) {
 	fake.removeSubscribedTrackMutex.Lock()
 	fake.removeSubscribedTrackArgsForCall = append(fake.removeSubscribedTrackArgsForCall, struct {
@@ -1983,6 +2333,67 @@ func (fake *FakeParticipant) SendActiveSpeakersReturnsOnCall(i int, result1 erro
 	}{result1}
 }
 
+func (fake *FakeParticipant) SendControlMessage(arg1 *types.ControlMessage) error {
+	fake.sendControlMessageMutex.Lock()
+	ret, specificReturn := fake.sendControlMessageReturnsOnCall[len(fake.sendControlMessageArgsForCall)]
+	fake.sendControlMessageArgsForCall = append(fake.sendControlMessageArgsForCall, struct {
+		arg1 *types.ControlMessage
+	}{arg1})
+	stub := fake.SendControlMessageStub
+	fakeReturns := fake.sendControlMessageReturns
+	fake.recordInvocation("SendControlMessage", []interface{}{arg1})
+	fake.sendControlMessageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) SendControlMessageCallCount() int {
+	fake.sendControlMessageMutex.RLock()
+	defer fake.sendControlMessageMutex.RUnlock()
+	return len(fake.sendControlMessageArgsForCall)
+}
+
+func (fake *FakeParticipant) SendControlMessageCalls(stub func(*types.ControlMessage) error) {
+	fake.sendControlMessageMutex.Lock()
+	defer fake.sendControlMessageMutex.Unlock()
+	fake.SendControlMessageStub = stub
+}
+
+func (fake *FakeParticipant) SendControlMessageArgsForCall(i int) *types.ControlMessage {
+	fake.sendControlMessageMutex.RLock()
+	defer fake.sendControlMessageMutex.RUnlock()
+	argsForCall := fake.sendControlMessageArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeParticipant) SendControlMessageReturns(result1 error) {
+	fake.sendControlMessageMutex.Lock()
+	defer fake.sendControlMessageMutex.Unlock()
+	fake.SendControlMessageStub = nil
+	fake.sendControlMessageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeParticipant) SendControlMessageReturnsOnCall(i int, result1 error) {
+	fake.sendControlMessageMutex.Lock()
+	defer fake.sendControlMessageMutex.Unlock()
+	fake.SendControlMessageStub = nil
+	if fake.sendControlMessageReturnsOnCall == nil {
+		fake.sendControlMessageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.sendControlMessageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeParticipant) SendDataPacket(arg1 *livekit.DataPacket) error {
 	fake.sendDataPacketMutex.Lock()
 	ret, specificReturn := fake.sendDataPacketReturnsOnCall[len(fake.sendDataPacketArgsForCall)]
@@ -2183,6 +2594,43 @@ func (fake *FakeParticipant) SendParticipantUpdateReturnsOnCall(i int, result1 e
 	}{result1}
 }
 
+func (fake *FakeParticipant) SetLastNPublishers(arg1 []string) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.setLastNPublishersMutex.Lock()
+	fake.setLastNPublishersArgsForCall = append(fake.setLastNPublishersArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	stub := fake.SetLastNPublishersStub
+	fake.recordInvocation("SetLastNPublishers", []interface{}{arg1Copy})
+	fake.setLastNPublishersMutex.Unlock()
+	if stub != nil {
+		fake.SetLastNPublishersStub(arg1)
+	}
+}
+
+func (fake *FakeParticipant) SetLastNPublishersCallCount() int {
+	fake.setLastNPublishersMutex.RLock()
+	defer fake.setLastNPublishersMutex.RUnlock()
+	return len(fake.setLastNPublishersArgsForCall)
+}
+
+func (fake *FakeParticipant) SetLastNPublishersCalls(stub func([]string)) {
+	fake.setLastNPublishersMutex.Lock()
+	defer fake.setLastNPublishersMutex.Unlock()
+	fake.SetLastNPublishersStub = stub
+}
+
+func (fake *FakeParticipant) SetLastNPublishersArgsForCall(i int) []string {
+	fake.setLastNPublishersMutex.RLock()
+	defer fake.setLastNPublishersMutex.RUnlock()
+	argsForCall := fake.setLastNPublishersArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeParticipant) SetMetadata(arg1 string) {
 	fake.setMetadataMutex.Lock()
 	fake.setMetadataArgsForCall = append(fake.setMetadataArgsForCall, struct {
@@ -2549,6 +2997,43 @@ func (fake *FakeParticipant) ToProtoReturnsOnCall(i int, result1 *livekit.Partic
 	}{result1}
 }
 
+func (fake *FakeParticipant) UpdateActiveSpeakers(arg1 []*livekit.SpeakerInfo) {
+	var arg1Copy []*livekit.SpeakerInfo
+	if arg1 != nil {
+		arg1Copy = make([]*livekit.SpeakerInfo, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.updateActiveSpeakersMutex.Lock()
+	fake.updateActiveSpeakersArgsForCall = append(fake.updateActiveSpeakersArgsForCall, struct {
+		arg1 []*livekit.SpeakerInfo
+	}{arg1Copy})
+	stub := fake.UpdateActiveSpeakersStub
+	fake.recordInvocation("UpdateActiveSpeakers", []interface{}{arg1Copy})
+	fake.updateActiveSpeakersMutex.Unlock()
+	if stub != nil {
+		fake.UpdateActiveSpeakersStub(arg1)
+	}
+}
+
+func (fake *FakeParticipant) UpdateActiveSpeakersCallCount() int {
+	fake.updateActiveSpeakersMutex.RLock()
+	defer fake.updateActiveSpeakersMutex.RUnlock()
+	return len(fake.updateActiveSpeakersArgsForCall)
+}
+
+func (fake *FakeParticipant) UpdateActiveSpeakersCalls(stub func([]*livekit.SpeakerInfo)) {
+	fake.updateActiveSpeakersMutex.Lock()
+	defer fake.updateActiveSpeakersMutex.Unlock()
+	fake.UpdateActiveSpeakersStub = stub
+}
+
+func (fake *FakeParticipant) UpdateActiveSpeakersArgsForCall(i int) []*livekit.SpeakerInfo {
+	fake.updateActiveSpeakersMutex.RLock()
+	defer fake.updateActiveSpeakersMutex.RUnlock()
+	argsForCall := fake.updateActiveSpeakersArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeParticipant) UpdateAfterActive() bool {
 	fake.updateAfterActiveMutex.Lock()
 	ret, specificReturn := fake.updateAfterActiveReturnsOnCall[len(fake.updateAfterActiveArgsForCall)]
@@ -2611,6 +3096,8 @@ func (fake *FakeParticipant) Invocations() map[string][][]interface{} {
 	defer fake.addSubscribedTrackMutex.RUnlock()
 	fake.addSubscriberMutex.RLock()
 	defer fake.addSubscriberMutex.RUnlock()
+	fake.addSubscriberTransceiverMutex.RLock()
+	defer fake.addSubscriberTransceiverMutex.RUnlock()
 	fake.addTrackMutex.RLock()
 	defer fake.addTrackMutex.RUnlock()
 	fake.canPublishMutex.RLock()
@@ -2621,16 +3108,22 @@ func (fake *FakeParticipant) Invocations() map[string][][]interface{} {
 	defer fake.canSubscribeMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
+	fake.closeWithReconnectMutex.RLock()
+	defer fake.closeWithReconnectMutex.RUnlock()
 	fake.connectedAtMutex.RLock()
 	defer fake.connectedAtMutex.RUnlock()
 	fake.debugInfoMutex.RLock()
 	defer fake.debugInfoMutex.RUnlock()
 	fake.getAudioLevelMutex.RLock()
 	defer fake.getAudioLevelMutex.RUnlock()
+	fake.getConnectionQualityMutex.RLock()
+	defer fake.getConnectionQualityMutex.RUnlock()
 	fake.getPublishedTracksMutex.RLock()
 	defer fake.getPublishedTracksMutex.RUnlock()
 	fake.getResponseSinkMutex.RLock()
 	defer fake.getResponseSinkMutex.RUnlock()
+	fake.getStatsMutex.RLock()
+	defer fake.getStatsMutex.RUnlock()
 	fake.getSubscribedTracksMutex.RLock()
 	defer fake.getSubscribedTracksMutex.RUnlock()
 	fake.handleAnswerMutex.RLock()
@@ -2665,18 +3158,24 @@ func (fake *FakeParticipant) Invocations() map[string][][]interface{} {
 	defer fake.protocolVersionMutex.RUnlock()
 	fake.rTCPChanMutex.RLock()
 	defer fake.rTCPChanMutex.RUnlock()
+	fake.relayedMutex.RLock()
+	defer fake.relayedMutex.RUnlock()
 	fake.removeSubscribedTrackMutex.RLock()
 	defer fake.removeSubscribedTrackMutex.RUnlock()
 	fake.removeSubscriberMutex.RLock()
 	defer fake.removeSubscriberMutex.RUnlock()
 	fake.sendActiveSpeakersMutex.RLock()
 	defer fake.sendActiveSpeakersMutex.RUnlock()
+	fake.sendControlMessageMutex.RLock()
+	defer fake.sendControlMessageMutex.RUnlock()
 	fake.sendDataPacketMutex.RLock()
 	defer fake.sendDataPacketMutex.RUnlock()
 	fake.sendJoinResponseMutex.RLock()
 	defer fake.sendJoinResponseMutex.RUnlock()
 	fake.sendParticipantUpdateMutex.RLock()
 	defer fake.sendParticipantUpdateMutex.RUnlock()
+	fake.setLastNPublishersMutex.RLock()
+	defer fake.setLastNPublishersMutex.RUnlock()
 	fake.setMetadataMutex.RLock()
 	defer fake.setMetadataMutex.RUnlock()
 	fake.setPermissionMutex.RLock()
@@ -2695,6 +3194,8 @@ func (fake *FakeParticipant) Invocations() map[string][][]interface{} {
 	defer fake.subscriberPCMutex.RUnlock()
 	fake.toProtoMutex.RLock()
 	defer fake.toProtoMutex.RUnlock()
+	fake.updateActiveSpeakersMutex.RLock()
+	defer fake.updateActiveSpeakersMutex.RUnlock()
 	fake.updateAfterActiveMutex.RLock()
 	defer fake.updateAfterActiveMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
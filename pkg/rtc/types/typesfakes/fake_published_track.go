@@ -2,10 +2,13 @@
 package typesfakes
 
 import (
+	"net"
 	"sync"
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/rtp"
+	webrtc "github.com/pion/webrtc/v3"
 )
 
 type FakePublishedTrack struct {
@@ -20,6 +23,31 @@ type FakePublishedTrack struct {
 	addSubscriberReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CodecStub        func() webrtc.RTPCodecParameters
+	codecMutex       sync.RWMutex
+	codecArgsForCall []struct {
+	}
+	codecReturns struct {
+		result1 webrtc.RTPCodecParameters
+	}
+	codecReturnsOnCall map[int]struct {
+		result1 webrtc.RTPCodecParameters
+	}
+	ForwardRTPStub        func(string, livekit.VideoQuality, func(header *rtp.Header, payload []byte)) (func(), error)
+	forwardRTPMutex       sync.RWMutex
+	forwardRTPArgsForCall []struct {
+		arg1 string
+		arg2 livekit.VideoQuality
+		arg3 func(header *rtp.Header, payload []byte)
+	}
+	forwardRTPReturns struct {
+		result1 func()
+		result2 error
+	}
+	forwardRTPReturnsOnCall map[int]struct {
+		result1 func()
+		result2 error
+	}
 	IDStub        func() string
 	iDMutex       sync.RWMutex
 	iDArgsForCall []struct {
@@ -94,6 +122,55 @@ type FakePublishedTrack struct {
 	startMutex       sync.RWMutex
 	startArgsForCall []struct {
 	}
+	StartRTPEgressStub        func(string, *net.UDPAddr, livekit.VideoQuality) (string, error)
+	startRTPEgressMutex       sync.RWMutex
+	startRTPEgressArgsForCall []struct {
+		arg1 string
+		arg2 *net.UDPAddr
+		arg3 livekit.VideoQuality
+	}
+	startRTPEgressReturns struct {
+		result1 string
+		result2 error
+	}
+	startRTPEgressReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	StartRecordingStub        func(string) (string, error)
+	startRecordingMutex       sync.RWMutex
+	startRecordingArgsForCall []struct {
+		arg1 string
+	}
+	startRecordingReturns struct {
+		result1 string
+		result2 error
+	}
+	startRecordingReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	StopRTPEgressStub        func(string) error
+	stopRTPEgressMutex       sync.RWMutex
+	stopRTPEgressArgsForCall []struct {
+		arg1 string
+	}
+	stopRTPEgressReturns struct {
+		result1 error
+	}
+	stopRTPEgressReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StopRecordingStub        func() error
+	stopRecordingMutex       sync.RWMutex
+	stopRecordingArgsForCall []struct {
+	}
+	stopRecordingReturns struct {
+		result1 error
+	}
+	stopRecordingReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ToProtoStub        func() *livekit.TrackInfo
 	toProtoMutex       sync.RWMutex
 	toProtoArgsForCall []struct {
@@ -169,6 +246,125 @@ func (fake *FakePublishedTrack) AddSubscriberReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakePublishedTrack) Codec() webrtc.RTPCodecParameters {
+	fake.codecMutex.Lock()
+	ret, specificReturn := fake.codecReturnsOnCall[len(fake.codecArgsForCall)]
+	fake.codecArgsForCall = append(fake.codecArgsForCall, struct {
+	}{})
+	stub := fake.CodecStub
+	fakeReturns := fake.codecReturns
+	fake.recordInvocation("Codec", []interface{}{})
+	fake.codecMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePublishedTrack) CodecCallCount() int {
+	fake.codecMutex.RLock()
+	defer fake.codecMutex.RUnlock()
+	return len(fake.codecArgsForCall)
+}
+
+func (fake *FakePublishedTrack) CodecCalls(stub func() webrtc.RTPCodecParameters) {
+	fake.codecMutex.Lock()
+	defer fake.codecMutex.Unlock()
+	fake.CodecStub = stub
+}
+
+func (fake *FakePublishedTrack) CodecReturns(result1 webrtc.RTPCodecParameters) {
+	fake.codecMutex.Lock()
+	defer fake.codecMutex.Unlock()
+	fake.CodecStub = nil
+	fake.codecReturns = struct {
+		result1 webrtc.RTPCodecParameters
+	}{result1}
+}
+
+func (fake *FakePublishedTrack) CodecReturnsOnCall(i int, result1 webrtc.RTPCodecParameters) {
+	fake.codecMutex.Lock()
+	defer fake.codecMutex.Unlock()
+	fake.CodecStub = nil
+	if fake.codecReturnsOnCall == nil {
+		fake.codecReturnsOnCall = make(map[int]struct {
+			result1 webrtc.RTPCodecParameters
+		})
+	}
+	fake.codecReturnsOnCall[i] = struct {
+		result1 webrtc.RTPCodecParameters
+	}{result1}
+}
+
+func (fake *FakePublishedTrack) ForwardRTP(arg1 string, arg2 livekit.VideoQuality, arg3 func(header *rtp.Header, payload []byte)) (func(), error) {
+	fake.forwardRTPMutex.Lock()
+	ret, specificReturn := fake.forwardRTPReturnsOnCall[len(fake.forwardRTPArgsForCall)]
+	fake.forwardRTPArgsForCall = append(fake.forwardRTPArgsForCall, struct {
+		arg1 string
+		arg2 livekit.VideoQuality
+		arg3 func(header *rtp.Header, payload []byte)
+	}{arg1, arg2, arg3})
+	stub := fake.ForwardRTPStub
+	fakeReturns := fake.forwardRTPReturns
+	fake.recordInvocation("ForwardRTP", []interface{}{arg1, arg2, arg3})
+	fake.forwardRTPMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePublishedTrack) ForwardRTPCallCount() int {
+	fake.forwardRTPMutex.RLock()
+	defer fake.forwardRTPMutex.RUnlock()
+	return len(fake.forwardRTPArgsForCall)
+}
+
+func (fake *FakePublishedTrack) ForwardRTPCalls(stub func(string, livekit.VideoQuality, func(header *rtp.Header, payload []byte)) (func(), error)) {
+	fake.forwardRTPMutex.Lock()
+	defer fake.forwardRTPMutex.Unlock()
+	fake.ForwardRTPStub = stub
+}
+
+func (fake *FakePublishedTrack) ForwardRTPArgsForCall(i int) (string, livekit.VideoQuality, func(header *rtp.Header, payload []byte)) {
+	fake.forwardRTPMutex.RLock()
+	defer fake.forwardRTPMutex.RUnlock()
+	argsForCall := fake.forwardRTPArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakePublishedTrack) ForwardRTPReturns(result1 func(), result2 error) {
+	fake.forwardRTPMutex.Lock()
+	defer fake.forwardRTPMutex.Unlock()
+	fake.ForwardRTPStub = nil
+	fake.forwardRTPReturns = struct {
+		result1 func()
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePublishedTrack) ForwardRTPReturnsOnCall(i int, result1 func(), result2 error) {
+	fake.forwardRTPMutex.Lock()
+	defer fake.forwardRTPMutex.Unlock()
+	fake.ForwardRTPStub = nil
+	if fake.forwardRTPReturnsOnCall == nil {
+		fake.forwardRTPReturnsOnCall = make(map[int]struct {
+			result1 func()
+			result2 error
+		})
+	}
+	fake.forwardRTPReturnsOnCall[i] = struct {
+		result1 func()
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakePublishedTrack) ID() string {
 	fake.iDMutex.Lock()
 	ret, specificReturn := fake.iDReturnsOnCall[len(fake.iDArgsForCall)]
@@ -586,6 +782,250 @@ func (fake *FakePublishedTrack) StartCalls(stub func()) {
 	fake.StartStub = stub
 }
 
+func (fake *FakePublishedTrack) StartRTPEgress(arg1 string, arg2 *net.UDPAddr, arg3 livekit.VideoQuality) (string, error) {
+	fake.startRTPEgressMutex.Lock()
+	ret, specificReturn := fake.startRTPEgressReturnsOnCall[len(fake.startRTPEgressArgsForCall)]
+	fake.startRTPEgressArgsForCall = append(fake.startRTPEgressArgsForCall, struct {
+		arg1 string
+		arg2 *net.UDPAddr
+		arg3 livekit.VideoQuality
+	}{arg1, arg2, arg3})
+	stub := fake.StartRTPEgressStub
+	fakeReturns := fake.startRTPEgressReturns
+	fake.recordInvocation("StartRTPEgress", []interface{}{arg1, arg2, arg3})
+	fake.startRTPEgressMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePublishedTrack) StartRTPEgressCallCount() int {
+	fake.startRTPEgressMutex.RLock()
+	defer fake.startRTPEgressMutex.RUnlock()
+	return len(fake.startRTPEgressArgsForCall)
+}
+
+func (fake *FakePublishedTrack) StartRTPEgressCalls(stub func(string, *net.UDPAddr, livekit.VideoQuality) (string, error)) {
+	fake.startRTPEgressMutex.Lock()
+	defer fake.startRTPEgressMutex.Unlock()
+	fake.StartRTPEgressStub = stub
+}
+
+func (fake *FakePublishedTrack) StartRTPEgressArgsForCall(i int) (string, *net.UDPAddr, livekit.VideoQuality) {
+	fake.startRTPEgressMutex.RLock()
+	defer fake.startRTPEgressMutex.RUnlock()
+	argsForCall := fake.startRTPEgressArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakePublishedTrack) StartRTPEgressReturns(result1 string, result2 error) {
+	fake.startRTPEgressMutex.Lock()
+	defer fake.startRTPEgressMutex.Unlock()
+	fake.StartRTPEgressStub = nil
+	fake.startRTPEgressReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePublishedTrack) StartRTPEgressReturnsOnCall(i int, result1 string, result2 error) {
+	fake.startRTPEgressMutex.Lock()
+	defer fake.startRTPEgressMutex.Unlock()
+	fake.StartRTPEgressStub = nil
+	if fake.startRTPEgressReturnsOnCall == nil {
+		fake.startRTPEgressReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.startRTPEgressReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePublishedTrack) StartRecording(arg1 string) (string, error) {
+	fake.startRecordingMutex.Lock()
+	ret, specificReturn := fake.startRecordingReturnsOnCall[len(fake.startRecordingArgsForCall)]
+	fake.startRecordingArgsForCall = append(fake.startRecordingArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StartRecordingStub
+	fakeReturns := fake.startRecordingReturns
+	fake.recordInvocation("StartRecording", []interface{}{arg1})
+	fake.startRecordingMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePublishedTrack) StartRecordingCallCount() int {
+	fake.startRecordingMutex.RLock()
+	defer fake.startRecordingMutex.RUnlock()
+	return len(fake.startRecordingArgsForCall)
+}
+
+func (fake *FakePublishedTrack) StartRecordingCalls(stub func(string) (string, error)) {
+	fake.startRecordingMutex.Lock()
+	defer fake.startRecordingMutex.Unlock()
+	fake.StartRecordingStub = stub
+}
+
+func (fake *FakePublishedTrack) StartRecordingArgsForCall(i int) string {
+	fake.startRecordingMutex.RLock()
+	defer fake.startRecordingMutex.RUnlock()
+	argsForCall := fake.startRecordingArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePublishedTrack) StartRecordingReturns(result1 string, result2 error) {
+	fake.startRecordingMutex.Lock()
+	defer fake.startRecordingMutex.Unlock()
+	fake.StartRecordingStub = nil
+	fake.startRecordingReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePublishedTrack) StartRecordingReturnsOnCall(i int, result1 string, result2 error) {
+	fake.startRecordingMutex.Lock()
+	defer fake.startRecordingMutex.Unlock()
+	fake.StartRecordingStub = nil
+	if fake.startRecordingReturnsOnCall == nil {
+		fake.startRecordingReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.startRecordingReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePublishedTrack) StopRTPEgress(arg1 string) error {
+	fake.stopRTPEgressMutex.Lock()
+	ret, specificReturn := fake.stopRTPEgressReturnsOnCall[len(fake.stopRTPEgressArgsForCall)]
+	fake.stopRTPEgressArgsForCall = append(fake.stopRTPEgressArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StopRTPEgressStub
+	fakeReturns := fake.stopRTPEgressReturns
+	fake.recordInvocation("StopRTPEgress", []interface{}{arg1})
+	fake.stopRTPEgressMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePublishedTrack) StopRTPEgressCallCount() int {
+	fake.stopRTPEgressMutex.RLock()
+	defer fake.stopRTPEgressMutex.RUnlock()
+	return len(fake.stopRTPEgressArgsForCall)
+}
+
+func (fake *FakePublishedTrack) StopRTPEgressCalls(stub func(string) error) {
+	fake.stopRTPEgressMutex.Lock()
+	defer fake.stopRTPEgressMutex.Unlock()
+	fake.StopRTPEgressStub = stub
+}
+
+func (fake *FakePublishedTrack) StopRTPEgressArgsForCall(i int) string {
+	fake.stopRTPEgressMutex.RLock()
+	defer fake.stopRTPEgressMutex.RUnlock()
+	argsForCall := fake.stopRTPEgressArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePublishedTrack) StopRTPEgressReturns(result1 error) {
+	fake.stopRTPEgressMutex.Lock()
+	defer fake.stopRTPEgressMutex.Unlock()
+	fake.StopRTPEgressStub = nil
+	fake.stopRTPEgressReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePublishedTrack) StopRTPEgressReturnsOnCall(i int, result1 error) {
+	fake.stopRTPEgressMutex.Lock()
+	defer fake.stopRTPEgressMutex.Unlock()
+	fake.StopRTPEgressStub = nil
+	if fake.stopRTPEgressReturnsOnCall == nil {
+		fake.stopRTPEgressReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.stopRTPEgressReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePublishedTrack) StopRecording() error {
+	fake.stopRecordingMutex.Lock()
+	ret, specificReturn := fake.stopRecordingReturnsOnCall[len(fake.stopRecordingArgsForCall)]
+	fake.stopRecordingArgsForCall = append(fake.stopRecordingArgsForCall, struct {
+	}{})
+	stub := fake.StopRecordingStub
+	fakeReturns := fake.stopRecordingReturns
+	fake.recordInvocation("StopRecording", []interface{}{})
+	fake.stopRecordingMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePublishedTrack) StopRecordingCallCount() int {
+	fake.stopRecordingMutex.RLock()
+	defer fake.stopRecordingMutex.RUnlock()
+	return len(fake.stopRecordingArgsForCall)
+}
+
+func (fake *FakePublishedTrack) StopRecordingCalls(stub func() error) {
+	fake.stopRecordingMutex.Lock()
+	defer fake.stopRecordingMutex.Unlock()
+	fake.StopRecordingStub = stub
+}
+
+func (fake *FakePublishedTrack) StopRecordingReturns(result1 error) {
+	fake.stopRecordingMutex.Lock()
+	defer fake.stopRecordingMutex.Unlock()
+	fake.StopRecordingStub = nil
+	fake.stopRecordingReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePublishedTrack) StopRecordingReturnsOnCall(i int, result1 error) {
+	fake.stopRecordingMutex.Lock()
+	defer fake.stopRecordingMutex.Unlock()
+	fake.StopRecordingStub = nil
+	if fake.stopRecordingReturnsOnCall == nil {
+		fake.stopRecordingReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.stopRecordingReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakePublishedTrack) ToProto() *livekit.TrackInfo {
 	fake.toProtoMutex.Lock()
 	ret, specificReturn := fake.toProtoReturnsOnCall[len(fake.toProtoArgsForCall)]
@@ -644,6 +1084,10 @@ func (fake *FakePublishedTrack) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.addSubscriberMutex.RLock()
 	defer fake.addSubscriberMutex.RUnlock()
+	fake.codecMutex.RLock()
+	defer fake.codecMutex.RUnlock()
+	fake.forwardRTPMutex.RLock()
+	defer fake.forwardRTPMutex.RUnlock()
 	fake.iDMutex.RLock()
 	defer fake.iDMutex.RUnlock()
 	fake.isMutedMutex.RLock()
@@ -664,6 +1108,14 @@ func (fake *FakePublishedTrack) Invocations() map[string][][]interface{} {
 	defer fake.setMutedMutex.RUnlock()
 	fake.startMutex.RLock()
 	defer fake.startMutex.RUnlock()
+	fake.startRTPEgressMutex.RLock()
+	defer fake.startRTPEgressMutex.RUnlock()
+	fake.startRecordingMutex.RLock()
+	defer fake.startRecordingMutex.RUnlock()
+	fake.stopRTPEgressMutex.RLock()
+	defer fake.stopRTPEgressMutex.RUnlock()
+	fake.stopRecordingMutex.RLock()
+	defer fake.stopRecordingMutex.RUnlock()
 	fake.toProtoMutex.RLock()
 	defer fake.toProtoMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -45,6 +45,12 @@ type FakeSubscribedTrack struct {
 	setPublisherMutedArgsForCall []struct {
 		arg1 bool
 	}
+	UpdateSubscriberDimensionsStub        func(uint32, uint32)
+	updateSubscriberDimensionsMutex       sync.RWMutex
+	updateSubscriberDimensionsArgsForCall []struct {
+		arg1 uint32
+		arg2 uint32
+	}
 	UpdateSubscriberSettingsStub        func(bool, livekit.VideoQuality)
 	updateSubscriberSettingsMutex       sync.RWMutex
 	updateSubscriberSettingsArgsForCall []struct {
@@ -246,6 +252,39 @@ func (fake *FakeSubscribedTrack) SetPublisherMutedArgsForCall(i int) bool {
 	return argsForCall.arg1
 }
 
+func (fake *FakeSubscribedTrack) UpdateSubscriberDimensions(arg1 uint32, arg2 uint32) {
+	fake.updateSubscriberDimensionsMutex.Lock()
+	fake.updateSubscriberDimensionsArgsForCall = append(fake.updateSubscriberDimensionsArgsForCall, struct {
+		arg1 uint32
+		arg2 uint32
+	}{arg1, arg2})
+	stub := fake.UpdateSubscriberDimensionsStub
+	fake.recordInvocation("UpdateSubscriberDimensions", []interface{}{arg1, arg2})
+	fake.updateSubscriberDimensionsMutex.Unlock()
+	if stub != nil {
+		fake.UpdateSubscriberDimensionsStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeSubscribedTrack) UpdateSubscriberDimensionsCallCount() int {
+	fake.updateSubscriberDimensionsMutex.RLock()
+	defer fake.updateSubscriberDimensionsMutex.RUnlock()
+	return len(fake.updateSubscriberDimensionsArgsForCall)
+}
+
+func (fake *FakeSubscribedTrack) UpdateSubscriberDimensionsCalls(stub func(uint32, uint32)) {
+	fake.updateSubscriberDimensionsMutex.Lock()
+	defer fake.updateSubscriberDimensionsMutex.Unlock()
+	fake.UpdateSubscriberDimensionsStub = stub
+}
+
+func (fake *FakeSubscribedTrack) UpdateSubscriberDimensionsArgsForCall(i int) (uint32, uint32) {
+	fake.updateSubscriberDimensionsMutex.RLock()
+	defer fake.updateSubscriberDimensionsMutex.RUnlock()
+	argsForCall := fake.updateSubscriberDimensionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeSubscribedTrack) UpdateSubscriberSettings(arg1 bool, arg2 livekit.VideoQuality) {
 	fake.updateSubscriberSettingsMutex.Lock()
 	fake.updateSubscriberSettingsArgsForCall = append(fake.updateSubscriberSettingsArgsForCall, struct {
@@ -290,6 +329,8 @@ func (fake *FakeSubscribedTrack) Invocations() map[string][][]interface{} {
 	defer fake.isMutedMutex.RUnlock()
 	fake.setPublisherMutedMutex.RLock()
 	defer fake.setPublisherMutedMutex.RUnlock()
+	fake.updateSubscriberDimensionsMutex.RLock()
+	defer fake.updateSubscriberDimensionsMutex.RUnlock()
 	fake.updateSubscriberSettingsMutex.RLock()
 	defer fake.updateSubscriberSettingsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
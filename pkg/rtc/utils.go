@@ -8,13 +8,17 @@ import (
 
 	"github.com/livekit/protocol/logger"
 	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 const (
 	trackIdSeparator = "|"
+	// field newer clients send the track source in
+	addTrackRequestSourceField = 8
 )
 
 func UnpackStreamID(packed string) (participantId string, trackId string) {
@@ -103,6 +107,54 @@ func ToProtoTrackKind(kind webrtc.RTPCodecType) livekit.TrackType {
 	panic("unsupported track direction")
 }
 
+// isReceiveOnlyOffer is true when every media section of an offer only receives media
+func isReceiveOnlyOffer(sd webrtc.SessionDescription) bool {
+	if sd.Type != webrtc.SDPTypeOffer {
+		return false
+	}
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(sd.SDP)); err != nil {
+		return false
+	}
+
+	hasMedia := false
+	for _, m := range parsed.MediaDescriptions {
+		if m.MediaName.Media != "audio" && m.MediaName.Media != "video" {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRecvOnly); !ok {
+			return false
+		}
+		hasMedia = true
+	}
+	return hasMedia
+}
+
+// TrackSourceFromRequest reads the source sent by newer clients, it's kept with the request's unknown fields
+func TrackSourceFromRequest(req *livekit.AddTrackRequest) types.TrackSource {
+	b := req.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		if num == addTrackRequestSourceField && typ == protowire.VarintType {
+			source, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				break
+			}
+			return types.TrackSource(source)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+	}
+	return types.TrackSourceUnknown
+}
+
 func IsEOF(err error) bool {
 	return err == io.ErrClosedPipe || err == io.EOF
 }
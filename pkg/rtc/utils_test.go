@@ -3,7 +3,13 @@ package rtc
 import (
 	"testing"
 
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 func TestPackStreamId(t *testing.T) {
@@ -27,3 +33,47 @@ func TestPackDataTrackLabel(t *testing.T) {
 	require.Equal(t, trackId, tr)
 	require.Equal(t, label, l)
 }
+
+func TestIsReceiveOnlyOffer(t *testing.T) {
+	offer := func(directions ...string) webrtc.SessionDescription {
+		sdp := "v=0\r\no=- 1 2 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"
+		for i, direction := range directions {
+			kind := "audio"
+			if i%2 == 1 {
+				kind = "video"
+			}
+			sdp += "m=" + kind + " 9 UDP/TLS/RTP/SAVPF 96\r\nc=IN IP4 0.0.0.0\r\na=" + direction + "\r\n"
+		}
+		return webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}
+	}
+
+	require.True(t, isReceiveOnlyOffer(offer("recvonly", "recvonly")))
+	require.False(t, isReceiveOnlyOffer(offer("recvonly", "sendrecv")))
+	require.False(t, isReceiveOnlyOffer(offer("sendonly")))
+	// no media sections
+	require.False(t, isReceiveOnlyOffer(offer()))
+
+	answer := offer("recvonly")
+	answer.Type = webrtc.SDPTypeAnswer
+	require.False(t, isReceiveOnlyOffer(answer))
+}
+
+func TestTrackSourceFromRequest(t *testing.T) {
+	t.Run("unknown when not sent", func(t *testing.T) {
+		req := &livekit.AddTrackRequest{Cid: "cid", Name: "screen"}
+		require.Equal(t, types.TrackSourceUnknown, TrackSourceFromRequest(req))
+	})
+
+	t.Run("reads source sent by newer clients", func(t *testing.T) {
+		data, err := proto.Marshal(&livekit.AddTrackRequest{Cid: "cid", Type: livekit.TrackType_VIDEO})
+		require.NoError(t, err)
+		// disable_dtx, then source
+		data = protowire.AppendVarint(protowire.AppendTag(data, 7, protowire.VarintType), 1)
+		data = protowire.AppendVarint(protowire.AppendTag(data, addTrackRequestSourceField, protowire.VarintType), uint64(types.TrackSourceScreenShare))
+
+		req := &livekit.AddTrackRequest{}
+		require.NoError(t, proto.Unmarshal(data, req))
+		require.Equal(t, "cid", req.Cid)
+		require.Equal(t, types.TrackSourceScreenShare, TrackSourceFromRequest(req))
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/twitchtv/twirp"
+	"gopkg.in/square/go-jose.v2/jwt"
 
 	"github.com/livekit/protocol/auth"
 )
@@ -15,6 +16,7 @@ const (
 	authorizationHeader = "Authorization"
 	bearerPrefix        = "Bearer "
 	grantsKey           = "grants"
+	regionKey           = "region"
 	accessTokenParam    = "access_token"
 )
 
@@ -74,7 +76,11 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 
 		// set grants in context
 		ctx := r.Context()
-		r = r.WithContext(context.WithValue(ctx, grantsKey, grants))
+		ctx = context.WithValue(ctx, grantsKey, grants)
+		if region := tokenRegion(authToken, secret); region != "" {
+			ctx = WithRegion(ctx, region)
+		}
+		r = r.WithContext(ctx)
 	}
 
 	next.ServeHTTP(w, r)
@@ -88,6 +94,32 @@ func GetGrants(ctx context.Context) *auth.ClaimGrants {
 	return claims
 }
 
+// GetRegion returns the region rooms created by the request are placed in, when it asks for one
+func GetRegion(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey).(string)
+	return region
+}
+
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey, region)
+}
+
+// tokenRegion reads the region claim of a token, which ClaimGrants doesn't include. the claim is only read
+// when the token's signature checks out with the secret
+func tokenRegion(token string, secret string) string {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return ""
+	}
+	claims := struct {
+		Region string `json:"region"`
+	}{}
+	if err = tok.Claims([]byte(secret), &claims); err != nil {
+		return ""
+	}
+	return claims.Region
+}
+
 func SetAuthorizationToken(r *http.Request, token string) {
 	r.Header.Set(authorizationHeader, bearerPrefix+token)
 }
@@ -120,6 +152,20 @@ func EnsureAdminPermission(ctx context.Context, room string) error {
 	return nil
 }
 
+// EnsureNodeAdminPermission requires admin of every room, for actions on the nodes of the cluster
+func EnsureNodeAdminPermission(ctx context.Context) error {
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil {
+		return ErrPermissionDenied
+	}
+
+	if !claims.Video.RoomAdmin || !claims.Video.RoomCreate || claims.Video.Room != "" {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
 func EnsureCreatePermission(ctx context.Context) error {
 	claims := GetGrants(ctx)
 	if claims == nil {
@@ -9,4 +9,9 @@ var (
 	ErrParticipantNotFound  = errors.New("participant does not exist")
 	ErrTrackNotFound        = errors.New("track is not found")
 	ErrWebHookMissingAPIKey = errors.New("api_key is required to use webhooks")
+	ErrTrackRecordingOff    = errors.New("track recording is not configured")
+	ErrIngressNotFound      = errors.New("ingress does not exist")
+	ErrRelayNotFound        = errors.New("relay does not exist")
+	ErrRoomOnAnotherNode    = errors.New("rooms are hosted on different nodes")
+	ErrInvalidNodeRequest   = errors.New("invalid node request")
 )
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	httpSignalTimeout = 10 * time.Second
+	// candidates are gathered until none arrive for this long
+	candidateIdleTimeout = 250 * time.Millisecond
+	// or until this long after the answer
+	candidateGatherTimeout = 2 * time.Second
+)
+
+var (
+	errSignalTimeout = errors.New("timed out waiting for RTC node")
+	errSessionClosed = errors.New("session closed by RTC node")
+
+	errInvalidContentType = errors.New("content type must be " + sdpContentType)
+)
+
+// httpSignalSession drives a participant's signal connection from HTTP requests instead of a WebSocket,
+// for clients that only exchange an offer and an answer
+type httpSignalSession struct {
+	id        string
+	roomName  string
+	identity  string
+	rtcNodeId string
+	reqSink   routing.MessageSink
+	resSource routing.MessageSource
+	// cancels the context the participant's signal connection was started with
+	cancel func()
+	// answers to renegotiations, passed on by run
+	answers chan webrtc.SessionDescription
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newHTTPSignalSession(id, roomName, identity, rtcNodeId string, reqSink routing.MessageSink, resSource routing.MessageSource, cancel func()) *httpSignalSession {
+	return &httpSignalSession{
+		id:        id,
+		roomName:  roomName,
+		identity:  identity,
+		rtcNodeId: rtcNodeId,
+		reqSink:   reqSink,
+		resSource: resSource,
+		cancel:    cancel,
+		answers:   make(chan webrtc.SessionDescription, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+// join waits for the participant to join the room, and returns the room as it was when joined
+func (s *httpSignalSession) join() (*livekit.JoinResponse, error) {
+	timeout := time.After(httpSignalTimeout)
+	for {
+		select {
+		case msg := <-s.resSource.ReadChan():
+			if msg == nil {
+				return nil, errSessionClosed
+			}
+			res, ok := msg.(*livekit.SignalResponse)
+			if !ok {
+				continue
+			}
+			switch m := res.Message.(type) {
+			case *livekit.SignalResponse_Join:
+				return m.Join, nil
+			case *livekit.SignalResponse_Leave:
+				return nil, errSessionClosed
+			}
+		case <-timeout:
+			return nil, errSignalTimeout
+		}
+	}
+}
+
+// negotiate sends requests, followed by the offer, and returns the answer with candidates of the target
+// connection in it. clients of HTTP sessions can't receive trickled candidates
+func (s *httpSignalSession) negotiate(offer webrtc.SessionDescription, target livekit.SignalTarget, requests ...*livekit.SignalRequest) (webrtc.SessionDescription, error) {
+	requests = append(requests, &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{
+			Offer: rtc.ToProtoSessionDescription(offer),
+		},
+	})
+	for _, req := range requests {
+		if err := s.reqSink.WriteMessage(req); err != nil {
+			return webrtc.SessionDescription{}, err
+		}
+	}
+
+	var answer *webrtc.SessionDescription
+	var candidates []webrtc.ICECandidateInit
+	timeout := time.After(httpSignalTimeout)
+	var gatherTimeout <-chan time.Time
+	for {
+		var idleTimeout <-chan time.Time
+		if answer != nil && len(candidates) > 0 {
+			idleTimeout = time.After(candidateIdleTimeout)
+		}
+
+		select {
+		case msg := <-s.resSource.ReadChan():
+			if msg == nil {
+				return webrtc.SessionDescription{}, errSessionClosed
+			}
+			res, ok := msg.(*livekit.SignalResponse)
+			if !ok {
+				continue
+			}
+			switch m := res.Message.(type) {
+			case *livekit.SignalResponse_Answer:
+				sd := rtc.FromProtoSessionDescription(m.Answer)
+				answer = &sd
+				gatherTimeout = time.After(candidateGatherTimeout)
+			case *livekit.SignalResponse_Trickle:
+				if m.Trickle.Target != target {
+					continue
+				}
+				if candidate, err := rtc.FromProtoTrickle(m.Trickle); err == nil {
+					candidates = append(candidates, candidate)
+				}
+			case *livekit.SignalResponse_Leave:
+				return webrtc.SessionDescription{}, errSessionClosed
+			}
+		case <-idleTimeout:
+			return addCandidates(*answer, candidates)
+		case <-gatherTimeout:
+			return addCandidates(*answer, candidates)
+		case <-timeout:
+			return webrtc.SessionDescription{}, errSignalTimeout
+		}
+	}
+}
+
+// renegotiate sends requests, followed by an offer for a connection that's established, and returns the
+// answer. it's only used while the session runs, candidates aren't needed again
+func (s *httpSignalSession) renegotiate(offer webrtc.SessionDescription, requests ...*livekit.SignalRequest) (webrtc.SessionDescription, error) {
+	// an answer to an earlier offer that timed out
+	select {
+	case <-s.answers:
+	default:
+	}
+
+	requests = append(requests, &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Offer{
+			Offer: rtc.ToProtoSessionDescription(offer),
+		},
+	})
+	for _, req := range requests {
+		if err := s.reqSink.WriteMessage(req); err != nil {
+			return webrtc.SessionDescription{}, err
+		}
+	}
+
+	select {
+	case answer := <-s.answers:
+		return answer, nil
+	case <-s.closed:
+		return webrtc.SessionDescription{}, errSessionClosed
+	case <-time.After(httpSignalTimeout):
+		return webrtc.SessionDescription{}, errSignalTimeout
+	}
+}
+
+// run drains responses until the participant leaves or the session is closed, passing them to onResponse
+// when it's set
+func (s *httpSignalSession) run(onResponse func(res *livekit.SignalResponse), onDone func()) {
+	defer func() {
+		s.Close()
+		onDone()
+	}()
+	defer rtc.Recover()
+
+	for {
+		select {
+		case <-s.closed:
+			return
+		case msg := <-s.resSource.ReadChan():
+			if msg == nil {
+				return
+			}
+			res, ok := msg.(*livekit.SignalResponse)
+			if !ok {
+				continue
+			}
+			switch m := res.Message.(type) {
+			case *livekit.SignalResponse_Leave:
+				return
+			case *livekit.SignalResponse_Answer:
+				select {
+				case s.answers <- rtc.FromProtoSessionDescription(m.Answer):
+				default:
+				}
+			}
+			if onResponse != nil {
+				onResponse(res)
+			}
+		}
+	}
+}
+
+// Close ends the participant's session
+func (s *httpSignalSession) Close() {
+	s.closeOnce.Do(func() {
+		s.reqSink.Close()
+		s.cancel()
+		close(s.closed)
+	})
+}
+
+// addCandidates adds candidates to every media section they belong to, followed by end-of-candidates
+func addCandidates(sd webrtc.SessionDescription, candidates []webrtc.ICECandidateInit) (webrtc.SessionDescription, error) {
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(sd.SDP)); err != nil {
+		return sd, err
+	}
+	for _, m := range parsed.MediaDescriptions {
+		mid, _ := m.Attribute(sdp.AttrKeyMID)
+		for _, c := range candidates {
+			if c.SDPMid != nil && *c.SDPMid != "" && *c.SDPMid != mid {
+				continue
+			}
+			m.WithValueAttribute(sdp.AttrKeyCandidate, strings.TrimPrefix(c.Candidate, "candidate:"))
+		}
+		m.WithPropertyAttribute(sdp.AttrKeyEndOfCandidates)
+	}
+	data, err := parsed.Marshal()
+	if err != nil {
+		return sd, err
+	}
+	return webrtc.SessionDescription{Type: sd.Type, SDP: string(data)}, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -30,16 +31,54 @@ type RoomStore interface {
 	LoadParticipant(ctx context.Context, roomName, identity string) (*livekit.ParticipantInfo, error)
 	ListParticipants(ctx context.Context, roomName string) ([]*livekit.ParticipantInfo, error)
 	DeleteParticipant(ctx context.Context, roomName, identity string) error
+
+	StoreConnectionQuality(ctx context.Context, roomName, identity string, quality types.ConnectionQuality) error
+	LoadConnectionQuality(ctx context.Context, roomName, identity string) (types.ConnectionQuality, error)
+
+	StoreRoomOptions(ctx context.Context, roomName string, opts *rtc.RoomOptions) error
+	LoadRoomOptions(ctx context.Context, roomName string) (*rtc.RoomOptions, error)
+
+	StoreRoomMetadata(ctx context.Context, roomName, metadata string) error
+	LoadRoomMetadata(ctx context.Context, roomName string) (string, error)
+
+	StoreDataHistory(ctx context.Context, roomName string, history []*rtc.DataHistoryEntry) error
+	LoadDataHistory(ctx context.Context, roomName string) ([]*rtc.DataHistoryEntry, error)
 }
 
 type RoomManager interface {
 	RoomStore
 
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error)
+	GetNodeForParticipant(ctx context.Context, roomName string) (string, error)
 	GetRoom(ctx context.Context, roomName string) *rtc.Room
+	GetRooms(ctx context.Context) []*rtc.Room
+	GetPublishedTrack(ctx context.Context, roomName, identity, trackID string) (types.PublishedTrack, error)
+	// GetParticipantStats computes track statistics of a participant connected to this node
+	GetParticipantStats(ctx context.Context, roomName, identity string) (*types.ParticipantStats, error)
 	DeleteRoom(ctx context.Context, roomName string) error
 	StartSession(ctx context.Context, roomName string, pi routing.ParticipantInit, requestSource routing.MessageSource, responseSink routing.MessageSink)
 	CleanupRooms() error
+	// ReapDeadNodes cleans up after crashed nodes, see LocalRoomManager.ReapDeadNodes
+	ReapDeadNodes() error
+	// FailoverRoom moves a room off a node that's died, see LocalRoomManager.FailoverRoom
+	FailoverRoom(ctx context.Context, roomName, deadNodeId string) (string, error)
 	CloseIdleRooms()
+	// Drain moves rooms off this node, see LocalRoomManager.Drain
+	Drain()
+	IsDraining() bool
 	Stop()
+
+	// records tracks of rooms hosted on this node
+	StartTrackRecording(ctx context.Context, roomName, identity, trackID string) (string, error)
+	StopTrackRecording(ctx context.Context, roomName, identity, trackID string) error
+
+	// forwards tracks of rooms hosted on this node as plain RTP
+	StartRTPEgress(ctx context.Context, roomName, identity, trackID, egressID, host string, port int, quality livekit.VideoQuality) (string, error)
+	StopRTPEgress(ctx context.Context, roomName, identity, trackID, egressID string) error
+
+	// updates metadata of rooms hosted on this node
+	UpdateRoomMetadata(ctx context.Context, roomName, metadata string) error
+
+	// moves participants between rooms hosted on this node
+	MoveParticipant(ctx context.Context, roomName, identity, toRoomName string) error
 }
@@ -6,6 +6,9 @@ import (
 	"time"
 
 	livekit "github.com/livekit/protocol/proto"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 // encapsulates CRUD operations for room settings
@@ -14,18 +17,30 @@ type LocalRoomStore struct {
 	rooms map[string]*livekit.Room
 	// map of roomName => roomId
 	roomIds map[string]string
+	// map of roomName => options
+	options map[string]*rtc.RoomOptions
 	// map of roomName => { identity: participant }
 	participants map[string]map[string]*livekit.ParticipantInfo
-	lock         sync.RWMutex
-	globalLock   sync.Mutex
+	// map of roomName => { identity: quality }
+	qualities map[string]map[string]types.ConnectionQuality
+	// map of roomName => metadata
+	metadata map[string]string
+	// map of roomName => data history
+	dataHistories map[string][]*rtc.DataHistoryEntry
+	lock          sync.RWMutex
+	globalLock    sync.Mutex
 }
 
 func NewLocalRoomStore() *LocalRoomStore {
 	return &LocalRoomStore{
-		rooms:        make(map[string]*livekit.Room),
-		roomIds:      make(map[string]string),
-		participants: make(map[string]map[string]*livekit.ParticipantInfo),
-		lock:         sync.RWMutex{},
+		rooms:         make(map[string]*livekit.Room),
+		roomIds:       make(map[string]string),
+		options:       make(map[string]*rtc.RoomOptions),
+		participants:  make(map[string]map[string]*livekit.ParticipantInfo),
+		qualities:     make(map[string]map[string]types.ConnectionQuality),
+		metadata:      make(map[string]string),
+		dataHistories: make(map[string][]*rtc.DataHistoryEntry),
+		lock:          sync.RWMutex{},
 	}
 }
 
@@ -76,12 +91,34 @@ func (p *LocalRoomStore) DeleteRoom(ctx context.Context, idOrName string) error
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
+	delete(p.options, room.Name)
 	delete(p.participants, room.Name)
+	delete(p.qualities, room.Name)
+	delete(p.metadata, room.Name)
+	delete(p.dataHistories, room.Name)
 	delete(p.roomIds, room.Name)
 	delete(p.rooms, room.Sid)
 	return nil
 }
 
+func (p *LocalRoomStore) StoreRoomOptions(ctx context.Context, roomName string, opts *rtc.RoomOptions) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.options[roomName] = opts
+	return nil
+}
+
+func (p *LocalRoomStore) LoadRoomOptions(ctx context.Context, roomName string) (*rtc.RoomOptions, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	opts := p.options[roomName]
+	if opts == nil {
+		return nil, ErrRoomNotFound
+	}
+	return opts, nil
+}
+
 func (p *LocalRoomStore) LockRoom(ctx context.Context, name string, duration time.Duration) (string, error) {
 	// local rooms lock & unlock globally
 	p.globalLock.Lock()
@@ -146,5 +183,57 @@ func (p *LocalRoomStore) DeleteParticipant(ctx context.Context, roomName, identi
 	if roomParticipants != nil {
 		delete(roomParticipants, identity)
 	}
+	if roomQualities := p.qualities[roomName]; roomQualities != nil {
+		delete(roomQualities, identity)
+	}
+	return nil
+}
+
+func (p *LocalRoomStore) StoreConnectionQuality(ctx context.Context, roomName, identity string, quality types.ConnectionQuality) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	roomQualities := p.qualities[roomName]
+	if roomQualities == nil {
+		roomQualities = make(map[string]types.ConnectionQuality)
+		p.qualities[roomName] = roomQualities
+	}
+	roomQualities[identity] = quality
+	return nil
+}
+
+func (p *LocalRoomStore) LoadConnectionQuality(ctx context.Context, roomName, identity string) (types.ConnectionQuality, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	quality, ok := p.qualities[roomName][identity]
+	if !ok {
+		return types.ConnectionQualityPoor, ErrParticipantNotFound
+	}
+	return quality, nil
+}
+
+func (p *LocalRoomStore) StoreRoomMetadata(ctx context.Context, roomName, metadata string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.metadata[roomName] = metadata
+	return nil
+}
+
+func (p *LocalRoomStore) LoadRoomMetadata(ctx context.Context, roomName string) (string, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.metadata[roomName], nil
+}
+
+func (p *LocalRoomStore) StoreDataHistory(ctx context.Context, roomName string, history []*rtc.DataHistoryEntry) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.dataHistories[roomName] = history
 	return nil
 }
+
+func (p *LocalRoomStore) LoadDataHistory(ctx context.Context, roomName string) ([]*rtc.DataHistoryEntry, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.dataHistories[roomName], nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+// MessageBusLocksBucket has a key for each lock taken on the message bus
+const MessageBusLocksBucket = "message_bus_locks"
+
+// messages a subscription holds before they're read
+const natsPubSubBufferSize = 1024
+
+// NATSMessageBus sends messages on core NATS subjects named like the channels, keeping locks in a JetStream
+// key-value bucket
+type NATSMessageBus struct {
+	nc    *nats.Conn
+	locks nats.KeyValue
+}
+
+func NewNATSMessageBus(nc *nats.Conn) (*NATSMessageBus, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not use JetStream")
+	}
+	locks, err := routing.NATSBucket(js, &nats.KeyValueConfig{Bucket: MessageBusLocksBucket})
+	if err != nil {
+		return nil, err
+	}
+	return &NATSMessageBus{
+		nc:    nc,
+		locks: locks,
+	}, nil
+}
+
+// Lock takes the lock when it's free or has expired. updates are conditional on the revision read, so only one
+// caller takes it
+func (b *NATSMessageBus) Lock(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	key = routing.NATSKey(key)
+	entry, err := b.locks.Get(key)
+	if err == nats.ErrKeyNotFound {
+		_, err = b.locks.Create(key, nil)
+	} else if err != nil {
+		return false, err
+	} else if time.Since(entry.Created()) > expiration {
+		_, err = b.locks.Update(key, nil, entry.Revision())
+	} else {
+		return false, nil
+	}
+	// someone else took it since it was read
+	return err == nil, nil
+}
+
+func (b *NATSMessageBus) Subscribe(ctx context.Context, channel string) (utils.PubSub, error) {
+	msgs := make(chan *nats.Msg, natsPubSubBufferSize)
+	sub, err := b.nc.ChanSubscribe(channel, msgs)
+	if err != nil {
+		return nil, err
+	}
+	// messages published once this returns are received, even by other nodes
+	if err = b.nc.Flush(); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, err
+	}
+
+	ps := &NATSPubSub{
+		sub:  sub,
+		c:    make(chan interface{}),
+		done: make(chan struct{}),
+	}
+	go ps.forward(msgs)
+	return ps, nil
+}
+
+func (b *NATSMessageBus) Publish(ctx context.Context, channel string, msg interface{}) error {
+	var data []byte
+	switch m := msg.(type) {
+	case nil:
+	case string:
+		data = []byte(m)
+	case []byte:
+		data = m
+	default:
+		return fmt.Errorf("can't publish message of type %T", msg)
+	}
+	return b.nc.Publish(channel, data)
+}
+
+type NATSPubSub struct {
+	sub       *nats.Subscription
+	c         chan interface{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *NATSPubSub) Channel() <-chan interface{} {
+	return p.c
+}
+
+func (p *NATSPubSub) Payload(msg interface{}) []byte {
+	return msg.(*nats.Msg).Data
+}
+
+func (p *NATSPubSub) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		err = p.sub.Unsubscribe()
+	})
+	return err
+}
+
+// forward passes messages on until the subscription is closed, then closes its channel
+func (p *NATSPubSub) forward(msgs <-chan *nats.Msg) {
+	defer close(p.c)
+	for {
+		select {
+		case msg := <-msgs:
+			select {
+			case p.c <- msg:
+			case <-p.done:
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
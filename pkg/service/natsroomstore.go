@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// RoomOptionsBucket has a key for each room, of RoomOptions JSON
+	RoomOptionsBucket = "room_options"
+
+	// RoomParticipantsBucket has keys of room_name.participant_name => ParticipantInfo
+	RoomParticipantsBucket = "room_participants"
+
+	// RoomLockBucket has a key for each locked room, of the lock's uid
+	RoomLockBucket = "room_lock"
+
+	// RoomParticipantQualityBucket has keys of room_name.participant_name => connection quality
+	RoomParticipantQualityBucket = "room_participant_quality"
+
+	// RoomDataHistoryBucket has a key for each room, of DataHistoryEntry list JSON
+	RoomDataHistoryBucket = "room_data_history"
+)
+
+// NATSRoomStore stores rooms in JetStream key-value buckets, named like the keys RedisRoomStore uses
+type NATSRoomStore struct {
+	rooms        nats.KeyValue
+	roomIds      nats.KeyValue
+	options      nats.KeyValue
+	participants nats.KeyValue
+	locks        nats.KeyValue
+	quality      nats.KeyValue
+	metadata     nats.KeyValue
+	dataHistory  nats.KeyValue
+}
+
+func NewNATSRoomStore(nc *nats.Conn) (*NATSRoomStore, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not use JetStream")
+	}
+	s := &NATSRoomStore{}
+	buckets := []struct {
+		kv     *nats.KeyValue
+		config nats.KeyValueConfig
+	}{
+		{&s.rooms, nats.KeyValueConfig{Bucket: RoomsKey}},
+		{&s.roomIds, nats.KeyValueConfig{Bucket: RoomIdMap}},
+		{&s.options, nats.KeyValueConfig{Bucket: RoomOptionsBucket}},
+		{&s.participants, nats.KeyValueConfig{Bucket: RoomParticipantsBucket}},
+		{&s.locks, nats.KeyValueConfig{Bucket: RoomLockBucket}},
+		{&s.metadata, nats.KeyValueConfig{Bucket: RoomMetadataKey}},
+		// quality and history of rooms that are gone without being deleted expire
+		{&s.quality, nats.KeyValueConfig{Bucket: RoomParticipantQualityBucket, TTL: roomParticipantQualityTTL}},
+		{&s.dataHistory, nats.KeyValueConfig{Bucket: RoomDataHistoryBucket, TTL: roomDataHistoryTTL}},
+	}
+	for _, b := range buckets {
+		if *b.kv, err = routing.NATSBucket(js, &b.config); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *NATSRoomStore) StoreRoom(ctx context.Context, room *livekit.Room) error {
+	if room.CreationTime == 0 {
+		room.CreationTime = time.Now().Unix()
+	}
+
+	data, err := proto.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.roomIds.PutString(routing.NATSKey(room.Sid), room.Name); err != nil {
+		return errors.Wrap(err, "could not create room")
+	}
+	if _, err = s.rooms.Put(routing.NATSKey(room.Name), data); err != nil {
+		return errors.Wrap(err, "could not create room")
+	}
+	return nil
+}
+
+func (s *NATSRoomStore) LoadRoom(ctx context.Context, idOrName string) (*livekit.Room, error) {
+	// see if matches any ids
+	name := idOrName
+	if entry, err := s.roomIds.Get(routing.NATSKey(idOrName)); err == nil {
+		name = string(entry.Value())
+	}
+
+	entry, err := s.rooms.Get(routing.NATSKey(name))
+	if err == nats.ErrKeyNotFound {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	room := livekit.Room{}
+	if err := proto.Unmarshal(entry.Value(), &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (s *NATSRoomStore) ListRooms(ctx context.Context) ([]*livekit.Room, error) {
+	entries, err := routing.NATSEntries(s.rooms, nats.AllKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rooms")
+	}
+
+	rooms := make([]*livekit.Room, 0, len(entries))
+	for _, entry := range entries {
+		room := livekit.Room{}
+		if err := proto.Unmarshal(entry.Value(), &room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, &room)
+	}
+	return rooms, nil
+}
+
+func (s *NATSRoomStore) DeleteRoom(ctx context.Context, idOrName string) error {
+	room, err := s.LoadRoom(ctx, idOrName)
+	var sid, name string
+
+	if err == ErrRoomNotFound {
+		// try to clean up as best as we could
+		sid = idOrName
+		name = idOrName
+	} else if err == nil {
+		sid = room.Sid
+		name = room.Name
+	} else {
+		return err
+	}
+
+	deletes := []struct {
+		kv  nats.KeyValue
+		key string
+	}{
+		{s.roomIds, routing.NATSKey(sid)},
+		{s.rooms, routing.NATSKey(name)},
+		{s.options, routing.NATSKey(name)},
+		{s.metadata, routing.NATSKey(name)},
+		{s.dataHistory, routing.NATSKey(name)},
+	}
+	for _, d := range deletes {
+		if err := d.kv.Delete(d.key); err != nil {
+			return err
+		}
+	}
+	for _, kv := range []nats.KeyValue{s.participants, s.quality} {
+		if err := deleteNATSEntries(kv, routing.NATSKey(name)+".*"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NATSRoomStore) StoreRoomOptions(ctx context.Context, roomName string, opts *rtc.RoomOptions) error {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.options.Put(routing.NATSKey(roomName), data)
+	return err
+}
+
+func (s *NATSRoomStore) LoadRoomOptions(ctx context.Context, roomName string) (*rtc.RoomOptions, error) {
+	entry, err := s.options.Get(routing.NATSKey(roomName))
+	if err == nats.ErrKeyNotFound {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	opts := rtc.RoomOptions{}
+	if err := json.Unmarshal(entry.Value(), &opts); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// LockRoom takes the lock when it's free or has expired, a lock expires duration after it was taken. updates are
+// conditional on the revision read, so only one caller takes it
+func (s *NATSRoomStore) LockRoom(ctx context.Context, name string, duration time.Duration) (string, error) {
+	token := utils.NewGuid("LOCK")
+	key := routing.NATSKey(name)
+
+	startTime := time.Now()
+	for {
+		entry, err := s.locks.Get(key)
+		if err == nats.ErrKeyNotFound {
+			// fails when it's been locked since
+			if _, err = s.locks.Create(key, []byte(token)); err == nil {
+				return token, nil
+			}
+		} else if err != nil {
+			return "", err
+		} else if time.Since(entry.Created()) > duration {
+			// fails when it's been locked since
+			if _, err = s.locks.Update(key, []byte(token), entry.Revision()); err == nil {
+				return token, nil
+			}
+		}
+
+		// stop waiting past lock duration
+		if time.Now().Sub(startTime) > duration {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return "", ErrRoomLockFailed
+}
+
+func (s *NATSRoomStore) UnlockRoom(ctx context.Context, name string, uid string) error {
+	key := routing.NATSKey(name)
+
+	entry, err := s.locks.Get(key)
+	if err == nats.ErrKeyNotFound {
+		// already unlocked
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if string(entry.Value()) != uid {
+		return ErrRoomUnlockFailed
+	}
+	return s.locks.Delete(key)
+}
+
+func (s *NATSRoomStore) StoreParticipant(ctx context.Context, roomName string, participant *livekit.ParticipantInfo) error {
+	data, err := proto.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.participants.Put(participantNATSKey(roomName, participant.Identity), data)
+	return err
+}
+
+func (s *NATSRoomStore) LoadParticipant(ctx context.Context, roomName, identity string) (*livekit.ParticipantInfo, error) {
+	entry, err := s.participants.Get(participantNATSKey(roomName, identity))
+	if err == nats.ErrKeyNotFound {
+		return nil, ErrParticipantNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	pi := livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(entry.Value(), &pi); err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+func (s *NATSRoomStore) ListParticipants(ctx context.Context, roomName string) ([]*livekit.ParticipantInfo, error) {
+	entries, err := routing.NATSEntries(s.participants, routing.NATSKey(roomName)+".*")
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make([]*livekit.ParticipantInfo, 0, len(entries))
+	for _, entry := range entries {
+		pi := livekit.ParticipantInfo{}
+		if err := proto.Unmarshal(entry.Value(), &pi); err != nil {
+			return nil, err
+		}
+		participants = append(participants, &pi)
+	}
+	return participants, nil
+}
+
+func (s *NATSRoomStore) DeleteParticipant(ctx context.Context, roomName, identity string) error {
+	key := participantNATSKey(roomName, identity)
+	if err := s.participants.Delete(key); err != nil {
+		return err
+	}
+	return s.quality.Delete(key)
+}
+
+func (s *NATSRoomStore) StoreConnectionQuality(ctx context.Context, roomName, identity string, quality types.ConnectionQuality) error {
+	_, err := s.quality.PutString(participantNATSKey(roomName, identity), quality.String())
+	return err
+}
+
+func (s *NATSRoomStore) LoadConnectionQuality(ctx context.Context, roomName, identity string) (types.ConnectionQuality, error) {
+	entry, err := s.quality.Get(participantNATSKey(roomName, identity))
+	if err == nats.ErrKeyNotFound {
+		return types.ConnectionQualityPoor, ErrParticipantNotFound
+	} else if err != nil {
+		return types.ConnectionQualityPoor, err
+	}
+
+	var quality types.ConnectionQuality
+	err = quality.UnmarshalText(entry.Value())
+	return quality, err
+}
+
+func (s *NATSRoomStore) StoreRoomMetadata(ctx context.Context, roomName, metadata string) error {
+	_, err := s.metadata.PutString(routing.NATSKey(roomName), metadata)
+	return err
+}
+
+func (s *NATSRoomStore) LoadRoomMetadata(ctx context.Context, roomName string) (string, error) {
+	entry, err := s.metadata.Get(routing.NATSKey(roomName))
+	if err == nats.ErrKeyNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return string(entry.Value()), nil
+}
+
+func (s *NATSRoomStore) StoreDataHistory(ctx context.Context, roomName string, history []*rtc.DataHistoryEntry) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.dataHistory.Put(routing.NATSKey(roomName), data)
+	return err
+}
+
+func (s *NATSRoomStore) LoadDataHistory(ctx context.Context, roomName string) ([]*rtc.DataHistoryEntry, error) {
+	entry, err := s.dataHistory.Get(routing.NATSKey(roomName))
+	if err == nats.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var history []*rtc.DataHistoryEntry
+	if err := json.Unmarshal(entry.Value(), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func participantNATSKey(roomName, identity string) string {
+	return routing.NATSKey(roomName) + "." + routing.NATSKey(identity)
+}
+
+// deleteNATSEntries deletes the keys matching filter
+func deleteNATSEntries(kv nats.KeyValue, filter string) error {
+	entries, err := routing.NATSEntries(kv, filter)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := kv.Delete(entry.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
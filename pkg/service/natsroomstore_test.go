@@ -0,0 +1,181 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestNATSRoomStore(t *testing.T) {
+	ctx := context.Background()
+	rs, err := service.NewNATSRoomStore(natsConn(t, startNATSServer(t)))
+	require.NoError(t, err)
+	roomName := "room with spaces/and:symbols"
+
+	t.Run("stores rooms", func(t *testing.T) {
+		room := &livekit.Room{Sid: "RM_1", Name: roomName}
+		require.NoError(t, rs.StoreRoom(ctx, room))
+		require.NoError(t, rs.StoreRoom(ctx, &livekit.Room{Sid: "RM_2", Name: "other room"}))
+		require.NoError(t, rs.StoreRoomMetadata(ctx, roomName, "metadata"))
+		require.NoError(t, rs.StoreDataHistory(ctx, roomName, []*rtc.DataHistoryEntry{{ParticipantSid: "PA_1", Payload: []byte("hello")}}))
+
+		// by name or ID
+		loaded, err := rs.LoadRoom(ctx, room.Name)
+		require.NoError(t, err)
+		require.Equal(t, room.Sid, loaded.Sid)
+		loaded, err = rs.LoadRoom(ctx, room.Sid)
+		require.NoError(t, err)
+		require.Equal(t, room.Name, loaded.Name)
+
+		rooms, err := rs.ListRooms(ctx)
+		require.NoError(t, err)
+		require.Len(t, rooms, 2)
+		metadata, err := rs.LoadRoomMetadata(ctx, roomName)
+		require.NoError(t, err)
+		require.Equal(t, "metadata", metadata)
+		history, err := rs.LoadDataHistory(ctx, roomName)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	})
+
+	t.Run("stores participants of each room", func(t *testing.T) {
+		p := &livekit.ParticipantInfo{Sid: "PA_1", Identity: "user.1"}
+		require.NoError(t, rs.StoreParticipant(ctx, roomName, p))
+		require.NoError(t, rs.StoreParticipant(ctx, "other room", &livekit.ParticipantInfo{Sid: "PA_2", Identity: "user.2"}))
+		require.NoError(t, rs.StoreConnectionQuality(ctx, roomName, p.Identity, types.ConnectionQualityGood))
+
+		loaded, err := rs.LoadParticipant(ctx, roomName, p.Identity)
+		require.NoError(t, err)
+		require.Equal(t, p.Sid, loaded.Sid)
+		participants, err := rs.ListParticipants(ctx, roomName)
+		require.NoError(t, err)
+		require.Len(t, participants, 1)
+		quality, err := rs.LoadConnectionQuality(ctx, roomName, p.Identity)
+		require.NoError(t, err)
+		require.Equal(t, types.ConnectionQualityGood, quality)
+
+		require.NoError(t, rs.DeleteParticipant(ctx, roomName, p.Identity))
+		_, err = rs.LoadParticipant(ctx, roomName, p.Identity)
+		require.Equal(t, service.ErrParticipantNotFound, err)
+		_, err = rs.LoadConnectionQuality(ctx, roomName, p.Identity)
+		require.Equal(t, service.ErrParticipantNotFound, err)
+	})
+
+	t.Run("locks rooms", func(t *testing.T) {
+		lockInterval := 50 * time.Millisecond
+		token, err := rs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+		require.Equal(t, service.ErrRoomUnlockFailed, rs.UnlockRoom(ctx, roomName, "other"))
+		require.NoError(t, rs.UnlockRoom(ctx, roomName, token))
+
+		_, err = rs.LockRoom(ctx, roomName, lockInterval)
+		require.NoError(t, err)
+		// taken once the first lock expires
+		token, err = rs.LockRoom(ctx, roomName, 10*lockInterval)
+		require.NoError(t, err)
+		require.NoError(t, rs.UnlockRoom(ctx, roomName, token))
+	})
+
+	t.Run("deletes rooms with their participants", func(t *testing.T) {
+		require.NoError(t, rs.StoreParticipant(ctx, roomName, &livekit.ParticipantInfo{Sid: "PA_3", Identity: "user3"}))
+		require.NoError(t, rs.DeleteRoom(ctx, "RM_1"))
+
+		_, err := rs.LoadRoom(ctx, roomName)
+		require.Equal(t, service.ErrRoomNotFound, err)
+		participants, err := rs.ListParticipants(ctx, roomName)
+		require.NoError(t, err)
+		require.Len(t, participants, 0)
+		metadata, err := rs.LoadRoomMetadata(ctx, roomName)
+		require.NoError(t, err)
+		require.Empty(t, metadata)
+		history, err := rs.LoadDataHistory(ctx, roomName)
+		require.NoError(t, err)
+		require.Nil(t, history)
+
+		// other rooms are kept
+		participants, err = rs.ListParticipants(ctx, "other room")
+		require.NoError(t, err)
+		require.Len(t, participants, 1)
+	})
+}
+
+func TestNATSMessageBus(t *testing.T) {
+	ctx := context.Background()
+	url := startNATSServer(t)
+	busA, err := service.NewNATSMessageBus(natsConn(t, url))
+	require.NoError(t, err)
+	busB, err := service.NewNATSMessageBus(natsConn(t, url))
+	require.NoError(t, err)
+
+	t.Run("sends messages to subscribers on other connections", func(t *testing.T) {
+		sub, err := busA.Subscribe(ctx, "channel:1")
+		require.NoError(t, err)
+		require.NoError(t, busB.Publish(ctx, "channel:1", "hello"))
+		require.NoError(t, busB.Publish(ctx, "channel:1", nil))
+
+		for _, expected := range []string{"hello", ""} {
+			select {
+			case m := <-sub.Channel():
+				require.Equal(t, expected, string(sub.Payload(m)))
+			case <-time.After(time.Second):
+				t.Fatal("message was not received")
+			}
+		}
+
+		// the channel is closed with the subscription
+		require.NoError(t, sub.Close())
+		require.NoError(t, sub.Close())
+		select {
+		case _, ok := <-sub.Channel():
+			require.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel was not closed")
+		}
+	})
+
+	t.Run("locks until the lock expires", func(t *testing.T) {
+		acquired, err := busA.Lock(ctx, "lock:1", 50*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		acquired, err = busB.Lock(ctx, "lock:1", 50*time.Millisecond)
+		require.NoError(t, err)
+		require.False(t, acquired)
+
+		time.Sleep(100 * time.Millisecond)
+		acquired, err = busB.Lock(ctx, "lock:1", 50*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	})
+}
+
+func startNATSServer(t *testing.T) string {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns.ClientURL()
+}
+
+func natsConn(t *testing.T, url string) *nats.Conn {
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	return nc
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/twitchtv/twirp"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	nodeRequestTimeout = 5 * time.Second
+
+	// prefix of the IDs of forwarded requests
+	nodeRequestPrefix = "NR_"
+)
+
+// track and room requests forwarded to the node hosting the room, and node requests to that node
+type nodeRequest struct {
+	RequestId string                  `json:"request_id"`
+	Action    string                  `json:"action"`
+	Recording *TrackRecordingRequest  `json:"recording,omitempty"`
+	Egress    *RTPEgressRequest       `json:"egress,omitempty"`
+	Ingress   *RTPIngressRequest      `json:"ingress,omitempty"`
+	Metadata  *RoomMetadataRequest    `json:"metadata,omitempty"`
+	Move      *MoveParticipantRequest `json:"move,omitempty"`
+	Bridge    *TrackBridgeRequest     `json:"bridge,omitempty"`
+	Relay     *participantRelay       `json:"relay,omitempty"`
+	Drain     *DrainNodeRequest       `json:"drain,omitempty"`
+	// participant to compute statistics of
+	Participant *livekit.RoomParticipantIdentity `json:"participant,omitempty"`
+}
+
+type nodeRequestResult struct {
+	FilePath string `json:"file_path,omitempty"`
+	Sdp      string `json:"sdp,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	// ports of relayed tracks, by SID. only those added when the relay is updated
+	Ports    map[string]int          `json:"ports,omitempty"`
+	Stats    *types.ParticipantStats `json:"stats,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+	NotFound bool                    `json:"not_found,omitempty"`
+}
+
+// nodeRequestHandler runs a request on this node, filling in its result
+type nodeRequestHandler func(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error
+
+// NodeRequests runs requests on the node they're for, forwarding them over the message bus when that's another
+// node. services handle the actions of their own requests
+type NodeRequests struct {
+	mb          utils.MessageBus
+	router      routing.Router
+	currentNode routing.LocalNode
+	sub         utils.PubSub
+
+	lock sync.RWMutex
+	// by action
+	handlers map[string]nodeRequestHandler
+}
+
+func NewNodeRequests(mb utils.MessageBus, router routing.Router, currentNode routing.LocalNode) *NodeRequests {
+	return &NodeRequests{
+		mb:          mb,
+		router:      router,
+		currentNode: currentNode,
+		handlers:    make(map[string]nodeRequestHandler),
+	}
+}
+
+// Handle runs requests with any of the actions with handler, when they're for this node
+func (r *NodeRequests) Handle(handler nodeRequestHandler, actions ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, action := range actions {
+		r.handlers[action] = handler
+	}
+}
+
+// Start listens for requests other nodes forward to this node
+func (r *NodeRequests) Start() error {
+	if r.mb == nil {
+		return nil
+	}
+	sub, err := r.mb.Subscribe(context.Background(), nodeRequestChannel(r.currentNode.Id))
+	if err != nil {
+		return err
+	}
+	r.sub = sub
+	go r.nodeRequestWorker(sub)
+	return nil
+}
+
+func (r *NodeRequests) Stop() {
+	if r.sub != nil {
+		_ = r.sub.Close()
+	}
+}
+
+// handleRoomRequest runs the request on the node hosting the room, returning the node's ID
+func (r *NodeRequests) handleRoomRequest(ctx context.Context, roomName string, msg *nodeRequest) (string, *nodeRequestResult, error) {
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err == routing.ErrNotFound {
+		return "", nil, twirp.NotFoundError(ErrRoomNotFound.Error())
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	result, err := r.handleNodeRequest(ctx, node.Id, msg)
+	if err != nil {
+		return "", nil, err
+	}
+	return node.Id, result, nil
+}
+
+// handleNodeRequest runs the request on the node
+func (r *NodeRequests) handleNodeRequest(ctx context.Context, nodeId string, msg *nodeRequest) (*nodeRequestResult, error) {
+	var result *nodeRequestResult
+	var err error
+	if nodeId == r.currentNode.Id {
+		result = r.handleLocalNodeRequest(ctx, msg)
+	} else if r.mb != nil {
+		if result, err = r.forwardNodeRequest(ctx, nodeId, msg); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("request is for another node, redis or NATS required")
+	}
+
+	if result.NotFound {
+		return nil, twirp.NotFoundError(result.Error)
+	} else if result.Error != "" {
+		return nil, twirp.NewError(twirp.FailedPrecondition, result.Error)
+	}
+	return result, nil
+}
+
+func (r *NodeRequests) handleLocalNodeRequest(ctx context.Context, msg *nodeRequest) *nodeRequestResult {
+	r.lock.RLock()
+	handler := r.handlers[msg.Action]
+	r.lock.RUnlock()
+
+	result := &nodeRequestResult{}
+	err := ErrInvalidNodeRequest
+	if handler != nil {
+		err = handler(ctx, msg, result)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.NotFound = err == ErrRoomNotFound || err == ErrParticipantNotFound || err == ErrTrackNotFound ||
+			err == rtc.ErrNotRecording || err == rtc.ErrEgressNotFound || err == ErrIngressNotFound ||
+			err == ErrRelayNotFound
+	}
+	return result
+}
+
+// forwardNodeRequest sends the request to another node, and waits for its result
+func (r *NodeRequests) forwardNodeRequest(ctx context.Context, nodeId string, msg *nodeRequest) (*nodeRequestResult, error) {
+	forwarded := *msg
+	forwarded.RequestId = utils.NewGuid(nodeRequestPrefix)
+	data, err := json.Marshal(&forwarded)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := r.mb.Subscribe(ctx, nodeResponseChannel(forwarded.RequestId))
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+
+	if err = r.mb.Publish(ctx, nodeRequestChannel(nodeId), string(data)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case m := <-sub.Channel():
+		result := &nodeRequestResult{}
+		if err := json.Unmarshal(sub.Payload(m), result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case <-time.After(nodeRequestTimeout):
+		return nil, twirp.NewError(twirp.DeadlineExceeded, "node hosting the room did not respond")
+	}
+}
+
+// nodeRequestWorker runs each request in its own goroutine, so requests that take a while, like moving a
+// participant, don't hold up the others
+func (r *NodeRequests) nodeRequestWorker(sub utils.PubSub) {
+	for m := range sub.Channel() {
+		msg := &nodeRequest{}
+		if err := json.Unmarshal(sub.Payload(m), msg); err != nil {
+			logger.Errorw("could not parse node request", err)
+			continue
+		}
+		go r.respondToNodeRequest(msg)
+	}
+}
+
+func (r *NodeRequests) respondToNodeRequest(msg *nodeRequest) {
+	ctx := context.Background()
+	data, err := json.Marshal(r.handleLocalNodeRequest(ctx, msg))
+	if err != nil {
+		logger.Errorw("could not encode node request result", err)
+		return
+	}
+	if err := r.mb.Publish(ctx, nodeResponseChannel(msg.RequestId), string(data)); err != nil {
+		logger.Errorw("could not send node request result", err)
+	}
+}
+
+func nodeRequestChannel(nodeId string) string {
+	return "node_request:" + nodeId
+}
+
+func nodeResponseChannel(requestId string) string {
+	return "node_response:" + requestId
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+)
+
+func TestNodeRequests(t *testing.T) {
+	node := &livekit.Node{Id: "node-1"}
+	r := NewNodeRequests(nil, nil, node)
+	r.Handle(func(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error {
+		if msg.Recording.TrackSid == "" {
+			return ErrTrackNotFound
+		}
+		result.FilePath = msg.Recording.TrackSid + ".ogg"
+		return nil
+	}, trackActionStartRecording)
+
+	t.Run("runs requests for this node with the handler of their action", func(t *testing.T) {
+		result, err := r.handleNodeRequest(context.Background(), node.Id, &nodeRequest{
+			Action:    trackActionStartRecording,
+			Recording: &TrackRecordingRequest{TrackSid: "TR_1"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "TR_1.ogg", result.FilePath)
+	})
+
+	t.Run("maps errors to twirp errors", func(t *testing.T) {
+		_, err := r.handleNodeRequest(context.Background(), node.Id, &nodeRequest{
+			Action:    trackActionStartRecording,
+			Recording: &TrackRecordingRequest{},
+		})
+		require.Equal(t, twirp.NotFound, err.(twirp.Error).Code())
+
+		_, err = r.handleNodeRequest(context.Background(), node.Id, &nodeRequest{
+			Action: trackActionEndRecording,
+		})
+		require.Equal(t, twirp.FailedPrecondition, err.(twirp.Error).Code())
+		require.Equal(t, ErrInvalidNodeRequest.Error(), err.(twirp.Error).Msg())
+	})
+
+	t.Run("needs a message bus for other nodes", func(t *testing.T) {
+		_, err := r.handleNodeRequest(context.Background(), "node-2", &nodeRequest{
+			Action: trackActionStartRecording,
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestForwardedNodeRequests(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	newNode := func(id string) *NodeRequests {
+		nc, err := nats.Connect(ns.ClientURL())
+		require.NoError(t, err)
+		t.Cleanup(nc.Close)
+		mb, err := NewNATSMessageBus(nc)
+		require.NoError(t, err)
+		r := NewNodeRequests(mb, nil, &livekit.Node{Id: id})
+		require.NoError(t, r.Start())
+		t.Cleanup(r.Stop)
+		return r
+	}
+	nodeA := newNode("node-a")
+	nodeB := newNode("node-b")
+
+	// the first request waits for the second, which only arrives when they're handled concurrently
+	second := make(chan struct{})
+	nodeB.Handle(func(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error {
+		if msg.Recording.TrackSid == "TR_1" {
+			select {
+			case <-second:
+			case <-time.After(time.Second):
+				return ErrTrackNotFound
+			}
+		} else {
+			close(second)
+		}
+		result.FilePath = msg.Recording.TrackSid + ".ogg"
+		return nil
+	}, trackActionStartRecording)
+
+	var wg sync.WaitGroup
+	for _, trackSid := range []string{"TR_1", "TR_2"} {
+		trackSid := trackSid
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := nodeA.handleNodeRequest(context.Background(), "node-b", &nodeRequest{
+				Action:    trackActionStartRecording,
+				Recording: &TrackRecordingRequest{TrackSid: trackSid},
+			})
+			require.NoError(t, err)
+			require.Equal(t, trackSid+".ogg", result.FilePath)
+		}()
+		if trackSid == "TR_1" {
+			// lets the first request arrive first
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	wg.Wait()
+}
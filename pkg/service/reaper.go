@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	// lease held by the node that reaps dead nodes
+	reaperLease = "dead_node_reaper"
+	// dead nodes are looked for this often, the lease outlives a few misses by its holder
+	reapInterval   = 10 * time.Second
+	reaperLeaseTTL = 3 * reapInterval
+)
+
+// ReapDeadNodes cleans up after nodes that have stopped updating their stats, which are taken to have crashed.
+// rooms they hosted are deleted, and the webhooks the nodes would have sent for them are sent. rooms with connected
+// participants have been failed over by then. rooms they were cascaded to are left on the other nodes. a node is
+// removed once all its rooms are reaped, so rooms that couldn't be are tried again. it's only done on the node
+// holding the reaper lease, so it's run periodically on every node
+func (r *LocalRoomManager) ReapDeadNodes() error {
+	leader, err := r.router.AcquireLease(reaperLease, reaperLeaseTTL)
+	if err != nil || !leader {
+		return err
+	}
+
+	nodes, err := r.router.ListDeadNodes()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	for _, node := range nodes {
+		if err := r.reapNode(ctx, node.Id); err != nil {
+			logger.Errorw("could not reap node", err, "nodeID", node.Id)
+		}
+	}
+	return nil
+}
+
+func (r *LocalRoomManager) reapNode(ctx context.Context, nodeId string) error {
+	rooms, err := r.router.GetNodeRooms(ctx, nodeId)
+	if err != nil {
+		return err
+	}
+	reaped := true
+	for _, roomName := range rooms {
+		if err := r.reapRoom(ctx, roomName, nodeId); err != nil {
+			logger.Errorw("could not reap room", err, "room", roomName, "nodeID", nodeId)
+			reaped = false
+		}
+	}
+	if !reaped {
+		return nil
+	}
+	logger.Infow("removing dead node", "nodeID", nodeId, "rooms", len(rooms))
+	return r.router.RemoveNode(nodeId)
+}
+
+// reapRoom deletes the room when it's hosted on the dead node, or removes the dead node from those it's cascaded to
+func (r *LocalRoomManager) reapRoom(ctx context.Context, roomName, deadNodeId string) error {
+	// the room isn't reassigned while it's checked
+	token, err := r.LockRoom(ctx, roomName, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.UnlockRoom(ctx, roomName, token)
+	}()
+
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err == nil && node.Id != deadNodeId {
+		// hosted elsewhere, or failed over already
+		logger.Infow("removing dead node from room", "room", roomName, "nodeID", deadNodeId)
+		return r.router.RemoveRoomNode(ctx, roomName, deadNodeId)
+	} else if err != nil && err != routing.ErrNotFound {
+		return err
+	}
+
+	rm, err := r.LoadRoom(ctx, roomName)
+	if err == ErrRoomNotFound {
+		return r.router.ClearRoomState(ctx, roomName)
+	} else if err != nil {
+		return err
+	}
+	participants, err := r.ListParticipants(ctx, roomName)
+	if err != nil {
+		return err
+	}
+
+	logger.Infow("reaping room of dead node", "room", roomName, "roomID", rm.Sid, "participants", len(participants))
+	for _, p := range participants {
+		if err := r.router.ClearParticipantState(ctx, roomName, p.Identity); err != nil {
+			logger.Warnw("could not clear participant state", err, "room", roomName, "participant", p.Identity)
+		}
+		r.notifyEvent(&livekit.WebhookEvent{
+			Event:       webhook.EventParticipantLeft,
+			Room:        rm,
+			Participant: p,
+		})
+	}
+	if err := r.DeleteRoom(ctx, roomName); err != nil {
+		return err
+	}
+	r.notifyEvent(&livekit.WebhookEvent{
+		Event: webhook.EventRoomFinished,
+		Room:  rm,
+	})
+	return nil
+}
+
+// FailoverRoom moves the room off a node that's died to one picked by the selector, where it's recreated from the
+// store as its participants reconnect. it returns the node the room is on, which may have been failed over to
+// already, or not have been on the dead node when it was only cascaded to it. rooms nobody reconnects to are
+// left like rooms created through RoomService that haven't started
+func (r *LocalRoomManager) FailoverRoom(ctx context.Context, roomName, deadNodeId string) (string, error) {
+	token, err := r.LockRoom(ctx, roomName, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = r.UnlockRoom(ctx, roomName, token)
+	}()
+
+	if err = r.router.RemoveRoomNode(ctx, roomName, deadNodeId); err != nil {
+		return "", err
+	}
+	if err = r.clearParticipantRTCNodes(ctx, roomName, deadNodeId); err != nil {
+		return "", err
+	}
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err == nil && node.Id != deadNodeId && routing.IsAvailable(node) {
+		return node.Id, nil
+	} else if err != nil && err != routing.ErrNotFound {
+		return "", err
+	}
+
+	rm, err := r.LoadRoom(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		return "", err
+	}
+	selected, err := r.selectNode(ctx, nodes, rm)
+	if err != nil {
+		return "", err
+	}
+	if err = r.router.SetNodeForRoom(ctx, rm.Name, selected.Id); err != nil {
+		return "", err
+	}
+	logger.Infow("failed over room", "room", rm.Name, "roomID", rm.Sid, "deadNodeID", deadNodeId, "nodeID", selected.Id)
+	return selected.Id, nil
+}
+
+// clearParticipantRTCNodes removes the routes of the room's participants on a node that's died, so resumed sessions
+// aren't sent to it. they're routed to the room's node instead, which has them rejoin
+func (r *LocalRoomManager) clearParticipantRTCNodes(ctx context.Context, roomName, nodeId string) error {
+	participants, err := r.ListParticipants(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	for _, p := range participants {
+		rtcNodeId, err := r.router.GetParticipantRTCNode(roomName, p.Identity)
+		if err == routing.ErrNodeNotFound || (err == nil && rtcNodeId != nodeId) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err = r.router.ClearParticipantState(ctx, roomName, p.Identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,19 +3,232 @@ package service
 import (
 	"context"
 	"errors"
+	"net"
+	"strings"
+	"sync"
 	"time"
 
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/livekit/protocol/utils"
+	"github.com/twitchtv/twirp"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	RTPEgressPrefix   = "EG_"
+	RTPIngressPrefix  = "IN_"
+	TrackBridgePrefix = "TB_"
+	RelayPrefix       = "RL_"
 )
 
 type RecordingService struct {
-	mb utils.MessageBus
+	mb           utils.MessageBus
+	roomManager  RoomManager
+	router       routing.Router
+	currentNode  routing.LocalNode
+	conf         *config.Config
+	nodeRequests *NodeRequests
+
+	ingressLock sync.Mutex
+	// ingresses running on this node, including those of track bridges and relays, by ID
+	ingresses map[string]*rtc.RTPIngress
+}
+
+// TrackRecordingRequest identifies a published track to record on the node hosting its room
+type TrackRecordingRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+}
+
+type TrackRecordingResponse struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+	NodeId   string `json:"node_id"`
+	// path of the recording on the node, set when recording starts
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// RTPEgressRequest forwards a published track as plain RTP to host:port, with RTCP sent to the next port.
+// EgressId is only needed to stop it
+type RTPEgressRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	// simulcast layer to forward: LOW, MEDIUM or HIGH. defaults to HIGH
+	Quality  string `json:"quality,omitempty"`
+	EgressId string `json:"egress_id,omitempty"`
+}
+
+type RTPEgressResponse struct {
+	EgressId string `json:"egress_id"`
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+	NodeId   string `json:"node_id"`
+	// session description for receivers of the stream, set when egress starts
+	Sdp string `json:"sdp,omitempty"`
+}
+
+// RTPIngressRequest receives plain RTP on the node hosting the room, and publishes it as the participant
+// Identity. IngressId is only needed to stop it
+type RTPIngressRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity,omitempty"`
+	// VP8 or H264, video isn't received when empty
+	VideoCodec string `json:"video_codec,omitempty"`
+	Audio      bool   `json:"audio,omitempty"`
+	// only RTP sent from this IP is received when it's set
+	SourceIp  string `json:"source_ip,omitempty"`
+	IngressId string `json:"ingress_id,omitempty"`
+}
+
+type RTPIngressResponse struct {
+	IngressId string `json:"ingress_id"`
+	Room      string `json:"room"`
+	Identity  string `json:"identity"`
+	NodeId    string `json:"node_id"`
+	// where the source sends RTP to, set when ingress starts
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// payload types the source sends each stream with
+	VideoPayloadType int `json:"video_payload_type,omitempty"`
+	AudioPayloadType int `json:"audio_payload_type,omitempty"`
 }
 
-func NewRecordingService(mb utils.MessageBus) *RecordingService {
-	return &RecordingService{mb: mb}
+// TrackBridgeRequest publishes a track of Room into DestinationRoom, as the participant DestinationIdentity.
+// BridgeId is only needed to end it
+type TrackBridgeRequest struct {
+	Room                string `json:"room"`
+	Identity            string `json:"identity"`
+	TrackSid            string `json:"track_sid"`
+	DestinationRoom     string `json:"destination_room,omitempty"`
+	DestinationIdentity string `json:"destination_identity,omitempty"`
+	// simulcast layer to bridge: LOW, MEDIUM or HIGH. defaults to HIGH
+	Quality  string `json:"quality,omitempty"`
+	BridgeId string `json:"bridge_id,omitempty"`
+}
+
+type TrackBridgeResponse struct {
+	BridgeId            string `json:"bridge_id"`
+	Room                string `json:"room"`
+	Identity            string `json:"identity"`
+	TrackSid            string `json:"track_sid"`
+	DestinationRoom     string `json:"destination_room,omitempty"`
+	DestinationIdentity string `json:"destination_identity,omitempty"`
+	NodeId              string `json:"node_id"`
+}
+
+const (
+	trackActionStartRecording = "start_recording"
+	trackActionEndRecording   = "end_recording"
+	trackActionStartRTPEgress = "start_rtp_egress"
+	trackActionEndRTPEgress   = "end_rtp_egress"
+	trackActionStartIngress   = "start_rtp_ingress"
+	trackActionEndIngress     = "end_rtp_ingress"
+	trackActionStartBridge    = "start_track_bridge"
+	trackActionEndBridge      = "end_track_bridge"
+)
+
+func NewRecordingService(mb utils.MessageBus, roomManager RoomManager, router routing.Router, currentNode routing.LocalNode, conf *config.Config, nodeRequests *NodeRequests) *RecordingService {
+	s := &RecordingService{
+		mb:           mb,
+		roomManager:  roomManager,
+		router:       router,
+		currentNode:  currentNode,
+		conf:         conf,
+		nodeRequests: nodeRequests,
+		ingresses:    make(map[string]*rtc.RTPIngress),
+	}
+	nodeRequests.Handle(s.handleLocalTrackRequest,
+		trackActionStartRecording, trackActionEndRecording,
+		trackActionStartRTPEgress, trackActionEndRTPEgress,
+		trackActionStartIngress, trackActionEndIngress,
+		trackActionStartBridge, trackActionEndBridge)
+	return s
+}
+
+// NewRecordingServiceServer creates the twirp server for RecordingService, along with track recording
+// methods that are only available to JSON clients
+func NewRecordingServiceServer(svc *RecordingService) livekit.TwirpServer {
+	server := NewTwirpExtServer(livekit.NewRecordingServiceServer(svc))
+	server.Handle("StartTrackRecording", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &TrackRecordingRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.StartTrackRecording(ctx, req)
+	})
+	server.Handle("EndTrackRecording", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &TrackRecordingRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.EndTrackRecording(ctx, req)
+	})
+	server.Handle("StartRTPEgress", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &RTPEgressRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.StartRTPEgress(ctx, req)
+	})
+	server.Handle("EndRTPEgress", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &RTPEgressRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.EndRTPEgress(ctx, req)
+	})
+	server.Handle("StartRTPIngress", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &RTPIngressRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.StartRTPIngress(ctx, req)
+	})
+	server.Handle("EndRTPIngress", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &RTPIngressRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.EndRTPIngress(ctx, req)
+	})
+	server.Handle("StartTrackBridge", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &TrackBridgeRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.StartTrackBridge(ctx, req)
+	})
+	server.Handle("EndTrackBridge", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &TrackBridgeRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.EndTrackBridge(ctx, req)
+	})
+	return server
+}
+
+// Stop closes the ingresses running on this node
+func (s *RecordingService) Stop() {
+	s.ingressLock.Lock()
+	ingresses := make([]*rtc.RTPIngress, 0, len(s.ingresses))
+	for _, ingress := range s.ingresses {
+		ingresses = append(ingresses, ingress)
+	}
+	s.ingressLock.Unlock()
+	for _, ingress := range ingresses {
+		ingress.Close()
+	}
 }
 
 func (s *RecordingService) StartRecording(ctx context.Context, req *livekit.StartRecordingRequest) (*livekit.RecordingResponse, error) {
@@ -87,3 +300,285 @@ func (s *RecordingService) EndRecording(ctx context.Context, req *livekit.EndRec
 
 	return &livekit.RecordingResponse{RecordingId: req.RecordingId}, nil
 }
+
+// StartTrackRecording records a published track to a file on the node hosting the room
+func (s *RecordingService) StartTrackRecording(ctx context.Context, req *TrackRecordingRequest) (*TrackRecordingResponse, error) {
+	return s.handleTrackRecording(ctx, req, trackActionStartRecording)
+}
+
+// EndTrackRecording finishes a recording started with StartTrackRecording
+func (s *RecordingService) EndTrackRecording(ctx context.Context, req *TrackRecordingRequest) (*TrackRecordingResponse, error) {
+	return s.handleTrackRecording(ctx, req, trackActionEndRecording)
+}
+
+func (s *RecordingService) handleTrackRecording(ctx context.Context, req *TrackRecordingRequest, action string) (*TrackRecordingResponse, error) {
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	nodeId, result, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action:    action,
+		Recording: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrackRecordingResponse{
+		Room:     req.Room,
+		Identity: req.Identity,
+		TrackSid: req.TrackSid,
+		NodeId:   nodeId,
+		FilePath: result.FilePath,
+	}, nil
+}
+
+// StartRTPEgress forwards a published track as plain RTP from the node hosting the room
+func (s *RecordingService) StartRTPEgress(ctx context.Context, req *RTPEgressRequest) (*RTPEgressResponse, error) {
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.Host == "" || req.Port <= 0 || req.Port > 65534 {
+		return nil, twirp.InvalidArgumentError("host", "host and port are required")
+	}
+	if _, err := parseVideoQuality(req.Quality); err != nil {
+		return nil, twirp.InvalidArgumentError("quality", err.Error())
+	}
+
+	egress := *req
+	egress.EgressId = utils.NewGuid(RTPEgressPrefix)
+	nodeId, result, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action: trackActionStartRTPEgress,
+		Egress: &egress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RTPEgressResponse{
+		EgressId: egress.EgressId,
+		Room:     req.Room,
+		Identity: req.Identity,
+		TrackSid: req.TrackSid,
+		NodeId:   nodeId,
+		Sdp:      result.Sdp,
+	}, nil
+}
+
+func (s *RecordingService) EndRTPEgress(ctx context.Context, req *RTPEgressRequest) (*RTPEgressResponse, error) {
+	if err := EnsureRecordPermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.EgressId == "" {
+		return nil, twirp.RequiredArgumentError("egress_id")
+	}
+
+	nodeId, _, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action: trackActionEndRTPEgress,
+		Egress: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RTPEgressResponse{
+		EgressId: req.EgressId,
+		Room:     req.Room,
+		Identity: req.Identity,
+		TrackSid: req.TrackSid,
+		NodeId:   nodeId,
+	}, nil
+}
+
+// StartRTPIngress receives plain RTP on the node hosting the room, and publishes it into the room.
+// the room is created if it doesn't exist
+func (s *RecordingService) StartRTPIngress(ctx context.Context, req *RTPIngressRequest) (*RTPIngressResponse, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.VideoCodec == "" && !req.Audio {
+		return nil, twirp.InvalidArgumentError("video_codec", "video_codec or audio is required")
+	}
+	if req.SourceIp != "" && net.ParseIP(req.SourceIp) == nil {
+		return nil, twirp.InvalidArgumentError("source_ip", "must be an IP address")
+	}
+	if _, err := s.roomManager.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.Room}); err != nil {
+		return nil, err
+	}
+
+	ingress := *req
+	ingress.IngressId = utils.NewGuid(RTPIngressPrefix)
+	if ingress.Identity == "" {
+		ingress.Identity = ingress.IngressId
+	}
+	nodeId, result, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action:  trackActionStartIngress,
+		Ingress: &ingress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RTPIngressResponse{
+		IngressId: ingress.IngressId,
+		Room:      req.Room,
+		Identity:  ingress.Identity,
+		NodeId:    nodeId,
+		Host:      result.Host,
+		Port:      result.Port,
+	}
+	if req.VideoCodec != "" {
+		res.VideoPayloadType = rtc.RTPIngressVideoPayloadType
+	}
+	if req.Audio {
+		res.AudioPayloadType = rtc.RTPIngressAudioPayloadType
+	}
+	return res, nil
+}
+
+// EndRTPIngress stops an ingress, its participant leaves the room
+func (s *RecordingService) EndRTPIngress(ctx context.Context, req *RTPIngressRequest) (*RTPIngressResponse, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.IngressId == "" {
+		return nil, twirp.RequiredArgumentError("ingress_id")
+	}
+
+	nodeId, _, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action:  trackActionEndIngress,
+		Ingress: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RTPIngressResponse{
+		IngressId: req.IngressId,
+		Room:      req.Room,
+		Identity:  req.Identity,
+		NodeId:    nodeId,
+	}, nil
+}
+
+// StartTrackBridge publishes a track into another room, where it's subscribed to like any other track. the
+// bridge runs on the node hosting the track's room, and the destination room is created if it doesn't exist.
+// it ends when the track is unpublished
+func (s *RecordingService) StartTrackBridge(ctx context.Context, req *TrackBridgeRequest) (*TrackBridgeResponse, error) {
+	// admin of the track's room, and allowed to create the destination room
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.DestinationRoom == "" || req.DestinationRoom == req.Room {
+		return nil, twirp.InvalidArgumentError("destination_room", "must be another room")
+	}
+	if _, err := parseVideoQuality(req.Quality); err != nil {
+		return nil, twirp.InvalidArgumentError("quality", err.Error())
+	}
+	if _, err := s.roomManager.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.DestinationRoom}); err != nil {
+		return nil, err
+	}
+
+	bridge := *req
+	bridge.BridgeId = utils.NewGuid(TrackBridgePrefix)
+	if bridge.DestinationIdentity == "" {
+		bridge.DestinationIdentity = bridge.BridgeId
+	}
+	nodeId, _, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action: trackActionStartBridge,
+		Bridge: &bridge,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrackBridgeResponse{
+		BridgeId:            bridge.BridgeId,
+		Room:                req.Room,
+		Identity:            req.Identity,
+		TrackSid:            req.TrackSid,
+		DestinationRoom:     req.DestinationRoom,
+		DestinationIdentity: bridge.DestinationIdentity,
+		NodeId:              nodeId,
+	}, nil
+}
+
+// EndTrackBridge removes the bridged track from the destination room
+func (s *RecordingService) EndTrackBridge(ctx context.Context, req *TrackBridgeRequest) (*TrackBridgeResponse, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.BridgeId == "" {
+		return nil, twirp.RequiredArgumentError("bridge_id")
+	}
+
+	nodeId, _, err := s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action: trackActionEndBridge,
+		Bridge: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrackBridgeResponse{
+		BridgeId:            req.BridgeId,
+		Room:                req.Room,
+		Identity:            req.Identity,
+		TrackSid:            req.TrackSid,
+		DestinationRoom:     req.DestinationRoom,
+		DestinationIdentity: req.DestinationIdentity,
+		NodeId:              nodeId,
+	}, nil
+}
+
+// handleLocalTrackRequest runs track requests for rooms on this node
+func (s *RecordingService) handleLocalTrackRequest(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error {
+	var err error
+	switch {
+	case msg.Action == trackActionStartRecording && msg.Recording != nil:
+		req := msg.Recording
+		result.FilePath, err = s.roomManager.StartTrackRecording(ctx, req.Room, req.Identity, req.TrackSid)
+	case msg.Action == trackActionEndRecording && msg.Recording != nil:
+		req := msg.Recording
+		err = s.roomManager.StopTrackRecording(ctx, req.Room, req.Identity, req.TrackSid)
+	case msg.Action == trackActionStartRTPEgress && msg.Egress != nil:
+		req := msg.Egress
+		var quality livekit.VideoQuality
+		if quality, err = parseVideoQuality(req.Quality); err == nil {
+			result.Sdp, err = s.roomManager.StartRTPEgress(ctx, req.Room, req.Identity, req.TrackSid, req.EgressId,
+				req.Host, req.Port, quality)
+		}
+	case msg.Action == trackActionEndRTPEgress && msg.Egress != nil:
+		req := msg.Egress
+		err = s.roomManager.StopRTPEgress(ctx, req.Room, req.Identity, req.TrackSid, req.EgressId)
+	case msg.Action == trackActionStartIngress && msg.Ingress != nil:
+		result.Host = s.currentNode.Ip
+		result.Port, err = s.startLocalRTPIngress(ctx, msg.Ingress)
+	case msg.Action == trackActionEndIngress && msg.Ingress != nil:
+		err = s.endLocalRTPIngress(msg.Ingress.IngressId)
+	case msg.Action == trackActionStartBridge && msg.Bridge != nil:
+		err = s.startLocalTrackBridge(ctx, msg.Bridge)
+	case msg.Action == trackActionEndBridge && msg.Bridge != nil:
+		err = s.endLocalRTPIngress(msg.Bridge.BridgeId)
+	default:
+		err = ErrInvalidNodeRequest
+	}
+	return err
+}
+
+// empty quality is the highest
+func parseVideoQuality(quality string) (livekit.VideoQuality, error) {
+	switch strings.ToUpper(quality) {
+	case "LOW":
+		return livekit.VideoQuality_LOW, nil
+	case "MEDIUM":
+		return livekit.VideoQuality_MEDIUM, nil
+	case "", "HIGH":
+		return livekit.VideoQuality_HIGH, nil
+	default:
+		return livekit.VideoQuality_HIGH, errors.New("quality must be one of LOW, MEDIUM or HIGH")
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -9,6 +10,9 @@ import (
 	"github.com/livekit/protocol/utils"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 const (
@@ -18,12 +22,33 @@ const (
 	// RoomIdMap is hash of room_id => room name
 	RoomIdMap = "room_id_map"
 
+	// RoomOptionsPrefix is RoomOptions JSON
+	// a key for each room
+	RoomOptionsPrefix = "room_options:"
+
 	// RoomParticipantsPrefix is hash of participant_name => ParticipantInfo
 	// a key for each room, with expiration
 	RoomParticipantsPrefix = "room_participants:"
 
 	// RoomLockPrefix is a simple key containing a provided lock uid
 	RoomLockPrefix = "room_lock:"
+
+	// RoomParticipantQualityPrefix is hash of participant_name => connection quality
+	// a key for each room, with expiration
+	RoomParticipantQualityPrefix = "room_participant_quality:"
+
+	// RoomMetadataKey is hash of room_name => metadata
+	RoomMetadataKey = "room_metadata"
+
+	// RoomDataHistoryPrefix is DataHistoryEntry list JSON
+	// a key for each room, with expiration
+	RoomDataHistoryPrefix = "room_data_history:"
+
+	// history of rooms that are gone without being deleted expires this long after its last packet
+	roomDataHistoryTTL = 24 * time.Hour
+
+	// quality of rooms that are gone without being deleted expires this long after its last change
+	roomParticipantQualityTTL = 24 * time.Hour
 )
 
 type RedisRoomStore struct {
@@ -119,12 +144,40 @@ func (p *RedisRoomStore) DeleteRoom(ctx context.Context, idOrName string) error
 	pp := p.rc.Pipeline()
 	pp.HDel(p.ctx, RoomIdMap, sid)
 	pp.HDel(p.ctx, RoomsKey, name)
+	pp.Del(p.ctx, RoomOptionsPrefix+name)
+	pp.HDel(p.ctx, RoomMetadataKey, name)
+	pp.Del(p.ctx, RoomDataHistoryPrefix+name)
 	pp.Del(p.ctx, RoomParticipantsPrefix+name)
+	pp.Del(p.ctx, RoomParticipantQualityPrefix+name)
 
 	_, err = pp.Exec(p.ctx)
 	return err
 }
 
+func (p *RedisRoomStore) StoreRoomOptions(ctx context.Context, roomName string, opts *rtc.RoomOptions) error {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	return p.rc.Set(p.ctx, RoomOptionsPrefix+roomName, data, 0).Err()
+}
+
+func (p *RedisRoomStore) LoadRoomOptions(ctx context.Context, roomName string) (*rtc.RoomOptions, error) {
+	data, err := p.rc.Get(p.ctx, RoomOptionsPrefix+roomName).Result()
+	if err == redis.Nil {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	opts := rtc.RoomOptions{}
+	if err := json.Unmarshal([]byte(data), &opts); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
 func (p *RedisRoomStore) LockRoom(ctx context.Context, name string, duration time.Duration) (string, error) {
 	token := utils.NewGuid("LOCK")
 	key := RoomLockPrefix + name
@@ -215,7 +268,70 @@ func (p *RedisRoomStore) ListParticipants(ctx context.Context, roomName string)
 }
 
 func (p *RedisRoomStore) DeleteParticipant(ctx context.Context, roomName, identity string) error {
-	key := RoomParticipantsPrefix + roomName
+	pp := p.rc.Pipeline()
+	pp.HDel(p.ctx, RoomParticipantsPrefix+roomName, identity)
+	pp.HDel(p.ctx, RoomParticipantQualityPrefix+roomName, identity)
 
-	return p.rc.HDel(p.ctx, key, identity).Err()
+	_, err := pp.Exec(p.ctx)
+	return err
+}
+
+func (p *RedisRoomStore) StoreConnectionQuality(ctx context.Context, roomName, identity string, quality types.ConnectionQuality) error {
+	key := RoomParticipantQualityPrefix + roomName
+
+	pp := p.rc.Pipeline()
+	pp.HSet(p.ctx, key, identity, quality.String())
+	pp.Expire(p.ctx, key, roomParticipantQualityTTL)
+	_, err := pp.Exec(p.ctx)
+	return err
+}
+
+func (p *RedisRoomStore) LoadConnectionQuality(ctx context.Context, roomName, identity string) (types.ConnectionQuality, error) {
+	key := RoomParticipantQualityPrefix + roomName
+	data, err := p.rc.HGet(p.ctx, key, identity).Result()
+	if err == redis.Nil {
+		return types.ConnectionQualityPoor, ErrParticipantNotFound
+	} else if err != nil {
+		return types.ConnectionQualityPoor, err
+	}
+
+	var quality types.ConnectionQuality
+	err = quality.UnmarshalText([]byte(data))
+	return quality, err
+}
+
+func (p *RedisRoomStore) StoreRoomMetadata(ctx context.Context, roomName, metadata string) error {
+	return p.rc.HSet(p.ctx, RoomMetadataKey, roomName, metadata).Err()
+}
+
+func (p *RedisRoomStore) LoadRoomMetadata(ctx context.Context, roomName string) (string, error) {
+	metadata, err := p.rc.HGet(p.ctx, RoomMetadataKey, roomName).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return metadata, err
+}
+
+func (p *RedisRoomStore) StoreDataHistory(ctx context.Context, roomName string, history []*rtc.DataHistoryEntry) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	return p.rc.Set(p.ctx, RoomDataHistoryPrefix+roomName, data, roomDataHistoryTTL).Err()
+}
+
+func (p *RedisRoomStore) LoadDataHistory(ctx context.Context, roomName string) ([]*rtc.DataHistoryEntry, error) {
+	data, err := p.rc.Get(p.ctx, RoomDataHistoryPrefix+roomName).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var history []*rtc.DataHistoryEntry
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
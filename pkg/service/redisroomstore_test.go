@@ -10,6 +10,7 @@ import (
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/service"
 )
 
@@ -48,6 +49,12 @@ func TestParticipantPersistence(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, participants, 1)
 
+	// connection quality is stored alongside
+	require.NoError(t, rs.StoreConnectionQuality(ctx, roomName, p.Identity, types.ConnectionQualityGood))
+	quality, err := rs.LoadConnectionQuality(ctx, roomName, p.Identity)
+	require.NoError(t, err)
+	require.Equal(t, types.ConnectionQualityGood, quality)
+
 	// deleting participant should return back to normal
 	require.NoError(t, rs.DeleteParticipant(ctx, roomName, p.Identity))
 
@@ -58,6 +65,8 @@ func TestParticipantPersistence(t *testing.T) {
 	// shouldn't be able to get it
 	_, err = rs.LoadParticipant(ctx, roomName, p.Identity)
 	require.Equal(t, err, service.ErrParticipantNotFound)
+	_, err = rs.LoadConnectionQuality(ctx, roomName, p.Identity)
+	require.Equal(t, err, service.ErrParticipantNotFound)
 }
 
 func TestRoomLock(t *testing.T) {
@@ -0,0 +1,632 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// relays are updated as participants join other nodes of their rooms, and publish or unpublish tracks
+const relayCheckInterval = 2 * time.Second
+
+const (
+	relayActionStart  = "start_relay"
+	relayActionUpdate = "update_relay"
+	relayActionEnd    = "end_relay"
+)
+
+// RelayService relays participants of this node to the other nodes their rooms are cascaded to, and joins
+// those relayed to this node to its rooms. relays are received with ingresses of RecordingService
+type RelayService struct {
+	mb           utils.MessageBus
+	roomManager  RoomManager
+	router       routing.Router
+	currentNode  routing.LocalNode
+	nodeRequests *NodeRequests
+	recService   *RecordingService
+
+	// relays of participants on this node to other nodes of their rooms, only used by relayWorker
+	relays   map[string]*outgoingRelay
+	shutdown chan struct{}
+
+	// relays of participants of other nodes received on this node, by ID
+	relayLock   sync.Mutex
+	localRelays map[string]*localRelay
+}
+
+// participantRelay forwards the tracks of a participant to another node its room is cascaded to, where it
+// joins the room with the same identity. relays aren't routed or stored, the participant is only known on
+// other nodes through them.
+// tracks are sent as SRTP from the sending node's IP, to ports of the receiving node's RTC range, with a key
+// of the relay's own. the highest simulcast layer is relayed, and metadata follows the participant. data
+// packets, mute state and active speakers aren't relayed, and the relayed participant can't subscribe
+type participantRelay struct {
+	RelayId        string          `json:"relay_id"`
+	Room           string          `json:"room"`
+	Identity       string          `json:"identity"`
+	ParticipantSid string          `json:"participant_sid"`
+	Metadata       string          `json:"metadata,omitempty"`
+	Tracks         []*relayedTrack `json:"tracks"`
+	// IP of the sending node, tracks are only received from it
+	NodeIp string `json:"node_ip"`
+	// SRTP master key and salt of the tracks
+	Key []byte `json:"key"`
+}
+
+type relayedTrack struct {
+	Sid         string `json:"sid"`
+	Name        string `json:"name,omitempty"`
+	Video       bool   `json:"video,omitempty"`
+	MimeType    string `json:"mime_type"`
+	ClockRate   uint32 `json:"clock_rate"`
+	Channels    uint16 `json:"channels,omitempty"`
+	FmtpLine    string `json:"fmtp_line,omitempty"`
+	PayloadType uint8  `json:"payload_type"`
+}
+
+type outgoingRelay struct {
+	nodeId string
+	relay  *participantRelay
+	// tracks being sent, by SID
+	tracks map[string]*relayedTrackSender
+}
+
+type relayedTrackSender struct {
+	track    types.PublishedTrack
+	egressID string
+	sender   *rtc.SRTPSender
+}
+
+// localRelay is a relay received on this node, updates are applied one at a time
+type localRelay struct {
+	lock    sync.Mutex
+	relay   *participantRelay
+	ingress *rtc.RTPIngress
+	session *httpSignalSession
+}
+
+func NewRelayService(mb utils.MessageBus, roomManager RoomManager, router routing.Router, currentNode routing.LocalNode, nodeRequests *NodeRequests, recService *RecordingService) *RelayService {
+	s := &RelayService{
+		mb:           mb,
+		roomManager:  roomManager,
+		router:       router,
+		currentNode:  currentNode,
+		nodeRequests: nodeRequests,
+		recService:   recService,
+		relays:       make(map[string]*outgoingRelay),
+		localRelays:  make(map[string]*localRelay),
+		shutdown:     make(chan struct{}),
+	}
+	nodeRequests.Handle(s.handleLocalRelayRequest, relayActionStart, relayActionUpdate, relayActionEnd)
+	return s
+}
+
+// Start relays participants of this node to other nodes of their rooms
+func (s *RelayService) Start() error {
+	// rooms are only cascaded with multiple nodes
+	if s.mb != nil {
+		go s.relayWorker()
+	}
+	return nil
+}
+
+// Stop ends the relays of this node's participants. relays received on this node are closed with the
+// ingresses of RecordingService
+func (s *RelayService) Stop() {
+	select {
+	case <-s.shutdown:
+	default:
+		close(s.shutdown)
+	}
+}
+
+func (s *RelayService) handleLocalRelayRequest(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error {
+	if msg.Relay == nil {
+		return ErrInvalidNodeRequest
+	}
+
+	var err error
+	switch msg.Action {
+	case relayActionStart:
+		result.Host = s.currentNode.Ip
+		result.Ports, err = s.startLocalRelay(ctx, msg.Relay)
+	case relayActionUpdate:
+		result.Host = s.currentNode.Ip
+		result.Ports, err = s.updateLocalRelay(ctx, msg.Relay)
+	case relayActionEnd:
+		err = s.recService.endLocalRTPIngress(msg.Relay.RelayId)
+	default:
+		err = ErrInvalidNodeRequest
+	}
+	return err
+}
+
+func (s *RelayService) relayWorker() {
+	ticker := time.NewTicker(relayCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdown:
+			for key, relay := range s.relays {
+				s.stopRelay(relay)
+				delete(s.relays, key)
+			}
+			return
+		case <-ticker.C:
+			s.updateRelays()
+		}
+	}
+}
+
+// updateRelays relays each participant on this node to the other nodes with participants in its room
+func (s *RelayService) updateRelays() {
+	ctx := context.Background()
+	wanted := make(map[string]bool)
+	for _, room := range s.roomManager.GetRooms(ctx) {
+		roomName := room.Room.Name
+		roomNodes, err := s.router.GetRoomNodes(ctx, roomName)
+		if err != nil {
+			logger.Errorw("could not get room nodes", err, "room", roomName)
+			// keep the room's relays as they are until its nodes are known again
+			for key, relay := range s.relays {
+				if relay.relay.Room == roomName {
+					wanted[key] = true
+				}
+			}
+			continue
+		}
+
+		for nodeId, count := range roomNodes {
+			if nodeId == s.currentNode.Id || count == 0 {
+				continue
+			}
+			for _, p := range room.GetParticipants() {
+				if p.Relayed() || p.Hidden() || p.State() != livekit.ParticipantInfo_ACTIVE {
+					continue
+				}
+				tracks := p.GetPublishedTracks()
+				if len(tracks) == 0 {
+					continue
+				}
+
+				key := nodeId + "|" + roomName + "|" + p.Identity()
+				wanted[key] = true
+				existing := s.relays[key]
+				if existing != nil && existing.relay.ParticipantSid != p.ID() {
+					// the participant rejoined
+					s.stopRelay(existing)
+					delete(s.relays, key)
+					existing = nil
+				}
+
+				if existing != nil {
+					err = s.updateRelay(ctx, existing, p, tracks)
+				} else {
+					var relay *outgoingRelay
+					if relay, err = s.startRelay(ctx, nodeId, roomName, p, tracks); err == nil {
+						s.relays[key] = relay
+					}
+				}
+				if err == rtc.ErrTrackNotReady {
+					// retried once the tracks receive media
+					continue
+				} else if err != nil {
+					logger.Errorw("could not relay participant", err,
+						"room", roomName,
+						"participant", p.Identity(),
+						"node", nodeId)
+					if existing != nil {
+						// started again on the next check
+						s.stopRelay(existing)
+						delete(s.relays, key)
+					}
+				}
+			}
+		}
+	}
+
+	for key, relay := range s.relays {
+		if !wanted[key] {
+			s.stopRelay(relay)
+			delete(s.relays, key)
+		}
+	}
+}
+
+func (s *RelayService) startRelay(ctx context.Context, nodeId, roomName string, p types.Participant, tracks []types.PublishedTrack) (*outgoingRelay, error) {
+	key, err := rtc.NewSRTPKey()
+	if err != nil {
+		return nil, err
+	}
+	relay := &participantRelay{
+		RelayId:        utils.NewGuid(RelayPrefix),
+		Room:           roomName,
+		Identity:       p.Identity(),
+		ParticipantSid: p.ID(),
+		Metadata:       p.ToProto().Metadata,
+		Tracks:         relayedTracks(tracks),
+		NodeIp:         s.currentNode.Ip,
+		Key:            key,
+	}
+	if len(relay.Tracks) == 0 {
+		return nil, rtc.ErrTrackNotReady
+	}
+
+	result, err := s.nodeRequests.forwardNodeRequest(ctx, nodeId, &nodeRequest{
+		Action: relayActionStart,
+		Relay:  relay,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+
+	outgoing := &outgoingRelay{
+		nodeId: nodeId,
+		relay:  relay,
+		tracks: make(map[string]*relayedTrackSender),
+	}
+	for _, track := range tracks {
+		if port := result.Ports[track.ID()]; port != 0 {
+			if err = s.sendRelayedTrack(outgoing, track, result.Host, port); err != nil {
+				s.stopRelay(outgoing)
+				return nil, err
+			}
+		}
+	}
+
+	logger.Infow("relay started",
+		"room", roomName,
+		"participant", relay.Identity,
+		"node", nodeId,
+		"relay", relay.RelayId,
+		"tracks", len(relay.Tracks))
+	return outgoing, nil
+}
+
+// updateRelay relays tracks the participant published since the relay was last updated, and its metadata.
+// tracks that are still published are left as they are, so they keep their SIDs on the other node
+func (s *RelayService) updateRelay(ctx context.Context, outgoing *outgoingRelay, p types.Participant, tracks []types.PublishedTrack) error {
+	relay := *outgoing.relay
+	relay.Metadata = p.ToProto().Metadata
+	relay.Tracks = relayedTracks(tracks)
+	if relay.Metadata == outgoing.relay.Metadata && sameRelayedTracks(relay.Tracks, outgoing.relay.Tracks) {
+		return nil
+	}
+
+	result, err := s.nodeRequests.forwardNodeRequest(ctx, outgoing.nodeId, &nodeRequest{
+		Action: relayActionUpdate,
+		Relay:  &relay,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	outgoing.relay = &relay
+
+	relayed := make(map[string]bool, len(relay.Tracks))
+	for _, track := range relay.Tracks {
+		relayed[track.Sid] = true
+	}
+	for trackSid, sender := range outgoing.tracks {
+		if !relayed[trackSid] {
+			stopRelayedTrack(sender)
+			delete(outgoing.tracks, trackSid)
+		}
+	}
+	for _, track := range tracks {
+		port := result.Ports[track.ID()]
+		if outgoing.tracks[track.ID()] != nil || port == 0 {
+			continue
+		}
+		if err = s.sendRelayedTrack(outgoing, track, result.Host, port); err != nil {
+			return err
+		}
+	}
+
+	logger.Debugw("relay updated",
+		"room", relay.Room,
+		"participant", relay.Identity,
+		"node", outgoing.nodeId,
+		"relay", relay.RelayId,
+		"tracks", len(relay.Tracks))
+	return nil
+}
+
+// sendRelayedTrack forwards the track's highest layer to a port of the relay's ingress
+func (s *RelayService) sendRelayedTrack(outgoing *outgoingRelay, track types.PublishedTrack, host string, port int) error {
+	sender, err := rtc.NewSRTPSender(&net.UDPAddr{IP: net.ParseIP(s.currentNode.Ip)},
+		&net.UDPAddr{IP: net.ParseIP(host), Port: port}, outgoing.relay.Key)
+	if err != nil {
+		return err
+	}
+	egressID := utils.NewGuid(RTPEgressPrefix)
+	requestKeyFrame, err := track.ForwardRTP(egressID, livekit.VideoQuality_HIGH, sender.WriteRTP)
+	if err != nil {
+		sender.Close()
+		return err
+	}
+	sender.OnKeyFrameRequest(requestKeyFrame)
+	outgoing.tracks[track.ID()] = &relayedTrackSender{
+		track:    track,
+		egressID: egressID,
+		sender:   sender,
+	}
+	return nil
+}
+
+func stopRelayedTrack(sender *relayedTrackSender) {
+	// the track may have been unpublished already
+	_ = sender.track.StopRTPEgress(sender.egressID)
+	sender.sender.Close()
+}
+
+func (s *RelayService) stopRelay(outgoing *outgoingRelay) {
+	ctx := context.Background()
+	relay := outgoing.relay
+	for _, sender := range outgoing.tracks {
+		stopRelayedTrack(sender)
+	}
+	result, err := s.nodeRequests.forwardNodeRequest(ctx, outgoing.nodeId, &nodeRequest{
+		Action: relayActionEnd,
+		Relay:  relay,
+	})
+	if err == nil && result.Error != "" && !result.NotFound {
+		err = errors.New(result.Error)
+	}
+	if err != nil {
+		// the relay closes itself once the participant leaves
+		logger.Warnw("could not end relay", err,
+			"room", relay.Room,
+			"participant", relay.Identity,
+			"node", outgoing.nodeId,
+			"relay", relay.RelayId)
+		return
+	}
+	logger.Infow("relay ended",
+		"room", relay.Room,
+		"participant", relay.Identity,
+		"node", outgoing.nodeId,
+		"relay", relay.RelayId)
+}
+
+// startLocalRelay joins a participant of another node to the room on this node, and returns the port
+// each of its tracks is received on
+func (s *RelayService) startLocalRelay(ctx context.Context, relay *participantRelay) (map[string]int, error) {
+	room := s.roomManager.GetRoom(ctx, relay.Room)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	if room.GetParticipant(relay.Identity) != nil {
+		return nil, rtc.ErrAlreadyJoined
+	}
+	sourceIP := net.ParseIP(relay.NodeIp)
+	if sourceIP == nil {
+		return nil, errors.New("relay must be sent from a node IP")
+	}
+
+	params := s.recService.ingressParams(relay.RelayId)
+	params.SourceIP = sourceIP
+	params.SRTPKey = relay.Key
+	// tracks published later may use any of the room's codecs
+	params.EnabledCodecs = room.Room.EnabledCodecs
+	for _, track := range relay.Tracks {
+		params.Tracks = append(params.Tracks, track.ingressTrack())
+	}
+	ingress, err := rtc.NewRTPIngress(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pi := ingressParticipant(relay.Identity)
+	pi.Metadata = relay.Metadata
+	pi.Relayed = true
+	done := make(chan struct{})
+	session, err := s.recService.joinIngress(ingress, relay.Room, pi, func() {
+		s.relayLock.Lock()
+		delete(s.localRelays, relay.RelayId)
+		s.relayLock.Unlock()
+		close(done)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.relayLock.Lock()
+	s.localRelays[relay.RelayId] = &localRelay{
+		relay:   relay,
+		ingress: ingress,
+		session: session,
+	}
+	s.relayLock.Unlock()
+	go s.relayWatchdog(relay, ingress, done)
+
+	logger.Infow("relay joined",
+		"room", relay.Room,
+		"participant", relay.Identity,
+		"relay", relay.RelayId)
+	return relayPorts(ingress, relay.Tracks), nil
+}
+
+// updateLocalRelay publishes tracks added to a relay, unpublishes those removed and updates the participant's
+// metadata. it returns the ports of added tracks. the relay is ended when it can't be updated, so that it's
+// started again
+func (s *RelayService) updateLocalRelay(ctx context.Context, relay *participantRelay) (map[string]int, error) {
+	s.relayLock.Lock()
+	local := s.localRelays[relay.RelayId]
+	s.relayLock.Unlock()
+	if local == nil {
+		return nil, ErrRelayNotFound
+	}
+
+	local.lock.Lock()
+	defer local.lock.Unlock()
+	ports, err := s.applyRelayUpdate(ctx, local, relay)
+	if err != nil {
+		local.ingress.Close()
+		return nil, err
+	}
+	local.relay = relay
+	return ports, nil
+}
+
+func (s *RelayService) applyRelayUpdate(ctx context.Context, local *localRelay, relay *participantRelay) (map[string]int, error) {
+	if relay.Metadata != local.relay.Metadata {
+		if room := s.roomManager.GetRoom(ctx, relay.Room); room != nil {
+			if p := room.GetParticipant(relay.Identity); p != nil {
+				p.SetMetadata(relay.Metadata)
+			}
+		}
+	}
+
+	current := make(map[string]bool, len(local.relay.Tracks))
+	for _, track := range local.relay.Tracks {
+		current[track.Sid] = true
+	}
+	relayed := make(map[string]bool, len(relay.Tracks))
+	var added []*relayedTrack
+	for _, track := range relay.Tracks {
+		relayed[track.Sid] = true
+		if !current[track.Sid] {
+			added = append(added, track)
+		}
+	}
+	changed := len(added) > 0
+	for _, track := range local.relay.Tracks {
+		if relayed[track.Sid] {
+			continue
+		}
+		if err := local.ingress.RemoveTrack(track.Sid); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	cids := make(map[string]bool, len(added))
+	for _, track := range added {
+		if _, err := local.ingress.AddTrack(track.ingressTrack()); err != nil {
+			return nil, err
+		}
+		cids[track.Sid] = true
+	}
+	offer, err := local.ingress.CreateOffer()
+	if err != nil {
+		return nil, err
+	}
+	requests, err := ingressAddTrackRequests(local.ingress, offer, cids)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := local.session.renegotiate(offer, requests...)
+	if err != nil {
+		return nil, err
+	}
+	if err = local.ingress.SetAnswer(answer); err != nil {
+		return nil, err
+	}
+	return relayPorts(local.ingress, added), nil
+}
+
+// relayWatchdog closes the relay once the participant leaves its node, in case that node can't end it
+func (s *RelayService) relayWatchdog(relay *participantRelay, ingress *rtc.RTPIngress, done <-chan struct{}) {
+	ticker := time.NewTicker(relayCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			p, err := s.roomManager.LoadParticipant(context.Background(), relay.Room, relay.Identity)
+			if err == ErrParticipantNotFound || (err == nil && p.Sid != relay.ParticipantSid) {
+				ingress.Close()
+				return
+			}
+		}
+	}
+}
+
+// relayedTracks describes the tracks that can be relayed, those that haven't received media yet are left out
+func relayedTracks(tracks []types.PublishedTrack) []*relayedTrack {
+	var relayed []*relayedTrack
+	for _, track := range tracks {
+		codec := track.Codec()
+		if codec.MimeType == "" {
+			continue
+		}
+		relayed = append(relayed, &relayedTrack{
+			Sid:         track.ID(),
+			Name:        track.Name(),
+			Video:       track.Kind() == livekit.TrackType_VIDEO,
+			MimeType:    codec.MimeType,
+			ClockRate:   codec.ClockRate,
+			Channels:    codec.Channels,
+			FmtpLine:    codec.SDPFmtpLine,
+			PayloadType: uint8(codec.PayloadType),
+		})
+	}
+	return relayed
+}
+
+// ingressTrack is the track as it's received, on a port of its own
+func (t *relayedTrack) ingressTrack() rtc.RTPIngressTrack {
+	kind := webrtc.RTPCodecTypeAudio
+	if t.Video {
+		kind = webrtc.RTPCodecTypeVideo
+	}
+	return rtc.RTPIngressTrack{
+		ID:   t.Sid,
+		Name: t.Name,
+		Kind: kind,
+		Codec: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:    t.MimeType,
+				ClockRate:   t.ClockRate,
+				Channels:    t.Channels,
+				SDPFmtpLine: t.FmtpLine,
+			},
+			PayloadType: webrtc.PayloadType(t.PayloadType),
+		},
+	}
+}
+
+func relayPorts(ingress *rtc.RTPIngress, tracks []*relayedTrack) map[string]int {
+	ports := make(map[string]int, len(tracks))
+	for _, track := range tracks {
+		ports[track.Sid] = ingress.TrackPort(track.Sid)
+	}
+	return ports
+}
+
+// sameRelayedTracks is true when both describe the same tracks
+func sameRelayedTracks(a, b []*relayedTrack) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sids := make(map[string]bool, len(a))
+	for _, t := range a {
+		sids[t.Sid] = true
+	}
+	for _, t := range b {
+		if !sids[t.Sid] {
+			return false
+		}
+	}
+	return true
+}
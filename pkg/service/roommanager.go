@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,6 +23,19 @@ import (
 
 const (
 	roomPurgeSeconds = 24 * 60 * 60
+
+	// data history is stored at most this often, and at most this long after a packet
+	dataHistoryStoreInterval = time.Second
+
+	// session workers pick up moves between signal requests, unless the session has ended. below
+	// nodeRequestTimeout, so a move forwarded from another node fails with its own error rather than a timeout
+	participantMoveTimeout = 3 * time.Second
+
+	// rooms of a draining node are closed as they empty
+	drainCheckInterval = 5 * time.Second
+
+	// webhook for changes to room metadata, which isn't part of the protocol yet
+	EventRoomMetadataUpdated = "room_metadata_updated"
 )
 
 // LocalRoomManager manages rooms and its interaction with participants.
@@ -36,6 +52,18 @@ type LocalRoomManager struct {
 	config      *config.Config
 	webhookPool *workerpool.WorkerPool
 	rooms       map[string]*rtc.Room
+	// moves for each session worker, by participant SID
+	sessionMoves map[string]chan *participantMove
+
+	isDraining utils.AtomicFlag
+	drainOnce  sync.Once
+	drained    chan struct{}
+}
+
+// participantMove is carried out by the participant's session worker
+type participantMove struct {
+	to   *rtc.Room
+	done chan error
 }
 
 func NewLocalRoomManager(rp RoomStore, router routing.Router, currentNode routing.LocalNode, selector routing.NodeSelector,
@@ -46,16 +74,18 @@ func NewLocalRoomManager(rp RoomStore, router routing.Router, currentNode routin
 	}
 
 	r := &LocalRoomManager{
-		RoomStore:   rp,
-		lock:        sync.RWMutex{},
-		rtcConfig:   rtcConf,
-		config:      conf,
-		router:      router,
-		selector:    selector,
-		notifier:    notifier,
-		currentNode: currentNode,
-		webhookPool: workerpool.New(1),
-		rooms:       make(map[string]*rtc.Room),
+		RoomStore:    rp,
+		lock:         sync.RWMutex{},
+		rtcConfig:    rtcConf,
+		config:       conf,
+		router:       router,
+		selector:     selector,
+		notifier:     notifier,
+		currentNode:  currentNode,
+		webhookPool:  workerpool.New(1),
+		rooms:        make(map[string]*rtc.Room),
+		sessionMoves: make(map[string]chan *participantMove),
+		drained:      make(chan struct{}),
 	}
 
 	// hook up to router
@@ -119,7 +149,7 @@ func (r *LocalRoomManager) CreateRoom(ctx context.Context, req *livekit.CreateRo
 			return nil, err
 		}
 
-		node, err := r.selector.SelectNode(nodes, rm)
+		node, err := r.selectNode(ctx, nodes, rm)
 		if err != nil {
 			return nil, err
 		}
@@ -134,12 +164,248 @@ func (r *LocalRoomManager) CreateRoom(ctx context.Context, req *livekit.CreateRo
 	return rm, nil
 }
 
+// GetNodeForParticipant picks the node a participant joining the room connects to. that's the node hosting the
+// room, unless it has MaxParticipantsPerNode participants of the room, in which case the room is cascaded to
+// another node. nodes the room is already on are preferred, so tracks are relayed to as few as possible
+func (r *LocalRoomManager) GetNodeForParticipant(ctx context.Context, roomName string) (string, error) {
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	limit := r.config.Room.MaxParticipantsPerNode
+	if limit == 0 {
+		return node.Id, nil
+	}
+
+	roomNodes, err := r.router.GetRoomNodes(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	if roomNodes[node.Id] < limit {
+		return node.Id, nil
+	}
+
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		return "", err
+	}
+	var candidates []*livekit.Node
+	for _, n := range nodes {
+		if n.Id == node.Id || !routing.IsAvailable(n) || !routing.IsWithinLimits(n, r.config.Limit) {
+			continue
+		}
+		if count, ok := roomNodes[n.Id]; !ok {
+			candidates = append(candidates, n)
+		} else if count < limit {
+			return n.Id, nil
+		}
+	}
+	if len(candidates) == 0 {
+		// every node has its share, the room's node takes more
+		return node.Id, nil
+	}
+
+	rm, err := r.LoadRoom(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+	selected, err := r.selectNode(ctx, candidates, rm)
+	if err != nil {
+		return "", err
+	}
+	logger.Debugw("cascading room to node", "room", roomName, "nodeID", selected.Id)
+	if err = r.router.AddRoomNode(ctx, roomName, selected.Id); err != nil {
+		return "", err
+	}
+	return selected.Id, nil
+}
+
+// selectNode picks a node for the room, in the region the request asks for when the selector is region-aware.
+// nodes at their limits are never picked
+func (r *LocalRoomManager) selectNode(ctx context.Context, nodes []*livekit.Node, rm *livekit.Room) (*livekit.Node, error) {
+	nodes, err := routing.GetNodesWithinLimits(nodes, r.config.Limit)
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := r.selector.(routing.RegionSelector)
+	if !ok {
+		return r.selector.SelectNode(nodes, rm)
+	}
+	nodeRegions, err := r.router.GetNodeRegions()
+	if err != nil {
+		return nil, err
+	}
+	return rs.SelectNodeInRegion(nodes, nodeRegions, rm, GetRegion(ctx))
+}
+
 func (r *LocalRoomManager) GetRoom(ctx context.Context, roomName string) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	return r.rooms[roomName]
 }
 
+// GetRooms returns the rooms running on this node, including those cascaded to it
+func (r *LocalRoomManager) GetRooms(ctx context.Context) []*rtc.Room {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	rooms := make([]*rtc.Room, 0, len(r.rooms))
+	for _, rm := range r.rooms {
+		rooms = append(rooms, rm)
+	}
+	return rooms
+}
+
+// StartTrackRecording records a track of a room on this node to a file, returning its path
+func (r *LocalRoomManager) StartTrackRecording(ctx context.Context, roomName, identity, trackID string) (string, error) {
+	dir := r.config.TrackRecording.Directory
+	if dir == "" {
+		return "", ErrTrackRecordingOff
+	}
+	track, err := r.GetPublishedTrack(ctx, roomName, identity, trackID)
+	if err != nil {
+		return "", err
+	}
+
+	basePath := filepath.Join(dir, safeFileName(roomName),
+		fmt.Sprintf("%s_%s_%d", safeFileName(identity), safeFileName(trackID), time.Now().Unix()))
+	return track.StartRecording(basePath)
+}
+
+func (r *LocalRoomManager) StopTrackRecording(ctx context.Context, roomName, identity, trackID string) error {
+	track, err := r.GetPublishedTrack(ctx, roomName, identity, trackID)
+	if err != nil {
+		return err
+	}
+	return track.StopRecording()
+}
+
+// StartRTPEgress forwards a track of a room on this node as plain RTP to host:port, returning an SDP for receivers
+func (r *LocalRoomManager) StartRTPEgress(ctx context.Context, roomName, identity, trackID, egressID, host string, port int, quality livekit.VideoQuality) (string, error) {
+	track, err := r.GetPublishedTrack(ctx, roomName, identity, trackID)
+	if err != nil {
+		return "", err
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return "", err
+	}
+	return track.StartRTPEgress(egressID, addr, quality)
+}
+
+func (r *LocalRoomManager) StopRTPEgress(ctx context.Context, roomName, identity, trackID, egressID string) error {
+	track, err := r.GetPublishedTrack(ctx, roomName, identity, trackID)
+	if err != nil {
+		return err
+	}
+	return track.StopRTPEgress(egressID)
+}
+
+// UpdateRoomMetadata sends metadata to participants of a room on this node. rooms that aren't running
+// yet load it when they start
+func (r *LocalRoomManager) UpdateRoomMetadata(ctx context.Context, roomName, metadata string) error {
+	var info *livekit.Room
+	if room := r.GetRoom(ctx, roomName); room != nil {
+		room.SetMetadata(metadata)
+		info = room.Room
+	} else {
+		var err error
+		if info, err = r.LoadRoom(ctx, roomName); err != nil {
+			return err
+		}
+	}
+
+	r.notifyEvent(&livekit.WebhookEvent{
+		Event: EventRoomMetadataUpdated,
+		Room:  info,
+	})
+	return nil
+}
+
+// MoveParticipant moves a participant to another room on this node, without it having to reconnect. the
+// room it's moved to is started if it isn't running yet
+func (r *LocalRoomManager) MoveParticipant(ctx context.Context, roomName, identity, toRoomName string) error {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil {
+		return ErrParticipantNotFound
+	}
+
+	node, err := r.router.GetNodeForRoom(ctx, toRoomName)
+	if err == routing.ErrNotFound {
+		return ErrRoomNotFound
+	} else if err != nil {
+		return err
+	}
+	if node.Id != r.currentNode.Id {
+		return ErrRoomOnAnotherNode
+	}
+	to, err := r.getOrCreateRoom(ctx, toRoomName)
+	if err != nil {
+		return err
+	}
+
+	r.lock.RLock()
+	moves := r.sessionMoves[participant.ID()]
+	r.lock.RUnlock()
+	if moves == nil {
+		return ErrParticipantNotFound
+	}
+	move := &participantMove{
+		to:   to,
+		done: make(chan error, 1),
+	}
+	select {
+	case moves <- move:
+	case <-time.After(participantMoveTimeout):
+		return ErrParticipantNotFound
+	}
+	if err = <-move.done; err != nil {
+		return err
+	}
+
+	if err = r.DeleteParticipant(ctx, roomName, identity); err != nil {
+		logger.Warnw("could not delete moved participant", err,
+			"room", roomName, "participant", identity)
+	}
+	r.updateRoomNodeParticipants(ctx, room)
+	r.updateRoomNodeParticipants(ctx, to)
+	// so Room Service requests for the new room reach it
+	return r.router.SetParticipantRTCNode(toRoomName, identity, r.currentNode.Id)
+}
+
+func (r *LocalRoomManager) GetPublishedTrack(ctx context.Context, roomName, identity, trackID string) (types.PublishedTrack, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+	for _, track := range participant.GetPublishedTracks() {
+		if track.ID() == trackID {
+			return track, nil
+		}
+	}
+	return nil, ErrTrackNotFound
+}
+
+// GetParticipantStats computes statistics of the participant's tracks, as they are now
+func (r *LocalRoomManager) GetParticipantStats(ctx context.Context, roomName, identity string) (*types.ParticipantStats, error) {
+	room := r.GetRoom(ctx, roomName)
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(identity)
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+	return participant.GetStats(), nil
+}
+
 // DeleteRoom completely deletes all room information, including active sessions, room store, and routing info
 func (r *LocalRoomManager) DeleteRoom(ctx context.Context, roomName string) error {
 	logger.Infow("deleting room state", "room", roomName)
@@ -190,28 +456,143 @@ func (r *LocalRoomManager) CleanupRooms() error {
 }
 
 func (r *LocalRoomManager) CloseIdleRooms() {
-	r.lock.RLock()
-	rooms := make([]*rtc.Room, 0, len(r.rooms))
-	for _, rm := range r.rooms {
-		rooms = append(rooms, rm)
+	ctx := context.Background()
+	for _, room := range r.GetRooms(ctx) {
+		if r.config.Room.MaxParticipantsPerNode > 0 && r.keepCascadedRoom(ctx, room) {
+			continue
+		}
+		room.CloseIfEmpty()
 	}
-	r.lock.RUnlock()
+}
 
-	for _, room := range rooms {
-		room.CloseIfEmpty()
+// keepCascadedRoom keeps a room open while participants are on its other nodes, the node hosting it holds
+// the room's state. rooms that were deleted through another node are closed
+func (r *LocalRoomManager) keepCascadedRoom(ctx context.Context, room *rtc.Room) bool {
+	if _, err := r.LoadRoom(ctx, room.Room.Name); err == ErrRoomNotFound {
+		for _, p := range room.GetParticipants() {
+			_ = p.Close()
+		}
+		room.Close()
+		return true
+	}
+	if !r.hostsRoom(ctx, room.Room.Name) {
+		return false
+	}
+
+	roomNodes, err := r.router.GetRoomNodes(ctx, room.Room.Name)
+	if err != nil {
+		logger.Warnw("could not get nodes for room", err, "room", room.Room.Name)
+		return true
+	}
+	for nodeId, count := range roomNodes {
+		if nodeId != r.currentNode.Id && count > 0 {
+			return true
+		}
 	}
+	return false
 }
 
-func (r *LocalRoomManager) Stop() {
-	// disconnect all clients
-	r.lock.RLock()
-	rooms := make([]*rtc.Room, 0, len(r.rooms))
-	for _, rm := range r.rooms {
-		rooms = append(rooms, rm)
+// hostsRoom is false when the room is cascaded to this node, from the node hosting it
+func (r *LocalRoomManager) hostsRoom(ctx context.Context, roomName string) bool {
+	node, err := r.router.GetNodeForRoom(ctx, roomName)
+	return err != nil || node.Id == r.currentNode.Id
+}
+
+// updateRoomNodeParticipants records how many participants of the room are on this node, for cascading
+func (r *LocalRoomManager) updateRoomNodeParticipants(ctx context.Context, room *rtc.Room) {
+	if r.config.Room.MaxParticipantsPerNode == 0 {
+		return
 	}
-	r.lock.RUnlock()
+	count := uint32(room.NumLocalParticipants())
+	if err := r.router.SetRoomNodeParticipants(ctx, room.Room.Name, r.currentNode.Id, count); err != nil {
+		logger.Warnw("could not update participants on node", err, "room", room.Room.Name)
+	}
+}
 
-	for _, room := range rooms {
+// Drain stops rooms from being placed on this node, and waits for the rooms on it to empty. rooms that still
+// have participants once the drain timeout passes are handed over to other nodes, with their participants
+// asked to reconnect. the node is drained once, later calls wait for it
+func (r *LocalRoomManager) Drain() {
+	r.drainOnce.Do(func() {
+		go r.drain()
+	})
+	<-r.drained
+}
+
+func (r *LocalRoomManager) IsDraining() bool {
+	return r.isDraining.Get()
+}
+
+func (r *LocalRoomManager) drain() {
+	defer close(r.drained)
+	r.isDraining.TrySet(true)
+	if err := r.router.DrainNode(); err != nil {
+		logger.Errorw("could not drain node", err, "nodeID", r.currentNode.Id)
+	}
+	logger.Infow("draining node", "nodeID", r.currentNode.Id, "timeout", r.config.DrainTimeout)
+
+	ctx := context.Background()
+	deadline := time.After(r.config.DrainTimeout)
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for len(r.GetRooms(ctx)) > 0 {
+		select {
+		case <-deadline:
+			for _, room := range r.GetRooms(ctx) {
+				r.handOverRoom(ctx, room)
+			}
+			logger.Infow("node drained, remaining rooms handed over", "nodeID", r.currentNode.Id)
+			return
+		case <-ticker.C:
+			r.CloseIdleRooms()
+		}
+	}
+	logger.Infow("node drained", "nodeID", r.currentNode.Id)
+}
+
+// handOverRoom moves a room to another node, keeping its state, and asks its participants on this node to
+// reconnect there. cascaded rooms continue on their other nodes
+func (r *LocalRoomManager) handOverRoom(ctx context.Context, room *rtc.Room) {
+	roomName := room.Room.Name
+	// removed first, so the room isn't deleted once it's closed
+	r.lock.Lock()
+	if r.rooms[roomName] == room {
+		delete(r.rooms, roomName)
+	}
+	r.lock.Unlock()
+
+	if r.hostsRoom(ctx, roomName) {
+		nodes, err := r.router.ListNodes()
+		var node *livekit.Node
+		if err == nil {
+			node, err = r.selectNode(ctx, nodes, room.Room)
+		}
+		if err == nil {
+			err = r.router.SetNodeForRoom(ctx, roomName, node.Id)
+		} else {
+			// a node is selected when participants reconnect
+			err = r.router.ClearRoomState(ctx, roomName)
+		}
+		if err != nil {
+			logger.Warnw("could not hand over room", err, "room", roomName)
+		}
+	}
+
+	for _, p := range room.GetParticipants() {
+		_ = p.CloseWithReconnect()
+	}
+	if err := r.router.RemoveRoomNode(ctx, roomName, r.currentNode.Id); err != nil {
+		logger.Warnw("could not remove node from room", err, "room", roomName)
+	}
+	room.Close()
+	logger.Infow("room handed over",
+		"room", roomName,
+		"nodeID", r.currentNode.Id)
+}
+
+func (r *LocalRoomManager) Stop() {
+	// disconnect all clients
+	for _, room := range r.GetRooms(context.Background()) {
 		for _, p := range room.GetParticipants() {
 			_ = p.Close()
 		}
@@ -237,7 +618,14 @@ func (r *LocalRoomManager) StartSession(ctx context.Context, roomName string, pi
 	}
 
 	participant := room.GetParticipant(pi.Identity)
-	if participant != nil {
+	if participant != nil && pi.Relayed {
+		// the participant has joined this node as well
+		logger.Debugw("not relaying participant on this node",
+			"room", roomName,
+			"participant", pi.Identity,
+		)
+		return
+	} else if participant != nil {
 		// When reconnecting, it means WS has interrupted by underlying peer connection is still ok
 		// in this mode, we'll keep the participant SID, and just swap the sink for the underlying connection
 		if pi.Reconnect {
@@ -302,6 +690,7 @@ func (r *LocalRoomManager) StartSession(ctx context.Context, roomName string, pi
 		ThrottleConfig:  r.config.RTC.PLIThrottle,
 		EnabledCodecs:   room.Room.EnabledCodecs,
 		Hidden:          pi.Hidden,
+		Relayed:         pi.Relayed,
 	})
 	if err != nil {
 		logger.Errorw("could not create participant", err)
@@ -345,7 +734,30 @@ func (r *LocalRoomManager) getOrCreateRoom(ctx context.Context, roomName string)
 
 	// construct ice servers
 	room = rtc.NewRoom(ri, *r.rtcConfig, r.iceServersForRoom(ri), &r.config.Audio)
+	opts := defaultRoomOptions(&r.config.Room)
+	if stored, err := r.LoadRoomOptions(ctx, roomName); err == nil {
+		opts = *stored
+	} else if err != ErrRoomNotFound {
+		logger.Warnw("could not load room options", err, "room", roomName)
+	}
+	room.SetOptions(opts)
+	room.SetDataLimits(r.config.Room.DataLimits)
+	if metadata, err := r.LoadRoomMetadata(ctx, roomName); err == nil {
+		room.SetMetadata(metadata)
+	} else {
+		logger.Warnw("could not load room metadata", err, "room", roomName)
+	}
+	// or the room is cascaded to this node
+	hosted := r.hostsRoom(ctx, roomName)
 	room.OnClose(func() {
+		if !hosted {
+			r.closeCascadedRoom(ctx, room)
+			return
+		}
+		if r.GetRoom(ctx, roomName) != room {
+			// handed over by a draining node, the room continues on another
+			return
+		}
 		if err := r.DeleteRoom(ctx, roomName); err != nil {
 			logger.Errorw("could not delete room", err)
 		}
@@ -362,6 +774,14 @@ func (r *LocalRoomManager) getOrCreateRoom(ctx context.Context, roomName string)
 		)
 	})
 	room.OnParticipantChanged(func(p types.Participant) {
+		if p.Relayed() {
+			// stored by the node it's connected to
+			return
+		}
+		if state := p.State(); state == livekit.ParticipantInfo_JOINING || state == livekit.ParticipantInfo_DISCONNECTED {
+			r.updateRoomNodeParticipants(ctx, room)
+		}
+
 		var err error
 		if p.State() == livekit.ParticipantInfo_DISCONNECTED {
 			err = r.DeleteParticipant(ctx, roomName, p.Identity())
@@ -372,21 +792,74 @@ func (r *LocalRoomManager) getOrCreateRoom(ctx context.Context, roomName string)
 			logger.Errorw("could not handle participant change", err)
 		}
 	})
+	room.OnConnectionQualityChanged(func(p types.Participant, quality types.ConnectionQuality) {
+		if p.Relayed() {
+			// stored by the node it's connected to
+			return
+		}
+		if err := r.StoreConnectionQuality(ctx, roomName, p.Identity(), quality); err != nil {
+			logger.Errorw("could not store connection quality", err)
+		}
+	})
+	// stored for admins to fetch
+	room.OnDataHistoryUpdate(throttle(dataHistoryStoreInterval, func() {
+		if r.GetRoom(ctx, roomName) != room {
+			// the room was deleted or closed since the packet, storing would bring its history back
+			return
+		}
+		if err := r.StoreDataHistory(ctx, roomName, room.DataHistory()); err != nil {
+			logger.Errorw("could not store data history", err)
+		}
+	}))
 	r.lock.Lock()
 	r.rooms[roomName] = room
 	r.lock.Unlock()
 
-	r.notifyEvent(&livekit.WebhookEvent{
-		Event: webhook.EventRoomStarted,
-		Room:  room.Room,
-	})
+	if hosted {
+		r.notifyEvent(&livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  room.Room,
+		})
+	}
 
 	return room, nil
 }
 
+// closeCascadedRoom removes the room from this node, once its participants here have left. the room
+// continues on its other nodes, and relays of their participants are closed
+func (r *LocalRoomManager) closeCascadedRoom(ctx context.Context, room *rtc.Room) {
+	r.lock.Lock()
+	if r.rooms[room.Room.Name] == room {
+		delete(r.rooms, room.Room.Name)
+	}
+	r.lock.Unlock()
+
+	if err := r.router.RemoveRoomNode(ctx, room.Room.Name, r.currentNode.Id); err != nil {
+		logger.Warnw("could not remove node from room", err, "room", room.Room.Name)
+	}
+	for _, p := range room.GetParticipants() {
+		_ = p.Close()
+	}
+	logger.Infow("cascaded room closed on node",
+		"room", room.Room.Name,
+		"nodeID", r.currentNode.Id,
+		"incomingStats", room.GetIncomingStats().Copy(),
+		"outgoingStats", room.GetOutgoingStats().Copy(),
+	)
+}
+
 // manages an RTC session for a participant, runs on the RTC node
 func (r *LocalRoomManager) rtcSessionWorker(room *rtc.Room, participant types.Participant, requestSource routing.MessageSource) {
+	moves := make(chan *participantMove)
+	r.lock.Lock()
+	r.sessionMoves[participant.ID()] = moves
+	r.lock.Unlock()
+
 	defer func() {
+		r.lock.Lock()
+		delete(r.sessionMoves, participant.ID())
+		r.lock.Unlock()
+
 		logger.Debugw("RTC session finishing",
 			"participant", participant.Identity(),
 			"pID", participant.ID(),
@@ -395,19 +868,24 @@ func (r *LocalRoomManager) rtcSessionWorker(room *rtc.Room, participant types.Pa
 		)
 		_ = participant.Close()
 
+		if !participant.Relayed() {
+			r.notifyEvent(&livekit.WebhookEvent{
+				Event:       webhook.EventParticipantLeft,
+				Room:        room.Room,
+				Participant: participant.ToProto(),
+			})
+		}
+	}()
+	defer rtc.Recover()
+
+	// relays are reported by the node their participant is on
+	if !participant.Relayed() {
 		r.notifyEvent(&livekit.WebhookEvent{
-			Event:       webhook.EventParticipantLeft,
+			Event:       webhook.EventParticipantJoined,
 			Room:        room.Room,
 			Participant: participant.ToProto(),
 		})
-	}()
-	defer rtc.Recover()
-
-	r.notifyEvent(&livekit.WebhookEvent{
-		Event:       webhook.EventParticipantJoined,
-		Room:        room.Room,
-		Participant: participant.ToProto(),
-	})
+	}
 	for {
 		select {
 		case <-time.After(time.Millisecond * 50):
@@ -415,6 +893,34 @@ func (r *LocalRoomManager) rtcSessionWorker(room *rtc.Room, participant types.Pa
 			if participant.State() == livekit.ParticipantInfo_DISCONNECTED {
 				return
 			}
+		case move := <-moves:
+			err := room.MoveParticipant(participant.Identity(), move.to)
+			move.done <- err
+			if err != nil {
+				logger.Warnw("could not move participant", err,
+					"participant", participant.Identity(),
+					"pID", participant.ID(),
+					"room", room.Room.Name,
+					"toRoom", move.to.Room.Name)
+				break
+			}
+			logger.Infow("moved participant",
+				"participant", participant.Identity(),
+				"pID", participant.ID(),
+				"room", room.Room.Name,
+				"toRoom", move.to.Room.Name)
+
+			r.notifyEvent(&livekit.WebhookEvent{
+				Event:       webhook.EventParticipantLeft,
+				Room:        room.Room,
+				Participant: participant.ToProto(),
+			})
+			room = move.to
+			r.notifyEvent(&livekit.WebhookEvent{
+				Event:       webhook.EventParticipantJoined,
+				Room:        room.Room,
+				Participant: participant.ToProto(),
+			})
 		case obj := <-requestSource.ReadChan():
 			if obj == nil {
 				return
@@ -517,6 +1023,12 @@ func (r *LocalRoomManager) handleRTCMessage(ctx context.Context, roomName, ident
 			participant.SetPermission(rm.UpdateParticipant.Permission)
 		}
 	case *livekit.RTCNodeMessage_DeleteRoom:
+		if !r.hostsRoom(ctx, roomName) {
+			// its other nodes close the room once it's deleted
+			if err := r.DeleteRoom(ctx, roomName); err != nil {
+				logger.Errorw("could not delete room", err, "room", roomName)
+			}
+		}
 		for _, p := range room.GetParticipants() {
 			_ = p.Close()
 		}
@@ -597,6 +1109,15 @@ func applyDefaultRoomConfig(room *livekit.Room, conf *config.RoomConfig) {
 	}
 }
 
+// defaultRoomOptions are used by rooms that weren't created with options of their own
+func defaultRoomOptions(conf *config.RoomConfig) rtc.RoomOptions {
+	return rtc.RoomOptions{
+		LastN:              conf.LastN,
+		DataHistorySize:    conf.DataHistorySize,
+		DataHistorySeconds: conf.DataHistorySeconds,
+	}
+}
+
 func iceServerForStunServers(servers []string) *livekit.ICEServer {
 	iceServer := &livekit.ICEServer{}
 	for _, stunServer := range servers {
@@ -2,7 +2,9 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/stretchr/testify/require"
@@ -15,7 +17,7 @@ import (
 )
 
 func TestCreateRoom(t *testing.T) {
-	manager, conf := newTestRoomManager(t)
+	manager, conf, _ := newTestRoomManager(t)
 
 	t.Run("ensure default room settings are applied", func(t *testing.T) {
 		room, err := manager.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "myroom"})
@@ -25,7 +27,169 @@ func TestCreateRoom(t *testing.T) {
 	})
 }
 
-func newTestRoomManager(t *testing.T) (*service.LocalRoomManager, *config.Config) {
+func TestDrain(t *testing.T) {
+	manager, _, router := newTestRoomManager(t)
+	require.False(t, manager.IsDraining())
+
+	t.Run("node without rooms is drained right away", func(t *testing.T) {
+		manager.Drain()
+		require.True(t, manager.IsDraining())
+		require.Equal(t, 1, router.DrainNodeCallCount())
+	})
+
+	t.Run("node is only drained once", func(t *testing.T) {
+		manager.Drain()
+		require.Equal(t, 1, router.DrainNodeCallCount())
+	})
+}
+
+func TestReapDeadNodes(t *testing.T) {
+	live := &livekit.Room{Name: "live"}
+	orphaned := &livekit.Room{Name: "orphaned", Sid: "RM_orphaned"}
+	dead := &livekit.Node{Id: "ND_dead"}
+
+	// the dead node hosted one room, and another room hosted on the current node was cascaded to it
+	newReaper := func(t *testing.T) (*service.LocalRoomManager, *servicefakes.FakeRoomStore, *routingfakes.FakeRouter) {
+		conf, err := config.NewConfig("", nil)
+		require.NoError(t, err)
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		store := &servicefakes.FakeRoomStore{}
+		store.LoadRoomReturns(orphaned, nil)
+		store.ListParticipantsReturns([]*livekit.ParticipantInfo{{Identity: "alice"}}, nil)
+		router := &routingfakes.FakeRouter{}
+		router.AcquireLeaseReturns(true, nil)
+		router.ListDeadNodesReturns([]*livekit.Node{dead}, nil)
+		router.GetNodeRoomsReturns([]string{live.Name, orphaned.Name}, nil)
+		router.GetNodeForRoomStub = func(ctx context.Context, roomName string) (*livekit.Node, error) {
+			if roomName == live.Name {
+				return node, nil
+			}
+			return dead, nil
+		}
+		manager, err := service.NewLocalRoomManager(store, router, node, &routing.RandomSelector{}, nil, conf)
+		require.NoError(t, err)
+		t.Cleanup(manager.Stop)
+		return manager, store, router
+	}
+
+	t.Run("only the lease holder reaps", func(t *testing.T) {
+		manager, store, router := newReaper(t)
+		router.AcquireLeaseReturns(false, nil)
+		require.NoError(t, manager.ReapDeadNodes())
+		require.Equal(t, 0, router.ListDeadNodesCallCount())
+		require.Equal(t, 0, router.RemoveNodeCallCount())
+		require.Equal(t, 0, store.DeleteRoomCallCount())
+	})
+
+	t.Run("only rooms of dead nodes are checked", func(t *testing.T) {
+		manager, store, router := newReaper(t)
+		require.NoError(t, manager.ReapDeadNodes())
+		require.Equal(t, 0, store.ListRoomsCallCount())
+		require.Equal(t, 1, router.GetNodeRoomsCallCount())
+		_, nodeId := router.GetNodeRoomsArgsForCall(0)
+		require.Equal(t, dead.Id, nodeId)
+	})
+
+	t.Run("deletes rooms of dead nodes", func(t *testing.T) {
+		manager, store, router := newReaper(t)
+		require.NoError(t, manager.ReapDeadNodes())
+
+		require.Equal(t, 1, store.DeleteRoomCallCount())
+		_, roomName := store.DeleteRoomArgsForCall(0)
+		require.Equal(t, orphaned.Name, roomName)
+		require.Equal(t, 1, router.ClearRoomStateCallCount())
+		require.Equal(t, 1, router.ClearParticipantStateCallCount())
+		_, roomName, identity := router.ClearParticipantStateArgsForCall(0)
+		require.Equal(t, orphaned.Name, roomName)
+		require.Equal(t, "alice", identity)
+	})
+
+	t.Run("removes dead nodes rooms are cascaded to", func(t *testing.T) {
+		manager, _, router := newReaper(t)
+		require.NoError(t, manager.ReapDeadNodes())
+
+		require.Equal(t, 1, router.RemoveRoomNodeCallCount())
+		_, roomName, nodeId := router.RemoveRoomNodeArgsForCall(0)
+		require.Equal(t, live.Name, roomName)
+		require.Equal(t, dead.Id, nodeId)
+	})
+
+	t.Run("removes the node once its rooms are reaped", func(t *testing.T) {
+		manager, _, router := newReaper(t)
+		require.NoError(t, manager.ReapDeadNodes())
+		require.Equal(t, 1, router.RemoveNodeCallCount())
+		require.Equal(t, dead.Id, router.RemoveNodeArgsForCall(0))
+	})
+
+	t.Run("keeps the node when a room couldn't be reaped", func(t *testing.T) {
+		manager, store, router := newReaper(t)
+		store.ListParticipantsReturns(nil, errors.New("unavailable"))
+		require.NoError(t, manager.ReapDeadNodes())
+		require.Equal(t, 0, router.RemoveNodeCallCount())
+	})
+}
+
+func TestFailoverRoom(t *testing.T) {
+	conf, err := config.NewConfig("", nil)
+	require.NoError(t, err)
+	node, err := routing.NewLocalNode(conf)
+	require.NoError(t, err)
+	store := &servicefakes.FakeRoomStore{}
+	store.LoadRoomReturns(&livekit.Room{Name: "myroom"}, nil)
+	store.ListParticipantsReturns([]*livekit.ParticipantInfo{{Identity: "on_dead"}, {Identity: "on_live"}}, nil)
+	router := &routingfakes.FakeRouter{}
+	manager, err := service.NewLocalRoomManager(store, router, node, &routing.RandomSelector{}, nil, conf)
+	require.NoError(t, err)
+	t.Cleanup(manager.Stop)
+
+	dead := &livekit.Node{Id: "ND_dead", Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix() - 10}}
+	live := &livekit.Node{Id: "ND_live", Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix()}}
+	router.ListNodesReturns([]*livekit.Node{dead, live}, nil)
+	router.GetParticipantRTCNodeStub = func(roomName, identity string) (string, error) {
+		if identity == "on_dead" {
+			return dead.Id, nil
+		}
+		return live.Id, nil
+	}
+
+	t.Run("moves the room to a live node", func(t *testing.T) {
+		router.GetNodeForRoomReturns(dead, nil)
+		nodeId, err := manager.FailoverRoom(context.Background(), "myroom", dead.Id)
+		require.NoError(t, err)
+		require.Equal(t, live.Id, nodeId)
+
+		require.Equal(t, 1, router.SetNodeForRoomCallCount())
+		_, roomName, nodeId := router.SetNodeForRoomArgsForCall(0)
+		require.Equal(t, "myroom", roomName)
+		require.Equal(t, live.Id, nodeId)
+		_, _, removed := router.RemoveRoomNodeArgsForCall(0)
+		require.Equal(t, dead.Id, removed)
+
+		// resumed sessions aren't routed to the dead node
+		require.Equal(t, 1, router.ClearParticipantStateCallCount())
+		_, _, identity := router.ClearParticipantStateArgsForCall(0)
+		require.Equal(t, "on_dead", identity)
+	})
+
+	t.Run("keeps a room that's been failed over", func(t *testing.T) {
+		router.GetNodeForRoomReturns(live, nil)
+		nodeId, err := manager.FailoverRoom(context.Background(), "myroom", dead.Id)
+		require.NoError(t, err)
+		require.Equal(t, live.Id, nodeId)
+		require.Equal(t, 1, router.SetNodeForRoomCallCount())
+	})
+
+	t.Run("errors without live nodes", func(t *testing.T) {
+		router.GetNodeForRoomReturns(dead, nil)
+		router.ListNodesReturns([]*livekit.Node{dead}, nil)
+		_, err := manager.FailoverRoom(context.Background(), "myroom", dead.Id)
+		require.Equal(t, routing.ErrNoAvailableNodes, err)
+	})
+}
+
+func newTestRoomManager(t *testing.T) (*service.LocalRoomManager, *config.Config, *routingfakes.FakeRouter) {
 	store := &servicefakes.FakeRoomStore{}
 	store.LoadRoomReturns(nil, service.ErrRoomNotFound)
 	router := &routingfakes.FakeRouter{}
@@ -39,6 +203,8 @@ func newTestRoomManager(t *testing.T) (*service.LocalRoomManager, *config.Config
 
 	rm, err := service.NewLocalRoomManager(store, router, node, selector, nil, conf)
 	require.NoError(t, err)
+	// releases the RTC ports for the next manager
+	t.Cleanup(rm.Stop)
 
-	return rm, conf
+	return rm, conf, router
 }
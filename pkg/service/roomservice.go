@@ -2,42 +2,175 @@ package service
 
 import (
 	"context"
+	"time"
 
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/pkg/errors"
 	"github.com/thoas/go-funk"
 	"github.com/twitchtv/twirp"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	roomActionUpdateMetadata  = "update_room_metadata"
+	roomActionMoveParticipant = "move_participant"
+	nodeActionDrain           = "drain_node"
+	participantActionGetStats = "get_participant_stats"
 )
 
 // A rooms service that supports a single node
 type RoomService struct {
 	router      routing.Router
 	roomManager RoomManager
+	roomConf    config.RoomConfig
+	// runs requests on the node hosting a room
+	nodeRequests *NodeRequests
 }
 
-func NewRoomService(roomManager RoomManager, router routing.Router) (svc *RoomService, err error) {
+func NewRoomService(roomManager RoomManager, router routing.Router, conf *config.Config, nodeRequests *NodeRequests) (svc *RoomService, err error) {
 	svc = &RoomService{
-		router:      router,
-		roomManager: roomManager,
+		router:       router,
+		roomManager:  roomManager,
+		roomConf:     conf.Room,
+		nodeRequests: nodeRequests,
 	}
+	nodeRequests.Handle(svc.handleLocalRoomRequest,
+		roomActionUpdateMetadata, roomActionMoveParticipant, nodeActionDrain, participantActionGetStats)
 	return
 }
 
+// handleLocalRoomRequest runs room and node requests for this node
+func (s *RoomService) handleLocalRoomRequest(ctx context.Context, msg *nodeRequest, result *nodeRequestResult) error {
+	var err error
+	switch {
+	case msg.Action == roomActionUpdateMetadata && msg.Metadata != nil:
+		err = s.roomManager.UpdateRoomMetadata(ctx, msg.Metadata.Room, msg.Metadata.Metadata)
+	case msg.Action == roomActionMoveParticipant && msg.Move != nil:
+		req := msg.Move
+		err = s.roomManager.MoveParticipant(ctx, req.Room, req.Identity, req.DestinationRoom)
+	case msg.Action == nodeActionDrain && msg.Drain != nil:
+		// answered right away, draining takes up to the drain timeout
+		go s.roomManager.Drain()
+	case msg.Action == participantActionGetStats && msg.Participant != nil:
+		req := msg.Participant
+		result.Stats, err = s.roomManager.GetParticipantStats(ctx, req.Room, req.Identity)
+	default:
+		err = ErrInvalidNodeRequest
+	}
+	return err
+}
+
+// NewRoomServiceServer creates the twirp server for RoomService, along with extensions
+// that are only available to JSON clients
+func NewRoomServiceServer(svc *RoomService) livekit.TwirpServer {
+	server := NewTwirpExtServer(livekit.NewRoomServiceServer(svc))
+	server.Handle("CreateRoom", svc.createRoomExt)
+	server.Handle("GetParticipant", svc.getParticipantExt)
+	server.Handle("GetParticipantStats", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &livekit.RoomParticipantIdentity{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.GetParticipantStats(ctx, req)
+	})
+	server.Handle("UpdateRoomMetadata", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &RoomMetadataRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.UpdateRoomMetadata(ctx, req)
+	})
+	server.Handle("MoveParticipant", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &MoveParticipantRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.MoveParticipant(ctx, req)
+	})
+	server.Handle("GetDataHistory", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &DataHistoryRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.GetDataHistory(ctx, req)
+	})
+	server.Handle("DrainNode", func(ctx context.Context, body []byte) (interface{}, error) {
+		req := &DrainNodeRequest{}
+		if err := unmarshalExtRequest(body, req); err != nil {
+			return nil, err
+		}
+		return svc.DrainNode(ctx, req)
+	})
+	return server
+}
+
 func (s *RoomService) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (rm *livekit.Room, err error) {
 	if err = EnsureCreatePermission(ctx); err != nil {
 		return nil, twirpAuthError(err)
 	}
 
 	rm, err = s.roomManager.CreateRoom(ctx, req)
-	if err != nil {
+	if err == routing.ErrClusterFull {
+		err = twirp.NewError(twirp.ResourceExhausted, err.Error())
+	} else if err != nil {
 		err = errors.Wrap(err, "could not create room")
 	}
 
 	return
 }
 
+// createRoomExt accepts room options that aren't part of CreateRoomRequest. options that are left
+// out fall back to the server's room config
+func (s *RoomService) createRoomExt(ctx context.Context, body []byte) (interface{}, error) {
+	req := &livekit.CreateRoomRequest{}
+	if err := unmarshalExtRequest(body, req); err != nil {
+		return nil, err
+	}
+	ext := &struct {
+		LastN              *uint32 `json:"last_n"`
+		DataHistorySize    *uint32 `json:"data_history_size"`
+		DataHistorySeconds *uint32 `json:"data_history_seconds"`
+		// placed in the region when it's new, with the regionaware node selector
+		Region string `json:"region"`
+	}{}
+	if err := unmarshalExtRequest(body, ext); err != nil {
+		return nil, err
+	}
+	if ext.Region != "" {
+		ctx = WithRegion(ctx, ext.Region)
+	}
+
+	rm, err := s.CreateRoom(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if ext.LastN != nil || ext.DataHistorySize != nil || ext.DataHistorySeconds != nil {
+		opts := defaultRoomOptions(&s.roomConf)
+		if ext.LastN != nil {
+			opts.LastN = *ext.LastN
+		}
+		if ext.DataHistorySize != nil {
+			opts.DataHistorySize = *ext.DataHistorySize
+		}
+		if ext.DataHistorySeconds != nil {
+			opts.DataHistorySeconds = *ext.DataHistorySeconds
+		}
+		if err := s.roomManager.StoreRoomOptions(ctx, rm.Name, &opts); err != nil {
+			return nil, twirp.WrapError(twirp.InternalError("could not store room options"), err)
+		}
+		fields["last_n"] = opts.LastN
+		fields["data_history_size"] = opts.DataHistorySize
+		fields["data_history_seconds"] = opts.DataHistorySeconds
+	}
+	return extendMessage(rm, fields)
+}
+
 func (s *RoomService) ListRooms(ctx context.Context, req *livekit.ListRoomsRequest) (res *livekit.ListRoomsResponse, err error) {
 	err = EnsureListPermission(ctx)
 	if err != nil {
@@ -117,6 +250,174 @@ func (s *RoomService) GetParticipant(ctx context.Context, req *livekit.RoomParti
 	return
 }
 
+// getParticipantExt includes the participant's connection quality, when it's known
+func (s *RoomService) getParticipantExt(ctx context.Context, body []byte) (interface{}, error) {
+	req := &livekit.RoomParticipantIdentity{}
+	if err := unmarshalExtRequest(body, req); err != nil {
+		return nil, err
+	}
+
+	participant, err := s.GetParticipant(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if quality, err := s.roomManager.LoadConnectionQuality(ctx, req.Room, req.Identity); err == nil {
+		fields["connection_quality"] = quality
+	}
+	return extendMessage(participant, fields)
+}
+
+// GetParticipantStats returns track statistics of the participant, computed by the node it's connected to
+func (s *RoomService) GetParticipantStats(ctx context.Context, req *livekit.RoomParticipantIdentity) (*types.ParticipantStats, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	nodeId, err := s.router.GetParticipantRTCNode(req.Room, req.Identity)
+	if err == routing.ErrNodeNotFound {
+		return nil, twirp.NotFoundError(ErrParticipantNotFound.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	result, err := s.nodeRequests.handleNodeRequest(ctx, nodeId, &nodeRequest{
+		Action:      participantActionGetStats,
+		Participant: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Stats, nil
+}
+
+type RoomMetadataRequest struct {
+	Room     string `json:"room"`
+	Metadata string `json:"metadata"`
+}
+
+// UpdateRoomMetadata stores the room's metadata, and sends it to participants from the node hosting the room.
+// the room is returned with its metadata
+func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *RoomMetadataRequest) (interface{}, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	rm, err := s.roomManager.LoadRoom(ctx, req.Room)
+	if err == ErrRoomNotFound {
+		return nil, twirp.NotFoundError(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = s.roomManager.StoreRoomMetadata(ctx, rm.Name, req.Metadata); err != nil {
+		return nil, twirp.WrapError(twirp.InternalError("could not store room metadata"), err)
+	}
+	_, _, err = s.nodeRequests.handleRoomRequest(ctx, rm.Name, &nodeRequest{
+		Action:   roomActionUpdateMetadata,
+		Metadata: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return extendMessage(rm, map[string]interface{}{
+		"metadata": req.Metadata,
+	})
+}
+
+type MoveParticipantRequest struct {
+	Room            string `json:"room"`
+	Identity        string `json:"identity"`
+	DestinationRoom string `json:"destination_room"`
+}
+
+// MoveParticipant moves a participant to another room hosted on the same node, keeping its connections.
+// the destination room is created on that node when it doesn't exist. since the participant's token is
+// for the room it joined, it needs a new token to reconnect
+func (s *RoomService) MoveParticipant(ctx context.Context, req *MoveParticipantRequest) (*livekit.ParticipantInfo, error) {
+	// admin of the room it's in, and allowed to create the one it's moved to
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if err := EnsureCreatePermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.DestinationRoom == "" || req.DestinationRoom == req.Room {
+		return nil, twirp.InvalidArgumentError("destination_room", "must be another room")
+	}
+
+	_, err := s.roomManager.LoadParticipant(ctx, req.Room, req.Identity)
+	if err == ErrParticipantNotFound {
+		return nil, twirp.NotFoundError(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	node, err := s.router.GetNodeForRoom(ctx, req.Room)
+	if err != nil {
+		return nil, err
+	}
+	// rooms that are already running elsewhere are left there, and the move fails
+	_, err = s.roomManager.CreateRoom(ctx, &livekit.CreateRoomRequest{
+		Name:   req.DestinationRoom,
+		NodeId: node.Id,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create room")
+	}
+
+	_, _, err = s.nodeRequests.handleRoomRequest(ctx, req.Room, &nodeRequest{
+		Action: roomActionMoveParticipant,
+		Move:   req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.roomManager.LoadParticipant(ctx, req.DestinationRoom, req.Identity)
+}
+
+type DataHistoryRequest struct {
+	Room string `json:"room"`
+}
+
+type DataHistoryResponse struct {
+	Room string `json:"room"`
+	// oldest first
+	Packets []*rtc.DataHistoryEntry `json:"packets"`
+}
+
+// GetDataHistory returns the packets that participants joining the room are sent
+func (s *RoomService) GetDataHistory(ctx context.Context, req *DataHistoryRequest) (*DataHistoryResponse, error) {
+	if err := EnsureAdminPermission(ctx, req.Room); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if _, err := s.roomManager.LoadRoom(ctx, req.Room); err == ErrRoomNotFound {
+		return nil, twirp.NotFoundError(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	opts := defaultRoomOptions(&s.roomConf)
+	if stored, err := s.roomManager.LoadRoomOptions(ctx, req.Room); err == nil {
+		opts = *stored
+	} else if err != ErrRoomNotFound {
+		return nil, err
+	}
+	history, err := s.roomManager.LoadDataHistory(ctx, req.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stored history is trimmed as packets are sent, some may have expired since
+	packets := opts.TrimDataHistory(history, time.Now())
+	if packets == nil {
+		packets = []*rtc.DataHistoryEntry{}
+	}
+	return &DataHistoryResponse{
+		Room:    req.Room,
+		Packets: packets,
+	}, nil
+}
+
 func (s *RoomService) RemoveParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (res *livekit.RemoveParticipantResponse, err error) {
 	err = s.writeMessage(ctx, req.Room, req.Identity, &livekit.RTCNodeMessage{
 		Message: &livekit.RTCNodeMessage_RemoveParticipant{
@@ -218,6 +519,36 @@ func (s *RoomService) SendData(ctx context.Context, req *livekit.SendDataRequest
 	return &livekit.SendDataResponse{}, nil
 }
 
+type DrainNodeRequest struct {
+	NodeId string `json:"node_id"`
+}
+
+// DrainNode stops new rooms from being placed on the node, and moves the rooms on it to other nodes once
+// they've emptied, or the node's drain timeout passes. it returns once draining has started. it needs a token
+// that's admin of every room
+func (s *RoomService) DrainNode(ctx context.Context, req *DrainNodeRequest) (*livekit.Node, error) {
+	if err := EnsureNodeAdminPermission(ctx); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.NodeId == "" {
+		return nil, twirp.RequiredArgumentError("node_id")
+	}
+	node, err := s.router.GetNode(req.NodeId)
+	if err == routing.ErrNotFound {
+		return nil, twirp.NotFoundError("node does not exist")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.nodeRequests.handleNodeRequest(ctx, node.Id, &nodeRequest{
+		Action: nodeActionDrain,
+		Drain:  req,
+	}); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
 func (s *RoomService) writeMessage(ctx context.Context, room, identity string, msg *livekit.RTCNodeMessage) error {
 	if err := EnsureAdminPermission(ctx, room); err != nil {
 		return twirpAuthError(err)
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+// signal connections are checked for their RTC node dying this often
+const rtcNodeCheckInterval = 3 * time.Second
+
+// rtcNodeWatcher checks the RTC nodes that signal connections on this node are routed to, once for each node
+// however many connections it has. when one dies, each of its rooms is failed over once, and the connections
+// in the room are told
+type rtcNodeWatcher struct {
+	router      routing.Router
+	roomManager RoomManager
+
+	lock  sync.Mutex
+	nodes map[string]*watchedRTCNode
+}
+
+type watchedRTCNode struct {
+	// callbacks of connections by room name, then connection ID
+	rooms map[string]map[string]func()
+	done  chan struct{}
+}
+
+func newRTCNodeWatcher(router routing.Router, roomManager RoomManager) *rtcNodeWatcher {
+	return &rtcNodeWatcher{
+		router:      router,
+		roomManager: roomManager,
+		nodes:       make(map[string]*watchedRTCNode),
+	}
+}
+
+// watch calls onFailover once the room has been failed over off its RTC node. the function returned stops
+// watching for the connection
+func (w *rtcNodeWatcher) watch(roomName, nodeId, connId string, onFailover func()) func() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	node := w.nodes[nodeId]
+	if node == nil {
+		node = &watchedRTCNode{
+			rooms: make(map[string]map[string]func()),
+			done:  make(chan struct{}),
+		}
+		w.nodes[nodeId] = node
+		go w.checkNode(nodeId, node)
+	}
+	conns := node.rooms[roomName]
+	if conns == nil {
+		conns = make(map[string]func())
+		node.rooms[roomName] = conns
+	}
+	conns[connId] = onFailover
+
+	return func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		if conns := node.rooms[roomName]; conns != nil {
+			delete(conns, connId)
+			if len(conns) == 0 {
+				w.removeRoomLocked(nodeId, node, roomName)
+			}
+		}
+	}
+}
+
+// removeRoomLocked stops watching the room on the node, and the node once it has no rooms left
+func (w *rtcNodeWatcher) removeRoomLocked(nodeId string, node *watchedRTCNode, roomName string) {
+	delete(node.rooms, roomName)
+	if len(node.rooms) == 0 && w.nodes[nodeId] == node {
+		delete(w.nodes, nodeId)
+		close(node.done)
+	}
+}
+
+func (w *rtcNodeWatcher) checkNode(nodeId string, node *watchedRTCNode) {
+	ticker := time.NewTicker(rtcNodeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-node.done:
+			return
+		case <-ticker.C:
+		}
+
+		rtcNode, err := w.router.GetNode(nodeId)
+		if err == nil && routing.IsAvailable(rtcNode) {
+			continue
+		} else if err != nil && err != routing.ErrNotFound {
+			logger.Warnw("could not check RTC node", err, "nodeID", nodeId)
+			continue
+		}
+
+		w.lock.Lock()
+		roomNames := make([]string, 0, len(node.rooms))
+		for roomName := range node.rooms {
+			roomNames = append(roomNames, roomName)
+		}
+		w.lock.Unlock()
+
+		for _, roomName := range roomNames {
+			newNodeId, err := w.roomManager.FailoverRoom(context.Background(), roomName, nodeId)
+			if err != nil {
+				// retried until a node can take the room
+				logger.Errorw("could not fail over room", err, "room", roomName, "deadNodeID", nodeId)
+				continue
+			}
+
+			w.lock.Lock()
+			conns := node.rooms[roomName]
+			if conns != nil {
+				w.removeRoomLocked(nodeId, node, roomName)
+			}
+			w.lock.Unlock()
+
+			logger.Infow("RTC node died, reconnecting participants",
+				"room", roomName,
+				"deadNodeID", nodeId,
+				"nodeID", newNodeId,
+				"connections", len(conns))
+			for _, onFailover := range conns {
+				onFailover()
+			}
+		}
+	}
+}
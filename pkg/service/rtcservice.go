@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"github.com/livekit/protocol/logger"
@@ -23,15 +25,22 @@ type RTCService struct {
 	upgrader    websocket.Upgrader
 	currentNode routing.LocalNode
 	isDev       bool
+	nodeWatcher *rtcNodeWatcher
+
+	// signal connections of HTTP clients such as WHIP encoders, by session ID
+	sessionLock  sync.Mutex
+	httpSessions map[string]*httpSignalSession
 }
 
 func NewRTCService(conf *config.Config, roomManager RoomManager, router routing.Router, currentNode routing.LocalNode) *RTCService {
 	s := &RTCService{
-		router:      router,
-		roomManager: roomManager,
-		upgrader:    websocket.Upgrader{},
-		currentNode: currentNode,
-		isDev:       conf.Development,
+		router:       router,
+		roomManager:  roomManager,
+		upgrader:     websocket.Upgrader{},
+		currentNode:  currentNode,
+		isDev:        conf.Development,
+		nodeWatcher:  newRTCNodeWatcher(router, roomManager),
+		httpSessions: make(map[string]*httpSignalSession),
 	}
 
 	// allow connections from any origin, since script may be hosted anywhere
@@ -91,6 +100,26 @@ func (s *RTCService) validate(r *http.Request) (string, routing.ParticipantInit,
 	return roomName, pi, http.StatusOK, nil
 }
 
+// regionContext has the region a new room is placed in, from the token's region claim or the region
+// query parameter
+func regionContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if GetRegion(ctx) == "" {
+		if region := r.FormValue("region"); region != "" {
+			ctx = WithRegion(ctx, region)
+		}
+	}
+	return ctx
+}
+
+// sessionErrorCode is 503 when no node can take the session, so clients can retry later
+func sessionErrorCode(err error) int {
+	if err == routing.ErrClusterFull || err == routing.ErrNoAvailableNodes {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// reject non websocket requests
 	if !websocket.IsWebSocketUpgrade(r) {
@@ -105,9 +134,14 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create room if it doesn't exist, also assigns an RTC node for the room
-	rm, err := s.roomManager.CreateRoom(r.Context(), &livekit.CreateRoomRequest{Name: roomName})
+	ctx := regionContext(r)
+	rm, err := s.roomManager.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: roomName})
 	if err != nil {
-		handleError(w, http.StatusInternalServerError, err.Error())
+		handleError(w, sessionErrorCode(err), err.Error())
+		return
+	}
+	if pi.RTCNodeId, err = s.roomManager.GetNodeForParticipant(ctx, roomName); err != nil {
+		handleError(w, sessionErrorCode(err), err.Error())
 		return
 	}
 
@@ -144,6 +178,15 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"room", rm.Name,
 		"participant", pi.Identity,
 	)
+	// when the RTC node dies, the client reconnects to the node its room is failed over to
+	unwatch := s.nodeWatcher.watch(roomName, pi.RTCNodeId, connId, func() {
+		_ = sigConn.WriteResponse(&livekit.SignalResponse{
+			Message: &livekit.SignalResponse_Leave{
+				Leave: &livekit.LeaveRequest{CanReconnect: true},
+			},
+		})
+	})
+	defer unwatch()
 
 	// handle responses
 	go func() {
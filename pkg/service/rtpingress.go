@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"net"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// startLocalRTPIngress joins the ingress to the room as a publisher, and returns the port it receives on
+func (s *RecordingService) startLocalRTPIngress(ctx context.Context, req *RTPIngressRequest) (int, error) {
+	params := s.ingressParams(req.IngressId)
+	params.VideoCodec = req.VideoCodec
+	params.Audio = req.Audio
+	if req.SourceIp != "" {
+		params.SourceIP = net.ParseIP(req.SourceIp)
+	}
+	ingress, err := rtc.NewRTPIngress(params)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = s.joinIngress(ingress, req.Room, ingressParticipant(req.Identity), nil); err != nil {
+		return 0, err
+	}
+
+	logger.Infow("RTP ingress started",
+		"room", req.Room,
+		"participant", req.Identity,
+		"ingress", ingress.ID(),
+		"port", ingress.Port())
+	return ingress.Port(), nil
+}
+
+// ingressParams receives RTP on this node's IP, on a port of the range its WebRTC connections use
+func (s *RecordingService) ingressParams(id string) rtc.RTPIngressParams {
+	return rtc.RTPIngressParams{
+		ID:             id,
+		Addr:           &net.UDPAddr{IP: net.ParseIP(s.currentNode.Ip)},
+		PortRangeStart: uint16(s.conf.RTC.ICEPortRangeStart),
+		PortRangeEnd:   uint16(s.conf.RTC.ICEPortRangeEnd),
+	}
+}
+
+// ingressParticipant joins an ingress as a publisher, that's signaled like a WHIP client
+func ingressParticipant(identity string) routing.ParticipantInit {
+	return routing.ParticipantInit{
+		Identity: identity,
+		Permission: &livekit.ParticipantPermission{
+			CanPublish: true,
+		},
+		ProtocolVersion: httpSessionProtocolVersion,
+	}
+}
+
+// joinIngress joins the ingress to a room as the participant pi, with the names of the ingress's tracks, and
+// returns the session it's renegotiated with. it leaves the room when the ingress is closed, after which onClose
+// is called. the ingress is closed when it can't join
+func (s *RecordingService) joinIngress(ingress *rtc.RTPIngress, roomName string, pi routing.ParticipantInit, onClose func()) (*httpSignalSession, error) {
+	offer, err := ingress.CreateOffer()
+	if err != nil {
+		ingress.Close()
+		return nil, err
+	}
+	requests, err := ingressAddTrackRequests(ingress, offer, nil)
+	if err != nil {
+		ingress.Close()
+		return nil, err
+	}
+
+	// the session outlives the request that started it
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	var reqSink routing.MessageSink
+	var resSource routing.MessageSource
+	if pi.Relayed {
+		// relays join the room on this node, without being routed
+		reqChan := routing.NewMessageChannel()
+		resChan := routing.NewMessageChannel()
+		s.roomManager.StartSession(sessionCtx, roomName, pi, reqChan, resChan)
+		reqSink, resSource = reqChan, resChan
+	} else if _, reqSink, resSource, err = s.router.StartParticipantSignal(sessionCtx, roomName, pi); err != nil {
+		cancel()
+		ingress.Close()
+		return nil, err
+	}
+	session := newHTTPSignalSession(ingress.ID(), roomName, pi.Identity, pi.RTCNodeId, reqSink, resSource, cancel)
+
+	answer, err := session.negotiate(offer, livekit.SignalTarget_PUBLISHER, requests...)
+	if err == nil {
+		err = ingress.SetAnswer(answer)
+	}
+	if err != nil {
+		session.Close()
+		ingress.Close()
+		return nil, err
+	}
+
+	s.ingressLock.Lock()
+	s.ingresses[ingress.ID()] = ingress
+	s.ingressLock.Unlock()
+
+	ingress.OnClose(session.Close)
+	go session.run(nil, func() {
+		ingress.Close()
+		s.ingressLock.Lock()
+		delete(s.ingresses, ingress.ID())
+		s.ingressLock.Unlock()
+		logger.Infow("RTP ingress closed",
+			"room", roomName,
+			"participant", pi.Identity,
+			"ingress", ingress.ID())
+		if onClose != nil {
+			onClose()
+		}
+	})
+	return session, nil
+}
+
+// ingressAddTrackRequests publishes the tracks of the ingress's offer with their names, only those in cids
+// when it's set
+func ingressAddTrackRequests(ingress *rtc.RTPIngress, offer webrtc.SessionDescription, cids map[string]bool) ([]*livekit.SignalRequest, error) {
+	tracks, err := whipTracks(offer.SDP)
+	if err != nil {
+		return nil, err
+	}
+	var published []*livekit.AddTrackRequest
+	for _, track := range tracks {
+		if cids != nil && !cids[track.Cid] {
+			continue
+		}
+		if name := ingress.TrackName(track.Cid); name != "" {
+			track.Name = name
+		}
+		published = append(published, track)
+	}
+	return addTrackRequests(published), nil
+}
+
+func (s *RecordingService) endLocalRTPIngress(ingressID string) error {
+	s.ingressLock.Lock()
+	ingress := s.ingresses[ingressID]
+	s.ingressLock.Unlock()
+	if ingress == nil {
+		return ErrIngressNotFound
+	}
+	ingress.Close()
+	return nil
+}
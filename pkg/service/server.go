@@ -24,24 +24,29 @@ import (
 )
 
 type LivekitServer struct {
-	config      *config.Config
-	roomServer  livekit.TwirpServer
-	recServer   livekit.TwirpServer
-	rtcService  *RTCService
-	httpServer  *http.Server
-	promServer  *http.Server
-	router      routing.Router
-	roomManager *LocalRoomManager
-	turnServer  *turn.Server
-	currentNode routing.LocalNode
-	running     utils.AtomicFlag
-	doneChan    chan struct{}
-	closedChan  chan struct{}
+	config       *config.Config
+	roomServer   livekit.TwirpServer
+	recService   *RecordingService
+	recServer    livekit.TwirpServer
+	relayService *RelayService
+	nodeRequests *NodeRequests
+	rtcService   *RTCService
+	httpServer   *http.Server
+	promServer   *http.Server
+	router       routing.Router
+	roomManager  *LocalRoomManager
+	turnServer   *turn.Server
+	currentNode  routing.LocalNode
+	running      utils.AtomicFlag
+	doneChan     chan struct{}
+	closedChan   chan struct{}
 }
 
 func NewLivekitServer(conf *config.Config,
-	roomService livekit.RoomService,
-	recService livekit.RecordingService,
+	roomService *RoomService,
+	recService *RecordingService,
+	relayService *RelayService,
+	nodeRequests *NodeRequests,
 	rtcService *RTCService,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
@@ -50,12 +55,15 @@ func NewLivekitServer(conf *config.Config,
 	currentNode routing.LocalNode,
 ) (s *LivekitServer, err error) {
 	s = &LivekitServer{
-		config:      conf,
-		roomServer:  livekit.NewRoomServiceServer(roomService),
-		recServer:   livekit.NewRecordingServiceServer(recService),
-		rtcService:  rtcService,
-		router:      router,
-		roomManager: roomManager,
+		config:       conf,
+		roomServer:   NewRoomServiceServer(roomService),
+		recService:   recService,
+		recServer:    NewRecordingServiceServer(recService),
+		relayService: relayService,
+		nodeRequests: nodeRequests,
+		rtcService:   rtcService,
+		router:       router,
+		roomManager:  roomManager,
 		// turn server starts automatically
 		turnServer:  turnServer,
 		currentNode: currentNode,
@@ -75,6 +83,10 @@ func NewLivekitServer(conf *config.Config,
 	mux.Handle(s.recServer.PathPrefix(), s.recServer)
 	mux.Handle("/rtc", rtcService)
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
+	mux.HandleFunc(whipPath, rtcService.ServeWHIP)
+	mux.HandleFunc(whipPath+"/", rtcService.ServeWHIP)
+	mux.HandleFunc(whepPath, rtcService.ServeWHEP)
+	mux.HandleFunc(whepPath+"/", rtcService.ServeWHEP)
 	mux.HandleFunc("/", s.healthCheck)
 	if conf.Development {
 		mux.HandleFunc("/debug/goroutine", s.debugGoroutines)
@@ -97,7 +109,7 @@ func NewLivekitServer(conf *config.Config,
 	if err = roomManager.CleanupRooms(); err != nil {
 		return
 	}
-	if err = router.RemoveDeadNodes(); err != nil {
+	if err = roomManager.ReapDeadNodes(); err != nil {
 		return
 	}
 
@@ -130,6 +142,13 @@ func (s *LivekitServer) Start() error {
 		return err
 	}
 
+	if err := s.nodeRequests.Start(); err != nil {
+		return err
+	}
+	if err := s.relayService.Start(); err != nil {
+		return err
+	}
+
 	s.doneChan = make(chan struct{})
 
 	// ensure we could listen
@@ -197,12 +216,21 @@ func (s *LivekitServer) Start() error {
 		_ = s.turnServer.Close()
 	}
 
+	s.nodeRequests.Stop()
+	s.relayService.Stop()
+	s.recService.Stop()
 	s.roomManager.Stop()
 
 	close(s.closedChan)
 	return nil
 }
 
+// Drain moves the rooms on this node to other nodes, see LocalRoomManager.Drain. the server keeps running
+// until it's stopped
+func (s *LivekitServer) Drain() {
+	s.roomManager.Drain()
+}
+
 func (s *LivekitServer) Stop() {
 	if !s.running.TrySet(false) {
 		return
@@ -241,18 +269,30 @@ func (s *LivekitServer) debugInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *LivekitServer) healthCheck(w http.ResponseWriter, r *http.Request) {
+	// so load balancers send new connections to other nodes
+	if s.roomManager.IsDraining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
 // worker to perform periodic tasks per node
 func (s *LivekitServer) backgroundWorker() {
 	roomTicker := time.NewTicker(30 * time.Second)
+	reapTicker := time.NewTicker(reapInterval)
+	defer roomTicker.Stop()
+	defer reapTicker.Stop()
 	for {
 		select {
 		case <-s.doneChan:
 			return
 		case <-roomTicker.C:
 			s.roomManager.CloseIdleRooms()
+		case <-reapTicker.C:
+			if err := s.roomManager.ReapDeadNodes(); err != nil {
+				logger.Errorw("could not reap dead nodes", err)
+			}
 		}
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/service"
 	livekit "github.com/livekit/protocol/proto"
 )
@@ -63,6 +65,35 @@ type FakeRoomStore struct {
 		result1 []*livekit.Room
 		result2 error
 	}
+	LoadConnectionQualityStub        func(context.Context, string, string) (types.ConnectionQuality, error)
+	loadConnectionQualityMutex       sync.RWMutex
+	loadConnectionQualityArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	loadConnectionQualityReturns struct {
+		result1 types.ConnectionQuality
+		result2 error
+	}
+	loadConnectionQualityReturnsOnCall map[int]struct {
+		result1 types.ConnectionQuality
+		result2 error
+	}
+	LoadDataHistoryStub        func(context.Context, string) ([]*rtc.DataHistoryEntry, error)
+	loadDataHistoryMutex       sync.RWMutex
+	loadDataHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	loadDataHistoryReturns struct {
+		result1 []*rtc.DataHistoryEntry
+		result2 error
+	}
+	loadDataHistoryReturnsOnCall map[int]struct {
+		result1 []*rtc.DataHistoryEntry
+		result2 error
+	}
 	LoadParticipantStub        func(context.Context, string, string) (*livekit.ParticipantInfo, error)
 	loadParticipantMutex       sync.RWMutex
 	loadParticipantArgsForCall []struct {
@@ -92,6 +123,34 @@ type FakeRoomStore struct {
 		result1 *livekit.Room
 		result2 error
 	}
+	LoadRoomMetadataStub        func(context.Context, string) (string, error)
+	loadRoomMetadataMutex       sync.RWMutex
+	loadRoomMetadataArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	loadRoomMetadataReturns struct {
+		result1 string
+		result2 error
+	}
+	loadRoomMetadataReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	LoadRoomOptionsStub        func(context.Context, string) (*rtc.RoomOptions, error)
+	loadRoomOptionsMutex       sync.RWMutex
+	loadRoomOptionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	loadRoomOptionsReturns struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}
+	loadRoomOptionsReturnsOnCall map[int]struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}
 	LockRoomStub        func(context.Context, string, time.Duration) (string, error)
 	lockRoomMutex       sync.RWMutex
 	lockRoomArgsForCall []struct {
@@ -107,6 +166,33 @@ type FakeRoomStore struct {
 		result1 string
 		result2 error
 	}
+	StoreConnectionQualityStub        func(context.Context, string, string, types.ConnectionQuality) error
+	storeConnectionQualityMutex       sync.RWMutex
+	storeConnectionQualityArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 types.ConnectionQuality
+	}
+	storeConnectionQualityReturns struct {
+		result1 error
+	}
+	storeConnectionQualityReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreDataHistoryStub        func(context.Context, string, []*rtc.DataHistoryEntry) error
+	storeDataHistoryMutex       sync.RWMutex
+	storeDataHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []*rtc.DataHistoryEntry
+	}
+	storeDataHistoryReturns struct {
+		result1 error
+	}
+	storeDataHistoryReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreParticipantStub        func(context.Context, string, *livekit.ParticipantInfo) error
 	storeParticipantMutex       sync.RWMutex
 	storeParticipantArgsForCall []struct {
@@ -132,6 +218,32 @@ type FakeRoomStore struct {
 	storeRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreRoomMetadataStub        func(context.Context, string, string) error
+	storeRoomMetadataMutex       sync.RWMutex
+	storeRoomMetadataArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	storeRoomMetadataReturns struct {
+		result1 error
+	}
+	storeRoomMetadataReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreRoomOptionsStub        func(context.Context, string, *rtc.RoomOptions) error
+	storeRoomOptionsMutex       sync.RWMutex
+	storeRoomOptionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *rtc.RoomOptions
+	}
+	storeRoomOptionsReturns struct {
+		result1 error
+	}
+	storeRoomOptionsReturnsOnCall map[int]struct {
+		result1 error
+	}
 	UnlockRoomStub        func(context.Context, string, string) error
 	unlockRoomMutex       sync.RWMutex
 	unlockRoomArgsForCall []struct {
@@ -403,6 +515,137 @@ func (fake *FakeRoomStore) ListRoomsReturnsOnCall(i int, result1 []*livekit.Room
 	}{result1, result2}
 }
 
+func (fake *FakeRoomStore) LoadConnectionQuality(arg1 context.Context, arg2 string, arg3 string) (types.ConnectionQuality, error) {
+	fake.loadConnectionQualityMutex.Lock()
+	ret, specificReturn := fake.loadConnectionQualityReturnsOnCall[len(fake.loadConnectionQualityArgsForCall)]
+	fake.loadConnectionQualityArgsForCall = append(fake.loadConnectionQualityArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.LoadConnectionQualityStub
+	fakeReturns := fake.loadConnectionQualityReturns
+	fake.recordInvocation("LoadConnectionQuality", []interface{}{arg1, arg2, arg3})
+	fake.loadConnectionQualityMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomStore) LoadConnectionQualityCallCount() int {
+	fake.loadConnectionQualityMutex.RLock()
+	defer fake.loadConnectionQualityMutex.RUnlock()
+	return len(fake.loadConnectionQualityArgsForCall)
+}
+
+func (fake *FakeRoomStore) LoadConnectionQualityCalls(stub func(context.Context, string, string) (types.ConnectionQuality, error)) {
+	fake.loadConnectionQualityMutex.Lock()
+	defer fake.loadConnectionQualityMutex.Unlock()
+	fake.LoadConnectionQualityStub = stub
+}
+
+func (fake *FakeRoomStore) LoadConnectionQualityArgsForCall(i int) (context.Context, string, string) {
+	fake.loadConnectionQualityMutex.RLock()
+	defer fake.loadConnectionQualityMutex.RUnlock()
+	argsForCall := fake.loadConnectionQualityArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomStore) LoadConnectionQualityReturns(result1 types.ConnectionQuality, result2 error) {
+	fake.loadConnectionQualityMutex.Lock()
+	defer fake.loadConnectionQualityMutex.Unlock()
+	fake.LoadConnectionQualityStub = nil
+	fake.loadConnectionQualityReturns = struct {
+		result1 types.ConnectionQuality
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadConnectionQualityReturnsOnCall(i int, result1 types.ConnectionQuality, result2 error) {
+	fake.loadConnectionQualityMutex.Lock()
+	defer fake.loadConnectionQualityMutex.Unlock()
+	fake.LoadConnectionQualityStub = nil
+	if fake.loadConnectionQualityReturnsOnCall == nil {
+		fake.loadConnectionQualityReturnsOnCall = make(map[int]struct {
+			result1 types.ConnectionQuality
+			result2 error
+		})
+	}
+	fake.loadConnectionQualityReturnsOnCall[i] = struct {
+		result1 types.ConnectionQuality
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadDataHistory(arg1 context.Context, arg2 string) ([]*rtc.DataHistoryEntry, error) {
+	fake.loadDataHistoryMutex.Lock()
+	ret, specificReturn := fake.loadDataHistoryReturnsOnCall[len(fake.loadDataHistoryArgsForCall)]
+	fake.loadDataHistoryArgsForCall = append(fake.loadDataHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.LoadDataHistoryStub
+	fakeReturns := fake.loadDataHistoryReturns
+	fake.recordInvocation("LoadDataHistory", []interface{}{arg1, arg2})
+	fake.loadDataHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomStore) LoadDataHistoryCallCount() int {
+	fake.loadDataHistoryMutex.RLock()
+	defer fake.loadDataHistoryMutex.RUnlock()
+	return len(fake.loadDataHistoryArgsForCall)
+}
+
+func (fake *FakeRoomStore) LoadDataHistoryCalls(stub func(context.Context, string) ([]*rtc.DataHistoryEntry, error)) {
+	fake.loadDataHistoryMutex.Lock()
+	defer fake.loadDataHistoryMutex.Unlock()
+	fake.LoadDataHistoryStub = stub
+}
+
+func (fake *FakeRoomStore) LoadDataHistoryArgsForCall(i int) (context.Context, string) {
+	fake.loadDataHistoryMutex.RLock()
+	defer fake.loadDataHistoryMutex.RUnlock()
+	argsForCall := fake.loadDataHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomStore) LoadDataHistoryReturns(result1 []*rtc.DataHistoryEntry, result2 error) {
+	fake.loadDataHistoryMutex.Lock()
+	defer fake.loadDataHistoryMutex.Unlock()
+	fake.LoadDataHistoryStub = nil
+	fake.loadDataHistoryReturns = struct {
+		result1 []*rtc.DataHistoryEntry
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadDataHistoryReturnsOnCall(i int, result1 []*rtc.DataHistoryEntry, result2 error) {
+	fake.loadDataHistoryMutex.Lock()
+	defer fake.loadDataHistoryMutex.Unlock()
+	fake.LoadDataHistoryStub = nil
+	if fake.loadDataHistoryReturnsOnCall == nil {
+		fake.loadDataHistoryReturnsOnCall = make(map[int]struct {
+			result1 []*rtc.DataHistoryEntry
+			result2 error
+		})
+	}
+	fake.loadDataHistoryReturnsOnCall[i] = struct {
+		result1 []*rtc.DataHistoryEntry
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRoomStore) LoadParticipant(arg1 context.Context, arg2 string, arg3 string) (*livekit.ParticipantInfo, error) {
 	fake.loadParticipantMutex.Lock()
 	ret, specificReturn := fake.loadParticipantReturnsOnCall[len(fake.loadParticipantArgsForCall)]
@@ -534,6 +777,136 @@ func (fake *FakeRoomStore) LoadRoomReturnsOnCall(i int, result1 *livekit.Room, r
 	}{result1, result2}
 }
 
+func (fake *FakeRoomStore) LoadRoomMetadata(arg1 context.Context, arg2 string) (string, error) {
+	fake.loadRoomMetadataMutex.Lock()
+	ret, specificReturn := fake.loadRoomMetadataReturnsOnCall[len(fake.loadRoomMetadataArgsForCall)]
+	fake.loadRoomMetadataArgsForCall = append(fake.loadRoomMetadataArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.LoadRoomMetadataStub
+	fakeReturns := fake.loadRoomMetadataReturns
+	fake.recordInvocation("LoadRoomMetadata", []interface{}{arg1, arg2})
+	fake.loadRoomMetadataMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomStore) LoadRoomMetadataCallCount() int {
+	fake.loadRoomMetadataMutex.RLock()
+	defer fake.loadRoomMetadataMutex.RUnlock()
+	return len(fake.loadRoomMetadataArgsForCall)
+}
+
+func (fake *FakeRoomStore) LoadRoomMetadataCalls(stub func(context.Context, string) (string, error)) {
+	fake.loadRoomMetadataMutex.Lock()
+	defer fake.loadRoomMetadataMutex.Unlock()
+	fake.LoadRoomMetadataStub = stub
+}
+
+func (fake *FakeRoomStore) LoadRoomMetadataArgsForCall(i int) (context.Context, string) {
+	fake.loadRoomMetadataMutex.RLock()
+	defer fake.loadRoomMetadataMutex.RUnlock()
+	argsForCall := fake.loadRoomMetadataArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomStore) LoadRoomMetadataReturns(result1 string, result2 error) {
+	fake.loadRoomMetadataMutex.Lock()
+	defer fake.loadRoomMetadataMutex.Unlock()
+	fake.LoadRoomMetadataStub = nil
+	fake.loadRoomMetadataReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadRoomMetadataReturnsOnCall(i int, result1 string, result2 error) {
+	fake.loadRoomMetadataMutex.Lock()
+	defer fake.loadRoomMetadataMutex.Unlock()
+	fake.LoadRoomMetadataStub = nil
+	if fake.loadRoomMetadataReturnsOnCall == nil {
+		fake.loadRoomMetadataReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.loadRoomMetadataReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadRoomOptions(arg1 context.Context, arg2 string) (*rtc.RoomOptions, error) {
+	fake.loadRoomOptionsMutex.Lock()
+	ret, specificReturn := fake.loadRoomOptionsReturnsOnCall[len(fake.loadRoomOptionsArgsForCall)]
+	fake.loadRoomOptionsArgsForCall = append(fake.loadRoomOptionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.LoadRoomOptionsStub
+	fakeReturns := fake.loadRoomOptionsReturns
+	fake.recordInvocation("LoadRoomOptions", []interface{}{arg1, arg2})
+	fake.loadRoomOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomStore) LoadRoomOptionsCallCount() int {
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
+	return len(fake.loadRoomOptionsArgsForCall)
+}
+
+func (fake *FakeRoomStore) LoadRoomOptionsCalls(stub func(context.Context, string) (*rtc.RoomOptions, error)) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = stub
+}
+
+func (fake *FakeRoomStore) LoadRoomOptionsArgsForCall(i int) (context.Context, string) {
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
+	argsForCall := fake.loadRoomOptionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomStore) LoadRoomOptionsReturns(result1 *rtc.RoomOptions, result2 error) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = nil
+	fake.loadRoomOptionsReturns = struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomStore) LoadRoomOptionsReturnsOnCall(i int, result1 *rtc.RoomOptions, result2 error) {
+	fake.loadRoomOptionsMutex.Lock()
+	defer fake.loadRoomOptionsMutex.Unlock()
+	fake.LoadRoomOptionsStub = nil
+	if fake.loadRoomOptionsReturnsOnCall == nil {
+		fake.loadRoomOptionsReturnsOnCall = make(map[int]struct {
+			result1 *rtc.RoomOptions
+			result2 error
+		})
+	}
+	fake.loadRoomOptionsReturnsOnCall[i] = struct {
+		result1 *rtc.RoomOptions
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRoomStore) LockRoom(arg1 context.Context, arg2 string, arg3 time.Duration) (string, error) {
 	fake.lockRoomMutex.Lock()
 	ret, specificReturn := fake.lockRoomReturnsOnCall[len(fake.lockRoomArgsForCall)]
@@ -600,6 +973,138 @@ func (fake *FakeRoomStore) LockRoomReturnsOnCall(i int, result1 string, result2
 	}{result1, result2}
 }
 
+func (fake *FakeRoomStore) StoreConnectionQuality(arg1 context.Context, arg2 string, arg3 string, arg4 types.ConnectionQuality) error {
+	fake.storeConnectionQualityMutex.Lock()
+	ret, specificReturn := fake.storeConnectionQualityReturnsOnCall[len(fake.storeConnectionQualityArgsForCall)]
+	fake.storeConnectionQualityArgsForCall = append(fake.storeConnectionQualityArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 types.ConnectionQuality
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.StoreConnectionQualityStub
+	fakeReturns := fake.storeConnectionQualityReturns
+	fake.recordInvocation("StoreConnectionQuality", []interface{}{arg1, arg2, arg3, arg4})
+	fake.storeConnectionQualityMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomStore) StoreConnectionQualityCallCount() int {
+	fake.storeConnectionQualityMutex.RLock()
+	defer fake.storeConnectionQualityMutex.RUnlock()
+	return len(fake.storeConnectionQualityArgsForCall)
+}
+
+func (fake *FakeRoomStore) StoreConnectionQualityCalls(stub func(context.Context, string, string, types.ConnectionQuality) error) {
+	fake.storeConnectionQualityMutex.Lock()
+	defer fake.storeConnectionQualityMutex.Unlock()
+	fake.StoreConnectionQualityStub = stub
+}
+
+func (fake *FakeRoomStore) StoreConnectionQualityArgsForCall(i int) (context.Context, string, string, types.ConnectionQuality) {
+	fake.storeConnectionQualityMutex.RLock()
+	defer fake.storeConnectionQualityMutex.RUnlock()
+	argsForCall := fake.storeConnectionQualityArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRoomStore) StoreConnectionQualityReturns(result1 error) {
+	fake.storeConnectionQualityMutex.Lock()
+	defer fake.storeConnectionQualityMutex.Unlock()
+	fake.StoreConnectionQualityStub = nil
+	fake.storeConnectionQualityReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreConnectionQualityReturnsOnCall(i int, result1 error) {
+	fake.storeConnectionQualityMutex.Lock()
+	defer fake.storeConnectionQualityMutex.Unlock()
+	fake.StoreConnectionQualityStub = nil
+	if fake.storeConnectionQualityReturnsOnCall == nil {
+		fake.storeConnectionQualityReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeConnectionQualityReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreDataHistory(arg1 context.Context, arg2 string, arg3 []*rtc.DataHistoryEntry) error {
+	var arg3Copy []*rtc.DataHistoryEntry
+	if arg3 != nil {
+		arg3Copy = make([]*rtc.DataHistoryEntry, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.storeDataHistoryMutex.Lock()
+	ret, specificReturn := fake.storeDataHistoryReturnsOnCall[len(fake.storeDataHistoryArgsForCall)]
+	fake.storeDataHistoryArgsForCall = append(fake.storeDataHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 []*rtc.DataHistoryEntry
+	}{arg1, arg2, arg3Copy})
+	stub := fake.StoreDataHistoryStub
+	fakeReturns := fake.storeDataHistoryReturns
+	fake.recordInvocation("StoreDataHistory", []interface{}{arg1, arg2, arg3Copy})
+	fake.storeDataHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomStore) StoreDataHistoryCallCount() int {
+	fake.storeDataHistoryMutex.RLock()
+	defer fake.storeDataHistoryMutex.RUnlock()
+	return len(fake.storeDataHistoryArgsForCall)
+}
+
+func (fake *FakeRoomStore) StoreDataHistoryCalls(stub func(context.Context, string, []*rtc.DataHistoryEntry) error) {
+	fake.storeDataHistoryMutex.Lock()
+	defer fake.storeDataHistoryMutex.Unlock()
+	fake.StoreDataHistoryStub = stub
+}
+
+func (fake *FakeRoomStore) StoreDataHistoryArgsForCall(i int) (context.Context, string, []*rtc.DataHistoryEntry) {
+	fake.storeDataHistoryMutex.RLock()
+	defer fake.storeDataHistoryMutex.RUnlock()
+	argsForCall := fake.storeDataHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomStore) StoreDataHistoryReturns(result1 error) {
+	fake.storeDataHistoryMutex.Lock()
+	defer fake.storeDataHistoryMutex.Unlock()
+	fake.StoreDataHistoryStub = nil
+	fake.storeDataHistoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreDataHistoryReturnsOnCall(i int, result1 error) {
+	fake.storeDataHistoryMutex.Lock()
+	defer fake.storeDataHistoryMutex.Unlock()
+	fake.StoreDataHistoryStub = nil
+	if fake.storeDataHistoryReturnsOnCall == nil {
+		fake.storeDataHistoryReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeDataHistoryReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRoomStore) StoreParticipant(arg1 context.Context, arg2 string, arg3 *livekit.ParticipantInfo) error {
 	fake.storeParticipantMutex.Lock()
 	ret, specificReturn := fake.storeParticipantReturnsOnCall[len(fake.storeParticipantArgsForCall)]
@@ -725,6 +1230,132 @@ func (fake *FakeRoomStore) StoreRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRoomStore) StoreRoomMetadata(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.storeRoomMetadataMutex.Lock()
+	ret, specificReturn := fake.storeRoomMetadataReturnsOnCall[len(fake.storeRoomMetadataArgsForCall)]
+	fake.storeRoomMetadataArgsForCall = append(fake.storeRoomMetadataArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomMetadataStub
+	fakeReturns := fake.storeRoomMetadataReturns
+	fake.recordInvocation("StoreRoomMetadata", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomMetadataMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomStore) StoreRoomMetadataCallCount() int {
+	fake.storeRoomMetadataMutex.RLock()
+	defer fake.storeRoomMetadataMutex.RUnlock()
+	return len(fake.storeRoomMetadataArgsForCall)
+}
+
+func (fake *FakeRoomStore) StoreRoomMetadataCalls(stub func(context.Context, string, string) error) {
+	fake.storeRoomMetadataMutex.Lock()
+	defer fake.storeRoomMetadataMutex.Unlock()
+	fake.StoreRoomMetadataStub = stub
+}
+
+func (fake *FakeRoomStore) StoreRoomMetadataArgsForCall(i int) (context.Context, string, string) {
+	fake.storeRoomMetadataMutex.RLock()
+	defer fake.storeRoomMetadataMutex.RUnlock()
+	argsForCall := fake.storeRoomMetadataArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomStore) StoreRoomMetadataReturns(result1 error) {
+	fake.storeRoomMetadataMutex.Lock()
+	defer fake.storeRoomMetadataMutex.Unlock()
+	fake.StoreRoomMetadataStub = nil
+	fake.storeRoomMetadataReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreRoomMetadataReturnsOnCall(i int, result1 error) {
+	fake.storeRoomMetadataMutex.Lock()
+	defer fake.storeRoomMetadataMutex.Unlock()
+	fake.StoreRoomMetadataStub = nil
+	if fake.storeRoomMetadataReturnsOnCall == nil {
+		fake.storeRoomMetadataReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomMetadataReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreRoomOptions(arg1 context.Context, arg2 string, arg3 *rtc.RoomOptions) error {
+	fake.storeRoomOptionsMutex.Lock()
+	ret, specificReturn := fake.storeRoomOptionsReturnsOnCall[len(fake.storeRoomOptionsArgsForCall)]
+	fake.storeRoomOptionsArgsForCall = append(fake.storeRoomOptionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *rtc.RoomOptions
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomOptionsStub
+	fakeReturns := fake.storeRoomOptionsReturns
+	fake.recordInvocation("StoreRoomOptions", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomStore) StoreRoomOptionsCallCount() int {
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
+	return len(fake.storeRoomOptionsArgsForCall)
+}
+
+func (fake *FakeRoomStore) StoreRoomOptionsCalls(stub func(context.Context, string, *rtc.RoomOptions) error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = stub
+}
+
+func (fake *FakeRoomStore) StoreRoomOptionsArgsForCall(i int) (context.Context, string, *rtc.RoomOptions) {
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
+	argsForCall := fake.storeRoomOptionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomStore) StoreRoomOptionsReturns(result1 error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = nil
+	fake.storeRoomOptionsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomStore) StoreRoomOptionsReturnsOnCall(i int, result1 error) {
+	fake.storeRoomOptionsMutex.Lock()
+	defer fake.storeRoomOptionsMutex.Unlock()
+	fake.StoreRoomOptionsStub = nil
+	if fake.storeRoomOptionsReturnsOnCall == nil {
+		fake.storeRoomOptionsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomOptionsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRoomStore) UnlockRoom(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.unlockRoomMutex.Lock()
 	ret, specificReturn := fake.unlockRoomReturnsOnCall[len(fake.unlockRoomArgsForCall)]
@@ -799,16 +1430,32 @@ func (fake *FakeRoomStore) Invocations() map[string][][]interface{} {
 	defer fake.listParticipantsMutex.RUnlock()
 	fake.listRoomsMutex.RLock()
 	defer fake.listRoomsMutex.RUnlock()
+	fake.loadConnectionQualityMutex.RLock()
+	defer fake.loadConnectionQualityMutex.RUnlock()
+	fake.loadDataHistoryMutex.RLock()
+	defer fake.loadDataHistoryMutex.RUnlock()
 	fake.loadParticipantMutex.RLock()
 	defer fake.loadParticipantMutex.RUnlock()
 	fake.loadRoomMutex.RLock()
 	defer fake.loadRoomMutex.RUnlock()
+	fake.loadRoomMetadataMutex.RLock()
+	defer fake.loadRoomMetadataMutex.RUnlock()
+	fake.loadRoomOptionsMutex.RLock()
+	defer fake.loadRoomOptionsMutex.RUnlock()
 	fake.lockRoomMutex.RLock()
 	defer fake.lockRoomMutex.RUnlock()
+	fake.storeConnectionQualityMutex.RLock()
+	defer fake.storeConnectionQualityMutex.RUnlock()
+	fake.storeDataHistoryMutex.RLock()
+	defer fake.storeDataHistoryMutex.RUnlock()
 	fake.storeParticipantMutex.RLock()
 	defer fake.storeParticipantMutex.RUnlock()
 	fake.storeRoomMutex.RLock()
 	defer fake.storeRoomMutex.RUnlock()
+	fake.storeRoomMetadataMutex.RLock()
+	defer fake.storeRoomMetadataMutex.RUnlock()
+	fake.storeRoomOptionsMutex.RLock()
+	defer fake.storeRoomOptionsMutex.RUnlock()
 	fake.unlockRoomMutex.RLock()
 	defer fake.unlockRoomMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+// bridges end once their track is no longer published
+const trackBridgeCheckInterval = 2 * time.Second
+
+// startLocalTrackBridge publishes a track of a room on this node into the destination room. a subscriber of the
+// track's receiver passes its packets to a local ingress, which joins the destination room like any other
+// client, so that room may be hosted on another node. keyframe requests from the destination room are passed
+// back to the subscriber
+func (s *RecordingService) startLocalTrackBridge(ctx context.Context, req *TrackBridgeRequest) error {
+	track, err := s.roomManager.GetPublishedTrack(ctx, req.Room, req.Identity, req.TrackSid)
+	if err != nil {
+		return err
+	}
+	quality, err := parseVideoQuality(req.Quality)
+	if err != nil {
+		return err
+	}
+
+	kind := webrtc.RTPCodecTypeAudio
+	if track.Kind() == livekit.TrackType_VIDEO {
+		kind = webrtc.RTPCodecTypeVideo
+	}
+	ingress, err := rtc.NewRTPIngress(rtc.RTPIngressParams{
+		ID:    req.BridgeId,
+		Local: true,
+		Tracks: []rtc.RTPIngressTrack{{
+			ID:    req.BridgeId,
+			Name:  track.Name(),
+			Kind:  kind,
+			Codec: track.Codec(),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	egressID := utils.NewGuid(RTPEgressPrefix)
+	requestKeyFrame, err := track.ForwardRTP(egressID, quality, func(header *rtp.Header, payload []byte) {
+		// fails once the bridge is closing
+		_ = ingress.WriteRTP(req.BridgeId, header, payload)
+	})
+	if err != nil {
+		ingress.Close()
+		return err
+	}
+	ingress.OnKeyFrameRequest(func(string) {
+		requestKeyFrame()
+	})
+	stopEgress := func() {
+		// the track may have been unpublished already
+		_ = track.StopRTPEgress(egressID)
+	}
+
+	done := make(chan struct{})
+	_, err = s.joinIngress(ingress, req.DestinationRoom, ingressParticipant(req.DestinationIdentity), func() {
+		close(done)
+		stopEgress()
+		logger.Infow("track bridge ended",
+			"room", req.Room,
+			"track", req.TrackSid,
+			"destinationRoom", req.DestinationRoom,
+			"bridge", req.BridgeId)
+	})
+	if err != nil {
+		stopEgress()
+		return err
+	}
+	go s.trackBridgeWorker(req, ingress, done)
+
+	logger.Infow("track bridge started",
+		"room", req.Room,
+		"participant", req.Identity,
+		"track", req.TrackSid,
+		"destinationRoom", req.DestinationRoom,
+		"bridge", req.BridgeId)
+	return nil
+}
+
+// trackBridgeWorker closes the bridge once its track is unpublished, or its publisher leaves
+func (s *RecordingService) trackBridgeWorker(req *TrackBridgeRequest, ingress *rtc.RTPIngress, done <-chan struct{}) {
+	ticker := time.NewTicker(trackBridgeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if _, err := s.roomManager.GetPublishedTrack(context.Background(), req.Room, req.Identity, req.TrackSid); err != nil {
+				ingress.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExtMethod handles a JSON request body and returns a value to be encoded as the JSON response
+type ExtMethod func(ctx context.Context, body []byte) (interface{}, error)
+
+// TwirpExtServer serves methods that are not yet part of the protocol under the prefix of a
+// generated twirp server. Extended methods are available to JSON clients only, all other
+// requests are passed on to the twirp server
+type TwirpExtServer struct {
+	livekit.TwirpServer
+	methods map[string]ExtMethod
+}
+
+var (
+	// matches the encoding used by twirp for JSON clients
+	twirpJSONMarshaler = protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+	}
+	twirpJSONUnmarshaler = protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}
+)
+
+func NewTwirpExtServer(server livekit.TwirpServer) *TwirpExtServer {
+	return &TwirpExtServer{
+		TwirpServer: server,
+		methods:     make(map[string]ExtMethod),
+	}
+}
+
+// Handle registers a method, replacing the generated JSON handler if the method already exists
+func (s *TwirpExtServer) Handle(method string, handler ExtMethod) {
+	s.methods[method] = handler
+}
+
+func (s *TwirpExtServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, s.PathPrefix())
+	handler := s.methods[method]
+	if handler == nil || r.Method != http.MethodPost || !isJSONRequest(r) {
+		s.TwirpServer.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		_ = twirp.WriteError(w, twirp.WrapError(twirp.NewError(twirp.Malformed, "could not read request"), err))
+		return
+	}
+
+	res, err := handler(r.Context(), body)
+	if err != nil {
+		_ = twirp.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.Warnw("could not write response", err, "method", method)
+	}
+}
+
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if i := strings.Index(contentType, ";"); i != -1 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(strings.ToLower(contentType)) == "application/json"
+}
+
+// unmarshalExtRequest decodes a JSON request into a protocol message, or into a struct when the
+// request type isn't part of the protocol
+func unmarshalExtRequest(body []byte, req interface{}) error {
+	var err error
+	if msg, ok := req.(proto.Message); ok {
+		err = twirpJSONUnmarshaler.Unmarshal(body, msg)
+	} else {
+		err = json.Unmarshal(body, req)
+	}
+	if err != nil {
+		return twirp.WrapError(twirp.NewError(twirp.Malformed, "could not decode request"), err)
+	}
+	return nil
+}
+
+// extendMessage encodes msg the way twirp would, with additional fields added to it
+func extendMessage(msg proto.Message, fields map[string]interface{}) (map[string]json.RawMessage, error) {
+	data, err := twirpJSONMarshaler.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if res[k], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
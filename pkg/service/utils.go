@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/wire"
@@ -14,6 +16,7 @@ import (
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/webhook"
+	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
 
 	"github.com/livekit/livekit-server/pkg/config"
@@ -22,13 +25,16 @@ import (
 
 var ServiceSet = wire.NewSet(
 	createRedisClient,
-	utils.NewRedisMessageBus,
+	createNATSConn,
+	createMessageBus,
 	createRouter,
 	createStore,
 	CreateKeyProvider,
 	CreateWebhookNotifier,
 	CreateNodeSelector,
+	NewNodeRequests,
 	NewRecordingService,
+	NewRelayService,
 	NewRoomService,
 	NewRTCService,
 	NewLivekitServer,
@@ -36,8 +42,6 @@ var ServiceSet = wire.NewSet(
 	NewTurnServer,
 	config.GetAudioConfig,
 	wire.Bind(new(RoomManager), new(*LocalRoomManager)),
-	wire.Bind(new(livekit.RecordingService), new(*RecordingService)),
-	wire.Bind(new(livekit.RoomService), new(*RoomService)),
 )
 
 func CreateKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
@@ -84,6 +88,19 @@ func CreateNodeSelector(conf *config.Config) routing.NodeSelector {
 		return &routing.SystemLoadSelector{
 			SysloadLimit: conf.NodeSelector.SysloadLimit,
 		}
+	case "regionaware":
+		return &routing.RegionAwareSelector{
+			SystemLoadSelector: routing.SystemLoadSelector{
+				SysloadLimit: conf.NodeSelector.SysloadLimit,
+			},
+			CurrentRegion: conf.Region,
+			Regions:       conf.NodeSelector.Regions,
+		}
+	case "capacity":
+		return &routing.CapacitySelector{
+			Limits:       conf.Limit,
+			SysloadLimit: conf.NodeSelector.SysloadLimit,
+		}
 	default:
 		return &routing.RandomSelector{}
 	}
@@ -109,21 +126,54 @@ func createRedisClient(conf *config.Config) (*redis.Client, error) {
 	return rc, nil
 }
 
-func createRouter(rc *redis.Client, node routing.LocalNode) routing.Router {
+func createNATSConn(conf *config.Config) (*nats.Conn, error) {
+	if !conf.HasNATS() {
+		return nil, nil
+	}
+	logger.Infow("using multi-node routing via NATS", "url", conf.NATS.URL)
+	var opts []nats.Option
+	if conf.NATS.Username != "" {
+		opts = append(opts, nats.UserInfo(conf.NATS.Username, conf.NATS.Password))
+	}
+	nc, err := nats.Connect(conf.NATS.URL, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to NATS")
+	}
+	return nc, nil
+}
+
+func createRouter(conf *config.Config, rc *redis.Client, nc *nats.Conn, node routing.LocalNode) (routing.Router, error) {
+	if nc != nil {
+		return routing.NewNATSRouter(node, nc, conf.Region)
+	}
 	if rc != nil {
-		return routing.NewRedisRouter(node, rc)
+		return routing.NewRedisRouter(node, rc, conf.Region), nil
 	}
 
 	// local routing and store
 	logger.Infow("using single-node routing")
-	return routing.NewLocalRouter(node)
+	return routing.NewLocalRouter(node, conf.Region), nil
 }
 
-func createStore(rc *redis.Client) RoomStore {
+// message bus is only available with NATS or redis
+func createMessageBus(rc *redis.Client, nc *nats.Conn) (utils.MessageBus, error) {
+	if nc != nil {
+		return NewNATSMessageBus(nc)
+	}
 	if rc != nil {
-		return NewRedisRoomStore(rc)
+		return utils.NewRedisMessageBus(rc), nil
 	}
-	return NewLocalRoomStore()
+	return nil, nil
+}
+
+func createStore(rc *redis.Client, nc *nats.Conn) (RoomStore, error) {
+	if nc != nil {
+		return NewNATSRoomStore(nc)
+	}
+	if rc != nil {
+		return NewRedisRoomStore(rc), nil
+	}
+	return NewLocalRoomStore(), nil
 }
 
 func handleError(w http.ResponseWriter, status int, msg string) {
@@ -133,10 +183,17 @@ func handleError(w http.ResponseWriter, status int, msg string) {
 	_, _ = w.Write([]byte(msg))
 }
 
+// safeFileName replaces characters that aren't safe to use in a file name
+func safeFileName(s string) string {
+	return unsafeFileNameChars.ReplaceAllString(s, "_")
+}
+
 func boolValue(s string) bool {
 	return s == "1" || s == "true"
 }
 
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
 func IsValidDomain(domain string) bool {
 	domainRegexp := regexp.MustCompile(`^(?i)[a-z0-9-]+(\.[a-z0-9-]+)+\.?$`)
 	return domainRegexp.MatchString(domain)
@@ -159,3 +216,24 @@ func permissionFromGrant(claim *auth.VideoGrant) *livekit.ParticipantPermission
 	}
 	return p
 }
+
+// throttle returns a function that calls fn interval after it's first called, calls in the meantime are
+// combined into that one. fn is called at most once per interval, and at most interval after each call
+func throttle(interval time.Duration, fn func()) func() {
+	var lock sync.Mutex
+	pending := false
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if pending {
+			return
+		}
+		pending = true
+		time.AfterFunc(interval, func() {
+			lock.Lock()
+			pending = false
+			lock.Unlock()
+			fn()
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	calls := int32(0)
+	fn := throttle(50*time.Millisecond, func() {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	// calls keep arriving, but fn runs while they do
+	for i := 0; i < 15; i++ {
+		fn()
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.GreaterOrEqual(t, atomic.LoadInt32(&calls), int32(2))
+
+	// and once after the last one
+	time.Sleep(100 * time.Millisecond)
+	count := atomic.LoadInt32(&calls)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, count, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/sdp/v3"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const whepPath = "/whep"
+
+var errOfferNotReceiveOnly = errors.New("offer must only receive media")
+
+// ServeWHEP implements WebRTC-HTTP egress: players POST a receive-only offer to watch a room, or a single
+// participant with the participant query parameter, and DELETE the session resource when they are done.
+// tracks published after the player started are sent while it has unused media sections
+func (s *RTCService) ServeWHEP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == whepPath:
+		s.startWHEP(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, whepPath+"/"):
+		s.endHTTPSession(w, r, strings.TrimPrefix(r.URL.Path, whepPath+"/"))
+	case strings.HasPrefix(r.URL.Path, whepPath+"/"):
+		// trickle and ICE restarts are not supported
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *RTCService) startWHEP(w http.ResponseWriter, r *http.Request) {
+	roomName, pi, code, err := s.validate(r)
+	if err != nil {
+		handleError(w, code, err.Error())
+		return
+	}
+	if !pi.Permission.CanSubscribe {
+		handleError(w, http.StatusUnauthorized, rtc.ErrCannotSubscribe.Error())
+		return
+	}
+
+	offer, err := readOffer(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	numAudio, numVideo, err := whepOfferedMedia(offer.SDP)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "invalid offer: "+err.Error())
+		return
+	}
+
+	// subscribe-only, to the tracks picked
+	pi.Permission.CanPublish = false
+	pi.Permission.CanPublishData = false
+	pi.AutoSubscribe = false
+	pi.ProtocolVersion = int32(types.HTTPSubscriberProtocol)
+
+	session, err := s.startHTTPSession(r, roomName, pi)
+	if err != nil {
+		handleError(w, sessionErrorCode(err), "could not start session: "+err.Error())
+		return
+	}
+
+	join, err := session.join()
+	if err != nil {
+		session.Close()
+		handleError(w, http.StatusInternalServerError, "could not join: "+err.Error())
+		return
+	}
+	player := newWHEPPlayer(pi.Identity, r.FormValue("participant"), numAudio, numVideo)
+	trackSids := player.pick(join.OtherParticipants)
+
+	// tracks subscribed before the offer are sent on the transceivers the player offered, in their own codecs
+	var requests []*livekit.SignalRequest
+	if len(trackSids) > 0 {
+		requests = append(requests, subscriptionRequest(trackSids))
+	}
+	answer, err := session.negotiate(offer, livekit.SignalTarget_SUBSCRIBER, requests...)
+	if err != nil {
+		session.Close()
+		handleError(w, http.StatusInternalServerError, "could not negotiate: "+err.Error())
+		return
+	}
+	s.serveHTTPSession(session, func(res *livekit.SignalResponse) {
+		update, ok := res.Message.(*livekit.SignalResponse_Update)
+		if !ok {
+			return
+		}
+		// tracks published later are sent on transceivers that are still unused
+		if trackSids := player.pick(update.Update.Participants); len(trackSids) > 0 {
+			if err := session.reqSink.WriteMessage(subscriptionRequest(trackSids)); err != nil {
+				logger.Warnw("could not subscribe WHEP player", err,
+					"room", roomName,
+					"participant", pi.Identity,
+					"tracks", trackSids)
+			}
+		}
+	})
+
+	logger.Infow("WHEP session started",
+		"room", roomName,
+		"participant", pi.Identity,
+		"session", session.id,
+		"tracks", trackSids)
+
+	w.Header().Set("Content-Type", sdpContentType)
+	w.Header().Set("Location", whepPath+"/"+session.id)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer.SDP))
+}
+
+// whepOfferedMedia counts the audio and video sections of a receive-only offer
+func whepOfferedMedia(offer string) (numAudio int, numVideo int, err error) {
+	parsed := &sdp.SessionDescription{}
+	if err = parsed.Unmarshal([]byte(offer)); err != nil {
+		return
+	}
+
+	for _, m := range parsed.MediaDescriptions {
+		if m.MediaName.Media != "audio" && m.MediaName.Media != "video" {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRecvOnly); !ok {
+			return 0, 0, errOfferNotReceiveOnly
+		}
+		if m.MediaName.Media == "audio" {
+			numAudio++
+		} else {
+			numVideo++
+		}
+	}
+	if numAudio == 0 && numVideo == 0 {
+		err = errOfferNotReceiveOnly
+	}
+	return
+}
+
+// whepPlayer picks the tracks a player is sent, one for each audio and video section of its offer. sections
+// are answered once, so they aren't picked again when their track is unpublished
+type whepPlayer struct {
+	viewer    string
+	publisher string
+	numAudio  int
+	numVideo  int
+	picked    map[string]bool
+}
+
+// newWHEPPlayer creates a player for viewer. only tracks of the publisher identity are picked when it's set
+func newWHEPPlayer(viewer, publisher string, numAudio, numVideo int) *whepPlayer {
+	return &whepPlayer{
+		viewer:    viewer,
+		publisher: publisher,
+		numAudio:  numAudio,
+		numVideo:  numVideo,
+		picked:    make(map[string]bool),
+	}
+}
+
+// pick picks published tracks that haven't been, from participants in the order they joined, while the
+// player has sections left for them
+func (p *whepPlayer) pick(participants []*livekit.ParticipantInfo) []string {
+	sorted := make([]*livekit.ParticipantInfo, 0, len(participants))
+	for _, pi := range participants {
+		if pi.Identity == p.viewer || (p.publisher != "" && pi.Identity != p.publisher) ||
+			pi.State == livekit.ParticipantInfo_DISCONNECTED {
+			continue
+		}
+		sorted = append(sorted, pi)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].JoinedAt < sorted[j].JoinedAt
+	})
+
+	var trackSids []string
+	for _, pi := range sorted {
+		for _, track := range pi.Tracks {
+			if p.picked[track.Sid] {
+				continue
+			}
+			switch {
+			case track.Type == livekit.TrackType_AUDIO && p.numAudio > 0:
+				p.numAudio--
+			case track.Type == livekit.TrackType_VIDEO && p.numVideo > 0:
+				p.numVideo--
+			default:
+				continue
+			}
+			p.picked[track.Sid] = true
+			trackSids = append(trackSids, track.Sid)
+		}
+	}
+	return trackSids
+}
+
+func subscriptionRequest(trackSids []string) *livekit.SignalRequest {
+	return &livekit.SignalRequest{
+		Message: &livekit.SignalRequest_Subscription{
+			Subscription: &livekit.UpdateSubscription{
+				TrackSids: trackSids,
+				Subscribe: true,
+			},
+		},
+	}
+}
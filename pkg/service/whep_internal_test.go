@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWHEPOfferedMedia(t *testing.T) {
+	numAudio, numVideo, err := whepOfferedMedia(
+		"v=0\r\no=- 1 2 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n" +
+			"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\nc=IN IP4 0.0.0.0\r\na=mid:0\r\na=recvonly\r\n" +
+			"m=video 9 UDP/TLS/RTP/SAVPF 96\r\nc=IN IP4 0.0.0.0\r\na=mid:1\r\na=recvonly\r\n" +
+			"m=video 9 UDP/TLS/RTP/SAVPF 96\r\nc=IN IP4 0.0.0.0\r\na=mid:2\r\na=recvonly\r\n")
+	require.NoError(t, err)
+	require.Equal(t, 1, numAudio)
+	require.Equal(t, 2, numVideo)
+
+	_, _, err = whepOfferedMedia(whipOffer)
+	require.Equal(t, errOfferNotReceiveOnly, err)
+}
+
+func TestWHEPPlayer(t *testing.T) {
+	participants := []*livekit.ParticipantInfo{
+		{
+			Identity: "late",
+			JoinedAt: 20,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_late_audio", Type: livekit.TrackType_AUDIO},
+				{Sid: "TR_late_video", Type: livekit.TrackType_VIDEO},
+			},
+		},
+		{
+			Identity: "early",
+			JoinedAt: 10,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_early_video", Type: livekit.TrackType_VIDEO},
+			},
+		},
+		{
+			Identity: "viewer",
+			JoinedAt: 5,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_viewer_audio", Type: livekit.TrackType_AUDIO},
+			},
+		},
+	}
+
+	t.Run("picks tracks in join order", func(t *testing.T) {
+		player := newWHEPPlayer("viewer", "", 1, 1)
+		require.Equal(t, []string{"TR_early_video", "TR_late_audio"}, player.pick(participants))
+	})
+
+	t.Run("picks tracks of the publisher", func(t *testing.T) {
+		player := newWHEPPlayer("viewer", "late", 1, 1)
+		require.Equal(t, []string{"TR_late_audio", "TR_late_video"}, player.pick(participants))
+	})
+
+	t.Run("no matching tracks", func(t *testing.T) {
+		player := newWHEPPlayer("viewer", "nobody", 1, 1)
+		require.Empty(t, player.pick(participants))
+	})
+
+	t.Run("picks tracks published later into unused sections", func(t *testing.T) {
+		player := newWHEPPlayer("viewer", "", 1, 2)
+		require.Equal(t, []string{"TR_early_video", "TR_late_audio", "TR_late_video"}, player.pick(participants))
+
+		updated := &livekit.ParticipantInfo{
+			Identity: "early",
+			JoinedAt: 10,
+			Tracks: []*livekit.TrackInfo{
+				{Sid: "TR_early_video", Type: livekit.TrackType_VIDEO},
+				{Sid: "TR_early_audio", Type: livekit.TrackType_AUDIO},
+			},
+		}
+		require.Empty(t, player.pick([]*livekit.ParticipantInfo{updated}))
+
+		player = newWHEPPlayer("viewer", "", 2, 2)
+		player.pick(participants)
+		require.Equal(t, []string{"TR_early_audio"}, player.pick([]*livekit.ParticipantInfo{updated}))
+	})
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/livekit/protocol/utils"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	whipPath          = "/whip"
+	sdpContentType    = "application/sdp"
+	maxSDPSize        = 64 * 1024
+	httpSessionPrefix = "HS_"
+	// clients of HTTP sessions don't use data channels, and never take offers from the server
+	httpSessionProtocolVersion = 2
+)
+
+// ServeWHIP implements WebRTC-HTTP ingestion: encoders POST an offer to publish into a room, and DELETE the
+// session resource when they are done
+func (s *RTCService) ServeWHIP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == whipPath:
+		s.startWHIP(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, whipPath+"/"):
+		s.endHTTPSession(w, r, strings.TrimPrefix(r.URL.Path, whipPath+"/"))
+	case strings.HasPrefix(r.URL.Path, whipPath+"/"):
+		// trickle and ICE restarts are not supported
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *RTCService) startWHIP(w http.ResponseWriter, r *http.Request) {
+	roomName, pi, code, err := s.validate(r)
+	if err != nil {
+		handleError(w, code, err.Error())
+		return
+	}
+	if !pi.Permission.CanPublish {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied.Error())
+		return
+	}
+
+	offer, err := readOffer(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tracks, err := whipTracks(offer.SDP)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "invalid offer: "+err.Error())
+		return
+	}
+	if len(tracks) == 0 {
+		handleError(w, http.StatusBadRequest, "offer does not publish any tracks")
+		return
+	}
+
+	// publish-only
+	pi.Permission.CanSubscribe = false
+	pi.AutoSubscribe = false
+	pi.ProtocolVersion = httpSessionProtocolVersion
+
+	session, err := s.startHTTPSession(r, roomName, pi)
+	if err != nil {
+		handleError(w, sessionErrorCode(err), "could not start session: "+err.Error())
+		return
+	}
+
+	// tracks are added on the client's behalf, so they're known when the offer is handled
+	answer, err := session.negotiate(offer, livekit.SignalTarget_PUBLISHER, addTrackRequests(tracks)...)
+	if err != nil {
+		session.Close()
+		handleError(w, http.StatusInternalServerError, "could not negotiate: "+err.Error())
+		return
+	}
+	s.serveHTTPSession(session, nil)
+
+	logger.Infow("WHIP session started",
+		"room", roomName,
+		"participant", pi.Identity,
+		"session", session.id,
+		"tracks", len(tracks))
+
+	w.Header().Set("Content-Type", sdpContentType)
+	w.Header().Set("Location", whipPath+"/"+session.id)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer.SDP))
+}
+
+// whipTracks finds the tracks published by an offer. track IDs from msid are used as client IDs, since
+// they're what the track is identified by when it arrives
+func whipTracks(offer string) ([]*livekit.AddTrackRequest, error) {
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(offer)); err != nil {
+		return nil, err
+	}
+
+	var tracks []*livekit.AddTrackRequest
+	for _, m := range parsed.MediaDescriptions {
+		var kind livekit.TrackType
+		switch m.MediaName.Media {
+		case "audio":
+			kind = livekit.TrackType_AUDIO
+		case "video":
+			kind = livekit.TrackType_VIDEO
+		default:
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyRecvOnly); ok {
+			continue
+		}
+		if _, ok := m.Attribute(sdp.AttrKeyInactive); ok {
+			continue
+		}
+
+		cid, _ := m.Attribute(sdp.AttrKeyMID)
+		if msid, ok := m.Attribute(sdp.AttrKeyMsid); ok {
+			if parts := strings.Fields(msid); len(parts) == 2 {
+				cid = parts[1]
+			}
+		}
+		tracks = append(tracks, &livekit.AddTrackRequest{
+			Cid:  cid,
+			Name: m.MediaName.Media,
+			Type: kind,
+		})
+	}
+	return tracks, nil
+}
+
+func addTrackRequests(tracks []*livekit.AddTrackRequest) []*livekit.SignalRequest {
+	requests := make([]*livekit.SignalRequest, 0, len(tracks))
+	for _, track := range tracks {
+		requests = append(requests, &livekit.SignalRequest{
+			Message: &livekit.SignalRequest_AddTrack{
+				AddTrack: track,
+			},
+		})
+	}
+	return requests
+}
+
+func readOffer(r *http.Request) (webrtc.SessionDescription, error) {
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, sdpContentType) {
+		return webrtc.SessionDescription{}, errInvalidContentType
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSDPSize))
+	if err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	return webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  string(body),
+	}, nil
+}
+
+// startHTTPSession joins the participant to the room, without a WebSocket
+func (s *RTCService) startHTTPSession(r *http.Request, roomName string, pi routing.ParticipantInit) (*httpSignalSession, error) {
+	// create room if it doesn't exist, also assigns an RTC node for the room
+	ctx := regionContext(r)
+	if _, err := s.roomManager.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: roomName}); err != nil {
+		return nil, err
+	}
+	nodeId, err := s.roomManager.GetNodeForParticipant(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	pi.RTCNodeId = nodeId
+
+	// the session outlives the request that started it
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	_, reqSink, resSource, err := s.router.StartParticipantSignal(sessionCtx, roomName, pi)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return newHTTPSignalSession(utils.NewGuid(httpSessionPrefix), roomName, pi.Identity, pi.RTCNodeId, reqSink, resSource, cancel), nil
+}
+
+// serveHTTPSession makes a negotiated session available to end, and drains its responses until it's closed.
+// it's only started once negotiated, so the answer isn't taken from negotiate
+func (s *RTCService) serveHTTPSession(session *httpSignalSession, onResponse func(res *livekit.SignalResponse)) {
+	s.sessionLock.Lock()
+	s.httpSessions[session.id] = session
+	s.sessionLock.Unlock()
+
+	// HTTP clients can't reconnect, so the session ends when the RTC node dies. the room is still failed over
+	unwatch := s.nodeWatcher.watch(session.roomName, session.rtcNodeId, session.id, session.Close)
+
+	go session.run(onResponse, func() {
+		unwatch()
+		s.sessionLock.Lock()
+		delete(s.httpSessions, session.id)
+		s.sessionLock.Unlock()
+		logger.Infow("HTTP session closed",
+			"room", session.roomName,
+			"participant", session.identity,
+			"session", session.id)
+	})
+}
+
+// endHTTPSession closes a session with the token it was started with
+func (s *RTCService) endHTTPSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	claims := GetGrants(r.Context())
+	if claims == nil || claims.Video == nil {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied.Error())
+		return
+	}
+
+	s.sessionLock.Lock()
+	session := s.httpSessions[sessionID]
+	s.sessionLock.Unlock()
+
+	if session == nil {
+		handleError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if session.identity != claims.Identity {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied.Error())
+		return
+	}
+	session.Close()
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	livekit "github.com/livekit/protocol/proto"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const whipOffer = "v=0\r\n" +
+	"o=- 4215775240449105457 2 IN IP4 127.0.0.1\r\n" +
+	"s=-\r\n" +
+	"t=0 0\r\n" +
+	"a=group:BUNDLE 0 1\r\n" +
+	"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=mid:0\r\n" +
+	"a=msid:stream audio-track\r\n" +
+	"a=sendonly\r\n" +
+	"a=rtpmap:111 opus/48000/2\r\n" +
+	"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=mid:1\r\n" +
+	"a=sendonly\r\n" +
+	"a=rtpmap:96 VP8/90000\r\n"
+
+func TestWHIPTracks(t *testing.T) {
+	tracks, err := whipTracks(whipOffer)
+	require.NoError(t, err)
+	require.Len(t, tracks, 2)
+
+	require.Equal(t, "audio-track", tracks[0].Cid)
+	require.Equal(t, livekit.TrackType_AUDIO, tracks[0].Type)
+	// falls back to mid without msid
+	require.Equal(t, "1", tracks[1].Cid)
+	require.Equal(t, livekit.TrackType_VIDEO, tracks[1].Type)
+
+	_, err = whipTracks("x=not an sdp\r\n")
+	require.Error(t, err)
+}
+
+func TestAddCandidates(t *testing.T) {
+	empty := ""
+	answer, err := addCandidates(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  whipOffer,
+	}, []webrtc.ICECandidateInit{
+		{Candidate: "candidate:1 1 udp 2130706431 10.0.0.1 7882 typ host", SDPMid: &empty},
+	})
+	require.NoError(t, err)
+	require.Equal(t, webrtc.SDPTypeAnswer, answer.Type)
+
+	// candidates without a mid belong to every section
+	require.Equal(t, 2, strings.Count(answer.SDP, "a=candidate:1 1 udp 2130706431 10.0.0.1 7882 typ host\r\n"))
+	require.Equal(t, 2, strings.Count(answer.SDP, "a=end-of-candidates\r\n"))
+}
+
+func TestHTTPSignalSessionRenegotiate(t *testing.T) {
+	reqChan := routing.NewMessageChannel()
+	resChan := routing.NewMessageChannel()
+	session := newHTTPSignalSession("HS_test", "room", "identity", "", reqChan, resChan, func() {})
+	go session.run(nil, func() {})
+	defer session.Close()
+
+	go func() {
+		for msg := range reqChan.ReadChan() {
+			req := msg.(*livekit.SignalRequest)
+			if offer, ok := req.Message.(*livekit.SignalRequest_Offer); ok {
+				_ = resChan.WriteMessage(&livekit.SignalResponse{
+					Message: &livekit.SignalResponse_Answer{
+						Answer: &livekit.SessionDescription{Type: "answer", Sdp: offer.Offer.Sdp},
+					},
+				})
+			}
+		}
+	}()
+
+	answer, err := session.renegotiate(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: whipOffer},
+		addTrackRequests([]*livekit.AddTrackRequest{{Cid: "audio-track"}})...)
+	require.NoError(t, err)
+	require.Equal(t, webrtc.SDPTypeAnswer, answer.Type)
+	require.Equal(t, whipOffer, answer.SDP)
+}
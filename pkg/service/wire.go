@@ -20,6 +20,7 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	wire.Build(
 		wire.NewSet(
 			createRedisClient,
+			createNATSConn,
 			createRouter,
 		),
 	)
@@ -8,7 +8,6 @@ package service
 import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
-	"github.com/livekit/protocol/utils"
 )
 
 // Injectors from wire.go:
@@ -18,8 +17,18 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	roomStore := createStore(client)
-	router := createRouter(client, currentNode)
+	conn, err := createNATSConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	roomStore, err := createStore(client, conn)
+	if err != nil {
+		return nil, err
+	}
+	router, err := createRouter(conf, client, conn, currentNode)
+	if err != nil {
+		return nil, err
+	}
 	nodeSelector := CreateNodeSelector(conf)
 	keyProvider, err := CreateKeyProvider(conf)
 	if err != nil {
@@ -33,18 +42,23 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	roomService, err := NewRoomService(localRoomManager, router)
+	messageBus, err := createMessageBus(client, conn)
+	if err != nil {
+		return nil, err
+	}
+	nodeRequests := NewNodeRequests(messageBus, router, currentNode)
+	recordingService := NewRecordingService(messageBus, localRoomManager, router, currentNode, conf, nodeRequests)
+	relayService := NewRelayService(messageBus, localRoomManager, router, currentNode, nodeRequests, recordingService)
+	roomService, err := NewRoomService(localRoomManager, router, conf, nodeRequests)
 	if err != nil {
 		return nil, err
 	}
-	messageBus := utils.NewRedisMessageBus(client)
-	recordingService := NewRecordingService(messageBus)
 	rtcService := NewRTCService(conf, localRoomManager, router, currentNode)
 	server, err := NewTurnServer(conf, roomStore, currentNode)
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, recordingService, rtcService, keyProvider, router, localRoomManager, server, currentNode)
+	livekitServer, err := NewLivekitServer(conf, roomService, recordingService, relayService, nodeRequests, rtcService, keyProvider, router, localRoomManager, server, currentNode)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +70,13 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
-	router := createRouter(client, currentNode)
+	conn, err := createNATSConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	router, err := createRouter(conf, client, conn, currentNode)
+	if err != nil {
+		return nil, err
+	}
 	return router, nil
 }
@@ -40,6 +40,11 @@ var (
 		Subsystem: "fir",
 		Name:      "total",
 	}, promLabels)
+	promDataPacketDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: livekitNamespace,
+		Subsystem: "data_packet",
+		Name:      "dropped_total",
+	}, []string{"reason"})
 )
 
 func initPacketStats() {
@@ -48,6 +53,7 @@ func initPacketStats() {
 	prometheus.MustRegister(promNackTotal)
 	prometheus.MustRegister(promPliTotal)
 	prometheus.MustRegister(promFirTotal)
+	prometheus.MustRegister(promDataPacketDropped)
 }
 
 type PacketStats struct {
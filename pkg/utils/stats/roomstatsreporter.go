@@ -112,6 +112,11 @@ func (r *RoomStatsReporter) SubSubscribedTrack(kind string) {
 	atomic.AddInt32(&atomicTrackSubscribedTotal, -1)
 }
 
+// DataPacketDropped counts a data packet that wasn't forwarded, by the limit it went over
+func (r *RoomStatsReporter) DataPacketDropped(reason string) {
+	promDataPacketDropped.WithLabelValues(reason).Add(1)
+}
+
 func updateCurrentNodeRoomStats(nodeStats *livekit.NodeStats) {
 	nodeStats.NumClients = atomic.LoadInt32(&atomicParticipantTotal)
 	nodeStats.NumRooms = atomic.LoadInt32(&atomicRoomTotal)
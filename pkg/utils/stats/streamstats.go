@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/packetio"
+)
+
+// StreamStats keeps counters for each RTP stream of a PeerConnection, keyed by SSRC
+type StreamStats struct {
+	lock    sync.Mutex
+	streams map[uint32]*streamCounters
+}
+
+// StreamSnapshot is a copy of the counters of a single stream
+type StreamSnapshot struct {
+	Packets   uint64
+	Bytes     uint64
+	Bitrate   uint64
+	NackCount uint64
+	PLICount  uint64
+	FIRCount  uint64
+	// from the latest reception report
+	FractionLost uint8
+	TotalLost    uint32
+	Jitter       uint32
+}
+
+type streamCounters struct {
+	StreamSnapshot
+	bitrateBytes uint64
+	bitrateAt    time.Time
+}
+
+func NewStreamStats() *StreamStats {
+	return &StreamStats{
+		streams: make(map[uint32]*streamCounters),
+	}
+}
+
+func (s *StreamStats) AddPacket(ssrc uint32, bytes int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.getOrCreate(ssrc)
+	c.Packets++
+	c.Bytes += uint64(bytes)
+}
+
+// HandleRTCP records feedback about any stream
+func (s *StreamStats) HandleRTCP(pkts []rtcp.Packet) {
+	s.handleRTCP(pkts, func(uint32) bool { return true })
+}
+
+// Get returns counters for a stream, bitrate is updated at most once a second
+func (s *StreamStats) Get(ssrc uint32) (StreamSnapshot, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.streams[ssrc]
+	if c == nil {
+		return StreamSnapshot{}, false
+	}
+
+	now := time.Now()
+	if elapsed := now.Sub(c.bitrateAt); elapsed >= time.Second {
+		if !c.bitrateAt.IsZero() {
+			c.Bitrate = uint64(float64(c.Bytes-c.bitrateBytes) * 8 / elapsed.Seconds())
+		}
+		c.bitrateBytes = c.Bytes
+		c.bitrateAt = now
+	}
+	return c.StreamSnapshot, true
+}
+
+func (s *StreamStats) handleRTCP(pkts []rtcp.Packet, include func(ssrc uint32) bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, pkt := range pkts {
+		switch pkt := pkt.(type) {
+		case *rtcp.ReceiverReport:
+			s.handleReports(pkt.Reports, include)
+		case *rtcp.SenderReport:
+			s.handleReports(pkt.Reports, include)
+		case *rtcp.TransportLayerNack:
+			if include(pkt.MediaSSRC) {
+				s.getOrCreate(pkt.MediaSSRC).NackCount++
+			}
+		case *rtcp.PictureLossIndication:
+			if include(pkt.MediaSSRC) {
+				s.getOrCreate(pkt.MediaSSRC).PLICount++
+			}
+		case *rtcp.FullIntraRequest:
+			for _, entry := range pkt.FIR {
+				if include(entry.SSRC) {
+					s.getOrCreate(entry.SSRC).FIRCount++
+				}
+			}
+		}
+	}
+}
+
+// assumes lock is held
+func (s *StreamStats) handleReports(reports []rtcp.ReceptionReport, include func(ssrc uint32) bool) {
+	for _, report := range reports {
+		if !include(report.SSRC) {
+			continue
+		}
+		c := s.getOrCreate(report.SSRC)
+		c.FractionLost = report.FractionLost
+		c.TotalLost = report.TotalLost
+		c.Jitter = report.Jitter
+	}
+}
+
+// assumes lock is held
+func (s *StreamStats) getOrCreate(ssrc uint32) *streamCounters {
+	c := s.streams[ssrc]
+	if c == nil {
+		c = &streamCounters{}
+		s.streams[ssrc] = c
+	}
+	return c
+}
+
+// StreamStatsBufferWrapper wraps a buffer factory to count incoming packets and feedback for each stream
+type StreamStatsBufferWrapper struct {
+	CreateBufferFunc func(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser
+	Stats            *StreamStats
+}
+
+func (w *StreamStatsBufferWrapper) CreateBuffer(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
+	return &streamStatsWriter{
+		ReadWriteCloser: w.CreateBufferFunc(packetType, ssrc),
+		packetType:      packetType,
+		ssrc:            ssrc,
+		stats:           w.Stats,
+	}
+}
+
+type streamStatsWriter struct {
+	io.ReadWriteCloser
+	packetType packetio.BufferPacketType
+	ssrc       uint32
+	stats      *StreamStats
+}
+
+func (w *streamStatsWriter) Write(p []byte) (n int, err error) {
+	if w.packetType == packetio.RTPBufferPacket {
+		w.stats.AddPacket(w.ssrc, len(p))
+	} else if pkts, err := rtcp.Unmarshal(p); err == nil {
+		// compound packets are delivered to every stream they reference, only count what's about this one
+		w.stats.handleRTCP(pkts, func(ssrc uint32) bool { return ssrc == w.ssrc })
+	}
+	return w.ReadWriteCloser.Write(p)
+}
+
+// StreamStatsInterceptor counts outgoing packets and feedback for each stream
+type StreamStatsInterceptor struct {
+	interceptor.NoOp
+	stats *StreamStats
+}
+
+func NewStreamStatsInterceptor(stats *StreamStats) *StreamStatsInterceptor {
+	return &StreamStatsInterceptor{
+		stats: stats,
+	}
+}
+
+func (s *StreamStatsInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		s.stats.HandleRTCP(pkts)
+		return writer.Write(pkts, attributes)
+	})
+}
+
+func (s *StreamStatsInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		s.stats.AddPacket(info.SSRC, len(payload))
+		return writer.Write(header, payload, attributes)
+	})
+}
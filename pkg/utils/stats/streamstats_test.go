@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamStats(t *testing.T) {
+	t.Run("counts packets and feedback", func(t *testing.T) {
+		s := NewStreamStats()
+		s.AddPacket(1, 100)
+		s.AddPacket(1, 200)
+		s.HandleRTCP([]rtcp.Packet{
+			&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+				{SSRC: 1, FractionLost: 64, TotalLost: 10, Jitter: 90},
+			}},
+			&rtcp.TransportLayerNack{MediaSSRC: 1},
+			&rtcp.PictureLossIndication{MediaSSRC: 1},
+			&rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: 1}}},
+		})
+
+		snapshot, ok := s.Get(1)
+		require.True(t, ok)
+		require.EqualValues(t, 2, snapshot.Packets)
+		require.EqualValues(t, 300, snapshot.Bytes)
+		require.EqualValues(t, 1, snapshot.NackCount)
+		require.EqualValues(t, 1, snapshot.PLICount)
+		require.EqualValues(t, 1, snapshot.FIRCount)
+		require.EqualValues(t, 64, snapshot.FractionLost)
+		require.EqualValues(t, 10, snapshot.TotalLost)
+		require.EqualValues(t, 90, snapshot.Jitter)
+
+		_, ok = s.Get(2)
+		require.False(t, ok)
+	})
+
+	t.Run("compound packets are counted once per stream", func(t *testing.T) {
+		s := NewStreamStats()
+		pkts := []rtcp.Packet{
+			&rtcp.PictureLossIndication{MediaSSRC: 1},
+			&rtcp.PictureLossIndication{MediaSSRC: 2},
+		}
+		// delivered to the buffers of both streams
+		for _, ssrc := range []uint32{1, 2} {
+			ssrc := ssrc
+			s.handleRTCP(pkts, func(target uint32) bool { return target == ssrc })
+		}
+
+		for _, ssrc := range []uint32{1, 2} {
+			snapshot, ok := s.Get(ssrc)
+			require.True(t, ok)
+			require.EqualValues(t, 1, snapshot.PLICount)
+		}
+	})
+}
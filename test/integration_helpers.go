@@ -13,14 +13,17 @@ import (
 	"github.com/livekit/protocol/logger"
 	livekit "github.com/livekit/protocol/proto"
 	"github.com/livekit/protocol/utils"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/twitchtv/twirp"
 
+	testclient "github.com/livekit/livekit-server/pkg/client"
 	"github.com/livekit/livekit-server/pkg/config"
 	serverlogger "github.com/livekit/livekit-server/pkg/logger"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/livekit-server/pkg/testutils"
-	testclient "github.com/livekit/livekit-server/test/client"
 )
 
 const (
@@ -197,6 +200,11 @@ func createRTCClient(name string, port int, opts *testclient.Options) *testclien
 	if err != nil {
 		panic(err)
 	}
+	c.OnRTPPacket = func(_ *webrtc.TrackRemote, pkt *rtp.Packet) {
+		receivedLock.Lock()
+		receivedBytes[c] += uint64(pkt.MarshalSize())
+		receivedLock.Unlock()
+	}
 
 	go c.Run()
 
@@ -231,7 +239,52 @@ func createRoomToken() string {
 	return t
 }
 
-func stopWriters(writers ...*testclient.TrackWriter) {
+var (
+	receivedLock sync.Mutex
+	// bytes of RTP received by each client
+	receivedBytes = make(map[*testclient.RTCClient]uint64)
+)
+
+func bytesReceived(c *testclient.RTCClient) uint64 {
+	receivedLock.Lock()
+	defer receivedLock.Unlock()
+	return receivedBytes[c]
+}
+
+// nullTrackWriter writes null samples to a published track until it's stopped
+type nullTrackWriter struct {
+	cancel context.CancelFunc
+}
+
+func publishNullTrack(c *testclient.RTCClient, mime, id, label string) (*nullTrackWriter, error) {
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: mime}, id, label)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.PublishTrack(track); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sample := media.Sample{Data: []byte{0x0, 0xff, 0xff, 0xff, 0xff}, Duration: 30 * time.Millisecond}
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				_ = track.WriteSample(sample)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &nullTrackWriter{cancel: cancel}, nil
+}
+
+func (w *nullTrackWriter) Stop() {
+	w.cancel()
+}
+
+func stopWriters(writers ...*nullTrackWriter) {
 	for _, w := range writers {
 		w.Stop()
 	}
@@ -31,7 +31,7 @@ func TestMultiNodeRouting(t *testing.T) {
 	defer stopClients(c1, c2)
 
 	// c1 publishing, and c2 receiving
-	t1, err := c1.AddStaticTrack("audio/opus", "audio", "webcam")
+	t1, err := publishNullTrack(c1, "audio/opus", "audio", "webcam")
 	require.NoError(t, err)
 	if t1 != nil {
 		defer t1.Stop()
@@ -7,8 +7,8 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/stretchr/testify/require"
 
+	testclient "github.com/livekit/livekit-server/pkg/client"
 	"github.com/livekit/livekit-server/pkg/testutils"
-	testclient "github.com/livekit/livekit-server/test/client"
 )
 
 // a scenario with lots of clients connecting, publishing, and leaving at random periods
@@ -97,7 +97,7 @@ func scenarioReceiveBeforePublish(t *testing.T) {
 
 	// c2 should see some bytes flowing through
 	success := testutils.WithTimeout(t, "waiting to receive bytes on c2", func() bool {
-		return c2.BytesReceived() > 20
+		return bytesReceived(c2) > 20
 	})
 	if !success {
 		t.FailNow()
@@ -129,16 +129,16 @@ func scenarioWSReconnect(t *testing.T) {
 	// c1 publishes track, but disconnects websockets and reconnects
 }
 
-func publishTracksForClients(t *testing.T, clients ...*testclient.RTCClient) []*testclient.TrackWriter {
+func publishTracksForClients(t *testing.T, clients ...*testclient.RTCClient) []*nullTrackWriter {
 	logger.Infow("publishing tracks for clients")
-	var writers []*testclient.TrackWriter
+	var writers []*nullTrackWriter
 	for i, _ := range clients {
 		c := clients[i]
-		tw, err := c.AddStaticTrack("audio/opus", "audio", "webcam")
+		tw, err := publishNullTrack(c, "audio/opus", "audio", "webcam")
 		require.NoError(t, err)
 
 		writers = append(writers, tw)
-		tw, err = c.AddStaticTrack("video/vp8", "video", "webcam")
+		tw, err = publishNullTrack(c, "video/vp8", "video", "webcam")
 		require.NoError(t, err)
 		writers = append(writers, tw)
 	}
@@ -8,8 +8,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	testclient "github.com/livekit/livekit-server/pkg/client"
 	"github.com/livekit/livekit-server/pkg/testutils"
-	testclient "github.com/livekit/livekit-server/test/client"
 )
 
 func TestClientCouldConnect(t *testing.T) {
@@ -49,10 +49,10 @@ func TestSinglePublisher(t *testing.T) {
 	waitUntilConnected(t, c1, c2)
 
 	// publish a track and ensure clients receive it ok
-	t1, err := c1.AddStaticTrack("audio/opus", "audio", "webcam")
+	t1, err := publishNullTrack(c1, "audio/opus", "audio", "webcam")
 	require.NoError(t, err)
 	defer t1.Stop()
-	t2, err := c1.AddStaticTrack("video/vp8", "video", "webcam")
+	t2, err := publishNullTrack(c1, "video/vp8", "video", "webcam")
 	require.NoError(t, err)
 	defer t2.Stop()
 
@@ -134,7 +134,7 @@ func TestAutoSubDisabled(t *testing.T) {
 	waitUntilConnected(t, c1, c2)
 
 	// c2 should not receive any tracks c1 publishes
-	t1, err := c1.AddStaticTrack("audio/opus", "audio", "webcam")
+	t1, err := publishNullTrack(c1, "audio/opus", "audio", "webcam")
 	require.NoError(t, err)
 	defer t1.Stop()
 